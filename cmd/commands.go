@@ -0,0 +1,405 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// adminCommand is an operational task run from the command line instead of starting the service.
+//
+// Admin commands call the interactor with a system identity, so they are checked and audited like requests.
+type adminCommand struct {
+	name        string
+	description string
+	run         func(ctx context.Context, i interaction.Interactor, args []string, out io.Writer) error
+}
+
+var errCommandUsage = errors.New("invalid arguments")
+
+var adminCommands = []adminCommand{
+	{
+		name:        "list-transactions",
+		description: "lists the transactions of a debitor, in any status",
+		run:         listTransactions,
+	},
+	{
+		name:        "show-transaction",
+		description: "shows all fields of a transaction",
+		run:         showTransaction,
+	},
+	{
+		name:        "book-payment",
+		description: "books a valid payment, e.g. for a bank transfer that arrived",
+		run:         bookPayment,
+	},
+	{
+		name:        "recompute-balance",
+		description: "adds up the valid transactions of a debitor, and compares with the database",
+		run:         recomputeBalance,
+	},
+	{
+		name:        "void-paylinks",
+		description: "marks the tentative payments (paylinks) of a debitor deleted",
+		run:         voidPaylinks,
+	},
+	{
+		name:        "resend-payments-changed",
+		description: "notifies the attendee service again that the payments of a debitor changed",
+		run:         resendPaymentsChanged,
+	},
+}
+
+func findAdminCommand(name string) (adminCommand, bool) {
+	for _, c := range adminCommands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return adminCommand{}, false
+}
+
+// systemIdentity names the operating system user that runs the command.
+func systemIdentity() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	return "cli/" + name
+}
+
+// runAdminCommand runs the command and returns the exit code.
+func runAdminCommand(ctx context.Context, logger logging.Logger, i interaction.Interactor, c adminCommand, args []string) int {
+	identity := systemIdentity()
+	reqID := uuid.NewString()[:8]
+
+	ctx = interaction.WithSystemIdentity(ctx, identity)
+	ctx = logging.ChildCtxWithRequestID(ctx, reqID)
+
+	logger.Info("running command %s %q as system identity %s, request id %s", c.name, args, identity, reqID)
+
+	err := c.run(ctx, i, args, os.Stdout)
+	if errors.Is(err, errCommandUsage) {
+		return exitUsage
+	}
+	if err != nil {
+		logger.Error("command %s failed. [error]: %v", c.name, err)
+		return exitError
+	}
+
+	return exitOK
+}
+
+func newCommandFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+func parseCommandFlags(fs *flag.FlagSet, args []string, required ...string) error {
+	if err := fs.Parse(args); err != nil {
+		return errCommandUsage
+	}
+
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments %v\n", fs.Args())
+		fs.Usage()
+		return errCommandUsage
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, name := range required {
+		if !set[name] {
+			fmt.Fprintf(fs.Output(), "-%s is required\n", name)
+			fs.Usage()
+			return errCommandUsage
+		}
+	}
+
+	return nil
+}
+
+func listTransactions(ctx context.Context, i interaction.Interactor, args []string, out io.Writer) error {
+	fs := newCommandFlagSet("list-transactions")
+	debitorID := fs.Int64("debitor", 0, "the debitor id (required)")
+	event := fs.String("event", "", "only list the transactions of this event")
+	if err := parseCommandFlags(fs, args, "debitor"); err != nil {
+		return err
+	}
+
+	transactions, err := i.GetTransactionsForDebitor(ctx, entities.TransactionQuery{DebitorID: *debitorID, Event: *event})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TRANSACTION\tEVENT\tTYPE\tMETHOD\tSTATUS\tAMOUNT\tEFFECTIVE\tCOMMENT")
+	for _, t := range transactions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.TransactionID, t.Event, t.TransactionType, t.PaymentMethod, t.TransactionStatus,
+			formatAmount(t.Amount), formatDate(t.EffectiveDate), t.Comment)
+	}
+	return w.Flush()
+}
+
+func showTransaction(ctx context.Context, i interaction.Interactor, args []string, out io.Writer) error {
+	fs := newCommandFlagSet("show-transaction")
+	transactionID := fs.String("id", "", "the transaction id (required)")
+	if err := parseCommandFlags(fs, args, "id"); err != nil {
+		return err
+	}
+
+	transactions, err := i.GetTransactionsForDebitor(ctx, entities.TransactionQuery{TransactionIdentifier: *transactionID})
+	if err != nil {
+		return err
+	}
+	if len(transactions) == 0 {
+		return fmt.Errorf("transaction %s not found", *transactionID)
+	}
+
+	t := transactions[0]
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "transaction\t%s\n", t.TransactionID)
+	fmt.Fprintf(w, "debitor\t%d\n", t.DebitorID)
+	fmt.Fprintf(w, "event\t%s\n", t.Event)
+	fmt.Fprintf(w, "type\t%s\n", t.TransactionType)
+	fmt.Fprintf(w, "method\t%s\n", t.PaymentMethod)
+	fmt.Fprintf(w, "status\t%s\n", t.TransactionStatus)
+	fmt.Fprintf(w, "amount\t%s\n", formatAmount(t.Amount))
+	fmt.Fprintf(w, "vat rate\t%.2f\n", t.Amount.VatRate)
+	fmt.Fprintf(w, "comment\t%s\n", t.Comment)
+	fmt.Fprintf(w, "effective\t%s\n", formatDate(t.EffectiveDate))
+	fmt.Fprintf(w, "due\t%s\n", formatDate(t.DueDate))
+	fmt.Fprintf(w, "paylink\t%s\n", t.PaymentStartUrl)
+	if info := t.ProcessorInfo.String(); info != "" {
+		fmt.Fprintf(w, "processor info\t%s\n", info)
+	}
+	if t.GroupReference != "" {
+		fmt.Fprintf(w, "group\t%s\n", t.GroupReference)
+	}
+	for _, l := range t.LineItems {
+		fmt.Fprintf(w, "line item %d\t%d x %s at %.2f%% vat, %s\n", l.Position, l.Quantity, formatCents(l.UnitCent), l.VatRate, l.Description)
+	}
+	if t.TransactionType == entities.TransactionTypeDue {
+		for _, v := range t.VatTotals() {
+			fmt.Fprintf(w, "vat %.2f%%\tnet %s, vat %s, gross %s\n", v.VatRate, formatCents(v.NetCent), formatCents(v.VatCent), formatCents(v.GrossCent))
+		}
+	}
+	fmt.Fprintf(w, "created\t%s\n", t.CreatedAt.Format(time.RFC3339))
+	if t.Deletion.By != "" {
+		fmt.Fprintf(w, "deleted by\t%s\n", t.Deletion.By)
+		fmt.Fprintf(w, "status before deletion\t%s\n", t.Deletion.Status)
+		fmt.Fprintf(w, "comment before deletion\t%s\n", t.Deletion.Comment)
+	}
+	return w.Flush()
+}
+
+func bookPayment(ctx context.Context, i interaction.Interactor, args []string, out io.Writer) error {
+	conf, err := config.GetApplicationConfig()
+	if err != nil {
+		return err
+	}
+
+	fs := newCommandFlagSet("book-payment")
+	debitorID := fs.Int64("debitor", 0, "the debitor id (required unless -reference is given)")
+	amount := fs.Int64("amount", 0, "the amount in cents (required)")
+	method := fs.String("method", string(entities.PaymentMethodTransfer), "the payment method")
+	comment := fs.String("comment", "", "the comment, e.g. the bank reference (required)")
+	currency := fs.String("currency", firstOrEmpty(conf.Service.AllowedCurrencies), "the currency")
+	vatRate := fs.Float64("vat", 0, "the vat rate in percent")
+	effective := fs.String("effective", "", "the date the payment was made, as YYYY-MM-DD, defaults to today")
+	event := fs.String("event", "", "the event the payment is for, defaults to the one covering the effective date")
+	reference := fs.String("reference", "", "the transaction id or creditor reference from the bank statement, settles the open payment it refers to")
+	if err := parseCommandFlags(fs, args, "amount", "comment"); err != nil {
+		return err
+	}
+	if *debitorID == 0 && *reference == "" {
+		fmt.Fprintf(fs.Output(), "-debitor or -reference is required\n")
+		return errCommandUsage
+	}
+
+	effectiveDate := sql.NullTime{}
+	if *effective != "" {
+		parsed, err := time.Parse(time.DateOnly, *effective)
+		if err != nil {
+			fmt.Fprintf(fs.Output(), "-effective must be a date in the format YYYY-MM-DD\n")
+			return errCommandUsage
+		}
+		effectiveDate = sql.NullTime{Time: parsed, Valid: true}
+	}
+
+	payment := entities.Transaction{
+		DebitorID:         *debitorID,
+		TransactionType:   entities.TransactionTypePayment,
+		PaymentMethod:     entities.PaymentMethod(*method),
+		TransactionStatus: entities.TransactionStatusValid,
+		Comment:           *comment,
+		EffectiveDate:     effectiveDate,
+		Event:             *event,
+		Amount: entities.Amount{
+			ISOCurrency: *currency,
+			VatRate:     *vatRate,
+			GrossCent:   *amount,
+		},
+	}
+
+	if *reference != "" {
+		matched, err := i.GetTransactionsForDebitor(ctx, entities.TransactionQuery{DebitorID: *debitorID, TransactionIdentifier: *reference})
+		if err != nil {
+			return err
+		}
+		if len(matched) == 0 {
+			return fmt.Errorf("no transaction found for reference %s", *reference)
+		}
+
+		open := matched[0]
+		if isOpenPaymentOver(open, payment.Amount) {
+			open.TransactionStatus = entities.TransactionStatusValid
+			open.Comment = *comment
+			if effectiveDate.Valid {
+				open.EffectiveDate = effectiveDate
+			}
+			if err := i.UpdateTransaction(ctx, &open); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(out, "settled payment %s over %s for debitor %d\n", open.TransactionID, formatAmount(open.Amount), open.DebitorID)
+			return nil
+		}
+
+		// the money arrived all the same, so it is booked as a new payment of the debitor
+		fmt.Fprintf(out, "transaction %s is not an open payment over %s, booking a new payment\n", open.TransactionID, formatAmount(payment.Amount))
+		payment.DebitorID = open.DebitorID
+		if payment.Event == "" {
+			payment.Event = open.Event
+		}
+	}
+
+	created, err := i.CreateTransaction(ctx, &payment)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "booked payment %s over %s for debitor %d\n", created.TransactionID, formatAmount(created.Amount), created.DebitorID)
+	return nil
+}
+
+// isOpenPaymentOver reports whether the transaction is a payment still waiting for exactly the given amount.
+func isOpenPaymentOver(tran entities.Transaction, amount entities.Amount) bool {
+	return tran.TransactionType == entities.TransactionTypePayment &&
+		(tran.TransactionStatus == entities.TransactionStatusTentative || tran.TransactionStatus == entities.TransactionStatusPending) &&
+		tran.Amount.ISOCurrency == amount.ISOCurrency &&
+		tran.Amount.GrossCent == amount.GrossCent
+}
+
+func recomputeBalance(ctx context.Context, i interaction.Interactor, args []string, out io.Writer) error {
+	fs := newCommandFlagSet("recompute-balance")
+	debitorID := fs.Int64("debitor", 0, "the debitor id (required)")
+	event := fs.String("event", "", "the event, defaults to the current one")
+	if err := parseCommandFlags(fs, args, "debitor"); err != nil {
+		return err
+	}
+
+	balance, err := i.GetBalance(ctx, *debitorID, *event)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "event\t%s\n", balance.Event)
+	fmt.Fprintf(w, "dues\t%s\n", formatCents(balance.DuesCent))
+	fmt.Fprintf(w, "payments\t%s\n", formatCents(balance.PaymentsCent))
+	fmt.Fprintf(w, "outstanding\t%s\n", formatCents(balance.OutstandingCent))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !balance.Consistent() {
+		return fmt.Errorf("the database calculates an outstanding amount of %s instead", formatCents(balance.StoredOutstandingCent))
+	}
+	return nil
+}
+
+func voidPaylinks(ctx context.Context, i interaction.Interactor, args []string, out io.Writer) error {
+	fs := newCommandFlagSet("void-paylinks")
+	debitorID := fs.Int64("debitor", 0, "the debitor id (required)")
+	if err := parseCommandFlags(fs, args, "debitor"); err != nil {
+		return err
+	}
+
+	voided, err := i.VoidTentativePayments(ctx, *debitorID)
+	for _, transactionID := range voided {
+		fmt.Fprintf(out, "voided %s\n", transactionID)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "voided %d paylinks for debitor %d\n", len(voided), *debitorID)
+	return nil
+}
+
+func resendPaymentsChanged(ctx context.Context, i interaction.Interactor, args []string, out io.Writer) error {
+	fs := newCommandFlagSet("resend-payments-changed")
+	debitorID := fs.Int64("debitor", 0, "the debitor id (required)")
+	if err := parseCommandFlags(fs, args, "debitor"); err != nil {
+		return err
+	}
+
+	if err := i.ResendPaymentsChanged(ctx, *debitorID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "notified the attendee service about debitor %d\n", *debitorID)
+	return nil
+}
+
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+func formatAmount(a entities.Amount) string {
+	return formatCents(a.GrossCent) + " " + a.ISOCurrency
+}
+
+func formatDate(d sql.NullTime) string {
+	if !d.Valid {
+		return "-"
+	}
+	return d.Time.Format(time.DateOnly)
+}
+
+func firstOrEmpty(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
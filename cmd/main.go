@@ -3,7 +3,8 @@ package main
 import (
 	"errors"
 	"flag"
-	"net/http"
+	"fmt"
+	"io"
 	"path/filepath"
 
 	"github.com/eurofurence/reg-payment-service/internal/repository/downstreams/authservice"
@@ -18,12 +19,12 @@ import (
 	"github.com/eurofurence/reg-payment-service/internal/repository/database"
 	"github.com/eurofurence/reg-payment-service/internal/repository/database/mysql"
 	"github.com/eurofurence/reg-payment-service/internal/server"
+	"github.com/eurofurence/reg-payment-service/internal/tracing"
 
 	"context"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 )
 
 var errHelpRequested = errors.New("help text was requested")
@@ -33,12 +34,11 @@ var (
 	migrate        bool
 	ecsJsonLogging bool
 	configFilePath string
+	command        string
+	commandArgs    []string
 )
 
-const (
-	envDbPassword = "REG_SECRET_DB_PASSWORD"
-	envApiToken   = "REG_SECRET_API_TOKEN"
-)
+const commandVerifyLedger = "verify-ledger"
 
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -59,28 +59,50 @@ func main() {
 	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 
 	logger.Debug("loading configuration")
-	conf, err := readConfigFile(logger)
+	conf, err := readConfigFile(config.UnmarshalFromYamlConfiguration)
 	if err != nil {
 		logger.Fatal("%v", err)
 	}
 
 	logger.Debug("applying environment variable config overrides")
-	envConfigOverrides(conf)
+	if err := config.ApplyEnvironment(conf, os.Environ()); err != nil {
+		logger.Fatal("%v", err)
+	}
 
 	err = config.Validate(conf, logger.Warn)
 	if err != nil {
 		logger.Fatal("%v", err)
 	}
 
+	logging.SetLoglevel(conf.Logging.Severity)
+	config.OnReload(func(conf *config.Application) {
+		logging.SetLoglevel(conf.Logging.Severity)
+	})
+
+	// components are stopped in reverse order, so register them before the ones that use them
+	lifecycle := server.NewLifecycle(&conf.Server, logger)
+
+	shutdownTracing, err := tracing.Setup(ctx, conf.Tracing, conf.Service.Name)
+	if err != nil {
+		logger.Fatal("%v", err)
+	}
+	lifecycle.Register(server.Hook{Name: "tracing", Stop: shutdownTracing})
+
 	repo := constructOrFail(ctx, logger, func() (database.Repository, error) {
 		if conf.Database.Use == config.Mysql {
-			return mysql.NewMySQLConnector(conf.Database, logger)
+			return mysql.NewMySQLConnector(conf.Database, conf.Service.ConfiguredEvents(), logger)
 		} else if conf.Database.Use == config.Inmemory {
 			return inmemory.NewInMemoryProvider(), nil
 		} else {
 			return nil, errors.New("invalid configuration")
 		}
 	})
+	lifecycle.Register(server.Hook{
+		Name: "database",
+		Stop: func(context.Context) error {
+			return repo.Close()
+		},
+	})
 
 	if migrate {
 		if err := repo.Migrate(); err != nil {
@@ -88,6 +110,12 @@ func main() {
 		}
 	}
 
+	if command == commandVerifyLedger {
+		os.Exit(runCommand(ctx, logger, lifecycle, func() int {
+			return verifyLedger(ctx, logger, repo)
+		}))
+	}
+
 	//playDatabase(ctx, repo)
 
 	attClient := constructOrFail(ctx, logger, func() (attendeeservice.AttendeeService, error) {
@@ -106,29 +134,43 @@ func main() {
 		return interaction.NewServiceInteractor(repo, attClient, ccClient)
 	})
 
+	if c, ok := findAdminCommand(command); ok {
+		os.Exit(runCommand(ctx, logger, lifecycle, func() int {
+			return runAdminCommand(ctx, logger, i, c, commandArgs)
+		}))
+	}
+
 	logger.Debug("Setting up router")
-	handler := server.CreateRouter(i, conf.Security)
+	handler := server.CreateRouter(i, conf.Security, lifecycle)
 
 	logger.Debug("setting up server")
-	srv := server.NewServer(ctx, &conf.Server, handler)
-
-	go func() {
-		<-sig
-		defer cancel()
-		logger.Info("Stopping services now")
+	lifecycle.Register(configReloadHook(logger))
+	if conf.Server.MetricsPort != 0 {
+		lifecycle.RegisterServer("metrics server", server.NewMetricsServer(ctx, &conf.Server))
+	}
+	lifecycle.RegisterServer("http server", server.NewServer(ctx, &conf.Server, handler))
 
-		tCtx, tcancel := context.WithTimeout(ctx, time.Second*5)
-		defer tcancel()
+	if err := lifecycle.Start(ctx); err != nil {
+		logger.Fatal("%v", err)
+	}
+	logger.Info("Running service on port %d", conf.Server.Port)
 
-		if err := srv.Shutdown(tCtx); err != nil {
-			logger.Fatal("Couldn't shutdown server gracefully")
-		}
-	}()
+	failed := false
+	select {
+	case <-sig:
+		logger.Info("Stopping services now")
+	case err := <-lifecycle.Failed():
+		logger.Error("Stopping services, because a component failed. [error]: %v", err)
+		failed = true
+	}
 
-	logger.Info("Running service on port %d", conf.Server.Port)
-	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-		logger.Fatal("Server closed unexpectedly. [error]: %v", err)
+	err = lifecycle.Shutdown()
+	// in-flight requests use this context, so only cancel it once they are done
+	cancel()
+	if err != nil || failed {
+		logger.Fatal("Service did not stop cleanly")
 	}
+	logger.Info("Service stopped")
 }
 
 func parseArgs() error {
@@ -137,13 +179,36 @@ func parseArgs() error {
 	flag.BoolVar(&migrate, "migrate-database", false, "Performs database migrations before the service starts")
 	flag.BoolVar(&ecsJsonLogging, "ecs-json-logging", false, "Enable json logging")
 
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage: %s [flags] [command [command flags]]\n\nCommands, run instead of the service:\n", os.Args[0])
+		fmt.Fprintf(out, "  %s: checks the hash chains of the transaction log and exits\n", commandVerifyLedger)
+		for _, c := range adminCommands {
+			fmt.Fprintf(out, "  %s: %s\n", c.name, c.description)
+		}
+		fmt.Fprintf(out, "\nFlags:\n")
+		flag.PrintDefaults()
+	}
+
 	flag.Parse()
 
 	if showHelp {
-		flag.PrintDefaults()
+		flag.Usage()
 		return errHelpRequested
 	}
 
+	command = flag.Arg(0)
+	commandArgs = flag.Args()[min(1, flag.NArg()):]
+	if _, ok := findAdminCommand(command); !ok && command != "" && command != commandVerifyLedger {
+		flag.Usage()
+		return fmt.Errorf("unknown command %s", command)
+	}
+
+	if command == commandVerifyLedger && len(commandArgs) > 0 {
+		flag.Usage()
+		return fmt.Errorf("%s takes no arguments", commandVerifyLedger)
+	}
+
 	if configFilePath == "" {
 		flag.PrintDefaults()
 		return errors.New("no config file was provided")
@@ -152,7 +217,48 @@ func parseArgs() error {
 	return nil
 }
 
-func readConfigFile(logger logging.Logger) (*config.Application, error) {
+// verifyLedger checks the transaction log and returns the exit code, which is 1 if any chain is broken.
+func verifyLedger(ctx context.Context, logger logging.Logger, repo database.Repository) int {
+	report, err := interaction.VerifyLedger(ctx, repo)
+	if err != nil {
+		logger.Error("could not verify the ledger. [error]: %v", err)
+		return 2
+	}
+
+	for _, b := range report.Breaks {
+		if b.LogID != 0 {
+			logger.Error("transaction %s, log entry %d: %s", b.TransactionID, b.LogID, b.Reason)
+		} else {
+			logger.Error("transaction %s: %s", b.TransactionID, b.Reason)
+		}
+	}
+
+	if len(report.Breaks) > 0 {
+		logger.Error("ledger verification failed: %d problems in %d entries of %d transactions", len(report.Breaks), report.Entries, report.Chains)
+		return 1
+	}
+
+	logger.Info("ledger verification successful: %d entries of %d transactions", report.Entries, report.Chains)
+	return 0
+}
+
+// runCommand runs a command instead of the service and returns its exit code. The components registered so far
+// are started for it, and stopped afterwards, so spans are flushed and the database pool is closed before exiting.
+func runCommand(ctx context.Context, logger logging.Logger, lifecycle *server.Lifecycle, run func() int) int {
+	if err := lifecycle.Start(ctx); err != nil {
+		logger.Error("%v", err)
+		return exitError
+	}
+
+	code := run()
+	if err := lifecycle.Shutdown(); err != nil && code == exitOK {
+		return exitError
+	}
+	return code
+}
+
+// readConfigFile opens the configuration file and decodes it with decode.
+func readConfigFile(decode func(io.Reader) (*config.Application, error)) (*config.Application, error) {
 	fi, err := os.Stat(configFilePath)
 	if err != nil {
 		return nil, err
@@ -167,7 +273,9 @@ func readConfigFile(logger logging.Logger) (*config.Application, error) {
 		return nil, err
 	}
 
-	conf, err := config.UnmarshalFromYamlConfiguration(f)
+	defer f.Close()
+
+	conf, err := decode(f)
 	if err != nil {
 		return nil, err
 	}
@@ -175,15 +283,6 @@ func readConfigFile(logger logging.Logger) (*config.Application, error) {
 	return conf, nil
 }
 
-func envConfigOverrides(conf *config.Application) {
-	if dbPassword := os.Getenv(envDbPassword); dbPassword != "" {
-		conf.Database.Password = dbPassword
-	}
-	if apiToken := os.Getenv(envApiToken); apiToken != "" {
-		conf.Security.Fixed.Api = apiToken
-	}
-}
-
 func constructOrFail[T any](ctx context.Context, logger logging.Logger, constructor func() (T, error)) T {
 	const failMsg = "Construction failed. [error]: %v"
 	if constructor == nil {
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/server"
+)
+
+const configWatchInterval = 5 * time.Second
+
+// configReloadHook reloads the configuration file on SIGHUP, and whenever the file is modified.
+func configReloadHook(logger logging.Logger) server.Hook {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	return server.Hook{
+		Name: "config reload",
+		Start: func(ctx context.Context) error {
+			hup := make(chan os.Signal, 1)
+			signal.Notify(hup, syscall.SIGHUP)
+			lastModified := configFileModTime()
+
+			go func() {
+				defer close(done)
+				defer signal.Stop(hup)
+
+				ticker := time.NewTicker(configWatchInterval)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-stop:
+						return
+					case <-hup:
+						logger.Info("received SIGHUP, reloading configuration")
+						lastModified = configFileModTime()
+						reloadConfig(logger)
+					case <-ticker.C:
+						// the file may be missing for a moment while it is replaced, wait until it is back
+						if modified := configFileModTime(); !modified.IsZero() && !modified.Equal(lastModified) {
+							logger.Info("configuration file was modified, reloading configuration")
+							lastModified = modified
+							reloadConfig(logger)
+						}
+					}
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			close(stop)
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// reloadConfig reads the configuration file again, and applies it if it is valid.
+// Otherwise, the service keeps running with the current configuration.
+func reloadConfig(logger logging.Logger) {
+	conf, err := readConfigFile(config.ParseYamlConfiguration)
+	if err != nil {
+		logger.Error("could not read configuration file, keeping the current configuration. [error]: %v", err)
+		return
+	}
+
+	if err := config.ApplyEnvironment(conf, os.Environ()); err != nil {
+		logger.Error("keeping the current configuration. [error]: %v", err)
+		return
+	}
+
+	if err := config.Reload(conf, logger.Warn); err != nil {
+		logger.Error("keeping the current configuration. [error]: %v", err)
+		return
+	}
+	logger.Info("configuration reloaded")
+}
+
+func configFileModTime() time.Time {
+	fi, err := os.Stat(configFilePath)
+	if err != nil {
+		return time.Time{}
+	}
+	return fi.ModTime()
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strings"
 )
 
 var _ error = (*StatusError)(nil)
@@ -16,6 +19,7 @@ const (
 	KnownReasonForbidden
 	KnownReasonNotFound
 	KnownReasonConflict
+	KnownReasonUnprocessableEntity
 	KnownReasonInternalServerError
 	KnownReasonUnknown
 )
@@ -25,6 +29,9 @@ type Status struct {
 	Code    int
 	Message string
 	Details string
+	// Fields maps a field name (as used in the json body or query) to the
+	// problems found with it. May be nil.
+	Fields url.Values
 }
 
 type StatusError struct {
@@ -43,6 +50,16 @@ func (se *StatusError) Status() Status {
 	return se.ErrStatus
 }
 
+// WithField adds a field level problem description to the error
+// and returns the error for chaining.
+func (se *StatusError) WithField(field string, message string) *StatusError {
+	if se.ErrStatus.Fields == nil {
+		se.ErrStatus.Fields = url.Values{}
+	}
+	se.ErrStatus.Fields.Add(field, message)
+	return se
+}
+
 // NewBadRequest creates a new StatusError with error code 400
 func NewBadRequest(details string) *StatusError {
 	return &StatusError{
@@ -103,6 +120,28 @@ func NewConflict(details string) *StatusError {
 	}
 }
 
+// NewUnprocessableEntity creates a new StatusError with error code 422
+//
+// It is used when the request could be parsed, but the field values failed to validate.
+// The details are generated from the field names, so they are stable for a given set of fields.
+func NewUnprocessableEntity(fields url.Values) *StatusError {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return &StatusError{
+		ErrStatus: Status{
+			Reason:  KnownReasonUnprocessableEntity,
+			Code:    http.StatusUnprocessableEntity,
+			Message: "Status Error (Unprocessable Entity)",
+			Details: fmt.Sprintf("invalid values for fields: %s", strings.Join(names, ", ")),
+			Fields:  fields,
+		},
+	}
+}
+
 // NewInternalServerError creates a new StatusError with error code 500
 func NewInternalServerError(details string) *StatusError {
 	return &StatusError{
@@ -155,6 +194,11 @@ func IsConflictError(err error) bool {
 	return isReasonOrCodeForError(KnownReasonConflict, http.StatusConflict, err)
 }
 
+// IsUnprocessableEntityError checks if error is of type `unprocessable entity`
+func IsUnprocessableEntityError(err error) bool {
+	return isReasonOrCodeForError(KnownReasonUnprocessableEntity, http.StatusUnprocessableEntity, err)
+}
+
 // IsInternalServerError checks if error is of type `internal server error`
 func IsInternalServerError(err error) bool {
 	return isReasonOrCodeForError(KnownReasonInternalServerError, http.StatusInternalServerError, err)
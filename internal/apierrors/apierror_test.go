@@ -1,3 +1,38 @@
 package apierrors
 
-// TODO Write tests for apierror package
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUnprocessableEntity(t *testing.T) {
+	err := NewUnprocessableEntity(url.Values{
+		"method":     {"invalid payment method Kevin"},
+		"debitor_id": {"must be greater than zero"},
+	})
+
+	require.Equal(t, http.StatusUnprocessableEntity, err.Status().Code)
+	require.Equal(t, "Status Error (Unprocessable Entity) - invalid values for fields: debitor_id, method", err.Error())
+	require.True(t, IsUnprocessableEntityError(err))
+	require.True(t, IsUnprocessableEntityError(fmt.Errorf("wrapped: %w", err)))
+	require.False(t, IsBadRequestError(err))
+}
+
+func TestWithField(t *testing.T) {
+	err := NewBadRequest("invalid amount").
+		WithField("amount.currency", "not allowed").
+		WithField("amount.currency", "must not be empty")
+
+	require.Equal(t, url.Values{"amount.currency": {"not allowed", "must not be empty"}}, AsAPIStatus(err).Status().Fields)
+	require.True(t, IsBadRequestError(err))
+}
+
+func TestIsUnknownError(t *testing.T) {
+	require.True(t, IsUnknownError(errors.New("plain")))
+	require.Nil(t, AsAPIStatus(errors.New("plain")))
+}
@@ -4,13 +4,15 @@ import (
 	"crypto/rsa"
 	"errors"
 	"io"
+	"sync/atomic"
 
 	"gopkg.in/yaml.v3"
 )
 
 type (
-	DatabaseType string
-	LogStyle     string
+	DatabaseType    string
+	LogStyle        string
+	TracingExporter string
 )
 
 const (
@@ -19,9 +21,13 @@ const (
 
 	Plain LogStyle = "plain"
 	ECS   LogStyle = "ecs" // default
+
+	TracingNone   TracingExporter = "none" // default
+	TracingOtlp   TracingExporter = "otlp"
+	TracingStdout TracingExporter = "stdout"
 )
 
-var appConfig *Application
+var appConfig atomic.Pointer[Application]
 
 type (
 	// Application is the root configuration type
@@ -32,18 +38,62 @@ type (
 		Database DatabaseConfig `yaml:"database"`
 		Security SecurityConfig `yaml:"security"`
 		Logging  LoggingConfig  `yaml:"logging"`
+		Tracing  TracingConfig  `yaml:"tracing"`
+
+		sources map[string]string // yaml path to the environment variable or file a value was read from
 	}
 
 	// ServiceConfig contains configuration values
 	// for service related tasks. E.g. URL to payment provider adapter
 	ServiceConfig struct {
-		Name                  string            `yaml:"name"`
-		AttendeeService       string            `yaml:"attendee_service"`
-		ProviderAdapter       string            `yaml:"provider_adapter"`
-		TransactionIDPrefix   string            `yaml:"transaction_id_prefix"`
-		AllowedCurrencies     []string          `yaml:"allowed_currencies"`
-		DefaultPaymentComment map[string]string `yaml:"payment_default_comment"`
-		PublicSepaLinkURL     string            `yaml:"public_sepa_link_url"`
+		Name                        string            `yaml:"name"`
+		AttendeeService             string            `yaml:"attendee_service"`
+		AttendeeServiceCacheSeconds int               `yaml:"attendee_service_cache_seconds"` // 0 disables caching of registration lookups
+		ProviderAdapter             string            `yaml:"provider_adapter"`
+		TransactionIDPrefix         string            `yaml:"transaction_id_prefix"`
+		AllowedCurrencies           []string          `yaml:"allowed_currencies"`
+		DefaultPaymentComment       map[string]string `yaml:"payment_default_comment"`
+		PublicSepaLinkURL           string            `yaml:"public_sepa_link_url"`
+		Events                      []EventConfig     `yaml:"events"` // if empty, there is a single event named after transaction_id_prefix, allowing allowed_currencies
+		// the installment plans that may be attached to a debitor, without a plan attendees must pay their dues in full
+		InstallmentPlans map[string]InstallmentPlanConfig `yaml:"installment_plans"`
+		Issuer           IssuerConfig                     `yaml:"issuer"`
+		Sepa             SepaConfig                       `yaml:"sepa"`
+	}
+
+	// EventConfig describes one convention or season that transactions belong to
+	EventConfig struct {
+		Name                string    `yaml:"name"`                  // stored with each transaction, e.g. EF2024
+		TransactionIDPrefix string    `yaml:"transaction_id_prefix"` // defaults to the name
+		AllowedCurrencies   []string  `yaml:"allowed_currencies"`    // defaults to service.allowed_currencies
+		VatRates            []float64 `yaml:"vat_rates"`             // the vat rates transactions may use, any rate is allowed if empty
+		ValidFrom           string    `yaml:"valid_from"`            // first effective date (yyyy-mm-dd) booked to this event by default, optional
+		ValidUntil          string    `yaml:"valid_until"`           // last effective date (yyyy-mm-dd) booked to this event by default, optional
+	}
+
+	// InstallmentPlanConfig describes how the dues of a debitor with this plan are split into installments
+	InstallmentPlanConfig struct {
+		Installments           int   `yaml:"installments"`             // number of installments, at least 2
+		IntervalDays           int   `yaml:"interval_days"`            // days between the due dates of two installments
+		MinimumDuesCent        int64 `yaml:"minimum_dues_cent"`        // the plan may only be attached if the outstanding dues are at least this much
+		MinimumInstallmentCent int64 `yaml:"minimum_installment_cent"` // installments are never smaller than this, except the one paying off the rest
+	}
+
+	// IssuerConfig is printed on the invoices and receipts the service renders
+	IssuerConfig struct {
+		Name                string   `yaml:"name"`                  // invoices and receipts are unavailable if unset
+		Address             []string `yaml:"address"`               // printed below the name, one entry per line
+		VatID               string   `yaml:"vat_id"`                // optional
+		Email               string   `yaml:"email"`                 // optional
+		InvoiceNumberPrefix string   `yaml:"invoice_number_prefix"` // defaults to INV
+		ReceiptNumberPrefix string   `yaml:"receipt_number_prefix"` // defaults to RCT
+	}
+
+	// SepaConfig is the bank account attendees transfer their payments to, encoded in the EPC QR codes of sepa transfers
+	SepaConfig struct {
+		BeneficiaryName string `yaml:"beneficiary_name"` // at most 70 characters, qr codes are unavailable if unset
+		IBAN            string `yaml:"iban"`
+		BIC             string `yaml:"bic"` // optional within the EEA
 	}
 
 	// ServerConfig contains all values for
@@ -54,6 +104,10 @@ type (
 		ReadTimeout  int    `yaml:"read_timeout_seconds"`
 		WriteTimeout int    `yaml:"write_timeout_seconds"`
 		IdleTimeout  int    `yaml:"idle_timeout_seconds"`
+		MetricsPort  int    `yaml:"metrics_port"` // serves /metrics for prometheus, 0 disables metrics
+
+		ShutdownDelay        int `yaml:"shutdown_delay_seconds"`         // how long the readiness check fails before shutting down, so the load balancer stops routing requests here
+		ShutdownDrainTimeout int `yaml:"shutdown_drain_timeout_seconds"` // how long to wait for in-flight requests and background work on shutdown, defaults to 5
 	}
 
 	// DatabaseConfig configures which db to use (mysql, inmemory)
@@ -71,6 +125,8 @@ type (
 		Fixed        FixedTokenConfig    `yaml:"fixed_token"`
 		Oidc         OpenIdConnectConfig `yaml:"oidc"`
 		Cors         CorsConfig          `yaml:"cors"`
+		Ledger       LedgerConfig        `yaml:"ledger"`
+		RateLimit    RateLimitConfig     `yaml:"rate_limit"`
 		RequireLogin bool                `yaml:"require_login_for_reg"`
 	}
 
@@ -88,9 +144,40 @@ type (
 		Issuer                string   `yaml:"issuer"`
 	}
 
+	LedgerConfig struct {
+		CheckpointKey string `yaml:"checkpoint_key"` // secret for signing ledger checkpoints, checkpoints are unavailable if unset
+	}
+
+	// RateLimitConfig limits how often a single caller may call the listed routes
+	RateLimitConfig struct {
+		Routes   []RouteRateLimitConfig `yaml:"routes"`
+		ApiToken RateLimit              `yaml:"api_token"` // own budget for calls with the api token, which are exempt if requests_per_minute is 0
+	}
+
+	RouteRateLimitConfig struct {
+		Method    string `yaml:"method"`
+		Path      string `yaml:"path"` // the request path, e.g. /api/rest/v1/transactions/{id}/receipt, where {id} matches any single segment
+		RateLimit `yaml:",inline"`
+	}
+
+	RateLimit struct {
+		RequestsPerMinute int `yaml:"requests_per_minute"`
+		Burst             int `yaml:"burst"` // how many requests may be made in quick succession, defaults to 1
+	}
+
+	// CorsConfig configures which browser origins may call the service
+	//
+	// If DisableCors is set, the origins in AllowOrigin are allowed with default settings. This is meant for
+	// local development. Otherwise, the remaining fields configure the policy.
 	CorsConfig struct {
-		DisableCors bool   `yaml:"disable"`
-		AllowOrigin string `yaml:"allow_origin"`
+		DisableCors      bool     `yaml:"disable"`
+		AllowOrigin      string   `yaml:"allow_origin"`      // comma separated list of origins, only used if DisableCors is set
+		AllowedOrigins   []string `yaml:"allowed_origins"`   // e.g. https://regsys.example.com, or https://*.example.com for all subdomains
+		AllowedMethods   []string `yaml:"allowed_methods"`   // defaults to GET, POST, PUT, DELETE
+		AllowedHeaders   []string `yaml:"allowed_headers"`   // request headers besides the CORS safelisted ones, defaults to Content-Type, Authorization, X-Admin-Request
+		ExposedHeaders   []string `yaml:"exposed_headers"`   // response headers the browser may read, defaults to Location, X-Request-Id
+		AllowCredentials bool     `yaml:"allow_credentials"` // needed for the cookie based authentication
+		MaxAgeSeconds    int      `yaml:"max_age_seconds"`   // how long browsers may cache preflight responses, 0 to not send the header
 	}
 
 	// LoggingConfig configures logging
@@ -98,33 +185,64 @@ type (
 		Style    LogStyle `yaml:"style"`
 		Severity string   `yaml:"severity"`
 	}
+
+	// TracingConfig configures where OpenTelemetry traces are exported to
+	TracingConfig struct {
+		Exporter    TracingExporter `yaml:"exporter"`     // none, otlp or stdout
+		Endpoint    string          `yaml:"endpoint"`     // base url of the otlp/http collector, e.g. http://localhost:4318, spans are sent to /v1/traces below it. Only used for otlp
+		SampleRatio float64         `yaml:"sample_ratio"` // share of the traces started by this service that are recorded, from 0 to 1, defaults to 1. Traces started by the caller follow the caller's decision.
+	}
 )
 
-var parsedKeySet []*rsa.PublicKey
+// parsedKeySet holds the token public keys of the last valid configuration. It is replaced as a whole,
+// so readers never see a partially parsed key set.
+var parsedKeySet atomic.Pointer[[]*rsa.PublicKey]
 
 func OidcKeySet() []*rsa.PublicKey {
-	return parsedKeySet
+	if keys := parsedKeySet.Load(); keys != nil {
+		return *keys
+	}
+	return nil
 }
 
-// UnmarshalFromYamlConfiguration decodes yaml data from an `io.Reader` interface.
+// UnmarshalFromYamlConfiguration decodes yaml data from an `io.Reader` interface,
+// and makes it the application configuration.
 func UnmarshalFromYamlConfiguration(file io.Reader) (*Application, error) {
+	conf, err := ParseYamlConfiguration(file)
+	if err != nil {
+		return nil, err
+	}
+
+	appConfig.Store(conf)
+	return conf, nil
+}
+
+// ParseYamlConfiguration decodes yaml data from an `io.Reader` interface,
+// without touching the application configuration. Use Reload to apply it.
+func ParseYamlConfiguration(file io.Reader) (*Application, error) {
 	d := yaml.NewDecoder(file)
 	d.KnownFields(true) // strict
 
 	var conf Application
+	// defaults for values whose zero value is valid, but not what an unset value should mean
+	conf.Tracing.SampleRatio = 1.0
 
 	if err := d.Decode(&conf); err != nil {
 		return nil, err
 	}
 
-	appConfig = &conf
 	return &conf, nil
 }
 
+// GetApplicationConfig returns the current application configuration.
+//
+// The configuration may be replaced by a reload at any time, so do not keep the result around,
+// but call this again where the values are needed. The returned value must not be modified.
 func GetApplicationConfig() (*Application, error) {
-	if appConfig == nil {
+	conf := appConfig.Load()
+	if conf == nil {
 		return nil, errors.New("config was not yet loaded")
 	}
 
-	return appConfig, nil
+	return conf, nil
 }
@@ -136,6 +136,10 @@ security:
     allow_origin: 'http://localhost:8000,http://localhost:8001'
 logging:
   severity: CAT
+tracing:
+  exporter: otlp
+  endpoint: 'localhost:4318'
+  sample_ratio: 2
 `)
 
 	b := bytes.NewBuffer(s)
@@ -153,7 +157,7 @@ logging:
 	expected := `configuration error: database.use: must be one of mysql, inmemory
 configuration error: logging.severity: must be one of DEBUG, INFO, WARN, ERROR
 configuration error: security.fixed_token.api: security.fixed_token.api field must be at least 16 and at most 256 characters long
-configuration error: security.oidc.admin_role: security.oidc.admin_role field must be at least 1 and at most 256 characters long
+configuration error: security.oidc.admin_group: security.oidc.admin_group field must be at least 1 and at most 256 characters long
 configuration error: security.oidc.token_public_keys_PEM[0]: failed to parse RSA public key in PEM format: invalid key: Key must be a PEM encoded PKCS1 or PKCS8 key
 configuration error: server.idle_timeout_seconds: server.idle_timeout_seconds field must be an integer at least 1 and at most 300
 configuration error: server.port: server.port field must be an integer at least 1 and at most 65535
@@ -161,7 +165,278 @@ configuration error: server.read_timeout_seconds: server.read_timeout_seconds fi
 configuration error: server.write_timeout_seconds: server.write_timeout_seconds field must be an integer at least 1 and at most 300
 configuration error: service.attendee_service: base url must start with http:// or https:// and may not end in a /
 configuration error: service.provider_adapter: base url must start with http:// or https:// and may not end in a /
+configuration error: tracing.endpoint: base url must start with http:// or https:// and may not end in a /
+configuration error: tracing.sample_ratio: must be between 0 and 1
 `
 	require.Equal(t, expected, logRecording.String())
 	require.Error(t, err)
 }
+
+func TestTracingSampleRatioDefault(t *testing.T) {
+	conf, err := ParseYamlConfiguration(strings.NewReader("tracing:\n  exporter: otlp\n"))
+	require.NoError(t, err)
+	require.Equal(t, 1.0, conf.Tracing.SampleRatio)
+
+	conf, err = ParseYamlConfiguration(strings.NewReader("tracing:\n  sample_ratio: 0\n"))
+	require.NoError(t, err)
+	require.Equal(t, 0.0, conf.Tracing.SampleRatio)
+}
+
+func TestValidationErrorsCors(t *testing.T) {
+	s := []byte(`service:
+  attendee_service: 'http://localhost:9091'
+  provider_adapter: 'http://localhost:9097'
+server:
+  port: 8080
+  read_timeout_seconds: 30
+  write_timeout_seconds: 30
+  idle_timeout_seconds: 120
+database:
+  use: inmemory
+security:
+  fixed_token:
+    api: 'some-api-token-must-be-long-enough'
+  oidc:
+    admin_group: 'admin'
+  cors:
+    allowed_origins:
+      - '*'
+      - 'https://regsys.example.com/'
+      - 'regsys.example.com'
+      - 'https://*.example.com'
+    allowed_methods: ['GET', 'TRACE']
+    allow_credentials: true
+    max_age_seconds: 100000
+logging:
+  severity: INFO
+`)
+
+	b := bytes.NewBuffer(s)
+
+	conf, err := UnmarshalFromYamlConfiguration(b)
+	require.NoError(t, err)
+
+	logRecording := strings.Builder{}
+	logFunc := func(format string, v ...interface{}) {
+		logRecording.WriteString(fmt.Sprintf(format, v...))
+		logRecording.WriteString("\n")
+	}
+	err = Validate(conf, logFunc)
+
+	expected := `configuration error: security.cors.allowed_methods[1]: must be one of GET, POST, PUT, DELETE, PATCH, HEAD
+configuration error: security.cors.allowed_origins[0]: * may not be used together with allow_credentials, list the origins instead
+configuration error: security.cors.allowed_origins[1]: must be * or scheme://host[:port] without a trailing /, the host may start with *. to allow all subdomains
+configuration error: security.cors.allowed_origins[2]: must be * or scheme://host[:port] without a trailing /, the host may start with *. to allow all subdomains
+configuration error: security.cors.max_age_seconds: security.cors.max_age_seconds field must be an integer at least 0 and at most 86400
+`
+	require.Equal(t, expected, logRecording.String())
+	require.Error(t, err)
+}
+
+func TestValidationErrorsEvents(t *testing.T) {
+	s := []byte(`service:
+  attendee_service: 'http://localhost:9091'
+  provider_adapter: 'http://localhost:9097'
+  events:
+    - name: 'EF2023'
+      allowed_currencies: ['eur']
+      vat_rates: [19.0, 107.0]
+      valid_from: '2023-12-31'
+      valid_until: '2023-01-01'
+    - name: 'EF-2024'
+      transaction_id_prefix: 'EF2023'
+      valid_from: '1.1.2024'
+    - name: 'EF2023'
+    - name: 'ef2023_lower'
+      transaction_id_prefix: 'ef2023'
+server:
+  port: 8080
+  read_timeout_seconds: 30
+  write_timeout_seconds: 30
+  idle_timeout_seconds: 120
+database:
+  use: inmemory
+security:
+  fixed_token:
+    api: 'some-api-token-must-be-long-enough'
+  oidc:
+    admin_group: 'admin'
+logging:
+  severity: INFO
+`)
+
+	b := bytes.NewBuffer(s)
+
+	conf, err := UnmarshalFromYamlConfiguration(b)
+	require.NoError(t, err)
+
+	logRecording := strings.Builder{}
+	logFunc := func(format string, v ...interface{}) {
+		logRecording.WriteString(fmt.Sprintf(format, v...))
+		logRecording.WriteString("\n")
+	}
+	err = Validate(conf, logFunc)
+
+	expected := `configuration error: service.events[0].allowed_currencies[0]: must be an ISO 4217 currency code, e.g. EUR
+configuration error: service.events[0].valid_until: must not be before valid_from
+configuration error: service.events[0].vat_rates[1]: must be between 0 and 100
+configuration error: service.events[1].name: must consist of 1 to 40 letters, digits or underscores
+configuration error: service.events[1].transaction_id_prefix: must be unique
+configuration error: service.events[1].valid_from: must be a date in the format yyyy-mm-dd
+configuration error: service.events[2].name: must be unique
+configuration error: service.events[2].transaction_id_prefix: must be unique
+configuration error: service.events[3].transaction_id_prefix: must be unique
+`
+	require.Equal(t, expected, logRecording.String())
+	require.Error(t, err)
+}
+
+func TestValidationErrorsInstallmentPlans(t *testing.T) {
+	s := []byte(`service:
+  attendee_service: 'http://localhost:9091'
+  provider_adapter: 'http://localhost:9097'
+  installment_plans:
+    sponsor:
+      installments: 3
+      interval_days: 30
+      minimum_dues_cent: 30000
+      minimum_installment_cent: 5000
+    Super-Sponsor:
+      installments: 1
+      interval_days: 0
+      minimum_installment_cent: -1
+server:
+  port: 8080
+  read_timeout_seconds: 30
+  write_timeout_seconds: 30
+  idle_timeout_seconds: 120
+database:
+  use: inmemory
+security:
+  fixed_token:
+    api: 'some-api-token-must-be-long-enough'
+  oidc:
+    admin_group: 'admin'
+logging:
+  severity: INFO
+`)
+
+	b := bytes.NewBuffer(s)
+
+	conf, err := UnmarshalFromYamlConfiguration(b)
+	require.NoError(t, err)
+	require.Equal(t, InstallmentPlanConfig{
+		Installments:           3,
+		IntervalDays:           30,
+		MinimumDuesCent:        300_00,
+		MinimumInstallmentCent: 50_00,
+	}, conf.Service.InstallmentPlans["sponsor"])
+
+	logRecording := strings.Builder{}
+	logFunc := func(format string, v ...interface{}) {
+		logRecording.WriteString(fmt.Sprintf(format, v...))
+		logRecording.WriteString("\n")
+	}
+	err = Validate(conf, logFunc)
+
+	expected := `configuration error: service.installment_plans.Super-Sponsor: name must consist of 1 to 40 lowercase letters, digits or underscores
+configuration error: service.installment_plans.Super-Sponsor.installments: service.installment_plans.Super-Sponsor.installments field must be an integer at least 2 and at most 24
+configuration error: service.installment_plans.Super-Sponsor.interval_days: service.installment_plans.Super-Sponsor.interval_days field must be an integer at least 1 and at most 365
+configuration error: service.installment_plans.Super-Sponsor.minimum_installment_cent: must not be negative
+`
+	require.Equal(t, expected, logRecording.String())
+	require.Error(t, err)
+}
+
+func TestValidationErrorsIssuer(t *testing.T) {
+	s := []byte(`service:
+  attendee_service: 'http://localhost:9091'
+  provider_adapter: 'http://localhost:9097'
+  issuer:
+    address:
+      - 'Sample Street 1'
+    invoice_number_prefix: 'RCT'
+server:
+  port: 8080
+  read_timeout_seconds: 30
+  write_timeout_seconds: 30
+  idle_timeout_seconds: 120
+database:
+  use: inmemory
+security:
+  fixed_token:
+    api: 'some-api-token-must-be-long-enough'
+  oidc:
+    admin_group: 'admin'
+logging:
+  severity: INFO
+`)
+
+	b := bytes.NewBuffer(s)
+
+	conf, err := UnmarshalFromYamlConfiguration(b)
+	require.NoError(t, err)
+	require.False(t, conf.Service.Issuer.IsConfigured())
+
+	logRecording := strings.Builder{}
+	logFunc := func(format string, v ...interface{}) {
+		logRecording.WriteString(fmt.Sprintf(format, v...))
+		logRecording.WriteString("\n")
+	}
+	err = Validate(conf, logFunc)
+
+	expected := `configuration error: service.issuer.name: must be set if any other issuer value is set
+configuration error: service.issuer.receipt_number_prefix: must differ from the invoice number prefix
+`
+	require.Equal(t, expected, logRecording.String())
+	require.Error(t, err)
+}
+
+func TestValidationErrorsSepa(t *testing.T) {
+	s := []byte(`service:
+  attendee_service: 'http://localhost:9091'
+  provider_adapter: 'http://localhost:9097'
+  sepa:
+    iban: 'DE89370400440532013001'
+    bic: 'COBADEFF'
+server:
+  port: 8080
+  read_timeout_seconds: 30
+  write_timeout_seconds: 30
+  idle_timeout_seconds: 120
+database:
+  use: inmemory
+security:
+  fixed_token:
+    api: 'some-api-token-must-be-long-enough'
+  oidc:
+    admin_group: 'admin'
+logging:
+  severity: INFO
+`)
+
+	b := bytes.NewBuffer(s)
+
+	conf, err := UnmarshalFromYamlConfiguration(b)
+	require.NoError(t, err)
+
+	logRecording := strings.Builder{}
+	logFunc := func(format string, v ...interface{}) {
+		logRecording.WriteString(fmt.Sprintf(format, v...))
+		logRecording.WriteString("\n")
+	}
+	err = Validate(conf, logFunc)
+
+	expected := `configuration error: service.sepa.beneficiary_name: must be between 1 and 70 characters long
+configuration error: service.sepa.iban: must be a valid IBAN in capital letters without spaces
+`
+	require.Equal(t, expected, logRecording.String())
+	require.Error(t, err)
+}
+
+func TestValidIBANChecksum(t *testing.T) {
+	require.True(t, validIBANChecksum("DE89370400440532013000"))
+	require.True(t, validIBANChecksum("GB82WEST12345698765432"))
+	require.False(t, validIBANChecksum("DE89370400440532013001"))
+	require.False(t, validIBANChecksum("DE89 3704 0044 0532 0130 00"))
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	envPrefix     = "REG_"
+	envFileSuffix = "_FILE"
+)
+
+// legacyEnvNames are the environment variables that were supported before any value could be set
+// from the environment. They have lower precedence than the variables named after the yaml path.
+var legacyEnvNames = map[string]string{
+	"database.password":              "REG_SECRET_DB_PASSWORD",
+	"security.fixed_token.api":       "REG_SECRET_API_TOKEN",
+	"security.ledger.checkpoint_key": "REG_SECRET_LEDGER_CHECKPOINT_KEY",
+}
+
+// ApplyEnvironment overrides configuration values from environment variables.
+//
+// Each value can be set by a variable named after its yaml path, e.g. REG_SERVICE_ALLOWED_CURRENCIES for
+// service.allowed_currencies. Lists are comma separated, and map entries have their own variables, e.g.
+// REG_SERVICE_PAYMENT_DEFAULT_COMMENT_CREDIT. Lists of structs, such as the rate limit routes, can only be set
+// in the configuration file. Empty variables are ignored.
+//
+// For secrets, the variable with the suffix _FILE may instead name a file to read the value from, with trailing
+// line breaks removed. Setting both variables for the same value is an error.
+//
+// The environment has precedence over the configuration file. Validate names the variable or file that a bad
+// value came from.
+func ApplyEnvironment(conf *Application, environ []string) error {
+	o := &envOverlay{
+		vars:    make(map[string]string),
+		sources: make(map[string]string),
+	}
+	for _, entry := range environ {
+		if name, value, ok := strings.Cut(entry, "="); ok && value != "" && strings.HasPrefix(name, envPrefix) {
+			o.vars[name] = value
+		}
+	}
+
+	o.applyStruct("", reflect.ValueOf(conf).Elem())
+	if len(o.errs) > 0 {
+		return errors.Join(o.errs...)
+	}
+
+	conf.sources = o.sources
+	return nil
+}
+
+type envOverlay struct {
+	vars    map[string]string
+	sources map[string]string
+	errs    []error
+}
+
+func envName(path string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+func (o *envOverlay) applyStruct(prefix string, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		path := prefix
+		if key := strings.Split(field.Tag.Get("yaml"), ",")[0]; key != "" {
+			path = strings.TrimPrefix(prefix+"."+key, ".")
+		}
+
+		fv := v.Field(i)
+		switch {
+		case fv.Kind() == reflect.Struct:
+			o.applyStruct(path, fv)
+		case fv.Kind() == reflect.Map && fv.Type().Key().Kind() == reflect.String && fv.Type().Elem().Kind() == reflect.String:
+			o.applyMap(path, fv)
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+			if value, source, ok := o.lookup(path); ok {
+				list := reflect.MakeSlice(fv.Type(), 0, 0)
+				for _, entry := range strings.Split(value, ",") {
+					if entry = strings.TrimSpace(entry); entry != "" {
+						list = reflect.Append(list, reflect.ValueOf(entry).Convert(fv.Type().Elem()))
+					}
+				}
+				fv.Set(list)
+				o.sources[path] = source
+			}
+		case fv.Kind() != reflect.Slice:
+			if value, source, ok := o.lookup(path); ok {
+				if err := setScalar(fv, value); err != nil {
+					o.errs = append(o.errs, fmt.Errorf("%s from %s %w", path, source, err))
+				}
+				o.sources[path] = source
+			}
+		}
+	}
+}
+
+// applyMap sets one map entry for each variable that starts with the name of the map.
+func (o *envOverlay) applyMap(path string, v reflect.Value) {
+	mapPrefix := envName(path) + "_"
+	keys := make(map[string]bool)
+	for name := range o.vars {
+		if key, ok := strings.CutPrefix(name, mapPrefix); ok {
+			keys[strings.ToLower(strings.TrimSuffix(key, envFileSuffix))] = true
+		}
+	}
+
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, key := range sortedKeys {
+		if value, source, ok := o.lookup(path + "." + key); ok {
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), reflect.ValueOf(value).Convert(v.Type().Elem()))
+			o.sources[path+"."+key] = source
+		}
+	}
+}
+
+// lookup returns the value for path from the environment, and a description of where it came from.
+func (o *envOverlay) lookup(path string) (string, string, bool) {
+	name := envName(path)
+	fileName, fromFile := o.vars[name+envFileSuffix]
+	value, fromVar := o.vars[name]
+
+	switch {
+	case fromFile && fromVar:
+		o.errs = append(o.errs, fmt.Errorf("%s: only one of %s and %s may be set", path, name, name+envFileSuffix))
+		return "", "", false
+	case fromFile:
+		contents, err := os.ReadFile(fileName)
+		if err != nil {
+			o.errs = append(o.errs, fmt.Errorf("%s: could not read file from %s: %w", path, name+envFileSuffix, err))
+			return "", "", false
+		}
+		return strings.TrimRight(string(contents), "\r\n"), fmt.Sprintf("file %s (%s)", fileName, name+envFileSuffix), true
+	case fromVar:
+		return value, "environment variable " + name, true
+	}
+
+	if legacyName, ok := legacyEnvNames[path]; ok {
+		if value, ok := o.vars[legacyName]; ok {
+			return value, "environment variable " + legacyName, true
+		}
+	}
+	return "", "", false
+}
+
+func setScalar(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("must be a whole number")
+		}
+		v.SetInt(int64(i))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
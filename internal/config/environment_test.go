@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyEnvironment(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "api-token")
+	require.NoError(t, os.WriteFile(secretFile, []byte("api-token-from-a-secret-file\n"), 0600))
+
+	tests := []struct {
+		name        string
+		environ     []string
+		expectedErr string
+		verify      func(t *testing.T, conf *Application)
+	}{
+		{
+			name: "should set values by their yaml path",
+			environ: []string{
+				"REG_SERVER_PORT=8081",
+				"REG_SERVICE_ALLOWED_CURRENCIES=EUR, USD",
+				"REG_SERVICE_PAYMENT_DEFAULT_COMMENT_TRANSFER=sepa payment",
+				"REG_SECURITY_CORS_ALLOW_CREDENTIALS=true",
+				"REG_TRACING_SAMPLE_RATIO=0.5",
+				"REG_DATABASE_USE=mysql",
+				"REG_LOGGING_SEVERITY=",
+				"OTHER_SERVER_PORT=1",
+			},
+			verify: func(t *testing.T, conf *Application) {
+				require.Equal(t, 8081, conf.Server.Port)
+				require.Equal(t, []string{"EUR", "USD"}, conf.Service.AllowedCurrencies)
+				require.Equal(t, map[string]string{"transfer": "sepa payment"}, conf.Service.DefaultPaymentComment)
+				require.True(t, conf.Security.Cors.AllowCredentials)
+				require.Equal(t, 0.5, conf.Tracing.SampleRatio)
+				require.Equal(t, Mysql, conf.Database.Use)
+				require.Equal(t, "INFO", conf.Logging.Severity)
+				require.Equal(t, "environment variable REG_SERVER_PORT", conf.sources["server.port"])
+			},
+		},
+		{
+			name:    "should read secrets from files",
+			environ: []string{"REG_SECURITY_FIXED_TOKEN_API_FILE=" + secretFile},
+			verify: func(t *testing.T, conf *Application) {
+				require.Equal(t, "api-token-from-a-secret-file", conf.Security.Fixed.Api)
+				require.Equal(t, fmt.Sprintf("file %s (REG_SECURITY_FIXED_TOKEN_API_FILE)", secretFile), conf.sources["security.fixed_token.api"])
+			},
+		},
+		{
+			name:    "should support the legacy secret variables",
+			environ: []string{"REG_SECRET_DB_PASSWORD=legacy", "REG_SECRET_API_TOKEN=legacy-api-token-value"},
+			verify: func(t *testing.T, conf *Application) {
+				require.Equal(t, "legacy", conf.Database.Password)
+				require.Equal(t, "legacy-api-token-value", conf.Security.Fixed.Api)
+			},
+		},
+		{
+			name:    "should prefer the yaml path over the legacy variables",
+			environ: []string{"REG_SECRET_DB_PASSWORD=legacy", "REG_DATABASE_PASSWORD=current"},
+			verify: func(t *testing.T, conf *Application) {
+				require.Equal(t, "current", conf.Database.Password)
+			},
+		},
+		{
+			name:        "should not allow a value and a file for the same value",
+			environ:     []string{"REG_SECURITY_FIXED_TOKEN_API=value", "REG_SECURITY_FIXED_TOKEN_API_FILE=" + secretFile},
+			expectedErr: "security.fixed_token.api: only one of REG_SECURITY_FIXED_TOKEN_API and REG_SECURITY_FIXED_TOKEN_API_FILE may be set",
+		},
+		{
+			name:        "should report values that cannot be parsed",
+			environ:     []string{"REG_SERVER_PORT=eighty", "REG_SECURITY_REQUIRE_LOGIN_FOR_REG=maybe"},
+			expectedErr: "server.port from environment variable REG_SERVER_PORT must be a whole number\nsecurity.require_login_for_reg from environment variable REG_SECURITY_REQUIRE_LOGIN_FOR_REG must be true or false",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := ParseYamlConfiguration(strings.NewReader(tstReloadConfig))
+			require.NoError(t, err)
+
+			err = ApplyEnvironment(conf, tt.environ)
+			if tt.expectedErr != "" {
+				require.EqualError(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.verify(t, conf)
+		})
+	}
+}
+
+func TestValidationErrorsNameEnvironmentSource(t *testing.T) {
+	conf, err := ParseYamlConfiguration(strings.NewReader(tstReloadConfig))
+	require.NoError(t, err)
+	require.NoError(t, ApplyEnvironment(conf, []string{"REG_SERVER_PORT=70000"}))
+
+	logRecording := strings.Builder{}
+	err = Validate(conf, func(format string, v ...interface{}) {
+		logRecording.WriteString(fmt.Sprintf(format, v...))
+		logRecording.WriteString("\n")
+	})
+	require.Error(t, err)
+	require.Equal(t, "configuration error: server.port: server.port field must be an integer at least 1 and at most 65535 (from environment variable REG_SERVER_PORT)\n", logRecording.String())
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"strings"
+	"time"
+)
+
+const eventDateFormat = "2006-01-02"
+
+// Prefix returns the transaction id prefix of the event.
+func (e EventConfig) Prefix() string {
+	if e.TransactionIDPrefix != "" {
+		return e.TransactionIDPrefix
+	}
+	return e.Name
+}
+
+// IsCurrencyAllowed checks the currency against the event's currencies, or against fallback if it has none.
+func (e EventConfig) IsCurrencyAllowed(isoCurrency string, fallback []string) bool {
+	allowed := e.AllowedCurrencies
+	if len(allowed) == 0 {
+		allowed = fallback
+	}
+	for _, cur := range allowed {
+		if strings.EqualFold(cur, isoCurrency) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsVatRateAllowed checks the vat rate against the event's rates, any rate is allowed if it has none.
+func (e EventConfig) IsVatRateAllowed(vatRate float64) bool {
+	if len(e.VatRates) == 0 {
+		return true
+	}
+	for _, rate := range e.VatRates {
+		if rate == vatRate {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers checks if the effective date falls into the event's date window.
+func (e EventConfig) Covers(effectiveDate time.Time) bool {
+	day := effectiveDate.Format(eventDateFormat)
+	// dates in this format compare correctly as strings
+	if e.ValidFrom != "" && day < e.ValidFrom {
+		return false
+	}
+	if e.ValidUntil != "" && day > e.ValidUntil {
+		return false
+	}
+	return true
+}
+
+// ConfiguredEvents returns the configured events, or the single event described by the legacy
+// transaction_id_prefix and allowed_currencies if there are none.
+func (c ServiceConfig) ConfiguredEvents() []EventConfig {
+	if len(c.Events) > 0 {
+		return c.Events
+	}
+	return []EventConfig{{
+		Name:              c.TransactionIDPrefix,
+		AllowedCurrencies: c.AllowedCurrencies,
+	}}
+}
+
+// EventByName returns the event with the given name.
+func (c ServiceConfig) EventByName(name string) (EventConfig, bool) {
+	for _, e := range c.ConfiguredEvents() {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return EventConfig{}, false
+}
+
+// EventByPrefix returns the event whose transaction ids start with the given prefix.
+func (c ServiceConfig) EventByPrefix(prefix string) (EventConfig, bool) {
+	for _, e := range c.ConfiguredEvents() {
+		if e.Prefix() == prefix {
+			return e, true
+		}
+	}
+	return EventConfig{}, false
+}
+
+// EventForDate returns the first event whose date window covers the effective date.
+func (c ServiceConfig) EventForDate(effectiveDate time.Time) (EventConfig, bool) {
+	for _, e := range c.ConfiguredEvents() {
+		if e.Covers(effectiveDate) {
+			return e, true
+		}
+	}
+	return EventConfig{}, false
+}
+
+// NearestEvent returns the event closest to a date that no event covers: the most recently ended
+// event, or the next one to start if none has ended yet.
+func (c ServiceConfig) NearestEvent(date time.Time) (EventConfig, bool) {
+	day := date.Format(eventDateFormat)
+	var recent, next EventConfig
+	var haveRecent, haveNext bool
+	for _, e := range c.ConfiguredEvents() {
+		if e.ValidUntil != "" && e.ValidUntil < day && (!haveRecent || e.ValidUntil > recent.ValidUntil) {
+			recent, haveRecent = e, true
+		}
+		if e.ValidFrom != "" && e.ValidFrom > day && (!haveNext || e.ValidFrom < next.ValidFrom) {
+			next, haveNext = e, true
+		}
+	}
+	if haveRecent {
+		return recent, true
+	}
+	return next, haveNext
+}
@@ -0,0 +1,22 @@
+package config
+
+// IsConfigured is true if invoices and receipts can be rendered.
+func (c IssuerConfig) IsConfigured() bool {
+	return c.Name != ""
+}
+
+// InvoicePrefix returns the prefix of invoice numbers.
+func (c IssuerConfig) InvoicePrefix() string {
+	if c.InvoiceNumberPrefix != "" {
+		return c.InvoiceNumberPrefix
+	}
+	return "INV"
+}
+
+// ReceiptPrefix returns the prefix of receipt numbers.
+func (c IssuerConfig) ReceiptPrefix() string {
+	if c.ReceiptNumberPrefix != "" {
+		return c.ReceiptNumberPrefix
+	}
+	return "RCT"
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	reloadMu        sync.Mutex
+	reloadListeners []func(conf *Application)
+)
+
+// OnReload registers a listener that is called with the new configuration after each successful reload.
+//
+// Use this for components that build their state from the configuration once, e.g. the cors policy.
+// Listeners are called one after the other, and should return quickly.
+func OnReload(listener func(conf *Application)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	reloadListeners = append(reloadListeners, listener)
+}
+
+// Reload validates conf and, if it is valid, replaces the application configuration with it.
+//
+// Only the values listed in clearLiveFields can change while the service is running. If any other
+// value differs from the current configuration, the reload is rejected and the changed fields are
+// logged, because they only take effect after a restart.
+func Reload(conf *Application, logFunc func(format string, v ...interface{})) error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	current := appConfig.Load()
+	if current == nil {
+		return errors.New("config was not yet loaded")
+	}
+
+	changed := changedFields("", reflect.ValueOf(clearLiveFields(*current)), reflect.ValueOf(clearLiveFields(*conf)))
+	if len(changed) > 0 {
+		for _, field := range changed {
+			logFunc("configuration reload: %s cannot change while the service is running", field)
+		}
+		return fmt.Errorf("configuration reload rejected, changing %s requires a restart", strings.Join(changed, ", "))
+	}
+
+	if err := Validate(conf, logFunc); err != nil {
+		return err
+	}
+
+	appConfig.Store(conf)
+	for _, listener := range reloadListeners {
+		listener(conf)
+	}
+
+	return nil
+}
+
+// clearLiveFields returns a copy of conf without the values that can change while the service is running.
+//
+// These are the values that are read from GetApplicationConfig where they are needed,
+// or that have a reload listener.
+func clearLiveFields(conf Application) Application {
+	conf.Service.AllowedCurrencies = nil
+	conf.Service.DefaultPaymentComment = nil
+	conf.Service.PublicSepaLinkURL = ""
+	conf.Service.Events = nil
+	conf.Service.InstallmentPlans = nil
+	conf.Service.Issuer = IssuerConfig{}
+	conf.Service.Sepa = SepaConfig{}
+	conf.Security.Cors = CorsConfig{}
+	conf.Security.Oidc.AdminGroup = ""
+	conf.Logging.Severity = ""
+	return conf
+}
+
+// changedFields compares two structs field by field, and returns the yaml keys of all fields that differ.
+func changedFields(prefix string, old reflect.Value, updated reflect.Value) []string {
+	changed := make([]string, 0)
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if key != "" && prefix != "" {
+			key = prefix + "." + key
+		} else if key == "" {
+			key = prefix // inline
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			changed = append(changed, changedFields(key, old.Field(i), updated.Field(i))...)
+		} else if !reflect.DeepEqual(old.Field(i).Interface(), updated.Field(i).Interface()) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const tstReloadConfig = `service:
+  name: 'TestServiceName'
+  attendee_service: 'http://localhost:9091'
+  provider_adapter: 'http://localhost:9097'
+  allowed_currencies:
+    - 'EUR'
+server:
+  port: 8080
+  read_timeout_seconds: 30
+  write_timeout_seconds: 30
+  idle_timeout_seconds: 120
+database:
+  use: inmemory
+security:
+  fixed_token:
+    api: 'some-api-token-must-be-long-enough'
+  oidc:
+    admin_group: 'admin'
+  cors:
+    allowed_origins:
+      - 'https://regsys.example.com'
+logging:
+  severity: INFO
+`
+
+func TestReload(t *testing.T) {
+	tests := []struct {
+		name           string
+		change         func(conf *Application)
+		expectedErr    string
+		expectedLog    string
+		expectReloaded bool
+	}{
+		{
+			name: "should apply values that can change live",
+			change: func(conf *Application) {
+				conf.Service.AllowedCurrencies = []string{"EUR", "USD"}
+				conf.Security.Cors.AllowedOrigins = []string{"https://reg.example.com"}
+				conf.Logging.Severity = "DEBUG"
+			},
+			expectReloaded: true,
+		},
+		{
+			name: "should reject values that need a restart",
+			change: func(conf *Application) {
+				conf.Server.Port = 8081
+				conf.Database.Use = Mysql
+				conf.Logging.Severity = "DEBUG"
+			},
+			expectedErr: "configuration reload rejected, changing server.port, database.use requires a restart",
+			expectedLog: "configuration reload: server.port cannot change while the service is running\n" +
+				"configuration reload: database.use cannot change while the service is running\n",
+		},
+		{
+			name: "should reject invalid values",
+			change: func(conf *Application) {
+				conf.Logging.Severity = "LOUD"
+			},
+			expectedErr: "configuration values failed to validate, bailing out",
+			expectedLog: "configuration error: logging.severity: must be one of DEBUG, INFO, WARN, ERROR\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, err := UnmarshalFromYamlConfiguration(strings.NewReader(tstReloadConfig))
+			require.NoError(t, err)
+			require.NoError(t, Validate(current, func(format string, v ...interface{}) {}))
+
+			reloaded, err := ParseYamlConfiguration(strings.NewReader(tstReloadConfig))
+			require.NoError(t, err)
+			tt.change(reloaded)
+
+			var listenerCalled *Application
+			reloadListeners = []func(conf *Application){func(conf *Application) {
+				listenerCalled = conf
+			}}
+			defer func() { reloadListeners = nil }()
+
+			logRecording := strings.Builder{}
+			err = Reload(reloaded, func(format string, v ...interface{}) {
+				logRecording.WriteString(fmt.Sprintf(format, v...))
+				logRecording.WriteString("\n")
+			})
+
+			actual, _ := GetApplicationConfig()
+			if tt.expectReloaded {
+				require.NoError(t, err)
+				require.Same(t, reloaded, actual)
+				require.Same(t, reloaded, listenerCalled)
+			} else {
+				require.EqualError(t, err, tt.expectedErr)
+				require.Same(t, current, actual)
+				require.Nil(t, listenerCalled)
+			}
+			require.Equal(t, tt.expectedLog, logRecording.String())
+		})
+	}
+}
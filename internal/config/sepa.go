@@ -0,0 +1,6 @@
+package config
+
+// IsConfigured is true if EPC QR codes can be generated for sepa transfers.
+func (c SepaConfig) IsConfigured() bool {
+	return c.BeneficiaryName != ""
+}
@@ -4,9 +4,13 @@ import (
 	"crypto/rsa"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"regexp"
 	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -16,14 +20,16 @@ func Validate(conf *Application, logFunc func(format string, v ...interface{}))
 	validateServiceConfiguration(errs, conf.Service)
 	validateServerConfiguration(errs, conf.Server)
 	validateDatabaseConfiguration(errs, conf.Database)
-	validateSecurityConfiguration(errs, conf.Security)
+	keySet := validateSecurityConfiguration(errs, conf.Security)
 	validateLoggingConfiguration(errs, conf.Logging)
+	validateTracingConfiguration(errs, conf.Tracing)
 
 	if len(errs) > 0 {
-		logValidationErrorDetails(errs, logFunc)
+		logValidationErrorDetails(errs, conf.sources, logFunc)
 		return errors.New("configuration values failed to validate, bailing out")
 	}
 
+	parsedKeySet.Store(&keySet)
 	return nil
 }
 
@@ -33,9 +39,139 @@ func validateServiceConfiguration(errs url.Values, c ServiceConfig) {
 	if violatesPattern(downstreamPattern, c.AttendeeService) {
 		errs.Add("service.attendee_service", "base url must start with http:// or https:// and may not end in a /")
 	}
+	checkIntValueRange(errs, 0, 300, "service.attendee_service_cache_seconds", c.AttendeeServiceCacheSeconds)
 	if violatesPattern(downstreamPattern, c.ProviderAdapter) {
 		errs.Add("service.provider_adapter", "base url must start with http:// or https:// and may not end in a /")
 	}
+	validateEventConfiguration(errs, c.Events)
+	validateInstallmentPlanConfiguration(errs, c.InstallmentPlans)
+	validateIssuerConfiguration(errs, c.Issuer)
+	validateSepaConfiguration(errs, c.Sepa)
+}
+
+const (
+	eventNamePattern = "^[A-Za-z0-9_]{1,40}$" // no dashes, because the prefix is the first segment of the transaction id
+	currencyPattern  = "^[A-Z]{3}$"
+)
+
+func validateEventConfiguration(errs url.Values, events []EventConfig) {
+	names := make(map[string]bool)
+	prefixes := make(map[string]bool)
+	for i, e := range events {
+		key := fmt.Sprintf("service.events[%d]", i)
+		if violatesPattern(eventNamePattern, e.Name) {
+			errs.Add(key+".name", "must consist of 1 to 40 letters, digits or underscores")
+		} else if names[e.Name] {
+			errs.Add(key+".name", "must be unique")
+		}
+		names[e.Name] = true
+
+		if e.TransactionIDPrefix != "" && violatesPattern(eventNamePattern, e.TransactionIDPrefix) {
+			errs.Add(key+".transaction_id_prefix", "must consist of 1 to 40 letters, digits or underscores")
+		} else if prefixes[strings.ToUpper(e.Prefix())] {
+			// also ignoring case, because creditor references are upper case
+			errs.Add(key+".transaction_id_prefix", "must be unique")
+		}
+		prefixes[strings.ToUpper(e.Prefix())] = true
+
+		for j, currency := range e.AllowedCurrencies {
+			if violatesPattern(currencyPattern, currency) {
+				errs.Add(fmt.Sprintf("%s.allowed_currencies[%d]", key, j), "must be an ISO 4217 currency code, e.g. EUR")
+			}
+		}
+		for j, rate := range e.VatRates {
+			if rate < 0 || rate > 100 {
+				errs.Add(fmt.Sprintf("%s.vat_rates[%d]", key, j), "must be between 0 and 100")
+			}
+		}
+
+		from, fromErr := time.Parse(eventDateFormat, e.ValidFrom)
+		if e.ValidFrom != "" && fromErr != nil {
+			errs.Add(key+".valid_from", "must be a date in the format yyyy-mm-dd")
+		}
+		until, untilErr := time.Parse(eventDateFormat, e.ValidUntil)
+		if e.ValidUntil != "" && untilErr != nil {
+			errs.Add(key+".valid_until", "must be a date in the format yyyy-mm-dd")
+		}
+		if fromErr == nil && untilErr == nil && until.Before(from) {
+			errs.Add(key+".valid_until", "must not be before valid_from")
+		}
+	}
+}
+
+const installmentPlanNamePattern = "^[a-z0-9_]{1,40}$"
+
+func validateInstallmentPlanConfiguration(errs url.Values, plans map[string]InstallmentPlanConfig) {
+	for name, plan := range plans {
+		key := fmt.Sprintf("service.installment_plans.%s", name)
+		if violatesPattern(installmentPlanNamePattern, name) {
+			errs.Add(key, "name must consist of 1 to 40 lowercase letters, digits or underscores")
+		}
+		checkIntValueRange(errs, 2, 24, key+".installments", plan.Installments)
+		checkIntValueRange(errs, 1, 365, key+".interval_days", plan.IntervalDays)
+		if plan.MinimumDuesCent < 0 {
+			errs.Add(key+".minimum_dues_cent", "must not be negative")
+		}
+		if plan.MinimumInstallmentCent < 0 {
+			errs.Add(key+".minimum_installment_cent", "must not be negative")
+		}
+	}
+}
+
+const documentNumberPrefixPattern = "^[A-Za-z0-9_]{0,20}$"
+
+func validateIssuerConfiguration(errs url.Values, c IssuerConfig) {
+	if c.Name == "" && (len(c.Address) > 0 || c.VatID != "" || c.Email != "") {
+		errs.Add("service.issuer.name", "must be set if any other issuer value is set")
+	}
+	if violatesPattern(documentNumberPrefixPattern, c.InvoiceNumberPrefix) {
+		errs.Add("service.issuer.invoice_number_prefix", "must consist of up to 20 letters, digits or underscores")
+	}
+	if violatesPattern(documentNumberPrefixPattern, c.ReceiptNumberPrefix) {
+		errs.Add("service.issuer.receipt_number_prefix", "must consist of up to 20 letters, digits or underscores")
+	} else if c.InvoicePrefix() == c.ReceiptPrefix() {
+		errs.Add("service.issuer.receipt_number_prefix", "must differ from the invoice number prefix")
+	}
+}
+
+const (
+	ibanPattern = "^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$"
+	bicPattern  = "^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$"
+)
+
+func validateSepaConfiguration(errs url.Values, c SepaConfig) {
+	if c == (SepaConfig{}) {
+		return
+	}
+	if c.BeneficiaryName == "" || utf8.RuneCountInString(c.BeneficiaryName) > 70 {
+		errs.Add("service.sepa.beneficiary_name", "must be between 1 and 70 characters long")
+	}
+	if violatesPattern(ibanPattern, c.IBAN) || !validIBANChecksum(c.IBAN) {
+		errs.Add("service.sepa.iban", "must be a valid IBAN in capital letters without spaces")
+	}
+	if c.BIC != "" && violatesPattern(bicPattern, c.BIC) {
+		errs.Add("service.sepa.bic", "must be a BIC of 8 or 11 capital letters or digits")
+	}
+}
+
+// validIBANChecksum checks the two check digits of an IBAN, see ISO 13616.
+func validIBANChecksum(iban string) bool {
+	if len(iban) < 4 {
+		return false
+	}
+
+	remainder := 0
+	for _, c := range iban[4:] + iban[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			remainder = (remainder*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return remainder == 1
 }
 
 func validateServerConfiguration(errs url.Values, c ServerConfig) {
@@ -43,21 +179,76 @@ func validateServerConfiguration(errs url.Values, c ServerConfig) {
 	checkIntValueRange(errs, 1, 300, "server.read_timeout_seconds", c.ReadTimeout)
 	checkIntValueRange(errs, 1, 300, "server.write_timeout_seconds", c.WriteTimeout)
 	checkIntValueRange(errs, 1, 300, "server.idle_timeout_seconds", c.IdleTimeout)
+	checkIntValueRange(errs, 0, 65535, "server.metrics_port", c.MetricsPort)
+	checkIntValueRange(errs, 0, 60, "server.shutdown_delay_seconds", c.ShutdownDelay)
+	checkIntValueRange(errs, 0, 300, "server.shutdown_drain_timeout_seconds", c.ShutdownDrainTimeout)
+	if c.MetricsPort != 0 && c.MetricsPort == c.Port {
+		errs.Add("server.metrics_port", "must be different from server.port")
+	}
 }
 
-func validateSecurityConfiguration(errs url.Values, c SecurityConfig) {
+// validateSecurityConfiguration returns the parsed token public keys, which only take effect if the whole configuration is valid.
+func validateSecurityConfiguration(errs url.Values, c SecurityConfig) []*rsa.PublicKey {
 	checkLength(&errs, 16, 256, "security.fixed_token.api", c.Fixed.Api)
-	checkLength(&errs, 1, 256, "security.oidc.admin_role", c.Oidc.AdminGroup)
+	checkLength(&errs, 1, 256, "security.oidc.admin_group", c.Oidc.AdminGroup)
+	if c.Ledger.CheckpointKey != "" {
+		checkLength(&errs, 16, 256, "security.ledger.checkpoint_key", c.Ledger.CheckpointKey)
+	}
+	validateRateLimitConfiguration(errs, c.RateLimit)
+	validateCorsConfiguration(errs, c.Cors)
 
-	parsedKeySet = make([]*rsa.PublicKey, 0)
+	keySet := make([]*rsa.PublicKey, 0)
 	for i, keyStr := range c.Oidc.TokenPublicKeysPEM {
 		publicKeyPtr, err := jwt.ParseRSAPublicKeyFromPEM([]byte(keyStr))
 		if err != nil {
 			errs.Add(fmt.Sprintf("security.oidc.token_public_keys_PEM[%d]", i), fmt.Sprintf("failed to parse RSA public key in PEM format: %s", err.Error()))
 		} else {
-			parsedKeySet = append(parsedKeySet, publicKeyPtr)
+			keySet = append(keySet, publicKeyPtr)
+		}
+	}
+	return keySet
+}
+
+var allowedRateLimitMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+
+func validateRateLimitConfiguration(errs url.Values, c RateLimitConfig) {
+	for i, route := range c.Routes {
+		key := fmt.Sprintf("security.rate_limit.routes[%d]", i)
+		if notInAllowedValues(allowedRateLimitMethods, route.Method) {
+			errs.Add(key+".method", "must be one of GET, POST, PUT, DELETE")
 		}
+		if violatesPattern("^/", route.Path) {
+			errs.Add(key+".path", "must start with a /")
+		}
+		checkIntValueRange(errs, 1, 100000, key+".requests_per_minute", route.RequestsPerMinute)
+		checkIntValueRange(errs, 0, 10000, key+".burst", route.Burst)
 	}
+
+	checkIntValueRange(errs, 0, 100000, "security.rate_limit.api_token.requests_per_minute", c.ApiToken.RequestsPerMinute)
+	checkIntValueRange(errs, 0, 10000, "security.rate_limit.api_token.burst", c.ApiToken.Burst)
+}
+
+const corsOriginPattern = `^https?://(\*\.)?[a-zA-Z0-9.-]+(:[0-9]+)?$`
+
+var allowedCorsMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch, http.MethodHead}
+
+func validateCorsConfiguration(errs url.Values, c CorsConfig) {
+	for i, origin := range c.AllowedOrigins {
+		key := fmt.Sprintf("security.cors.allowed_origins[%d]", i)
+		if origin == "*" {
+			if c.AllowCredentials {
+				errs.Add(key, "* may not be used together with allow_credentials, list the origins instead")
+			}
+		} else if violatesPattern(corsOriginPattern, origin) {
+			errs.Add(key, "must be * or scheme://host[:port] without a trailing /, the host may start with *. to allow all subdomains")
+		}
+	}
+	for i, method := range c.AllowedMethods {
+		if notInAllowedValues(allowedCorsMethods, method) {
+			errs.Add(fmt.Sprintf("security.cors.allowed_methods[%d]", i), "must be one of GET, POST, PUT, DELETE, PATCH, HEAD")
+		}
+	}
+	checkIntValueRange(errs, 0, 86400, "security.cors.max_age_seconds", c.MaxAgeSeconds)
 }
 
 var allowedDatabases = []DatabaseType{Mysql, Inmemory}
@@ -81,6 +272,20 @@ func validateLoggingConfiguration(errs url.Values, c LoggingConfig) {
 	}
 }
 
+var allowedTracingExporters = []TracingExporter{"", TracingNone, TracingOtlp, TracingStdout}
+
+func validateTracingConfiguration(errs url.Values, c TracingConfig) {
+	if notInAllowedValues(allowedTracingExporters, c.Exporter) {
+		errs.Add("tracing.exporter", "must be one of none, otlp, stdout")
+	}
+	if c.Exporter == TracingOtlp && violatesPattern(downstreamPattern, c.Endpoint) {
+		errs.Add("tracing.endpoint", "base url must start with http:// or https:// and may not end in a /")
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		errs.Add("tracing.sample_ratio", "must be between 0 and 1")
+	}
+}
+
 func violatesPattern(pattern string, value string) bool {
 	matched, err := regexp.MatchString(pattern, value)
 	if err != nil {
@@ -114,7 +319,7 @@ func sliceContains[T comparable](s []T, e T) bool {
 	return false
 }
 
-func logValidationErrorDetails(errs url.Values, logFunc func(format string, v ...interface{})) {
+func logValidationErrorDetails(errs url.Values, sources map[string]string, logFunc func(format string, v ...interface{})) {
 	var keys []string
 	for key := range errs {
 		keys = append(keys, key)
@@ -124,6 +329,11 @@ func logValidationErrorDetails(errs url.Values, logFunc func(format string, v ..
 	for _, k := range keys {
 		key := k
 		val := errs[k]
-		logFunc("configuration error: %s: %s", key, val[0])
+		// list entries are reported with their index, e.g. security.oidc.token_public_keys_PEM[0]
+		if source, ok := sources[strings.Split(key, "[")[0]]; ok {
+			logFunc("configuration error: %s: %s (from %s)", key, val[0], source)
+		} else {
+			logFunc("configuration error: %s: %s", key, val[0])
+		}
 	}
 }
@@ -0,0 +1,163 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// A4 in points
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+)
+
+type font int
+
+const (
+	regular font = iota + 1 // the numbers match the font resources /F1 and /F2
+	bold
+)
+
+// pdf collects text on pages and writes it as a PDF 1.4 file.
+//
+// It only uses the standard Helvetica fonts, which every PDF viewer provides, so no fonts need to be embedded.
+// In exchange, text is limited to the characters of WinAnsiEncoding, others are printed as '?'.
+type pdf struct {
+	pages []*bytes.Buffer // one content stream per page
+}
+
+func (p *pdf) addPage() {
+	p.pages = append(p.pages, &bytes.Buffer{})
+}
+
+func (p *pdf) currentPage() *bytes.Buffer {
+	if len(p.pages) == 0 {
+		p.addPage()
+	}
+	return p.pages[len(p.pages)-1]
+}
+
+// text prints s starting at x, with its baseline at y. Coordinates start at the bottom left of the page.
+func (p *pdf) text(x, y float64, f font, size float64, s string) {
+	fmt.Fprintf(p.currentPage(), "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", f, num(size), num(x), num(y), escape(encodeWinAnsi(s)))
+}
+
+// textRight prints s so that it ends at x.
+func (p *pdf) textRight(x, y float64, f font, size float64, s string) {
+	p.text(x-textWidth(s, size), y, f, size, s)
+}
+
+// rule draws a thin horizontal line from x1 to x2.
+func (p *pdf) rule(x1, x2, y float64) {
+	fmt.Fprintf(p.currentPage(), "0.5 w %s %s m %s %s l S\n", num(x1), num(y), num(x2), num(y))
+}
+
+// bytes returns the complete file.
+func (p *pdf) bytes() []byte {
+	p.currentPage()
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// the comment with bytes above 127 marks the file as binary for transfer programs
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// catalog, page tree and fonts come first, then each page followed by its content stream
+	kids := make([]string, len(p.pages))
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, content := range p.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(pageWidth), num(pageHeight), 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// winAnsiExtras are the characters WinAnsiEncoding places between 0x80 and 0x9f, where Latin-1 has control characters
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '™': 0x99,
+}
+
+func encodeWinAnsi(s string) []byte {
+	result := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			result = append(result, byte(r))
+		case r == '\t' || r == '\n' || r == '\r':
+			result = append(result, ' ')
+		default:
+			if b, ok := winAnsiExtras[r]; ok {
+				result = append(result, b)
+			} else {
+				result = append(result, '?')
+			}
+		}
+	}
+	return result
+}
+
+func escape(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		if c == '\\' || c == '(' || c == ')' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// helveticaWidths are the widths of the printable ASCII characters in Helvetica, in thousandths of the font size.
+//
+// They are also used for Helvetica-Bold, which has the same widths for digits, punctuation and most capitals,
+// so right aligned amounts line up in both.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, // 0 to 9
+	278, 278, 584, 584, 584, 556, 1015, // : to @
+	667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, // A to M
+	722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, // N to Z
+	278, 278, 278, 469, 556, 333, // [ to `
+	556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, // a to m
+	556, 556, 556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, // n to z
+	334, 260, 334, 584, // { to ~
+}
+
+// textWidth measures s in points, characters outside of ASCII are assumed to be as wide as a digit.
+func textWidth(s string, size float64) float64 {
+	width := 0
+	for _, c := range encodeWinAnsi(s) {
+		if c >= 0x20 && c < 0x7f {
+			width += helveticaWidths[c-0x20]
+		} else {
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
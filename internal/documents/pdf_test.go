@@ -0,0 +1,47 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPdfCrossReferenceTable(t *testing.T) {
+	var p pdf
+	p.text(56, 700, bold, 12, "first page")
+	p.addPage()
+	p.textRight(539, 700, regular, 10, "second page")
+	p.rule(56, 539, 690)
+
+	out := p.bytes()
+	require.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	require.Contains(t, string(out), "/Type /Pages /Kids [5 0 R 7 0 R] /Count 2")
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	require.NotNil(t, startxref)
+	xref, err := strconv.Atoi(string(startxref[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(out[xref:], []byte("xref\n0 9\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(out[xref:], -1)
+	require.Len(t, entries, 8)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(out[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	require.Equal(t, "Gr\xfc\xdfe \\(5 \x80\\) a\\\\b ?", escape(encodeWinAnsi("Grüße (5 €) a\\b ✓")))
+}
+
+func TestTextWidth(t *testing.T) {
+	require.InDelta(t, 5.56*3+2.78, textWidth("1.00", 10), 0.001)
+	require.Less(t, textWidth("iii", 10), textWidth("WWW", 10))
+}
@@ -0,0 +1,230 @@
+package documents
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
+)
+
+const (
+	marginLeft   = 56.0
+	marginRight  = pageWidth - 56.0
+	marginTop    = pageHeight - 56.0
+	marginBottom = 56.0
+
+	// right ends of the columns of the item table, the description takes the space left of them
+	columnQuantity = 340.0
+	columnUnit     = 415.0
+	columnVat      = 460.0
+	columnAmount   = marginRight
+
+	// left end of the labels of the totals, their values line up with the amounts
+	columnTotals = 330.0
+
+	fontSize = 10.0
+)
+
+var paymentMethodNames = map[entities.PaymentMethod]string{
+	entities.PaymentMethodCredit:   "credit card",
+	entities.PaymentMethodPaypal:   "PayPal",
+	entities.PaymentMethodTransfer: "bank transfer",
+	entities.PaymentMethodInternal: "internal transfer",
+	entities.PaymentMethodGift:     "gift",
+	entities.PaymentMethodCash:     "cash",
+}
+
+// Render lays out the invoice or receipt on A4 pages and returns it as a PDF file.
+func Render(doc interaction.Document) []byte {
+	l := &layout{y: marginTop}
+	l.pdf.addPage()
+
+	l.header(doc)
+
+	switch doc.Kind {
+	case entities.DocumentKindInvoice:
+		l.invoiceItems(doc)
+	case entities.DocumentKindReceipt:
+		l.receiptItems(doc)
+	}
+
+	l.totals(doc)
+
+	if doc.Kind == entities.DocumentKindReceipt {
+		l.space(fontSize)
+		l.pdf.text(marginLeft, l.line(fontSize), regular, fontSize, "We have received the payment above, thank you.")
+	}
+
+	return l.pdf.bytes()
+}
+
+// Filename suggests a file name for the document, e.g. invoice-INV-000042.pdf.
+func Filename(doc interaction.Document) string {
+	return fmt.Sprintf("%s-%s.pdf", doc.Kind, doc.Number)
+}
+
+type layout struct {
+	pdf pdf
+	y   float64 // baseline of the last line
+}
+
+// line returns the baseline of the next line of the given font size, continuing on a new page if the current one is full.
+func (l *layout) line(size float64) float64 {
+	l.y -= size * 1.4
+	if l.y < marginBottom {
+		l.pdf.addPage()
+		l.y = marginTop - size*1.4
+	}
+	return l.y
+}
+
+func (l *layout) space(height float64) {
+	l.y -= height
+}
+
+func (l *layout) header(doc interaction.Document) {
+	issuer := doc.Issuer
+	l.pdf.text(marginLeft, l.line(12), bold, 12, issuer.Name)
+	for _, address := range issuer.Address {
+		l.pdf.text(marginLeft, l.line(fontSize), regular, fontSize, address)
+	}
+	if issuer.Email != "" {
+		l.pdf.text(marginLeft, l.line(fontSize), regular, fontSize, issuer.Email)
+	}
+	if issuer.VatID != "" {
+		l.pdf.text(marginLeft, l.line(fontSize), regular, fontSize, "VAT ID: "+issuer.VatID)
+	}
+
+	l.space(30)
+	title := "Invoice"
+	if doc.Kind == entities.DocumentKindReceipt {
+		title = "Receipt"
+	}
+	l.pdf.text(marginLeft, l.line(18), bold, 18, title)
+	l.space(6)
+
+	l.field(title+" number", doc.Number)
+	l.field("Date", doc.IssuedAt.Format("2006-01-02"))
+	l.field("Debitor", strconv.FormatInt(doc.DebitorID, 10))
+	if doc.Event != "" {
+		l.field("Event", doc.Event)
+	}
+	if doc.Kind == entities.DocumentKindReceipt {
+		for _, payment := range doc.Payments {
+			l.field("Transaction", payment.TransactionID)
+		}
+	}
+	l.space(20)
+}
+
+func (l *layout) field(label string, value string) {
+	y := l.line(fontSize)
+	l.pdf.text(marginLeft, y, bold, fontSize, label)
+	l.pdf.text(marginLeft+100, y, regular, fontSize, value)
+}
+
+func (l *layout) tableHeader() {
+	y := l.line(fontSize)
+	l.pdf.text(marginLeft, y, bold, fontSize, "Description")
+	l.pdf.textRight(columnQuantity, y, bold, fontSize, "Quantity")
+	l.pdf.textRight(columnUnit, y, bold, fontSize, "Unit price")
+	l.pdf.textRight(columnVat, y, bold, fontSize, "VAT")
+	l.pdf.textRight(columnAmount, y, bold, fontSize, "Amount")
+	l.pdf.rule(marginLeft, marginRight, y-4)
+	l.space(4)
+}
+
+func (l *layout) tableRow(description string, quantity int64, unitCent int64, vatRate float64, grossCent int64, currency string) {
+	y := l.line(fontSize)
+	l.pdf.text(marginLeft, y, regular, fontSize, fit(description, columnQuantity-marginLeft-60, fontSize))
+	l.pdf.textRight(columnQuantity, y, regular, fontSize, strconv.FormatInt(quantity, 10))
+	l.pdf.textRight(columnUnit, y, regular, fontSize, formatCent(unitCent))
+	l.pdf.textRight(columnVat, y, regular, fontSize, formatVatRate(vatRate))
+	l.pdf.textRight(columnAmount, y, regular, fontSize, formatAmount(grossCent, currency))
+}
+
+func (l *layout) invoiceItems(doc interaction.Document) {
+	l.tableHeader()
+	for _, due := range doc.Dues {
+		if len(due.LineItems) == 0 {
+			description := due.Comment
+			if description == "" {
+				description = "Dues " + due.TransactionID
+			}
+			l.tableRow(description, 1, due.Amount.GrossCent, due.Amount.VatRate, due.Amount.GrossCent, doc.Currency)
+			continue
+		}
+		for _, item := range due.LineItems {
+			l.tableRow(item.Description, item.Quantity, item.UnitCent, item.VatRate, item.GrossCent(), doc.Currency)
+		}
+	}
+	l.space(10)
+}
+
+func (l *layout) receiptItems(doc interaction.Document) {
+	l.tableHeader()
+	for _, payment := range doc.Payments {
+		description := "Payment"
+		if method, ok := paymentMethodNames[payment.PaymentMethod]; ok {
+			description += " by " + method
+		}
+		if payment.EffectiveDate.Valid {
+			description += " on " + payment.EffectiveDate.Time.Format("2006-01-02")
+		}
+		l.tableRow(description, 1, payment.Amount.GrossCent, payment.Amount.VatRate, payment.Amount.GrossCent, doc.Currency)
+	}
+	l.space(10)
+}
+
+func (l *layout) totals(doc interaction.Document) {
+	for _, vat := range doc.VatTotals {
+		l.total(regular, "Net amount at "+formatVatRate(vat.VatRate), formatAmount(vat.NetCent, doc.Currency))
+		l.total(regular, "VAT "+formatVatRate(vat.VatRate), formatAmount(vat.VatCent, doc.Currency))
+	}
+	l.pdf.rule(columnTotals, marginRight, l.y-4)
+	l.space(4)
+	l.total(bold, "Total", formatAmount(doc.TotalCent, doc.Currency))
+
+	if doc.Kind == entities.DocumentKindInvoice {
+		l.space(6)
+		l.total(regular, "Paid", formatAmount(doc.PaidCent, doc.Currency))
+		l.total(bold, "Outstanding", formatAmount(doc.OutstandingCent(), doc.Currency))
+	}
+}
+
+func (l *layout) total(f font, label string, value string) {
+	y := l.line(fontSize)
+	l.pdf.text(columnTotals, y, f, fontSize, label)
+	l.pdf.textRight(columnAmount, y, f, fontSize, value)
+}
+
+// fit shortens s so it is at most width points wide.
+func fit(s string, width float64, size float64) string {
+	if textWidth(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && textWidth(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return strings.TrimRight(string(runes), " ") + "..."
+}
+
+func formatCent(cent int64) string {
+	sign := ""
+	if cent < 0 {
+		sign = "-"
+		cent = -cent
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cent/100, cent%100)
+}
+
+func formatAmount(cent int64, currency string) string {
+	return formatCent(cent) + " " + currency
+}
+
+func formatVatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', -1, 64) + "%"
+}
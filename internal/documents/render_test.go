@@ -0,0 +1,107 @@
+package documents
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
+)
+
+func tstIssuer() config.IssuerConfig {
+	return config.IssuerConfig{
+		Name:    "Eurofurence e.V.",
+		Address: []string{"Sample Street 1", "12345 Sample City"},
+		VatID:   "DE123456789",
+	}
+}
+
+func TestRenderInvoice(t *testing.T) {
+	due := entities.Transaction{
+		TransactionID:   "EF2024-000010-0101-120000-0001",
+		TransactionType: entities.TransactionTypeDue,
+		Amount:          entities.Amount{ISOCurrency: "EUR", GrossCent: 19_00, VatRate: 19},
+		LineItems: []entities.LineItem{
+			{Position: 1, Description: "Sponsor upgrade", Quantity: 1, UnitCent: 15_00, VatRate: 19},
+			{Position: 2, Description: "T-Shirt (XL)", Quantity: 2, UnitCent: 2_00, VatRate: 7},
+		},
+	}
+	doc := interaction.Document{
+		Kind:      entities.DocumentKindInvoice,
+		Number:    "INV-000042",
+		IssuedAt:  time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Issuer:    tstIssuer(),
+		DebitorID: 10,
+		Event:     "EF2024",
+		Currency:  "EUR",
+		Dues:      []entities.Transaction{due},
+		VatTotals: due.VatTotals(),
+		TotalCent: 19_00,
+		PaidCent:  5_00,
+	}
+
+	out := string(Render(doc))
+	require.True(t, strings.HasPrefix(out, "%PDF-1.4"))
+	for _, expected := range []string{
+		"(Eurofurence e.V.)", "(VAT ID: DE123456789)", "(Invoice)", "(INV-000042)", "(2024-05-01)",
+		"(Sponsor upgrade)", "(T-Shirt \\(XL\\))", "(Net amount at 7%)", "(VAT 19%)",
+		"(19.00 EUR)", "(5.00 EUR)", "(14.00 EUR)",
+	} {
+		require.Contains(t, out, expected)
+	}
+	require.Equal(t, "invoice-INV-000042.pdf", Filename(doc))
+}
+
+func TestRenderReceipt(t *testing.T) {
+	payment := entities.Transaction{
+		TransactionID:   "EF2024-000010-0102-120000-0002",
+		TransactionType: entities.TransactionTypePayment,
+		PaymentMethod:   entities.PaymentMethodCredit,
+		EffectiveDate:   sql.NullTime{Time: time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC), Valid: true},
+		Amount:          entities.Amount{ISOCurrency: "EUR", GrossCent: 80_00, VatRate: 19},
+	}
+	doc := interaction.Document{
+		Kind:      entities.DocumentKindReceipt,
+		Number:    "RCT-000007",
+		Issuer:    tstIssuer(),
+		DebitorID: 10,
+		Currency:  "EUR",
+		Payments:  []entities.Transaction{payment},
+		VatTotals: payment.VatTotals(),
+		TotalCent: 80_00,
+		PaidCent:  80_00,
+	}
+
+	out := string(Render(doc))
+	require.Contains(t, out, "(Receipt)")
+	require.Contains(t, out, "(EF2024-000010-0102-120000-0002)")
+	require.Contains(t, out, "(Payment by credit card on 2024-04-30)")
+	require.Contains(t, out, "(67.23 EUR)")
+	require.NotContains(t, out, "(Outstanding)")
+}
+
+func TestRenderContinuesOnNewPages(t *testing.T) {
+	doc := interaction.Document{
+		Kind:     entities.DocumentKindInvoice,
+		Number:   "INV-000001",
+		Issuer:   tstIssuer(),
+		Currency: "EUR",
+	}
+	for n := 0; n < 100; n++ {
+		doc.Dues = append(doc.Dues, entities.Transaction{
+			TransactionID: fmt.Sprintf("EF2024-000010-0101-120000-%04d", n),
+			Comment:       strings.Repeat("a very long description ", 10),
+			Amount:        entities.Amount{ISOCurrency: "EUR", GrossCent: 1_00, VatRate: 19},
+		})
+	}
+
+	out := string(Render(doc))
+	require.Contains(t, out, "/Count 3")
+	require.Contains(t, out, "(a very long description a very long description a...)")
+}
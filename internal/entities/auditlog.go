@@ -0,0 +1,75 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+type AuditOutcome string
+
+const (
+	AuditOutcomeSuccess   AuditOutcome = "success"
+	AuditOutcomeForbidden AuditOutcome = "forbidden"
+	AuditOutcomeFailed    AuditOutcome = "failed"
+)
+
+// AuditLogEntry records a privileged action, or a forbidden attempt at any action
+//
+// This table is append only. Every entry carries the hash of its predecessor,
+// so modified or removed entries break the chain and can be detected.
+type AuditLogEntry struct {
+	ID            uint         `gorm:"primarykey"`
+	CreatedAt     time.Time    `gorm:"index"`
+	RequestID     string       `gorm:"type:varchar(8) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Actor         string       `gorm:"index;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL"`
+	Role          string       `gorm:"type:varchar(32) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL"`
+	ClientIP      string       `gorm:"type:varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	ForwardedFor  string       `gorm:"type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Endpoint      string       `gorm:"type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Action        string       `gorm:"type:varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL"`
+	DebitorID     int64        `gorm:"index;type:bigint"`
+	TransactionID string       `gorm:"index;type:varchar(80) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Diff          string       `gorm:"type:longtext CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Outcome       AuditOutcome `gorm:"type:enum('success', 'forbidden', 'failed')"`
+	Details       string       `gorm:"type:text CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	PrevHash      string       `gorm:"type:varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Hash          string       `gorm:"type:varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+}
+
+// ComputeHash calculates the hash over the content of the entry, including PrevHash.
+//
+// CreatedAt must already be set. It is included with second precision only, so the hash
+// does not depend on how precisely the database stores timestamps.
+func (e *AuditLogEntry) ComputeHash() string {
+	return hashFields(
+		e.PrevHash,
+		e.CreatedAt.UTC().Format(time.RFC3339),
+		e.RequestID,
+		e.Actor,
+		e.Role,
+		e.ClientIP,
+		e.ForwardedFor,
+		e.Endpoint,
+		e.Action,
+		fmt.Sprintf("%d", e.DebitorID),
+		e.TransactionID,
+		e.Diff,
+		string(e.Outcome),
+		e.Details,
+	)
+}
+
+type AuditLogQuery struct {
+	// filter by actor (subject or token name)
+	Actor string
+	// filter by debitor
+	DebitorID int64
+	// filter by transaction_identifier
+	TransactionIdentifier string
+	// filter by outcome
+	Outcome AuditOutcome
+	// filter by creation time (inclusive) lower bound
+	CreatedFrom time.Time
+	// filter by creation time (exclusive) upper bound
+	CreatedBefore time.Time
+}
@@ -0,0 +1,50 @@
+package entities
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxCreditorReferenceLength is the limit of ISO 11649 for the reference, excluding RF and the check digits
+const maxCreditorReferenceLength = 21
+
+var creditorReferencePattern = regexp.MustCompile("^RF[0-9]{2}[A-Z0-9]{1,21}$")
+
+// NewCreditorReference turns a reference of up to 21 letters or digits into an ISO 11649 creditor reference,
+// adding RF and the check digits.
+//
+// Returns an empty string if the reference is too long, or contains anything but letters and digits.
+func NewCreditorReference(reference string) string {
+	reference = strings.ToUpper(reference)
+	if len(reference) == 0 || len(reference) > maxCreditorReferenceLength || !creditorReferencePattern.MatchString("RF00"+reference) {
+		return ""
+	}
+
+	return fmt.Sprintf("RF%02d%s", 98-mod97(reference+"RF00"), reference)
+}
+
+// NormalizeCreditorReference converts a creditor reference from its printed form, e.g. RF18 5390 0754 7034,
+// to its electronic form without spaces.
+//
+// Returns an empty string if the result is not a creditor reference with valid check digits.
+func NormalizeCreditorReference(value string) string {
+	normalized := strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	if !creditorReferencePattern.MatchString(normalized) || mod97(normalized[4:]+normalized[:4]) != 1 {
+		return ""
+	}
+	return normalized
+}
+
+// mod97 computes the remainder of the number formed by replacing letters by 10 to 35, as ISO 7064 MOD 97-10 does.
+func mod97(value string) int {
+	remainder := 0
+	for _, c := range value {
+		if c >= 'A' && c <= 'Z' {
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(c-'0')) % 97
+		}
+	}
+	return remainder
+}
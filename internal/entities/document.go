@@ -0,0 +1,46 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+type DocumentKind string
+
+const (
+	DocumentKindInvoice DocumentKind = "invoice"
+	DocumentKindReceipt DocumentKind = "receipt"
+)
+
+// DocumentSequence remembers the last number handed out for a kind of document
+//
+// The repository locks the row while allocating a number, and stores the document in the
+// same database transaction, so the numbers have no gaps, even with several instances of the service.
+type DocumentSequence struct {
+	Kind      DocumentKind `gorm:"primaryKey;type:enum('invoice', 'receipt')"`
+	Last      int64        `gorm:"NOT NULL;default:0"`
+	UpdatedAt time.Time
+}
+
+// IssuedDocument records the number given to an invoice or receipt
+//
+// Subject identifies what the document is about, so rendering the same document again reuses its number.
+// For receipts this is the transaction identifier of the payment, for invoices the debitor, the event and the dues included.
+type IssuedDocument struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	Kind      DocumentKind `gorm:"uniqueIndex:idx_uq_document_kind_subject;uniqueIndex:idx_uq_document_kind_sequence;type:enum('invoice', 'receipt');NOT NULL"`
+	Subject   string       `gorm:"uniqueIndex:idx_uq_document_kind_subject;type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL"`
+	Sequence  int64        `gorm:"uniqueIndex:idx_uq_document_kind_sequence;NOT NULL"` // assigned by the repository
+	Prefix    string       `gorm:"type:varchar(20) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL;default:''"`
+	DebitorID int64        `gorm:"index;type:bigint;NOT NULL"`
+	Event     string       `gorm:"type:varchar(40) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL;default:''"`
+}
+
+// Number is the document number printed on the document, e.g. INV-000042.
+func (d IssuedDocument) Number() string {
+	if d.Prefix == "" {
+		return fmt.Sprintf("%06d", d.Sequence)
+	}
+	return fmt.Sprintf("%s-%06d", d.Prefix, d.Sequence)
+}
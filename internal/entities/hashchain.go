@@ -0,0 +1,51 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// TransactionLogChainPrefix is prepended to the transaction identifier to form the chain name
+// of the transaction log entries of a single transaction.
+const TransactionLogChainPrefix = "transaction_log:"
+
+// HashChainHead remembers the latest hash of an append only, hash chained table such as the audit log
+//
+// Writers lock the head row while appending, so concurrent writers cannot fork the chain,
+// even if they run in different instances of the service.
+type HashChainHead struct {
+	ChainName string `gorm:"primaryKey;type:varchar(120) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Hash      string `gorm:"type:varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Length    int64
+	UpdatedAt time.Time
+}
+
+// LedgerCheckpoint is a signed snapshot of all chain heads at a point in time
+//
+// Keeping checkpoints outside the database allows detecting that a chain was rewritten as a whole,
+// which the chain itself cannot reveal.
+type LedgerCheckpoint struct {
+	CreatedAt time.Time
+	Heads     []HashChainHead
+	// Digest is the hash over all heads, in the order given
+	Digest string
+	// Signature is the HMAC-SHA256 over CreatedAt and Digest
+	Signature string
+}
+
+func TransactionLogChainName(transactionID string) string {
+	return TransactionLogChainPrefix + transactionID
+}
+
+// hashFields calculates the SHA-256 over the given fields.
+//
+// Every field is prefixed with its length, so no two different lists of fields can produce the same input.
+func hashFields(fields ...string) string {
+	h := sha256.New()
+	for _, f := range fields {
+		_, _ = fmt.Fprintf(h, "%d:%s;", len(f), f)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,28 @@
+package entities
+
+import (
+	"database/sql"
+	"time"
+)
+
+// InstallmentPlan allows a debitor to pay the dues of an event in several installments
+//
+// The plan only fixes the number and due dates of the installments. Their amounts follow
+// from the dues at the time of payment, see the configuration of the plan.
+type InstallmentPlan struct {
+	ID           uint `gorm:"primarykey"`
+	CreatedAt    time.Time
+	DebitorID    int64         `gorm:"uniqueIndex:idx_uq_plan_debitor_event;type:bigint;NOT NULL"`
+	Event        string        `gorm:"uniqueIndex:idx_uq_plan_debitor_event;type:varchar(40) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL;default:''"`
+	Plan         string        `gorm:"type:varchar(40) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL"` // the name of the plan in the configuration
+	CreatedBy    string        `gorm:"type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Installments []Installment `gorm:"foreignKey:PlanID"`
+}
+
+// Installment is one scheduled payment of an InstallmentPlan
+type Installment struct {
+	ID      uint         `gorm:"primarykey"`
+	PlanID  uint         `gorm:"index;NOT NULL"`
+	Number  int          `gorm:"NOT NULL"` // starting at 1
+	DueDate sql.NullTime `gorm:"type:date;NOT NULL"`
+}
@@ -0,0 +1,102 @@
+package entities
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// LineItem is one position of an itemized due, for example the membership, a sponsor upgrade or a T-shirt.
+//
+// The gross amounts of all line items add up to the GrossCent of the due. Line items are written
+// together with their due, and never change afterwards.
+type LineItem struct {
+	ID uint `gorm:"primarykey"`
+	// TransactionRowID references the primary key of the due, not its TransactionID
+	TransactionRowID uint    `gorm:"index;NOT NULL"`
+	Position         int     `gorm:"NOT NULL"` // starting at 1
+	Description      string  `gorm:"type:varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL"`
+	Quantity         int64   `gorm:"NOT NULL"`
+	UnitCent         int64   `gorm:"NOT NULL"`
+	VatRate          float64 `gorm:"type:decimal(10,2)"`
+}
+
+// GrossCent is the quantity times the unit price.
+func (l LineItem) GrossCent() int64 {
+	return l.Quantity * l.UnitCent
+}
+
+func (l LineItem) String() string {
+	return fmt.Sprintf("%d x %d @ %.2f%% %s", l.Quantity, l.UnitCent, l.VatRate, l.Description)
+}
+
+// LineItemsDigest is a hash over the line items, in order of their position, or empty if there are none.
+//
+// It is stored in the transaction log, so the line items of a due are covered by its hash chain.
+func LineItemsDigest(items []LineItem) string {
+	if len(items) == 0 {
+		return ""
+	}
+
+	sorted := append([]LineItem(nil), items...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	fields := make([]string, 0, 5*len(sorted))
+	for _, l := range sorted {
+		fields = append(fields,
+			fmt.Sprintf("%d", l.Position),
+			l.Description,
+			fmt.Sprintf("%d", l.Quantity),
+			fmt.Sprintf("%d", l.UnitCent),
+			fmt.Sprintf("%.2f", l.VatRate),
+		)
+	}
+	return hashFields(fields...)
+}
+
+// VatTotal is the part of a transaction that is taxed at one vat rate.
+type VatTotal struct {
+	VatRate   float64
+	GrossCent int64
+	NetCent   int64
+	VatCent   int64
+}
+
+// VatTotals splits the gross amount of the transaction by vat rate, ordered by rate.
+//
+// Transactions without line items are taxed entirely at the rate of their amount. The vat is
+// calculated once per rate from the gross total, so rounding differences between lines do not add up.
+func (t Transaction) VatTotals() []VatTotal {
+	return VatTotalsOf([]Transaction{t})
+}
+
+// VatTotalsOf splits the summed gross amount of the transactions by vat rate, ordered by rate, see Transaction.VatTotals.
+func VatTotalsOf(transactions []Transaction) []VatTotal {
+	gross := make(map[float64]int64)
+	for _, t := range transactions {
+		if len(t.LineItems) == 0 {
+			gross[t.Amount.VatRate] += t.Amount.GrossCent
+		}
+		for _, l := range t.LineItems {
+			gross[l.VatRate] += l.GrossCent()
+		}
+	}
+
+	result := make([]VatTotal, 0, len(gross))
+	for rate, cents := range gross {
+		net := int64(math.Round(float64(cents) * 100 / (100 + rate)))
+		result = append(result, VatTotal{
+			VatRate:   rate,
+			GrossCent: cents,
+			NetCent:   net,
+			VatCent:   cents - net,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].VatRate < result[j].VatRate
+	})
+
+	return result
+}
@@ -0,0 +1,15 @@
+package entities
+
+// Overpayment is a debitor whose valid payments for an event exceed their valid dues.
+//
+// It is calculated from the transactions, not stored.
+type Overpayment struct {
+	DebitorID    int64
+	DuesCent     int64
+	PaymentsCent int64
+}
+
+// CreditCent is the amount paid in excess of the dues.
+func (o Overpayment) CreditCent() int64 {
+	return o.PaymentsCent - o.DuesCent
+}
@@ -0,0 +1,74 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Well known keys of PaymentProcessorInformation. The payment provider adapters may store other keys as well.
+const (
+	ProcessorInfoPaylinkID             = "paylink_id"
+	ProcessorInfoProviderTransactionID = "provider_transaction_id"
+	ProcessorInfoCardBrand             = "card_brand"
+	ProcessorInfoMaskedPAN             = "masked_pan"
+	ProcessorInfoFeeCent               = "fee_cent"
+)
+
+// PaymentProcessorInformation holds what the payment provider reported about a transaction.
+//
+// It is stored as a json column. An empty map is stored as NULL.
+type PaymentProcessorInformation map[string]interface{}
+
+// Value implements driver.Valuer
+func (p PaymentProcessorInformation) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	return p.canonicalJSON()
+}
+
+// Scan implements sql.Scanner
+func (p *PaymentProcessorInformation) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into payment processor information", value)
+	}
+
+	result := PaymentProcessorInformation{}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return err
+	}
+	*p = result
+	return nil
+}
+
+// canonicalJSON encodes the information with sorted keys, so equal content always gives the same string.
+func (p PaymentProcessorInformation) canonicalJSON() (string, error) {
+	// map keys are sorted by encoding/json
+	encoded, err := json.Marshal(map[string]interface{}(p))
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// String returns the canonical json encoding, or the empty string if there is no information.
+func (p PaymentProcessorInformation) String() string {
+	if len(p) == 0 {
+		return ""
+	}
+	encoded, err := p.canonicalJSON()
+	if err != nil {
+		return ""
+	}
+	return encoded
+}
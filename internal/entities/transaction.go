@@ -64,6 +64,7 @@ type Transaction struct {
 	gorm.Model
 	DebitorID         int64             `gorm:"index;type:bigint;NOT NULL"`
 	TransactionID     string            `gorm:"uniqueIndex:idx_uq_tid;type:varchar(80) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL"`
+	Event             string            `gorm:"index;type:varchar(40) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL;default:''"`
 	TransactionType   TransactionType   `gorm:"type:enum('due', 'payment')"`
 	PaymentMethod     PaymentMethod     `gorm:"type:enum('credit', 'paypal', 'transfer', 'internal', 'gift', 'cash')"`
 	PaymentStartUrl   string            `gorm:"type:text CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;default:NULL"`
@@ -74,6 +75,16 @@ type Transaction struct {
 	EffectiveDate     sql.NullTime      `gorm:"type:date;NOT NULL"`
 	DueDate           sql.NullTime      `gorm:"type:date;NULL;default:NULL"`
 	Reason            string            `gorm:"type:longtext CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;default:NULL"`
+	// ProcessorInfo is written by the payment provider adapters (api token) and admins
+	ProcessorInfo PaymentProcessorInformation `gorm:"type:json;NULL;default:NULL"`
+	// GroupReference links payments that belong together, the payments of a group payment
+	// settled with a single paylink, or both sides of a balance transfer
+	GroupReference string `gorm:"index;type:varchar(80) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL;default:''"`
+	// CreditorReference is an ISO 11649 creditor reference (RF) derived from the TransactionID of a payment, banks
+	// keep it intact as remittance information, and its check digits catch typing errors
+	CreditorReference sql.NullString `gorm:"uniqueIndex:idx_uq_rf;type:varchar(25) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NULL;default:NULL"`
+	// LineItems optionally itemize a due, ordered by position
+	LineItems []LineItem `gorm:"foreignKey:TransactionRowID"`
 }
 
 type Amount struct {
@@ -92,6 +103,7 @@ func (t *Transaction) ToTransactionLog() TransactionLog {
 	return TransactionLog{
 		DebitorID:         t.DebitorID,
 		TransactionID:     t.TransactionID,
+		Event:             t.Event,
 		TransactionType:   t.TransactionType,
 		PaymentMethod:     t.PaymentMethod,
 		PaymentStartUrl:   t.PaymentStartUrl,
@@ -107,8 +119,11 @@ func (t *Transaction) ToTransactionLog() TransactionLog {
 			Comment: t.Deletion.Comment,
 			By:      t.Deletion.By,
 		},
-		EffectiveDate: t.EffectiveDate,
-		DueDate:       t.DueDate,
-		Reason:        t.Reason,
+		EffectiveDate:   t.EffectiveDate,
+		DueDate:         t.DueDate,
+		Reason:          t.Reason,
+		ProcessorInfo:   t.ProcessorInfo,
+		GroupReference:  t.GroupReference,
+		LineItemsDigest: LineItemsDigest(t.LineItems),
 	}
 }
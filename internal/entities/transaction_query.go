@@ -7,6 +7,12 @@ type TransactionQuery struct {
 	DebitorID int64
 	// filter by transaction_identifier
 	TransactionIdentifier string
+	// filter by the creditor reference in its electronic form
+	CreditorReference string
+	// filter by the event the transaction belongs to
+	Event string
+	// filter by the group reference shared by the payments of a group payment or balance transfer
+	GroupReference string
 	// filter by effective date (inclusive) lower bound
 	EffectiveFrom time.Time
 	// filter by effective date (exclusive) upper bound - this makes it easy to get everything in a given month
@@ -2,8 +2,8 @@ package entities
 
 import (
 	"database/sql"
-
-	"gorm.io/gorm"
+	"fmt"
+	"time"
 )
 
 // type History struct {
@@ -17,21 +17,81 @@ import (
 
 // TransactionLog holds information about the state of a transaction for a given time
 //
-// This table is append only
+// This table is append only. There are no update or soft delete columns, and the database
+// rejects updates and deletes. Every entry carries the hash of the previous entry for the
+// same TransactionID, so changes made by other means break the chain and can be detected.
 type TransactionLog struct {
-	gorm.Model
-	DebitorID         int64             `gorm:"index;type:bigint;NOT NULL"`
-	TransactionID     string            `gorm:"index;type:varchar(80) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL"`
-	TransactionType   TransactionType   `gorm:"type:enum('due', 'payment')"`
-	PaymentMethod     PaymentMethod     `gorm:"type:enum('credit', 'paypal', 'transfer', 'internal', 'gift', 'cash')"`
-	PaymentStartUrl   string            `gorm:"type:text CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;default:NULL"`
-	TransactionStatus TransactionStatus `gorm:"type:enum('tentative', 'pending', 'valid', 'deleted')"`
-	Amount            Amount            `gorm:"embedded"`
-	Comment           string            `gorm:"type:text CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
-	Deletion          Deletion          `gorm:"embedded;embeddedPrefix:deleted_"`
-	EffectiveDate     sql.NullTime      `gorm:"type:date;NOT NULL"`
-	DueDate           sql.NullTime      `gorm:"type:date;NULL;default:NULL"`
-	Reason            string            `gorm:"type:longtext CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;default:NULL"`
+	ID                uint `gorm:"primarykey"`
+	CreatedAt         time.Time
+	DebitorID         int64                       `gorm:"index;type:bigint;NOT NULL"`
+	TransactionID     string                      `gorm:"index;type:varchar(80) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL"`
+	Event             string                      `gorm:"type:varchar(40) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL;default:''"`
+	TransactionType   TransactionType             `gorm:"type:enum('due', 'payment')"`
+	PaymentMethod     PaymentMethod               `gorm:"type:enum('credit', 'paypal', 'transfer', 'internal', 'gift', 'cash')"`
+	PaymentStartUrl   string                      `gorm:"type:text CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;default:NULL"`
+	TransactionStatus TransactionStatus           `gorm:"type:enum('tentative', 'pending', 'valid', 'deleted')"`
+	Amount            Amount                      `gorm:"embedded"`
+	Comment           string                      `gorm:"type:text CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Deletion          Deletion                    `gorm:"embedded;embeddedPrefix:deleted_"`
+	EffectiveDate     sql.NullTime                `gorm:"type:date;NOT NULL"`
+	DueDate           sql.NullTime                `gorm:"type:date;NULL;default:NULL"`
+	Reason            string                      `gorm:"type:longtext CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;default:NULL"`
+	ProcessorInfo     PaymentProcessorInformation `gorm:"type:json;NULL;default:NULL"`
+	GroupReference    string                      `gorm:"type:varchar(80) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL;default:''"`
+	LineItemsDigest   string                      `gorm:"type:varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci;NOT NULL;default:''"`
+	PrevHash          string                      `gorm:"type:varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+	Hash              string                      `gorm:"type:varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_general_ci"`
+}
+
+// ComputeHash calculates the hash over the content of the entry, including PrevHash.
+//
+// CreatedAt must already be set. All values are formatted with the precision the database
+// stores them with, so the hash can be recalculated from what is read back.
+//
+// The event, the payment processor information, the group reference and the line items digest are only included if set,
+// so entries written before they existed keep their hash. Each of them is tagged with its name, so a value cannot be mistaken for
+// one of the others.
+func (tl *TransactionLog) ComputeHash() string {
+	fields := []string{
+		tl.PrevHash,
+		tl.CreatedAt.UTC().Format(time.RFC3339),
+		fmt.Sprintf("%d", tl.DebitorID),
+		tl.TransactionID,
+		string(tl.TransactionType),
+		string(tl.PaymentMethod),
+		tl.PaymentStartUrl,
+		string(tl.TransactionStatus),
+		tl.Amount.ISOCurrency,
+		fmt.Sprintf("%d", tl.Amount.GrossCent),
+		fmt.Sprintf("%.2f", tl.Amount.VatRate),
+		tl.Comment,
+		string(tl.Deletion.Status),
+		tl.Deletion.Comment,
+		tl.Deletion.By,
+		formatNullDate(tl.EffectiveDate),
+		formatNullDate(tl.DueDate),
+		tl.Reason,
+	}
+	if tl.Event != "" {
+		fields = append(fields, "event="+tl.Event)
+	}
+	if info := tl.ProcessorInfo.String(); info != "" {
+		fields = append(fields, "processor="+info)
+	}
+	if tl.GroupReference != "" {
+		fields = append(fields, "group="+tl.GroupReference)
+	}
+	if tl.LineItemsDigest != "" {
+		fields = append(fields, "items="+tl.LineItemsDigest)
+	}
+	return hashFields(fields...)
+}
+
+func formatNullDate(d sql.NullTime) string {
+	if !d.Valid {
+		return ""
+	}
+	return d.Time.UTC().Format("2006-01-02")
 }
 
 // // TableName implements the Tabler interface to change from a pluarlized table name to
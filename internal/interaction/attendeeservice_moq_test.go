@@ -25,6 +25,9 @@ var _ attendeeservice.AttendeeService = &AttendeeServiceMock{}
 //			PaymentsChangedFunc: func(ctx context.Context, debitorId uint) error {
 //				panic("mock out the PaymentsChanged method")
 //			},
+//			RegistrationsChangedFunc: func(ctx context.Context, subject string)  {
+//				panic("mock out the RegistrationsChanged method")
+//			},
 //		}
 //
 //		// use mockedAttendeeService in code that requires attendeeservice.AttendeeService
@@ -38,6 +41,9 @@ type AttendeeServiceMock struct {
 	// PaymentsChangedFunc mocks the PaymentsChanged method.
 	PaymentsChangedFunc func(ctx context.Context, debitorId uint) error
 
+	// RegistrationsChangedFunc mocks the RegistrationsChanged method.
+	RegistrationsChangedFunc func(ctx context.Context, subject string)
+
 	// calls tracks calls to the methods.
 	calls struct {
 		// ListMyRegistrationIds holds details about calls to the ListMyRegistrationIds method.
@@ -52,9 +58,17 @@ type AttendeeServiceMock struct {
 			// DebitorId is the debitorId argument value.
 			DebitorId uint
 		}
+		// RegistrationsChanged holds details about calls to the RegistrationsChanged method.
+		RegistrationsChanged []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Subject is the subject argument value.
+			Subject string
+		}
 	}
 	lockListMyRegistrationIds sync.RWMutex
 	lockPaymentsChanged       sync.RWMutex
+	lockRegistrationsChanged  sync.RWMutex
 }
 
 // ListMyRegistrationIds calls ListMyRegistrationIdsFunc.
@@ -131,3 +145,39 @@ func (mock *AttendeeServiceMock) PaymentsChangedCalls() []struct {
 	mock.lockPaymentsChanged.RUnlock()
 	return calls
 }
+
+// RegistrationsChanged calls RegistrationsChangedFunc.
+func (mock *AttendeeServiceMock) RegistrationsChanged(ctx context.Context, subject string) {
+	callInfo := struct {
+		Ctx     context.Context
+		Subject string
+	}{
+		Ctx:     ctx,
+		Subject: subject,
+	}
+	mock.lockRegistrationsChanged.Lock()
+	mock.calls.RegistrationsChanged = append(mock.calls.RegistrationsChanged, callInfo)
+	mock.lockRegistrationsChanged.Unlock()
+	if mock.RegistrationsChangedFunc == nil {
+		return
+	}
+	mock.RegistrationsChangedFunc(ctx, subject)
+}
+
+// RegistrationsChangedCalls gets all the calls that were made to RegistrationsChanged.
+// Check the length with:
+//
+//	len(mockedAttendeeService.RegistrationsChangedCalls())
+func (mock *AttendeeServiceMock) RegistrationsChangedCalls() []struct {
+	Ctx     context.Context
+	Subject string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Subject string
+	}
+	mock.lockRegistrationsChanged.RLock()
+	calls = mock.calls.RegistrationsChanged
+	mock.lockRegistrationsChanged.RUnlock()
+	return calls
+}
@@ -0,0 +1,181 @@
+package interaction
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+)
+
+const (
+	auditActionReadTransactions     = "transaction.read"
+	auditActionCreateTransaction    = "transaction.create"
+	auditActionUpdateTransaction    = "transaction.update"
+	auditActionDeleteTransaction    = "transaction.delete"
+	auditActionRegistrationsChanged = "registrations.changed"
+	auditActionLedgerCheckpoint     = "ledger.checkpoint"
+	auditActionReadBalance          = "balance.read"
+	auditActionVoidPaylinks         = "paylinks.void"
+	auditActionPaymentsChanged      = "payments_changed.send"
+	auditActionTransferBalance      = "balance.transfer"
+	auditActionReadOverpayments     = "overpayments.read"
+	auditActionResolveOverpayment   = "overpayment.resolve"
+	auditActionIssueInvoice         = "invoice.issue"
+	auditActionIssueReceipt         = "receipt.issue"
+	auditActionReadSepaQR           = "sepa_qr.read"
+
+	auditActionReadInstallmentPlan   = "installment_plan.read"
+	auditActionAttachInstallmentPlan = "installment_plan.attach"
+	auditActionRemoveInstallmentPlan = "installment_plan.remove"
+)
+
+// auditChange is the before and after value of a single field
+type auditChange struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// GetAuditLog returns the audit log entries matching the query, oldest first. Only admins may read the audit log.
+func (s *serviceInteractor) GetAuditLog(ctx context.Context, query entities.AuditLogQuery) ([]entities.AuditLogEntry, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mgr.IsAdmin() {
+		return nil, apierrors.NewForbidden("only admins may read the audit log")
+	}
+
+	return s.store.GetAuditLogEntries(ctx, query)
+}
+
+// recordAudit appends an entry to the audit log.
+//
+// Everything admins, the api token and the command line do is recorded, except reads made with the api token,
+// because the other services do those all the time. For regular users, only forbidden attempts
+// are recorded.
+//
+// Failing to write the audit log is logged as an error, but does not fail the request.
+func (s *serviceInteractor) recordAudit(ctx context.Context, mgr *RBACValidator, action string, debitorID int64, transactionID string, diff string, err error) {
+	outcome := auditOutcome(err)
+	if outcome != entities.AuditOutcomeForbidden {
+		if !mgr.IsAdmin() && !mgr.IsAPITokenCall() && !mgr.IsSystemCall() {
+			return
+		}
+		if mgr.IsAPITokenCall() && action == auditActionReadTransactions {
+			return
+		}
+	}
+
+	entry := entities.AuditLogEntry{
+		RequestID:     logging.GetRequestID(ctx),
+		Actor:         mgr.Actor(),
+		Role:          mgr.Role(),
+		Action:        action,
+		DebitorID:     debitorID,
+		TransactionID: transactionID,
+		Diff:          diff,
+		Outcome:       outcome,
+	}
+
+	if info, ok := ctx.Value(common.CtxKeyRequestInfo{}).(common.RequestInfo); ok {
+		entry.ClientIP = info.ClientIP
+		entry.ForwardedFor = info.ForwardedFor
+		entry.Endpoint = fmt.Sprintf("%s %s", info.Method, info.Path)
+	}
+
+	if err != nil {
+		entry.Details = err.Error()
+	}
+
+	if err := s.store.CreateAuditLogEntry(ctx, entry); err != nil {
+		logging.LoggerFromContext(ctx).Error("failed to write audit log entry for %s by %s. [error]: %v", action, entry.Actor, err)
+	}
+}
+
+func auditOutcome(err error) entities.AuditOutcome {
+	if err == nil {
+		return entities.AuditOutcomeSuccess
+	}
+	if apierrors.IsForbiddenError(err) {
+		return entities.AuditOutcomeForbidden
+	}
+	return entities.AuditOutcomeFailed
+}
+
+// transactionDiff lists the fields that differ between before and after. Either may be nil.
+func transactionDiff(before, after *entities.Transaction) string {
+	b := auditFields(before)
+	a := auditFields(after)
+
+	diff := make(map[string]auditChange)
+	for k, v := range a {
+		if old, ok := b[k]; !ok || old != v {
+			diff[k] = auditChange{Before: b[k], After: v}
+		}
+	}
+	for k, v := range b {
+		if _, ok := a[k]; !ok {
+			diff[k] = auditChange{Before: v}
+		}
+	}
+
+	if len(diff) == 0 {
+		return ""
+	}
+
+	// map keys are sorted by encoding/json, so the result is stable
+	encoded, err := json.Marshal(diff)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+func auditFields(t *entities.Transaction) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+
+	fields := map[string]interface{}{
+		"debitor_id":             t.DebitorID,
+		"transaction_identifier": t.TransactionID,
+		"transaction_type":       string(t.TransactionType),
+		"method":                 string(t.PaymentMethod),
+		"status":                 string(t.TransactionStatus),
+		"currency":               t.Amount.ISOCurrency,
+		"gross_cent":             t.Amount.GrossCent,
+		"vat_rate":               t.Amount.VatRate,
+		"comment":                t.Comment,
+		"payment_start_url":      t.PaymentStartUrl,
+		"reason":                 t.Reason,
+	}
+
+	if t.EffectiveDate.Valid {
+		fields["effective_date"] = t.EffectiveDate.Time.Format("2006-01-02")
+	}
+	if t.DueDate.Valid {
+		fields["due_date"] = t.DueDate.Time.Format("2006-01-02")
+	}
+	if t.Deletion.By != "" {
+		fields["deleted_by"] = t.Deletion.By
+	}
+	if t.Event != "" {
+		fields["event"] = t.Event
+	}
+	if info := t.ProcessorInfo.String(); info != "" {
+		fields["payment_processor_information"] = info
+	}
+	if t.GroupReference != "" {
+		fields["group_reference"] = t.GroupReference
+	}
+	for _, l := range t.LineItems {
+		fields[fmt.Sprintf("line_item_%d", l.Position)] = l.String()
+	}
+
+	return fields
+}
@@ -0,0 +1,191 @@
+package interaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+)
+
+func TestAuditLogRecording(t *testing.T) {
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		debitorID       int64
+		expectedEntries int
+		expectedActor   string
+		expectedRole    string
+		expectedOutcome entities.AuditOutcome
+	}{
+		{
+			name:            "should record reads by admins",
+			ctx:             adminCtx(),
+			debitorID:       1,
+			expectedEntries: 1,
+			expectedActor:   "1234567890",
+			expectedRole:    "admin",
+			expectedOutcome: entities.AuditOutcomeSuccess,
+		},
+		{
+			name:            "should not record reads with the api token",
+			ctx:             apiKeyCtx(),
+			debitorID:       1,
+			expectedEntries: 0,
+		},
+		{
+			name:            "should record reads from the command line",
+			ctx:             WithSystemIdentity(context.Background(), "cli/test"),
+			debitorID:       1,
+			expectedEntries: 1,
+			expectedActor:   "system:cli/test",
+			expectedRole:    "system",
+			expectedOutcome: entities.AuditOutcomeSuccess,
+		},
+		{
+			name:            "should not record permitted reads by users",
+			ctx:             attendeeCtx(),
+			debitorID:       1,
+			expectedEntries: 0,
+		},
+		{
+			name:            "should record forbidden reads by users",
+			ctx:             attendeeCtx(),
+			debitorID:       2,
+			expectedEntries: 1,
+			expectedActor:   "1234567890",
+			expectedRole:    "user",
+			expectedOutcome: entities.AuditOutcomeForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attMock := &AttendeeServiceMock{
+				ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+					return []int64{1}, nil
+				},
+			}
+			db := inmemory.NewInMemoryProvider()
+			i := tstServiceInteractor(db, attMock, &CncrdAdapterMock{})
+
+			ctx := context.WithValue(tt.ctx, common.CtxKeyRequestInfo{}, common.RequestInfo{
+				ClientIP:     "10.0.0.1",
+				ForwardedFor: "192.168.0.1",
+				Method:       "GET",
+				Path:         "/api/rest/v1/transactions/1",
+			})
+
+			_, _ = i.GetTransactionsForDebitor(ctx, entities.TransactionQuery{DebitorID: tt.debitorID})
+
+			entries, err := db.GetAuditLogEntries(context.Background(), entities.AuditLogQuery{})
+			require.NoError(t, err)
+			require.Len(t, entries, tt.expectedEntries)
+
+			if tt.expectedEntries > 0 {
+				e := entries[0]
+				require.Equal(t, tt.expectedActor, e.Actor)
+				require.Equal(t, tt.expectedRole, e.Role)
+				require.Equal(t, tt.expectedOutcome, e.Outcome)
+				require.Equal(t, auditActionReadTransactions, e.Action)
+				require.Equal(t, tt.debitorID, e.DebitorID)
+				require.Equal(t, "10.0.0.1", e.ClientIP)
+				require.Equal(t, "192.168.0.1", e.ForwardedFor)
+				require.Equal(t, "GET /api/rest/v1/transactions/1", e.Endpoint)
+				require.Equal(t, e.ComputeHash(), e.Hash)
+			}
+		})
+	}
+}
+
+func TestAuditLogHashChain(t *testing.T) {
+	db := inmemory.NewInMemoryProvider()
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	for d := int64(1); d <= 3; d++ {
+		_, _ = i.GetTransactionsForDebitor(adminCtx(), entities.TransactionQuery{DebitorID: d})
+	}
+
+	entries, err := db.GetAuditLogEntries(context.Background(), entities.AuditLogQuery{})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	require.Empty(t, entries[0].PrevHash)
+	for n := 1; n < len(entries); n++ {
+		require.Equal(t, entries[n-1].Hash, entries[n].PrevHash)
+		require.Equal(t, entries[n].ComputeHash(), entries[n].Hash)
+	}
+
+	// tampering with an entry must change its hash
+	tampered := entries[1]
+	tampered.DebitorID = 42
+	require.NotEqual(t, entries[1].Hash, tampered.ComputeHash())
+}
+
+func TestGetAuditLog(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		expectedErr error
+	}{
+		{
+			name: "should allow admins",
+			ctx:  adminCtx(),
+		},
+		{
+			name:        "should not allow the api token",
+			ctx:         apiKeyCtx(),
+			expectedErr: apierrors.NewForbidden("only admins may read the audit log"),
+		},
+		{
+			name:        "should not allow users",
+			ctx:         attendeeCtx(),
+			expectedErr: apierrors.NewForbidden("only admins may read the audit log"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := inmemory.NewInMemoryProvider()
+			require.NoError(t, db.CreateAuditLogEntry(context.Background(), entities.AuditLogEntry{
+				Actor:   "someone",
+				Role:    "admin",
+				Action:  auditActionReadTransactions,
+				Outcome: entities.AuditOutcomeSuccess,
+			}))
+			i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+			entries, err := i.GetAuditLog(tt.ctx, entities.AuditLogQuery{Actor: "someone"})
+			if tt.expectedErr != nil {
+				require.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+		})
+	}
+}
+
+func TestTransactionDiff(t *testing.T) {
+	before := &entities.Transaction{
+		DebitorID:         1,
+		TransactionID:     "1234",
+		TransactionStatus: entities.TransactionStatusTentative,
+		Amount:            entities.Amount{ISOCurrency: "EUR", GrossCent: 1000},
+	}
+
+	after := *before
+	after.TransactionStatus = entities.TransactionStatusPending
+	after.Comment = "now pending"
+
+	require.Equal(t,
+		`{"comment":{"before":"","after":"now pending"},"status":{"before":"tentative","after":"pending"}}`,
+		transactionDiff(before, &after))
+	require.Empty(t, transactionDiff(before, before))
+	require.Contains(t, transactionDiff(nil, before), `"transaction_identifier":{"after":"1234"}`)
+}
@@ -22,6 +22,9 @@ var _ cncrdadapter.CncrdAdapter = &CncrdAdapterMock{}
 //			CreatePaylinkFunc: func(ctx context.Context, request cncrdadapter.PaymentLinkRequestDto) (cncrdadapter.PaymentLinkDto, error) {
 //				panic("mock out the CreatePaylink method")
 //			},
+//			CreateRefundFunc: func(ctx context.Context, request cncrdadapter.RefundRequestDto) (cncrdadapter.RefundDto, error) {
+//				panic("mock out the CreateRefund method")
+//			},
 //			GetPaylinkByIdFunc: func(ctx context.Context, id uint) (cncrdadapter.PaymentLinkDto, error) {
 //				panic("mock out the GetPaylinkById method")
 //			},
@@ -35,6 +38,9 @@ type CncrdAdapterMock struct {
 	// CreatePaylinkFunc mocks the CreatePaylink method.
 	CreatePaylinkFunc func(ctx context.Context, request cncrdadapter.PaymentLinkRequestDto) (cncrdadapter.PaymentLinkDto, error)
 
+	// CreateRefundFunc mocks the CreateRefund method.
+	CreateRefundFunc func(ctx context.Context, request cncrdadapter.RefundRequestDto) (cncrdadapter.RefundDto, error)
+
 	// GetPaylinkByIdFunc mocks the GetPaylinkById method.
 	GetPaylinkByIdFunc func(ctx context.Context, id uint) (cncrdadapter.PaymentLinkDto, error)
 
@@ -47,6 +53,13 @@ type CncrdAdapterMock struct {
 			// Request is the request argument value.
 			Request cncrdadapter.PaymentLinkRequestDto
 		}
+		// CreateRefund holds details about calls to the CreateRefund method.
+		CreateRefund []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Request is the request argument value.
+			Request cncrdadapter.RefundRequestDto
+		}
 		// GetPaylinkById holds details about calls to the GetPaylinkById method.
 		GetPaylinkById []struct {
 			// Ctx is the ctx argument value.
@@ -56,6 +69,7 @@ type CncrdAdapterMock struct {
 		}
 	}
 	lockCreatePaylink  sync.RWMutex
+	lockCreateRefund   sync.RWMutex
 	lockGetPaylinkById sync.RWMutex
 }
 
@@ -99,6 +113,46 @@ func (mock *CncrdAdapterMock) CreatePaylinkCalls() []struct {
 	return calls
 }
 
+// CreateRefund calls CreateRefundFunc.
+func (mock *CncrdAdapterMock) CreateRefund(ctx context.Context, request cncrdadapter.RefundRequestDto) (cncrdadapter.RefundDto, error) {
+	callInfo := struct {
+		Ctx     context.Context
+		Request cncrdadapter.RefundRequestDto
+	}{
+		Ctx:     ctx,
+		Request: request,
+	}
+	mock.lockCreateRefund.Lock()
+	mock.calls.CreateRefund = append(mock.calls.CreateRefund, callInfo)
+	mock.lockCreateRefund.Unlock()
+	if mock.CreateRefundFunc == nil {
+		var (
+			refundDtoOut cncrdadapter.RefundDto
+			errOut       error
+		)
+		return refundDtoOut, errOut
+	}
+	return mock.CreateRefundFunc(ctx, request)
+}
+
+// CreateRefundCalls gets all the calls that were made to CreateRefund.
+// Check the length with:
+//
+//	len(mockedCncrdAdapter.CreateRefundCalls())
+func (mock *CncrdAdapterMock) CreateRefundCalls() []struct {
+	Ctx     context.Context
+	Request cncrdadapter.RefundRequestDto
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Request cncrdadapter.RefundRequestDto
+	}
+	mock.lockCreateRefund.RLock()
+	calls = mock.calls.CreateRefund
+	mock.lockCreateRefund.RUnlock()
+	return calls
+}
+
 // GetPaylinkById calls GetPaylinkByIdFunc.
 func (mock *CncrdAdapterMock) GetPaylinkById(ctx context.Context, id uint) (cncrdadapter.PaymentLinkDto, error) {
 	callInfo := struct {
@@ -0,0 +1,248 @@
+package interaction
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+)
+
+// Document is the content of an invoice or receipt, ready to be rendered
+type Document struct {
+	Kind      entities.DocumentKind
+	Number    string
+	IssuedAt  time.Time
+	Issuer    config.IssuerConfig
+	DebitorID int64
+	Event     string
+	Currency  string
+	// Dues are the dues billed on an invoice, receipts have none
+	Dues []entities.Transaction
+	// Payments are the payments made towards an invoice, or the one payment confirmed by a receipt
+	Payments []entities.Transaction
+	// VatTotals break down the dues of an invoice, or the payment of a receipt, by vat rate
+	VatTotals []entities.VatTotal
+	TotalCent int64
+	PaidCent  int64
+}
+
+// OutstandingCent is what remains to be paid of an invoice.
+func (d Document) OutstandingCent() int64 {
+	return d.TotalCent - d.PaidCent
+}
+
+// GetReceipt returns the receipt for a valid payment, numbering it when it is requested for the first time.
+//
+// The same rules as for GetTransactionsForDebitor apply, so registered users may only get receipts for their own registrations.
+func (s *serviceInteractor) GetReceipt(ctx context.Context, transactionID string) (*Document, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, err := configuredIssuer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// will not return deleted transactions
+	transactions, err := s.store.GetTransactionsByFilter(ctx, transactionQuery(transactionID))
+	if err != nil {
+		return nil, err
+	}
+	if len(transactions) == 0 {
+		return nil, apierrors.NewNotFound(fmt.Sprintf("transaction %s could not be found", transactionID))
+	}
+	tran := transactions[0]
+
+	if err := s.authorizeDocument(ctx, mgr, tran.DebitorID); err != nil {
+		s.recordAudit(ctx, mgr, auditActionIssueReceipt, tran.DebitorID, transactionID, "", err)
+		return nil, err
+	}
+
+	if tran.TransactionType != entities.TransactionTypePayment || tran.TransactionStatus != entities.TransactionStatusValid || tran.Amount.GrossCent <= 0 {
+		err := apierrors.NewConflict(fmt.Sprintf("transaction %s is not a valid payment, receipts are only issued for those", transactionID))
+		s.recordAudit(ctx, mgr, auditActionIssueReceipt, tran.DebitorID, transactionID, "", err)
+		return nil, err
+	}
+
+	issued, err := s.store.IssueDocument(ctx, entities.IssuedDocument{
+		Kind:      entities.DocumentKindReceipt,
+		Subject:   tran.TransactionID,
+		Prefix:    issuer.ReceiptPrefix(),
+		DebitorID: tran.DebitorID,
+		Event:     tran.Event,
+	})
+	s.recordAudit(ctx, mgr, auditActionIssueReceipt, tran.DebitorID, transactionID, "", err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{
+		Kind:      entities.DocumentKindReceipt,
+		Number:    issued.Number(),
+		IssuedAt:  issued.CreatedAt,
+		Issuer:    issuer,
+		DebitorID: tran.DebitorID,
+		Event:     tran.Event,
+		Currency:  tran.Amount.ISOCurrency,
+		Payments:  []entities.Transaction{tran},
+		VatTotals: tran.VatTotals(),
+		TotalCent: tran.Amount.GrossCent,
+		PaidCent:  tran.Amount.GrossCent,
+	}, nil
+}
+
+// GetInvoice returns the invoice for the valid dues of the debitor for the event, or the current event if empty.
+//
+// The invoice keeps its number until the dues change, then the next request is given a new number.
+// The same rules as for GetTransactionsForDebitor apply, so registered users may only get invoices for their own registrations.
+func (s *serviceInteractor) GetInvoice(ctx context.Context, debitorID int64, event string) (*Document, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeDocument(ctx, mgr, debitorID); err != nil {
+		s.recordAudit(ctx, mgr, auditActionIssueInvoice, debitorID, "", "", err)
+		return nil, err
+	}
+
+	doc, err := s.issueInvoice(ctx, debitorID, event)
+	s.recordAudit(ctx, mgr, auditActionIssueInvoice, debitorID, "", "", err)
+	return doc, err
+}
+
+func (s *serviceInteractor) issueInvoice(ctx context.Context, debitorID int64, event string) (*Document, error) {
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	issuer, err := configuredIssuer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if event == "" {
+		current, err := currentEvent(appConfig, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		event = current.Name
+	} else if _, ok := appConfig.Service.EventByName(event); !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("unknown event %s", event))
+	}
+
+	transactions, err := s.store.GetValidTransactionsForDebitor(ctx, debitorID, eventFilter(appConfig, event))
+	if err != nil {
+		return nil, err
+	}
+	sortByTxID(transactions)
+
+	doc := Document{
+		Kind:      entities.DocumentKindInvoice,
+		Issuer:    issuer,
+		DebitorID: debitorID,
+		Event:     event,
+	}
+	for _, t := range transactions {
+		if t.TransactionType == entities.TransactionTypeDue {
+			doc.Dues = append(doc.Dues, t)
+			doc.TotalCent += t.Amount.GrossCent
+		}
+	}
+
+	if len(doc.Dues) == 0 {
+		return nil, apierrors.NewNotFound(fmt.Sprintf("debitor %d has no dues for event %s", debitorID, event))
+	}
+
+	doc.Currency = doc.Dues[0].Amount.ISOCurrency
+	for _, due := range doc.Dues {
+		if due.Amount.ISOCurrency != doc.Currency {
+			return nil, apierrors.NewConflict(fmt.Sprintf("the dues of debitor %d for event %s are in more than one currency", debitorID, event))
+		}
+	}
+
+	// payments in other currencies cannot be set off against the dues on the invoice
+	for _, t := range transactions {
+		if t.TransactionType == entities.TransactionTypePayment && t.Amount.ISOCurrency == doc.Currency {
+			doc.Payments = append(doc.Payments, t)
+			doc.PaidCent += t.Amount.GrossCent
+		}
+	}
+	doc.VatTotals = entities.VatTotalsOf(doc.Dues)
+
+	issued, err := s.store.IssueDocument(ctx, entities.IssuedDocument{
+		Kind:      entities.DocumentKindInvoice,
+		Subject:   invoiceSubject(debitorID, event, doc.Dues),
+		Prefix:    issuer.InvoicePrefix(),
+		DebitorID: debitorID,
+		Event:     event,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	doc.Number = issued.Number()
+	doc.IssuedAt = issued.CreatedAt
+	return &doc, nil
+}
+
+// authorizeDocument applies the rules of GetTransactionsForDebitor. Registered users may only get documents for their
+// own registrations, admins, the api token and the command line for any debitor.
+func (s *serviceInteractor) authorizeDocument(ctx context.Context, mgr *RBACValidator, debitorID int64) error {
+	if mgr.IsRegisteredUser() {
+		regIDs, err := s.attendeeClient.ListMyRegistrationIds(ctx)
+		if err != nil {
+			logging.LoggerFromContext(ctx).Error("could not call the attendee service. [error]: %v", err)
+			return apierrors.NewInternalServerError("attendee service error - see log for details")
+		}
+
+		if !containsDebitor(regIDs, debitorID) {
+			return apierrors.NewForbidden(fmt.Sprintf("subject %s may not retrieve documents for debitor %d", mgr.Subject(), debitorID))
+		}
+		return nil
+	}
+
+	if mgr.IsAdmin() || mgr.IsAPITokenCall() || mgr.IsSystemCall() {
+		return nil
+	}
+
+	return apierrors.NewForbidden("unable to determine the request permissions")
+}
+
+func configuredIssuer(ctx context.Context) (config.IssuerConfig, error) {
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return config.IssuerConfig{}, err
+	}
+
+	if !appConfig.Service.Issuer.IsConfigured() {
+		logging.LoggerFromContext(ctx).Error("invoice or receipt requested, but service.issuer.name is not configured")
+		return config.IssuerConfig{}, apierrors.NewInternalServerError("invoices and receipts are not configured")
+	}
+
+	return appConfig.Service.Issuer, nil
+}
+
+// invoiceSubject identifies an invoice by the dues on it, so any change to the dues results in a new invoice.
+func invoiceSubject(debitorID int64, event string, dues []entities.Transaction) string {
+	var sb strings.Builder
+	for _, due := range dues {
+		sb.WriteString(fmt.Sprintf("%s:%d:%s;", due.TransactionID, due.Amount.GrossCent, due.Amount.ISOCurrency))
+		for _, l := range due.LineItems {
+			sb.WriteString(l.String() + ";")
+		}
+	}
+	hash := sha256.Sum256([]byte(sb.String()))
+
+	return fmt.Sprintf("%d:%s:%s", debitorID, event, hex.EncodeToString(hash[:]))
+}
@@ -0,0 +1,161 @@
+package interaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+)
+
+func withIssuer(t *testing.T) {
+	original, err := config.GetApplicationConfig()
+	require.NoError(t, err)
+
+	conf := *original
+	conf.Service.Issuer = config.IssuerConfig{
+		Name:    "Eurofurence e.V.",
+		Address: []string{"Sample Street 1", "12345 Sample City"},
+		VatID:   "DE123456789",
+	}
+	require.NoError(t, config.Reload(&conf, t.Logf))
+	t.Cleanup(func() {
+		require.NoError(t, config.Reload(original, t.Logf))
+	})
+}
+
+func documentSeed() []entities.Transaction {
+	payment := func(debitorID int64, tranID string, status entities.TransactionStatus, grossCent int64) entities.Transaction {
+		return newTransaction(debitorID, tranID, entities.TransactionTypePayment, entities.PaymentMethodTransfer, status, entities.Amount{
+			ISOCurrency: "EUR",
+			GrossCent:   grossCent,
+			VatRate:     19.0,
+		})
+	}
+
+	seed := []entities.Transaction{
+		eurDue(10, "EF2023-000010-0101-120000-0001", 100_00),
+		eurDue(10, "EF2023-000010-0101-120000-0002", 19_00),
+		payment(10, "EF2023-000010-0102-120000-0003", entities.TransactionStatusValid, 80_00),
+		payment(10, "EF2023-000010-0102-120000-0004", entities.TransactionStatusTentative, 39_00),
+		payment(10, "EF2023-000010-0103-120000-0005", entities.TransactionStatusValid, 20_00),
+		eurDue(11, "EF2023-000011-0101-120000-0006", 100_00),
+	}
+	for i := range seed {
+		seed[i].Event = "EF2023"
+	}
+	return seed
+}
+
+func TestGetReceipt(t *testing.T) {
+	withEvents(t)
+	withIssuer(t)
+
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, documentSeed())
+
+	asm := &AttendeeServiceMock{
+		ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+			return []int64{10}, nil
+		},
+	}
+	i := tstServiceInteractor(db, asm, &CncrdAdapterMock{})
+
+	receipt, err := i.GetReceipt(attendeeCtx(), "EF2023-000010-0102-120000-0003")
+	require.NoError(t, err)
+	require.Equal(t, entities.DocumentKindReceipt, receipt.Kind)
+	require.Equal(t, "RCT-000001", receipt.Number)
+	require.Equal(t, "Eurofurence e.V.", receipt.Issuer.Name)
+	require.Equal(t, int64(10), receipt.DebitorID)
+	require.Equal(t, "EUR", receipt.Currency)
+	require.Equal(t, int64(80_00), receipt.TotalCent)
+	require.Len(t, receipt.Payments, 1)
+	require.Equal(t, []entities.VatTotal{{VatRate: 19, GrossCent: 80_00, NetCent: 67_23, VatCent: 12_77}}, receipt.VatTotals)
+
+	again, err := i.GetReceipt(adminCtx(), "EF2023-000010-0102-120000-0003")
+	require.NoError(t, err)
+	require.Equal(t, "RCT-000001", again.Number)
+	require.Equal(t, receipt.IssuedAt, again.IssuedAt)
+
+	next, err := i.GetReceipt(apiKeyCtx(), "EF2023-000010-0103-120000-0005")
+	require.NoError(t, err)
+	require.Equal(t, "RCT-000002", next.Number)
+
+	_, err = i.GetReceipt(attendeeCtx(), "EF2023-000011-0101-120000-0006")
+	require.EqualError(t, err, apierrors.NewForbidden("subject 1234567890 may not retrieve documents for debitor 11").Error())
+
+	_, err = i.GetReceipt(adminCtx(), "EF2023-000010-0102-120000-0004")
+	require.EqualError(t, err, apierrors.NewConflict("transaction EF2023-000010-0102-120000-0004 is not a valid payment, receipts are only issued for those").Error())
+
+	_, err = i.GetReceipt(adminCtx(), "EF2023-000010-0101-120000-0001")
+	require.True(t, apierrors.IsConflictError(err))
+
+	_, err = i.GetReceipt(adminCtx(), "EF2023-000010-0101-120000-9999")
+	require.EqualError(t, err, apierrors.NewNotFound("transaction EF2023-000010-0101-120000-9999 could not be found").Error())
+}
+
+func TestGetInvoice(t *testing.T) {
+	withEvents(t)
+	withIssuer(t)
+
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, documentSeed())
+
+	asm := &AttendeeServiceMock{
+		ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+			return []int64{10}, nil
+		},
+	}
+	i := tstServiceInteractor(db, asm, &CncrdAdapterMock{})
+
+	invoice, err := i.GetInvoice(attendeeCtx(), 10, "EF2023")
+	require.NoError(t, err)
+	require.Equal(t, entities.DocumentKindInvoice, invoice.Kind)
+	require.Equal(t, "INV-000001", invoice.Number)
+	require.Equal(t, "EF2023", invoice.Event)
+	require.Len(t, invoice.Dues, 2)
+	require.Len(t, invoice.Payments, 2)
+	require.Equal(t, int64(119_00), invoice.TotalCent)
+	require.Equal(t, int64(100_00), invoice.PaidCent)
+	require.Equal(t, int64(19_00), invoice.OutstandingCent())
+	require.Equal(t, []entities.VatTotal{{VatRate: 19, GrossCent: 119_00, NetCent: 100_00, VatCent: 19_00}}, invoice.VatTotals)
+
+	again, err := i.GetInvoice(adminCtx(), 10, "EF2023")
+	require.NoError(t, err)
+	require.Equal(t, "INV-000001", again.Number)
+
+	due := eurDue(10, "EF2023-000010-0104-120000-0007", 10_00)
+	due.Event = "EF2023"
+	seedDB(db, []entities.Transaction{due})
+
+	changed, err := i.GetInvoice(adminCtx(), 10, "EF2023")
+	require.NoError(t, err)
+	require.Equal(t, "INV-000002", changed.Number)
+	require.Equal(t, int64(129_00), changed.TotalCent)
+
+	_, err = i.GetInvoice(attendeeCtx(), 11, "EF2023")
+	require.EqualError(t, err, apierrors.NewForbidden("subject 1234567890 may not retrieve documents for debitor 11").Error())
+
+	_, err = i.GetInvoice(adminCtx(), 10, "EF2024")
+	require.EqualError(t, err, apierrors.NewNotFound("debitor 10 has no dues for event EF2024").Error())
+
+	_, err = i.GetInvoice(adminCtx(), 10, "EF2022")
+	require.EqualError(t, err, apierrors.NewBadRequest("unknown event EF2022").Error())
+}
+
+func TestGetInvoiceWithoutIssuer(t *testing.T) {
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, []entities.Transaction{eurDue(10, "1", 100_00)})
+
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	_, err := i.GetInvoice(adminCtx(), 10, "")
+	require.EqualError(t, err, apierrors.NewInternalServerError("invoices and receipts are not configured").Error())
+
+	_, err = i.GetReceipt(adminCtx(), "1")
+	require.EqualError(t, err, apierrors.NewInternalServerError("invoices and receipts are not configured").Error())
+}
@@ -0,0 +1,72 @@
+package interaction
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+)
+
+// resolveEvent determines the event a new transaction belongs to.
+//
+// An explicitly given event takes precedence, then the prefix of a given transaction id,
+// then the event whose date window covers the effective date.
+func resolveEvent(conf *config.Application, tran *entities.Transaction) (config.EventConfig, error) {
+	if tran.Event != "" {
+		event, ok := conf.Service.EventByName(tran.Event)
+		if !ok {
+			return config.EventConfig{}, apierrors.NewBadRequest(fmt.Sprintf("unknown event %s", tran.Event))
+		}
+		return event, nil
+	}
+
+	if tran.TransactionID != "" {
+		// the transaction id may be given as creditor reference, which contains the prefix, too
+		for _, event := range conf.Service.ConfiguredEvents() {
+			if _, ok := transactionIDFromCreditorReference(event.Prefix(), tran.TransactionID); ok {
+				return event, nil
+			}
+		}
+
+		prefix, _, _ := strings.Cut(tran.TransactionID, "-")
+		event, ok := conf.Service.EventByPrefix(prefix)
+		if !ok {
+			return config.EventConfig{}, apierrors.NewBadRequest("Invalid format for `TransactionID`")
+		}
+		return event, nil
+	}
+
+	event, ok := conf.Service.EventForDate(tran.EffectiveDate.Time)
+	if !ok {
+		return config.EventConfig{}, apierrors.NewBadRequest(fmt.Sprintf("no event configured for effective date %s", tran.EffectiveDate.Time.Format("2006-01-02")))
+	}
+	return event, nil
+}
+
+// currentEvent returns the event that transactions are booked to by default at the given time.
+//
+// Between events, this is the most recently ended event, or the next one if none has ended yet,
+// so requests without an explicit event keep working outside of all date windows.
+func currentEvent(conf *config.Application, now time.Time) (config.EventConfig, error) {
+	if event, ok := conf.Service.EventForDate(now); ok {
+		return event, nil
+	}
+	if event, ok := conf.Service.NearestEvent(now); ok {
+		return event, nil
+	}
+	return config.EventConfig{}, apierrors.NewBadRequest(fmt.Sprintf("no event configured for %s", now.Format("2006-01-02")))
+}
+
+// eventFilter returns the event to filter transactions by when calculating balances.
+//
+// Without configured events, transactions from before events existed have no event,
+// so balances are calculated across all transactions as before.
+func eventFilter(conf *config.Application, event string) string {
+	if len(conf.Service.Events) == 0 {
+		return ""
+	}
+	return event
+}
@@ -0,0 +1,212 @@
+package interaction
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+)
+
+// withEvents configures two consecutive events for the duration of the test.
+func withEvents(t *testing.T) {
+	original, err := config.GetApplicationConfig()
+	require.NoError(t, err)
+
+	conf := *original
+	conf.Service.Events = []config.EventConfig{
+		{
+			Name:       "EF2023",
+			VatRates:   []float64{19},
+			ValidFrom:  "2023-01-01",
+			ValidUntil: "2023-12-31",
+		},
+		{
+			Name:                "EF2024",
+			TransactionIDPrefix: "EF24",
+			AllowedCurrencies:   []string{"EUR", "CHF"},
+			ValidFrom:           "2024-01-01",
+		},
+	}
+	require.NoError(t, config.Reload(&conf, t.Logf))
+	t.Cleanup(func() {
+		require.NoError(t, config.Reload(original, t.Logf))
+	})
+}
+
+func tstDate(day string) sql.NullTime {
+	parsed, _ := time.Parse("2006-01-02", day)
+	return sql.NullTime{Time: parsed, Valid: true}
+}
+
+func TestCreateTransactionEvents(t *testing.T) {
+	withEvents(t)
+
+	tests := []struct {
+		name           string
+		tran           entities.Transaction
+		expectedEvent  string
+		expectedPrefix string
+		expectedErr    string
+	}{
+		{
+			name:           "should book to the event covering the effective date",
+			tran:           entities.Transaction{EffectiveDate: tstDate("2023-08-20"), Amount: entities.Amount{ISOCurrency: "EUR", VatRate: 19}},
+			expectedEvent:  "EF2023",
+			expectedPrefix: "EF2023-",
+		},
+		{
+			name:           "should use the prefix of the event",
+			tran:           entities.Transaction{EffectiveDate: tstDate("2024-02-01"), Amount: entities.Amount{ISOCurrency: "CHF", VatRate: 7.7}},
+			expectedEvent:  "EF2024",
+			expectedPrefix: "EF24-",
+		},
+		{
+			name:           "should book to an explicitly given event",
+			tran:           entities.Transaction{Event: "EF2023", EffectiveDate: tstDate("2024-02-01"), Amount: entities.Amount{ISOCurrency: "EUR", VatRate: 19}},
+			expectedEvent:  "EF2023",
+			expectedPrefix: "EF2023-",
+		},
+		{
+			name:           "should book to the event of a given transaction id",
+			tran:           entities.Transaction{TransactionID: "EF2023-000001-0820-120000-1234", EffectiveDate: tstDate("2024-02-01"), Amount: entities.Amount{ISOCurrency: "EUR", VatRate: 19}},
+			expectedEvent:  "EF2023",
+			expectedPrefix: "EF2023-",
+		},
+		{
+			name:        "should reject unknown events",
+			tran:        entities.Transaction{Event: "EF2019", Amount: entities.Amount{ISOCurrency: "EUR"}},
+			expectedErr: "unknown event EF2019",
+		},
+		{
+			name:        "should reject effective dates without event",
+			tran:        entities.Transaction{EffectiveDate: tstDate("2022-12-31"), Amount: entities.Amount{ISOCurrency: "EUR"}},
+			expectedErr: "no event configured for effective date 2022-12-31",
+		},
+		{
+			name:        "should reject currencies not allowed for the event",
+			tran:        entities.Transaction{EffectiveDate: tstDate("2023-08-20"), Amount: entities.Amount{ISOCurrency: "CHF", VatRate: 19}},
+			expectedErr: "invalid currency CHF provided",
+		},
+		{
+			name:        "should reject vat rates not allowed for the event",
+			tran:        entities.Transaction{EffectiveDate: tstDate("2023-08-20"), Amount: entities.Amount{ISOCurrency: "EUR", VatRate: 7}},
+			expectedErr: "invalid vat rate 7.00 provided for event EF2023",
+		},
+		{
+			name: "should reject line item vat rates not allowed for the event",
+			tran: entities.Transaction{EffectiveDate: tstDate("2023-08-20"), Amount: entities.Amount{ISOCurrency: "EUR", VatRate: 19}, LineItems: []entities.LineItem{
+				{Position: 1, Description: "Membership", Quantity: 1, UnitCent: 800, VatRate: 19},
+				{Position: 2, Description: "T-Shirt", Quantity: 1, UnitCent: 200, VatRate: 7},
+			}},
+			expectedErr: "invalid vat rate 7.00 provided for line item 2 of event EF2023",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := inmemory.NewInMemoryProvider()
+			i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+			tran := tt.tran
+			tran.DebitorID = 1
+			tran.TransactionType = entities.TransactionTypePayment
+			tran.PaymentMethod = entities.PaymentMethodTransfer
+			tran.TransactionStatus = entities.TransactionStatusValid
+			tran.Amount.GrossCent = 1000
+
+			created, err := i.CreateTransaction(apiKeyCtx(), &tran)
+			if tt.expectedErr != "" {
+				require.True(t, apierrors.IsBadRequestError(err))
+				require.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedEvent, created.Event)
+			require.True(t, strings.HasPrefix(created.TransactionID, tt.expectedPrefix), created.TransactionID)
+
+			stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{Event: tt.expectedEvent})
+			require.NoError(t, err)
+			require.Len(t, stored, 1)
+		})
+	}
+}
+
+func TestGetBalancePerEvent(t *testing.T) {
+	withEvents(t)
+
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, []entities.Transaction{
+		{
+			DebitorID:         1,
+			TransactionID:     "EF2023-000001-0101-120000-0001",
+			Event:             "EF2023",
+			TransactionType:   entities.TransactionTypeDue,
+			TransactionStatus: entities.TransactionStatusValid,
+			Amount:            entities.Amount{ISOCurrency: "EUR", GrossCent: 10000},
+		},
+		{
+			DebitorID:         1,
+			TransactionID:     "EF2023-000001-0101-120000-0002",
+			Event:             "EF2023",
+			TransactionType:   entities.TransactionTypePayment,
+			TransactionStatus: entities.TransactionStatusValid,
+			Amount:            entities.Amount{ISOCurrency: "EUR", GrossCent: 10000},
+		},
+		{
+			DebitorID:         1,
+			TransactionID:     "EF24-000001-0101-120000-0003",
+			Event:             "EF2024",
+			TransactionType:   entities.TransactionTypeDue,
+			TransactionStatus: entities.TransactionStatusValid,
+			Amount:            entities.Amount{ISOCurrency: "EUR", GrossCent: 12000},
+		},
+	})
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	balance, err := i.GetBalance(systemCtx(), 1, "EF2023")
+	require.NoError(t, err)
+	require.Equal(t, int64(0), balance.OutstandingCent)
+	require.True(t, balance.Consistent())
+
+	balance, err = i.GetBalance(systemCtx(), 1, "EF2024")
+	require.NoError(t, err)
+	require.Equal(t, int64(12000), balance.OutstandingCent)
+	require.True(t, balance.Consistent())
+
+	_, err = i.GetBalance(systemCtx(), 1, "EF2019")
+	require.True(t, apierrors.IsBadRequestError(err))
+}
+
+func TestCurrentEventBetweenEvents(t *testing.T) {
+	conf := &config.Application{Service: config.ServiceConfig{Events: []config.EventConfig{
+		{Name: "EF2023", ValidFrom: "2023-01-01", ValidUntil: "2023-09-30"},
+		{Name: "EF2024", ValidFrom: "2024-01-01", ValidUntil: "2024-09-30"},
+	}}}
+
+	tests := []struct {
+		day      string
+		expected string
+	}{
+		{day: "2023-08-20", expected: "EF2023"},
+		{day: "2022-06-01", expected: "EF2023"},
+		{day: "2023-11-15", expected: "EF2023"},
+		{day: "2024-03-01", expected: "EF2024"},
+		{day: "2025-03-01", expected: "EF2024"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.day, func(t *testing.T) {
+			event, err := currentEvent(conf, tstDate(tt.day).Time)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, event.Name)
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package interaction
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+)
+
+// GroupPayment is a single paylink that settles the outstanding dues of several debitors.
+//
+// Every debitor gets their own payment transaction for their share of the total,
+// all of them carrying the same GroupReference.
+type GroupPayment struct {
+	GroupReference  string
+	PaymentStartUrl string
+	Transactions    []entities.Transaction
+}
+
+// CreateGroupPaymentForOutstandingDues creates one paylink covering the outstanding dues of all the given debitors.
+//
+// Registered users may only pay for their own registrations, as listed by the attendee service.
+// A tentative payment is booked for each debitor, allocated their outstanding dues, so once the group payment
+// becomes valid, every debitor's balance is settled.
+func (s *serviceInteractor) CreateGroupPaymentForOutstandingDues(ctx context.Context, debitorIDs []int64, method entities.PaymentMethod) (*GroupPayment, error) {
+	logger := logging.LoggerFromContext(ctx)
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateGroupDebitors(debitorIDs); err != nil {
+		return nil, err
+	}
+
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if mgr.IsRegisteredUser() {
+		regIDs, err := s.attendeeClient.ListMyRegistrationIds(ctx)
+		if err != nil {
+			logger.Error("could not call the attendee service. [error]: %v", err)
+			return nil, apierrors.NewInternalServerError("attendee service error - see log for details")
+		}
+
+		for _, debitorID := range debitorIDs {
+			if !containsDebitor(regIDs, debitorID) {
+				return nil, apierrors.NewForbidden(fmt.Sprintf("subject %s may not initiate payments for debitor %d", mgr.Subject(), debitorID))
+			}
+		}
+	} else if !mgr.IsAdmin() && !mgr.IsAPITokenCall() && !mgr.IsSystemCall() {
+		return nil, apierrors.NewForbidden("unable to determine the request permissions")
+	}
+
+	if method == "" {
+		method = entities.PaymentMethodCredit
+	}
+
+	// only the payment provider reports the outcome by group reference, a bank transfer could not be matched to the group
+	if method != entities.PaymentMethodCredit {
+		return nil, apierrors.NewBadRequest("payment method not available for group payments")
+	}
+
+	comment, ok := appConfig.Service.DefaultPaymentComment[string(method)]
+	if !ok || comment == "" {
+		return nil, apierrors.NewBadRequest("payment method not available for initiate-payment")
+	}
+
+	// outstanding dues are paid for the current event
+	event, err := currentEvent(appConfig, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	filter := eventFilter(appConfig, event.Name)
+
+	groupReference := generateGroupReference(event.Prefix(), debitorIDs[0])
+	effective := sql.NullTime{Time: time.Now(), Valid: true}
+
+	members := make([]entities.Transaction, 0, len(debitorIDs))
+	prefixes := make([]string, 0, len(debitorIDs))
+	var total int64
+
+	// check everything before the first transaction is written
+	for _, debitorID := range debitorIDs {
+		validTransactions, err := s.store.GetValidTransactionsForDebitor(ctx, debitorID, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(validTransactions) == 0 {
+			return nil, apierrors.NewNotFound(fmt.Sprintf("no valid dues found for debitor %d in order to initiate payment", debitorID))
+		}
+
+		dues, err := s.store.QueryOutstandingDuesForDebitor(ctx, debitorID, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		if dues <= 0 {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("no outstanding dues for debitor %d", debitorID))
+		}
+
+		pending, err := s.arePendingPaymentsPresent(ctx, debitorID, filter, method)
+		if err != nil {
+			logger.Error("could not retrieve pending payments for debitor %d - [error]: %v", debitorID, err)
+			return nil, err
+		}
+
+		if pending {
+			return nil, apierrors.NewConflict(fmt.Sprintf("There are pending payments for attendee %d", debitorID))
+		}
+
+		first := validTransactions[0]
+		if len(members) > 0 && (first.Amount.ISOCurrency != members[0].Amount.ISOCurrency || first.Amount.VatRate != members[0].Amount.VatRate) {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("dues of debitor %d differ in currency or vat rate, they cannot be paid together", debitorID))
+		}
+
+		member := entities.Transaction{
+			DebitorID:         debitorID,
+			TransactionType:   entities.TransactionTypePayment,
+			PaymentMethod:     method,
+			TransactionStatus: entities.TransactionStatusTentative,
+			Comment:           comment,
+			Event:             event.Name,
+			EffectiveDate:     effective,
+			GroupReference:    groupReference,
+			Amount: entities.Amount{
+				ISOCurrency: first.Amount.ISOCurrency,
+				VatRate:     first.Amount.VatRate,
+				GrossCent:   dues,
+			},
+		}
+
+		members = append(members, member)
+		prefixes = append(prefixes, event.Prefix())
+		total += dues
+	}
+
+	// the payments of the group are created together, so there is never a partial group
+	err = s.createWithGeneratedIDs(ctx, members, prefixes, func(trs []entities.Transaction) error {
+		return s.store.CreateTransactions(ctx, trs)
+	})
+	for i := range members {
+		s.recordAudit(ctx, mgr, auditActionCreateTransaction, members[i].DebitorID, members[i].TransactionID, transactionDiff(nil, &members[i]), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range members {
+		transactionCreated(&members[i])
+	}
+
+	// the paylink is requested for the whole group, the group reference is what the provider reports back
+	paymentLink, err := s.createPaymentLink(ctx, entities.Transaction{
+		DebitorID:       members[0].DebitorID,
+		TransactionID:   groupReference,
+		TransactionType: entities.TransactionTypePayment,
+		PaymentMethod:   method,
+		Amount: entities.Amount{
+			ISOCurrency: members[0].Amount.ISOCurrency,
+			VatRate:     members[0].Amount.VatRate,
+			GrossCent:   total,
+		},
+	})
+	if err != nil {
+		s.voidGroupPayment(ctx, groupReference, "voided group payment - paylink could not be created")
+		return nil, apierrors.NewInternalServerError(err.Error())
+	}
+
+	for i := range members {
+		members[i].PaymentStartUrl = paymentLink
+	}
+
+	// update the payment link in the database, for all payments or none of them
+	if err := s.store.UpdateTransactions(ctx, members); err != nil {
+		logger.Error("could not store the paylink of group payment %s - [error]: %v", groupReference, err)
+		s.voidGroupPayment(ctx, groupReference, "voided group payment - paylink could not be stored")
+		return nil, err
+	}
+
+	for i := range members {
+		// inform the attendee service that there is a new payment in the database
+		if err := s.attendeeClient.PaymentsChanged(ctx, uint(members[i].DebitorID)); err != nil {
+			logger.Error("error when calling the attendee service webhook. [error]: %v", err)
+		}
+	}
+
+	return &GroupPayment{
+		GroupReference:  groupReference,
+		PaymentStartUrl: paymentLink,
+		Transactions:    members,
+	}, nil
+}
+
+// UpdateGroupPayment applies a status change to all payments of a group payment.
+//
+// This is how the outcome of a group paylink is reported, the payments of all debitors
+// move together. Each change is checked and audited just like a change of a single transaction,
+// and the attendee service is informed for each debitor.
+func (s *serviceInteractor) UpdateGroupPayment(ctx context.Context, groupReference string, status entities.TransactionStatus, comment string, info entities.PaymentProcessorInformation) ([]entities.Transaction, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.store.GetTransactionsByFilter(ctx, entities.TransactionQuery{GroupReference: groupReference})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(members) == 0 {
+		return nil, apierrors.NewNotFound(fmt.Sprintf("group payment %s could not be found", groupReference))
+	}
+	sortByTxID(members)
+
+	// reject the change before touching any payment, so the group never ends up split
+	for _, member := range members {
+		if member.TransactionStatus == status {
+			continue
+		}
+
+		changed := member
+		changed.TransactionStatus = status
+		if !isValidStatusChange(member, changed) {
+			return nil, apierrors.NewForbidden(
+				fmt.Sprintf("cannot change status from %s to %s for group payment %s",
+					member.TransactionStatus,
+					status,
+					groupReference,
+				))
+		}
+	}
+
+	action := auditActionUpdateTransaction
+	if status == entities.TransactionStatusDeleted {
+		action = auditActionDeleteTransaction
+	}
+
+	// check every change first, then store all of them in one database transaction
+	updates := make([]transactionUpdate, 0, len(members))
+	for _, member := range members {
+		tran := member
+		tran.TransactionStatus = status
+		tran.ProcessorInfo = info
+		if comment != "" {
+			tran.Comment = comment
+		}
+
+		update, err := s.checkTransactionUpdate(ctx, &tran, mgr)
+		if err != nil {
+			s.recordAudit(ctx, mgr, action, tran.DebitorID, tran.TransactionID, transactionDiff(update.before, update.after), err)
+			return nil, err
+		}
+		updates = append(updates, update)
+	}
+
+	updated := make([]entities.Transaction, 0, len(updates))
+	for _, update := range updates {
+		updated = append(updated, *update.after)
+	}
+
+	err = s.store.UpdateTransactions(ctx, updated)
+	for _, update := range updates {
+		s.recordAudit(ctx, mgr, action, update.after.DebitorID, update.after.TransactionID, transactionDiff(update.before, update.after), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, update := range updates {
+		s.transactionUpdated(ctx, update)
+	}
+
+	return updated, nil
+}
+
+// voidGroupPayment marks the remaining tentative payments of a group payment deleted,
+// and returns the ids of their transactions.
+//
+// A group paylink covers the dues of all of its debitors, so it cannot be used anymore
+// once one of its payments is gone.
+func (s *serviceInteractor) voidGroupPayment(ctx context.Context, groupReference string, comment string) []string {
+	logger := logging.LoggerFromContext(ctx)
+
+	members, err := s.store.GetTransactionsByFilter(ctx, entities.TransactionQuery{GroupReference: groupReference})
+	if err != nil {
+		logger.Error("could not retrieve payments of group payment %s - [error]: %v", groupReference, err)
+		return nil
+	}
+
+	voided := make([]string, 0)
+	deletions := make([]entities.Transaction, 0, len(members))
+	for _, tt := range members {
+		if tt.TransactionType != entities.TransactionTypePayment || tt.TransactionStatus != entities.TransactionStatusTentative {
+			continue
+		}
+
+		tt.Deletion = entities.Deletion{
+			Status:  tt.TransactionStatus,
+			Comment: tt.Comment,
+			By:      "internal",
+		}
+		tt.TransactionStatus = entities.TransactionStatusDeleted
+		tt.Comment = comment
+
+		deletions = append(deletions, tt)
+		voided = append(voided, tt.TransactionID)
+	}
+
+	if len(deletions) == 0 {
+		return voided
+	}
+
+	if err := s.store.UpdateTransactions(ctx, deletions); err != nil {
+		logger.Error("could not void the payments of group payment %s - [error]: %v", groupReference, err)
+		return nil
+	}
+
+	logger.Warn("deleted tentative payments %v of group payment %s", voided, groupReference)
+
+	return voided
+}
+
+func validateGroupDebitors(debitorIDs []int64) error {
+	fields := url.Values{}
+
+	if len(debitorIDs) < 2 {
+		fields.Add("debitor_ids", "must contain at least two debitors, use initiate-payment for a single debitor")
+	}
+
+	seen := make(map[int64]bool)
+	for _, debitorID := range debitorIDs {
+		if debitorID <= 0 {
+			fields.Add("debitor_ids", "must be greater than zero")
+		} else if seen[debitorID] {
+			fields.Add("debitor_ids", fmt.Sprintf("debitor %d is listed more than once", debitorID))
+		}
+		seen[debitorID] = true
+	}
+
+	if len(fields) > 0 {
+		return apierrors.NewUnprocessableEntity(fields)
+	}
+
+	return nil
+}
+
+// generateGroupReference looks like a transaction id, but the debitor segment starts with G,
+// so it can never collide with one.
+func generateGroupReference(prefix string, debitorID int64) string {
+	parsedTime := time.Now().UTC().Format(transactionIDTimeFormat)
+	return fmt.Sprintf("%s-G%06d-%s-%s", prefix, debitorID, parsedTime, randomDigits(4))
+}
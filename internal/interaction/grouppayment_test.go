@@ -0,0 +1,473 @@
+package interaction
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+	"github.com/eurofurence/reg-payment-service/internal/repository/downstreams/cncrdadapter"
+)
+
+func eurDue(debitorID int64, tranID string, grossCent int64) entities.Transaction {
+	return newTransaction(debitorID, tranID, entities.TransactionTypeDue, entities.PaymentMethodCredit, entities.TransactionStatusValid, entities.Amount{
+		ISOCurrency: "EUR",
+		GrossCent:   grossCent,
+		VatRate:     19.0,
+	})
+}
+
+func TestCreateGroupPaymentForOutstandingDues(t *testing.T) {
+	type args struct {
+		ctx        context.Context
+		debitorIDs []int64
+		seed       []entities.Transaction
+		regIDs     []int64
+		method     entities.PaymentMethod
+	}
+
+	type expected struct {
+		err           error
+		paylinkAmount int64
+		memberAmounts map[int64]int64
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		expected expected
+	}{
+		{
+			name: "should reject a single debitor",
+			args: args{
+				ctx:        attendeeCtx(),
+				debitorIDs: []int64{10},
+			},
+			expected: expected{
+				err: apierrors.NewUnprocessableEntity(url.Values{
+					"debitor_ids": {"must contain at least two debitors, use initiate-payment for a single debitor"},
+				}),
+			},
+		},
+		{
+			name: "should reject duplicate debitors",
+			args: args{
+				ctx:        attendeeCtx(),
+				debitorIDs: []int64{10, 10},
+			},
+			expected: expected{
+				err: apierrors.NewUnprocessableEntity(url.Values{
+					"debitor_ids": {"debitor 10 is listed more than once"},
+				}),
+			},
+		},
+		{
+			name: "should not allow paying for registrations of somebody else",
+			args: args{
+				ctx:        attendeeCtx(),
+				debitorIDs: []int64{10, 11},
+				regIDs:     []int64{10},
+				seed:       []entities.Transaction{eurDue(10, "1", 100_00), eurDue(11, "2", 50_00)},
+			},
+			expected: expected{
+				err: apierrors.NewForbidden("subject 1234567890 may not initiate payments for debitor 11"),
+			},
+		},
+		{
+			name: "should fail if one debitor has no outstanding dues",
+			args: args{
+				ctx:        attendeeCtx(),
+				debitorIDs: []int64{10, 11},
+				regIDs:     []int64{10, 11},
+				seed: []entities.Transaction{
+					eurDue(10, "1", 100_00),
+					eurDue(11, "2", 50_00),
+					newTransaction(11, "3", entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusValid, entities.Amount{
+						ISOCurrency: "EUR",
+						GrossCent:   50_00,
+						VatRate:     19.0,
+					}),
+				},
+			},
+			expected: expected{
+				err: apierrors.NewBadRequest("no outstanding dues for debitor 11"),
+			},
+		},
+		{
+			name: "should fail if one debitor has a pending payment",
+			args: args{
+				ctx:        attendeeCtx(),
+				debitorIDs: []int64{10, 11},
+				regIDs:     []int64{10, 11},
+				seed: []entities.Transaction{
+					eurDue(10, "1", 100_00),
+					eurDue(11, "2", 50_00),
+					newTransaction(11, "3", entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusPending, entities.Amount{
+						ISOCurrency: "EUR",
+						GrossCent:   50_00,
+						VatRate:     19.0,
+					}),
+				},
+			},
+			expected: expected{
+				err: apierrors.NewConflict("There are pending payments for attendee 11"),
+			},
+		},
+		{
+			name: "should reject bank transfers",
+			args: args{
+				ctx:        attendeeCtx(),
+				debitorIDs: []int64{10, 11},
+				regIDs:     []int64{10, 11},
+				seed:       []entities.Transaction{eurDue(10, "1", 100_00), eurDue(11, "2", 50_00)},
+				method:     entities.PaymentMethodTransfer,
+			},
+			expected: expected{
+				err: apierrors.NewBadRequest("payment method not available for group payments"),
+			},
+		},
+		{
+			name: "should create one paylink and one payment per debitor",
+			args: args{
+				ctx:        attendeeCtx(),
+				debitorIDs: []int64{10, 11},
+				regIDs:     []int64{10, 11, 12},
+				seed:       []entities.Transaction{eurDue(10, "1", 100_00), eurDue(11, "2", 50_00), eurDue(12, "3", 20_00)},
+			},
+			expected: expected{
+				paylinkAmount: 150_00,
+				memberAmounts: map[int64]int64{10: 100_00, 11: 50_00},
+			},
+		},
+		{
+			name: "should allow admins to create group payments for any debitor",
+			args: args{
+				ctx:        adminCtx(),
+				debitorIDs: []int64{10, 11},
+				seed:       []entities.Transaction{eurDue(10, "1", 100_00), eurDue(11, "2", 50_00)},
+			},
+			expected: expected{
+				paylinkAmount: 150_00,
+				memberAmounts: map[int64]int64{10: 100_00, 11: 50_00},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var notified []uint
+			asm := &AttendeeServiceMock{
+				ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+					return tt.args.regIDs, nil
+				},
+				PaymentsChangedFunc: func(ctx context.Context, debitorId uint) error {
+					notified = append(notified, debitorId)
+					return nil
+				},
+			}
+
+			var paylinkRequests []cncrdadapter.PaymentLinkRequestDto
+			ccm := &CncrdAdapterMock{
+				CreatePaylinkFunc: func(ctx context.Context, request cncrdadapter.PaymentLinkRequestDto) (cncrdadapter.PaymentLinkDto, error) {
+					paylinkRequests = append(paylinkRequests, request)
+					return cncrdadapter.PaymentLinkDto{ReferenceId: request.ReferenceId, Link: "https://example.com/paylink"}, nil
+				},
+			}
+
+			db := inmemory.NewInMemoryProvider()
+			seedDB(db, tt.args.seed)
+
+			i := tstServiceInteractor(db, asm, ccm)
+
+			res, err := i.CreateGroupPaymentForOutstandingDues(tt.args.ctx, tt.args.debitorIDs, tt.args.method)
+
+			if tt.expected.err != nil {
+				require.EqualError(t, err, tt.expected.err.Error())
+				require.Nil(t, res)
+				require.Empty(t, paylinkRequests)
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotEmpty(t, res.GroupReference)
+			require.Equal(t, "https://example.com/paylink", res.PaymentStartUrl)
+
+			require.Len(t, paylinkRequests, 1)
+			require.Equal(t, res.GroupReference, paylinkRequests[0].ReferenceId)
+			require.Equal(t, tt.expected.paylinkAmount, paylinkRequests[0].AmountDue)
+
+			require.Len(t, res.Transactions, len(tt.expected.memberAmounts))
+			for _, member := range res.Transactions {
+				require.Equal(t, tt.expected.memberAmounts[member.DebitorID], member.Amount.GrossCent)
+				require.Equal(t, entities.TransactionStatusTentative, member.TransactionStatus)
+				require.Equal(t, res.PaymentStartUrl, member.PaymentStartUrl)
+			}
+
+			stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{GroupReference: res.GroupReference})
+			require.NoError(t, err)
+			require.Len(t, stored, len(tt.expected.memberAmounts))
+
+			require.ElementsMatch(t, []uint{10, 11}, notified)
+		})
+	}
+}
+
+func TestUpdateGroupPayment(t *testing.T) {
+	member := func(debitorID int64, tranID string, status entities.TransactionStatus) entities.Transaction {
+		tran := newTransaction(debitorID, tranID, entities.TransactionTypePayment, entities.PaymentMethodCredit, status, entities.Amount{
+			ISOCurrency: "EUR",
+			GrossCent:   100_00,
+			VatRate:     19.0,
+		})
+		tran.GroupReference = "EF-G000010-0101-120000-1234"
+		return tran
+	}
+
+	type args struct {
+		ctx            context.Context
+		groupReference string
+		status         entities.TransactionStatus
+		seed           []entities.Transaction
+	}
+
+	tests := []struct {
+		name     string
+		args     args
+		err      error
+		notified []uint
+	}{
+		{
+			name: "should return not found for an unknown group",
+			args: args{
+				ctx:            apiKeyCtx(),
+				groupReference: "EF-G000099-0101-120000-1234",
+				status:         entities.TransactionStatusValid,
+			},
+			err: apierrors.NewNotFound("group payment EF-G000099-0101-120000-1234 could not be found"),
+		},
+		{
+			name: "should book all payments of the group",
+			args: args{
+				ctx:            apiKeyCtx(),
+				groupReference: "EF-G000010-0101-120000-1234",
+				status:         entities.TransactionStatusValid,
+				seed: []entities.Transaction{
+					member(10, "EF-000010-0101-120000-1111", entities.TransactionStatusPending),
+					member(11, "EF-000011-0101-120000-2222", entities.TransactionStatusPending),
+				},
+			},
+			notified: []uint{10, 11},
+		},
+		{
+			name: "should not change any payment if the change is not allowed for one of them",
+			args: args{
+				ctx:            attendeeCtx(),
+				groupReference: "EF-G000010-0101-120000-1234",
+				status:         entities.TransactionStatusPending,
+				seed: []entities.Transaction{
+					member(10, "EF-000010-0101-120000-1111", entities.TransactionStatusTentative),
+					member(11, "EF-000011-0101-120000-2222", entities.TransactionStatusValid),
+				},
+			},
+			err: apierrors.NewForbidden("cannot change status from valid to pending for group payment EF-G000010-0101-120000-1234"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var notified []uint
+			asm := &AttendeeServiceMock{
+				ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+					return []int64{10, 11}, nil
+				},
+				PaymentsChangedFunc: func(ctx context.Context, debitorId uint) error {
+					notified = append(notified, debitorId)
+					return nil
+				},
+			}
+
+			db := inmemory.NewInMemoryProvider()
+			seedDB(db, tt.args.seed)
+
+			i := tstServiceInteractor(db, asm, &CncrdAdapterMock{})
+
+			updated, err := i.UpdateGroupPayment(tt.args.ctx, tt.args.groupReference, tt.args.status, "", nil)
+
+			if tt.err != nil {
+				require.EqualError(t, err, tt.err.Error())
+				require.Empty(t, notified)
+
+				stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{})
+				require.NoError(t, err)
+				require.ElementsMatch(t, tt.args.seed, stripIDs(stored))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, updated, len(tt.args.seed))
+			for _, tran := range updated {
+				require.Equal(t, tt.args.status, tran.TransactionStatus)
+			}
+			require.Equal(t, tt.notified, notified)
+		})
+	}
+}
+
+func TestInvalidateTentativePaymentsVoidsWholeGroup(t *testing.T) {
+	member := func(debitorID int64, tranID string) entities.Transaction {
+		tran := newTransaction(debitorID, tranID, entities.TransactionTypePayment, entities.PaymentMethodCredit, entities.TransactionStatusTentative, entities.Amount{
+			ISOCurrency: "EUR",
+			GrossCent:   100_00,
+			VatRate:     19.0,
+		})
+		tran.GroupReference = "EF-G000010-0101-120000-1234"
+		return tran
+	}
+
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, []entities.Transaction{
+		member(10, "EF-000010-0101-120000-1111"),
+		member(11, "EF-000011-0101-120000-2222"),
+	})
+
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	voided, err := i.invalidateTentativePayments(context.Background(), 10, "", "internal", "voided paylink - dues have changed")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"EF-000010-0101-120000-1111", "EF-000011-0101-120000-2222"}, voided)
+
+	remaining, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{})
+	require.NoError(t, err)
+	require.Empty(t, remaining)
+}
+
+// failingUpdates fails storing any change of transactions that matches fail, all other calls reach the repository.
+type failingUpdates struct {
+	database.Repository
+	fail func(trs []entities.Transaction) bool
+}
+
+func (f *failingUpdates) UpdateTransactions(ctx context.Context, trs []entities.Transaction) error {
+	if f.fail(trs) {
+		return errors.New("database unavailable")
+	}
+	return f.Repository.UpdateTransactions(ctx, trs)
+}
+
+func TestCreateGroupPaymentVoidsGroupIfPaylinkCannotBeStored(t *testing.T) {
+	db := &failingUpdates{
+		Repository: inmemory.NewInMemoryProvider(),
+		fail: func(trs []entities.Transaction) bool {
+			return trs[0].PaymentStartUrl != ""
+		},
+	}
+	seedDB(db, []entities.Transaction{
+		eurDue(10, "1000", 100_00),
+		eurDue(11, "1100", 50_00),
+	})
+
+	asm := &AttendeeServiceMock{
+		ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+			return []int64{10, 11}, nil
+		},
+	}
+	ccm := &CncrdAdapterMock{
+		CreatePaylinkFunc: func(ctx context.Context, request cncrdadapter.PaymentLinkRequestDto) (cncrdadapter.PaymentLinkDto, error) {
+			return cncrdadapter.PaymentLinkDto{ReferenceId: request.ReferenceId, Link: "https://example.com/paylink"}, nil
+		},
+	}
+
+	i := tstServiceInteractor(db, asm, ccm)
+
+	res, err := i.CreateGroupPaymentForOutstandingDues(attendeeCtx(), []int64{10, 11}, "")
+	require.Error(t, err)
+	require.Nil(t, res)
+
+	remaining, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{})
+	require.NoError(t, err)
+	require.Len(t, remaining, 2)
+	for _, tran := range remaining {
+		require.Equal(t, entities.TransactionTypeDue, tran.TransactionType, "no member of the group may be left without its paylink")
+	}
+}
+
+func TestUpdateGroupPaymentChangesNothingIfStoringFails(t *testing.T) {
+	seed := []entities.Transaction{
+		newTransaction(10, "EF-000010-0101-120000-1111", entities.TransactionTypePayment, entities.PaymentMethodCredit, entities.TransactionStatusPending, entities.Amount{ISOCurrency: "EUR", GrossCent: 100_00, VatRate: 19.0}),
+		newTransaction(11, "EF-000011-0101-120000-2222", entities.TransactionTypePayment, entities.PaymentMethodCredit, entities.TransactionStatusPending, entities.Amount{ISOCurrency: "EUR", GrossCent: 100_00, VatRate: 19.0}),
+	}
+	for i := range seed {
+		seed[i].GroupReference = "EF-G000010-0101-120000-1234"
+	}
+
+	db := &failingUpdates{
+		Repository: inmemory.NewInMemoryProvider(),
+		fail: func([]entities.Transaction) bool {
+			return true
+		},
+	}
+	seedDB(db, seed)
+
+	var notified []uint
+	asm := &AttendeeServiceMock{
+		PaymentsChangedFunc: func(ctx context.Context, debitorId uint) error {
+			notified = append(notified, debitorId)
+			return nil
+		},
+	}
+
+	i := tstServiceInteractor(db, asm, &CncrdAdapterMock{})
+
+	updated, err := i.UpdateGroupPayment(apiKeyCtx(), "EF-G000010-0101-120000-1234", entities.TransactionStatusValid, "", nil)
+	require.Error(t, err)
+	require.Nil(t, updated)
+	require.Empty(t, notified)
+
+	stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{})
+	require.NoError(t, err)
+	require.ElementsMatch(t, seed, stripIDs(stored))
+}
+
+func stripIDs(transactions []entities.Transaction) []entities.Transaction {
+	result := make([]entities.Transaction, len(transactions))
+	for i, tran := range transactions {
+		tran.Model = entities.Transaction{}.Model
+		result[i] = tran
+	}
+	return result
+}
+
+func TestCreateGroupPaymentRetriesCollidingIDs(t *testing.T) {
+	db := &collidingCreates{Repository: inmemory.NewInMemoryProvider()}
+	seedDB(db, []entities.Transaction{
+		eurDue(10, "1000", 100_00),
+		eurDue(11, "1100", 50_00),
+	})
+	db.collisions, db.attempts = 1, nil
+
+	asm := &AttendeeServiceMock{
+		ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+			return []int64{10, 11}, nil
+		},
+		PaymentsChangedFunc: func(ctx context.Context, debitorId uint) error {
+			return nil
+		},
+	}
+	ccm := &CncrdAdapterMock{
+		CreatePaylinkFunc: func(ctx context.Context, request cncrdadapter.PaymentLinkRequestDto) (cncrdadapter.PaymentLinkDto, error) {
+			return cncrdadapter.PaymentLinkDto{ReferenceId: request.ReferenceId, Link: "https://example.com/paylink"}, nil
+		},
+	}
+
+	i := tstServiceInteractor(db, asm, ccm)
+
+	res, err := i.CreateGroupPaymentForOutstandingDues(attendeeCtx(), []int64{10, 11}, "")
+	require.NoError(t, err)
+	requireCreatedAfterCollision(t, db, res.Transactions)
+}
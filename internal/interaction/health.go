@@ -0,0 +1,102 @@
+package interaction
+
+import (
+	"context"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/repository/downstreams"
+)
+
+type HealthStatus string
+
+const (
+	HealthUp       HealthStatus = "up"
+	HealthDegraded HealthStatus = "degraded"
+	HealthDown     HealthStatus = "down"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// ComponentHealth is the state of a single dependency of the service
+type ComponentHealth struct {
+	Name   string
+	Status HealthStatus
+	// Optional components do not make the service unready when they fail
+	Optional bool
+	Details  string
+}
+
+// HealthReport is the result of the readiness check
+//
+// The service is down if a required component is down, and degraded if only optional components fail.
+type HealthReport struct {
+	Status     HealthStatus
+	Components []ComponentHealth
+}
+
+// circuitBreakerStates is replaced in tests
+var circuitBreakerStates = downstreams.CircuitBreakerStates
+
+// CheckReadiness checks the database and reports the circuit breakers of the downstream services.
+//
+// The downstream services are optional. They are shared by all instances, so taking this instance
+// out of rotation would not help, and many requests can still be served without them.
+func (s *serviceInteractor) CheckReadiness(ctx context.Context) *HealthReport {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+	logger := logging.LoggerFromContext(ctx)
+
+	components := make([]ComponentHealth, 0)
+
+	database := ComponentHealth{Name: "database", Status: HealthUp}
+	if err := s.store.Ping(ctx); err != nil {
+		logger.Error("readiness check: database unavailable. [error]: %v", err)
+		database.Status = HealthDown
+		database.Details = "database cannot be reached"
+	}
+	components = append(components, database)
+
+	schema := ComponentHealth{Name: "schema", Status: HealthUp}
+	if database.Status != HealthUp {
+		schema.Status = HealthDown
+		schema.Details = "database cannot be reached"
+	} else if err := s.store.CheckSchema(ctx); err != nil {
+		logger.Error("readiness check: database schema incomplete. [error]: %v", err)
+		schema.Status = HealthDown
+		schema.Details = "database schema is incomplete - see log for details"
+	}
+	components = append(components, schema)
+
+	for _, breaker := range circuitBreakerStates() {
+		component := ComponentHealth{Name: breaker.Name, Status: HealthUp, Optional: true}
+		switch breaker.State {
+		case downstreams.CircuitBreakerHalfOpen:
+			component.Status = HealthDegraded
+			component.Details = "circuit breaker half-open, trying to recover"
+		case downstreams.CircuitBreakerOpen:
+			component.Status = HealthDown
+			component.Details = "circuit breaker open, calls are failing fast"
+		}
+		components = append(components, component)
+	}
+
+	return &HealthReport{
+		Status:     overallHealth(components),
+		Components: components,
+	}
+}
+
+func overallHealth(components []ComponentHealth) HealthStatus {
+	status := HealthUp
+	for _, c := range components {
+		if c.Status == HealthUp {
+			continue
+		}
+		if !c.Optional {
+			return HealthDown
+		}
+		status = HealthDegraded
+	}
+	return status
+}
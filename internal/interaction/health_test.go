@@ -0,0 +1,95 @@
+package interaction
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/repository/downstreams"
+)
+
+func TestCheckReadiness(t *testing.T) {
+	tests := []struct {
+		name               string
+		pingErr            error
+		schemaErr          error
+		breakers           []downstreams.CircuitBreakerStatus
+		expectedStatus     HealthStatus
+		expectedComponents []ComponentHealth
+	}{
+		{
+			name: "should be up if everything is up",
+			breakers: []downstreams.CircuitBreakerStatus{
+				{Name: "attendee-service", State: downstreams.CircuitBreakerClosed},
+			},
+			expectedStatus: HealthUp,
+			expectedComponents: []ComponentHealth{
+				{Name: "database", Status: HealthUp},
+				{Name: "schema", Status: HealthUp},
+				{Name: "attendee-service", Status: HealthUp, Optional: true},
+			},
+		},
+		{
+			name: "should be degraded if a downstream breaker is open",
+			breakers: []downstreams.CircuitBreakerStatus{
+				{Name: "attendee-service", State: downstreams.CircuitBreakerClosed},
+				{Name: "cncrd-adapter", State: downstreams.CircuitBreakerOpen},
+				{Name: "auth-service", State: downstreams.CircuitBreakerHalfOpen},
+			},
+			expectedStatus: HealthDegraded,
+			expectedComponents: []ComponentHealth{
+				{Name: "database", Status: HealthUp},
+				{Name: "schema", Status: HealthUp},
+				{Name: "attendee-service", Status: HealthUp, Optional: true},
+				{Name: "cncrd-adapter", Status: HealthDown, Optional: true, Details: "circuit breaker open, calls are failing fast"},
+				{Name: "auth-service", Status: HealthDegraded, Optional: true, Details: "circuit breaker half-open, trying to recover"},
+			},
+		},
+		{
+			name:           "should be down if the database cannot be reached",
+			pingErr:        errors.New("connection refused"),
+			expectedStatus: HealthDown,
+			expectedComponents: []ComponentHealth{
+				{Name: "database", Status: HealthDown, Details: "database cannot be reached"},
+				{Name: "schema", Status: HealthDown, Details: "database cannot be reached"},
+			},
+		},
+		{
+			name:           "should be down if the schema was not migrated",
+			schemaErr:      errors.New("table pay_audit_log_entries is missing, the database needs to be migrated"),
+			expectedStatus: HealthDown,
+			expectedComponents: []ComponentHealth{
+				{Name: "database", Status: HealthUp},
+				{Name: "schema", Status: HealthDown, Details: "database schema is incomplete - see log for details"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			circuitBreakerStates = func() []downstreams.CircuitBreakerStatus {
+				return tt.breakers
+			}
+			t.Cleanup(func() {
+				circuitBreakerStates = downstreams.CircuitBreakerStates
+			})
+
+			repo := &RepositoryMock{
+				PingFunc: func(ctx context.Context) error {
+					return tt.pingErr
+				},
+				CheckSchemaFunc: func(ctx context.Context) error {
+					return tt.schemaErr
+				},
+			}
+
+			i := tstServiceInteractor(repo, nil, nil)
+			report := i.CheckReadiness(context.Background())
+
+			require.Equal(t, tt.expectedStatus, report.Status)
+			require.Equal(t, tt.expectedComponents, report.Components)
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package interaction
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
+)
+
+// InstallmentSchedule is the installment plan of a debitor, with the amounts worked out from the current dues
+type InstallmentSchedule struct {
+	DebitorID    int64
+	Event        string
+	Plan         string
+	DuesCent     int64
+	PaymentsCent int64
+	Installments []ScheduledInstallment
+	// Next is the installment to be paid next, nil once the dues are paid
+	Next *ScheduledInstallment
+	// NextAmountCent is the amount attendees may create a paylink for, 0 once the dues are paid
+	NextAmountCent int64
+}
+
+// ScheduledInstallment is one installment of an InstallmentSchedule
+type ScheduledInstallment struct {
+	Number  int
+	DueDate time.Time
+	// AmountCent is the share of the dues falling due with this installment
+	AmountCent int64
+	// OpenCent is what is still to be paid of it, payments are applied to the installments in order
+	OpenCent int64
+}
+
+// GetInstallmentPlan returns the installment plan of the debitor for the current event.
+//
+// Registered users may only read the plans of their own registrations.
+func (s *serviceInteractor) GetInstallmentPlan(ctx context.Context, debitorID int64) (*InstallmentSchedule, error) {
+	logger := logging.LoggerFromContext(ctx)
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if mgr.IsRegisteredUser() {
+		regIDs, err := s.attendeeClient.ListMyRegistrationIds(ctx)
+		if err != nil {
+			logger.Error("could not call the attendee service. [error]: %v", err)
+			return nil, apierrors.NewInternalServerError("attendee service error - see log for details")
+		}
+
+		if !containsDebitor(regIDs, debitorID) {
+			err := apierrors.NewForbidden(fmt.Sprintf("subject %s may not read the installment plan of debitor %d", mgr.Subject(), debitorID))
+			s.recordAudit(ctx, mgr, auditActionReadInstallmentPlan, debitorID, "", "", err)
+			return nil, err
+		}
+	} else if !mgr.IsAdmin() && !mgr.IsAPITokenCall() && !mgr.IsSystemCall() {
+		err := apierrors.NewForbidden("unable to determine the request permissions")
+		s.recordAudit(ctx, mgr, auditActionReadInstallmentPlan, debitorID, "", "", err)
+		return nil, err
+	}
+
+	schedule, err := s.currentInstallmentSchedule(ctx, debitorID)
+	if err == nil && schedule == nil {
+		err = apierrors.NewNotFound(fmt.Sprintf("debitor %d has no installment plan", debitorID))
+	}
+	s.recordAudit(ctx, mgr, auditActionReadInstallmentPlan, debitorID, "", "", err)
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// AttachInstallmentPlan allows the debitor to pay the dues of the current event according to the configured plan.
+//
+// The first installment is due on firstDueDate, or today if it is zero, the others follow in the interval configured for the plan.
+// Only admins, the api token and the command line may attach plans.
+func (s *serviceInteractor) AttachInstallmentPlan(ctx context.Context, debitorID int64, plan string, firstDueDate time.Time) (*InstallmentSchedule, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mgr.IsAdmin() && !mgr.IsAPITokenCall() && !mgr.IsSystemCall() {
+		err := apierrors.NewForbidden("no permission to attach installment plans")
+		s.recordAudit(ctx, mgr, auditActionAttachInstallmentPlan, debitorID, "", "", err)
+		return nil, err
+	}
+
+	entity, err := s.attachInstallmentPlan(ctx, debitorID, plan, firstDueDate, mgr.Subject())
+	s.recordAudit(ctx, mgr, auditActionAttachInstallmentPlan, debitorID, "", installmentPlanDiff(nil, entity), err)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.currentInstallmentSchedule(ctx, debitorID)
+}
+
+func (s *serviceInteractor) attachInstallmentPlan(ctx context.Context, debitorID int64, plan string, firstDueDate time.Time, by string) (*entities.InstallmentPlan, error) {
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	planConfig, ok := appConfig.Service.InstallmentPlans[plan]
+	if !ok {
+		return nil, apierrors.NewUnprocessableEntity(url.Values{"plan": {"must be one of the configured installment plans"}})
+	}
+
+	event, err := currentEvent(appConfig, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.store.GetInstallmentPlans(ctx, debitorID, event.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, apierrors.NewConflict(fmt.Sprintf("debitor %d already has installment plan %s for event %s", debitorID, existing[0].Plan, event.Name))
+	}
+
+	dues, err := s.store.QueryOutstandingDuesForDebitor(ctx, debitorID, eventFilter(appConfig, event.Name))
+	if err != nil {
+		return nil, err
+	}
+	if dues <= 0 || dues < planConfig.MinimumDuesCent {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("outstanding dues of debitor %d are below the minimum for installment plan %s", debitorID, plan))
+	}
+
+	if firstDueDate.IsZero() {
+		firstDueDate = time.Now()
+	}
+
+	entity := entities.InstallmentPlan{
+		DebitorID:    debitorID,
+		Event:        event.Name,
+		Plan:         plan,
+		CreatedBy:    by,
+		Installments: make([]entities.Installment, planConfig.Installments),
+	}
+	for i := range entity.Installments {
+		entity.Installments[i] = entities.Installment{
+			Number:  i + 1,
+			DueDate: sql.NullTime{Time: firstDueDate.AddDate(0, 0, i*planConfig.IntervalDays), Valid: true},
+		}
+	}
+
+	if err := s.store.CreateInstallmentPlan(ctx, entity); err != nil {
+		if errors.Is(err, database.ErrInstallmentPlanExists) {
+			// a concurrent request attached a plan after the check above
+			return nil, apierrors.NewConflict(fmt.Sprintf("debitor %d already has an installment plan for event %s", debitorID, event.Name))
+		}
+		return nil, err
+	}
+
+	return &entity, nil
+}
+
+// RemoveInstallmentPlan removes the installment plan of the debitor for the current event,
+// so the remaining dues must be paid in full again.
+//
+// Only admins, the api token and the command line may remove plans.
+func (s *serviceInteractor) RemoveInstallmentPlan(ctx context.Context, debitorID int64) error {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !mgr.IsAdmin() && !mgr.IsAPITokenCall() && !mgr.IsSystemCall() {
+		err := apierrors.NewForbidden("no permission to remove installment plans")
+		s.recordAudit(ctx, mgr, auditActionRemoveInstallmentPlan, debitorID, "", "", err)
+		return err
+	}
+
+	plan, err := s.currentInstallmentPlan(ctx, debitorID)
+	if err == nil && plan == nil {
+		err = apierrors.NewNotFound(fmt.Sprintf("debitor %d has no installment plan", debitorID))
+	}
+	if err == nil {
+		err = s.store.DeleteInstallmentPlan(ctx, plan.ID)
+	}
+
+	s.recordAudit(ctx, mgr, auditActionRemoveInstallmentPlan, debitorID, "", installmentPlanDiff(plan, nil), err)
+	return err
+}
+
+// currentInstallmentPlan returns the plan of the debitor for the current event, or nil if there is none.
+func (s *serviceInteractor) currentInstallmentPlan(ctx context.Context, debitorID int64) (*entities.InstallmentPlan, error) {
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := currentEvent(appConfig, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.installmentPlan(ctx, debitorID, event.Name)
+}
+
+// installmentPlan returns the plan of the debitor for the event, or nil if there is none.
+func (s *serviceInteractor) installmentPlan(ctx context.Context, debitorID int64, event string) (*entities.InstallmentPlan, error) {
+	plans, err := s.store.GetInstallmentPlans(ctx, debitorID, event)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, plan := range plans {
+		if plan.Event == event {
+			return &plan, nil
+		}
+	}
+	return nil, nil
+}
+
+// currentInstallmentSchedule returns the schedule of the debitor for the current event, or nil if there is no plan.
+func (s *serviceInteractor) currentInstallmentSchedule(ctx context.Context, debitorID int64) (*InstallmentSchedule, error) {
+	plan, err := s.currentInstallmentPlan(ctx, debitorID)
+	if err != nil || plan == nil {
+		return nil, err
+	}
+
+	return s.installmentSchedule(ctx, *plan)
+}
+
+func (s *serviceInteractor) installmentSchedule(ctx context.Context, plan entities.InstallmentPlan) (*InstallmentSchedule, error) {
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.computeBalance(ctx, plan.DebitorID, plan.Event)
+	if err != nil {
+		return nil, err
+	}
+
+	// a plan removed from the configuration keeps its installments, just without a minimum amount
+	planConfig := appConfig.Service.InstallmentPlans[plan.Plan]
+
+	return computeInstallmentSchedule(plan, planConfig, balance.DuesCent, balance.PaymentsCent), nil
+}
+
+// computeInstallmentSchedule splits the dues into equal installments, the first ones taking the odd cents,
+// and applies the payments to them in order.
+func computeInstallmentSchedule(plan entities.InstallmentPlan, planConfig config.InstallmentPlanConfig, dues int64, payments int64) *InstallmentSchedule {
+	schedule := InstallmentSchedule{
+		DebitorID:    plan.DebitorID,
+		Event:        plan.Event,
+		Plan:         plan.Plan,
+		DuesCent:     dues,
+		PaymentsCent: payments,
+		Installments: make([]ScheduledInstallment, len(plan.Installments)),
+	}
+
+	count := int64(len(plan.Installments))
+	if count == 0 {
+		return &schedule
+	}
+
+	var base, odd int64
+	if dues > 0 {
+		base = dues / count
+		odd = dues % count
+	}
+
+	var before int64 // the dues falling due with the installments before the current one
+	for i, inst := range plan.Installments {
+		amount := base
+		if int64(i) < odd {
+			amount++
+		}
+
+		paid := min(max(payments-before, 0), amount)
+		schedule.Installments[i] = ScheduledInstallment{
+			Number:     inst.Number,
+			DueDate:    inst.DueDate.Time,
+			AmountCent: amount,
+			OpenCent:   amount - paid,
+		}
+		before += amount
+	}
+
+	for i := range schedule.Installments {
+		if schedule.Installments[i].OpenCent > 0 {
+			schedule.Next = &schedule.Installments[i]
+			break
+		}
+	}
+
+	if schedule.Next != nil {
+		remaining := dues - payments
+		schedule.NextAmountCent = schedule.Next.OpenCent
+		if schedule.NextAmountCent < planConfig.MinimumInstallmentCent {
+			schedule.NextAmountCent = min(remaining, planConfig.MinimumInstallmentCent)
+		}
+	}
+
+	return &schedule
+}
+
+// nextInstallmentAmount returns what the debitor may pay as the next installment for the event, or 0 if there is no plan.
+func (s *serviceInteractor) nextInstallmentAmount(ctx context.Context, debitorID int64, event string) (int64, error) {
+	plan, err := s.installmentPlan(ctx, debitorID, event)
+	if err != nil || plan == nil {
+		return 0, err
+	}
+
+	schedule, err := s.installmentSchedule(ctx, *plan)
+	if err != nil {
+		return 0, err
+	}
+	return schedule.NextAmountCent, nil
+}
+
+func installmentPlanDiff(before, after *entities.InstallmentPlan) string {
+	fields := func(plan *entities.InstallmentPlan) interface{} {
+		if plan == nil {
+			return nil
+		}
+
+		dueDates := make([]string, len(plan.Installments))
+		for i, inst := range plan.Installments {
+			dueDates[i] = inst.DueDate.Time.Format("2006-01-02")
+		}
+		return map[string]interface{}{
+			"plan":      plan.Plan,
+			"event":     plan.Event,
+			"due_dates": dueDates,
+		}
+	}
+
+	if before == nil && after == nil {
+		return ""
+	}
+
+	encoded, err := json.Marshal(map[string]auditChange{
+		"installment_plan": {Before: fields(before), After: fields(after)},
+	})
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
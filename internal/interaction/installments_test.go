@@ -0,0 +1,256 @@
+package interaction
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+	"github.com/eurofurence/reg-payment-service/internal/repository/downstreams/cncrdadapter"
+)
+
+func TestComputeInstallmentSchedule(t *testing.T) {
+	plan := entities.InstallmentPlan{
+		DebitorID: 10,
+		Plan:      "sponsor",
+		Installments: []entities.Installment{
+			{Number: 1, DueDate: sql.NullTime{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true}},
+			{Number: 2, DueDate: sql.NullTime{Time: time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), Valid: true}},
+			{Number: 3, DueDate: sql.NullTime{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Valid: true}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		minimum     int64
+		dues        int64
+		payments    int64
+		amounts     []int64
+		open        []int64
+		nextNumber  int
+		nextAmount  int64
+		expectNoNxt bool
+	}{
+		{
+			name:       "should split the dues evenly, the first installments taking the odd cents",
+			dues:       100_01,
+			amounts:    []int64{33_34, 33_34, 33_33},
+			open:       []int64{33_34, 33_34, 33_33},
+			nextNumber: 1,
+			nextAmount: 33_34,
+		},
+		{
+			name:       "should apply payments to the installments in order",
+			dues:       300_00,
+			payments:   150_00,
+			amounts:    []int64{100_00, 100_00, 100_00},
+			open:       []int64{0, 50_00, 100_00},
+			nextNumber: 2,
+			nextAmount: 50_00,
+		},
+		{
+			name:       "should raise the next amount to the minimum installment",
+			minimum:    80_00,
+			dues:       300_00,
+			payments:   150_00,
+			amounts:    []int64{100_00, 100_00, 100_00},
+			open:       []int64{0, 50_00, 100_00},
+			nextNumber: 2,
+			nextAmount: 80_00,
+		},
+		{
+			name:       "should not ask for more than the remaining dues",
+			minimum:    80_00,
+			dues:       300_00,
+			payments:   250_00,
+			amounts:    []int64{100_00, 100_00, 100_00},
+			open:       []int64{0, 0, 50_00},
+			nextNumber: 3,
+			nextAmount: 50_00,
+		},
+		{
+			name:        "should have no next installment once the dues are paid",
+			dues:        300_00,
+			payments:    300_00,
+			amounts:     []int64{100_00, 100_00, 100_00},
+			open:        []int64{0, 0, 0},
+			expectNoNxt: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule := computeInstallmentSchedule(plan, config.InstallmentPlanConfig{MinimumInstallmentCent: tt.minimum}, tt.dues, tt.payments)
+
+			require.Len(t, schedule.Installments, 3)
+			for i, inst := range schedule.Installments {
+				require.Equal(t, i+1, inst.Number)
+				require.Equal(t, plan.Installments[i].DueDate.Time, inst.DueDate)
+				require.Equal(t, tt.amounts[i], inst.AmountCent, "amount of installment %d", i+1)
+				require.Equal(t, tt.open[i], inst.OpenCent, "open amount of installment %d", i+1)
+			}
+
+			if tt.expectNoNxt {
+				require.Nil(t, schedule.Next)
+				require.Zero(t, schedule.NextAmountCent)
+			} else {
+				require.NotNil(t, schedule.Next)
+				require.Equal(t, tt.nextNumber, schedule.Next.Number)
+				require.Equal(t, tt.nextAmount, schedule.NextAmountCent)
+			}
+		})
+	}
+}
+
+// withInstallmentPlans configures the installment plans for the duration of the test.
+func withInstallmentPlans(t *testing.T, plans map[string]config.InstallmentPlanConfig) {
+	original, err := config.GetApplicationConfig()
+	require.NoError(t, err)
+
+	conf := *original
+	conf.Service.InstallmentPlans = plans
+	require.NoError(t, config.Reload(&conf, t.Logf))
+	t.Cleanup(func() {
+		require.NoError(t, config.Reload(original, t.Logf))
+	})
+}
+
+func TestInstallmentPlanLifecycle(t *testing.T) {
+	withInstallmentPlans(t, map[string]config.InstallmentPlanConfig{
+		"sponsor": {Installments: 3, IntervalDays: 30, MinimumDuesCent: 200_00},
+	})
+
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, []entities.Transaction{eurDue(10, "1", 300_00), eurDue(11, "2", 100_00)})
+
+	asm := &AttendeeServiceMock{
+		ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+			return []int64{10}, nil
+		},
+	}
+	i := tstServiceInteractor(db, asm, &CncrdAdapterMock{})
+
+	first := time.Date(2030, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	_, err := i.AttachInstallmentPlan(attendeeCtx(), 10, "sponsor", first)
+	require.EqualError(t, err, apierrors.NewForbidden("no permission to attach installment plans").Error())
+
+	_, err = i.AttachInstallmentPlan(adminCtx(), 10, "platinum", first)
+	require.Equal(t, apierrors.NewUnprocessableEntity(url.Values{"plan": {"must be one of the configured installment plans"}}), err)
+
+	_, err = i.AttachInstallmentPlan(adminCtx(), 11, "sponsor", first)
+	require.EqualError(t, err, apierrors.NewBadRequest("outstanding dues of debitor 11 are below the minimum for installment plan sponsor").Error())
+
+	schedule, err := i.AttachInstallmentPlan(adminCtx(), 10, "sponsor", first)
+	require.NoError(t, err)
+	require.Len(t, schedule.Installments, 3)
+	require.Equal(t, first.AddDate(0, 0, 60), schedule.Installments[2].DueDate)
+	require.Equal(t, int64(100_00), schedule.NextAmountCent)
+
+	_, err = i.AttachInstallmentPlan(adminCtx(), 10, "sponsor", first)
+	require.True(t, apierrors.IsConflictError(err))
+
+	read, err := i.GetInstallmentPlan(attendeeCtx(), 10)
+	require.NoError(t, err)
+	require.Equal(t, schedule, read)
+
+	_, err = i.GetInstallmentPlan(attendeeCtx(), 11)
+	require.EqualError(t, err, apierrors.NewForbidden("subject 1234567890 may not read the installment plan of debitor 11").Error())
+
+	require.NoError(t, i.RemoveInstallmentPlan(adminCtx(), 10))
+
+	_, err = i.GetInstallmentPlan(adminCtx(), 10)
+	require.EqualError(t, err, apierrors.NewNotFound("debitor 10 has no installment plan").Error())
+}
+
+func TestInstallmentPayments(t *testing.T) {
+	withInstallmentPlans(t, map[string]config.InstallmentPlanConfig{
+		"sponsor": {Installments: 3, IntervalDays: 30},
+	})
+
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, []entities.Transaction{eurDue(10, "1", 300_00)})
+
+	asm := &AttendeeServiceMock{
+		ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+			return []int64{10}, nil
+		},
+		PaymentsChangedFunc: func(ctx context.Context, debitorId uint) error {
+			return nil
+		},
+	}
+	ccm := &CncrdAdapterMock{
+		CreatePaylinkFunc: func(ctx context.Context, request cncrdadapter.PaymentLinkRequestDto) (cncrdadapter.PaymentLinkDto, error) {
+			return cncrdadapter.PaymentLinkDto{ReferenceId: request.ReferenceId, Link: "https://example.com/paylink"}, nil
+		},
+	}
+	i := tstServiceInteractor(db, asm, ccm)
+
+	partial := func() *entities.Transaction {
+		return &entities.Transaction{
+			DebitorID:         10,
+			TransactionType:   entities.TransactionTypePayment,
+			PaymentMethod:     entities.PaymentMethodCredit,
+			TransactionStatus: entities.TransactionStatusTentative,
+			Amount:            entities.Amount{ISOCurrency: "EUR", GrossCent: 100_00, VatRate: 19.0},
+		}
+	}
+
+	// without a plan, partial payments are rejected
+	_, err := i.CreateTransaction(attendeeCtx(), partial())
+	require.EqualError(t, err, apierrors.NewBadRequest("no outstanding dues or partial payment").Error())
+
+	first := time.Now().AddDate(0, 0, 7).UTC().Truncate(24 * time.Hour)
+	_, err = i.AttachInstallmentPlan(adminCtx(), 10, "sponsor", first)
+	require.NoError(t, err)
+
+	// initiate-payment creates the paylink for the next installment
+	tran, err := i.CreateTransactionForOutstandingDues(attendeeCtx(), 10, entities.PaymentMethodCredit)
+	require.NoError(t, err)
+	require.Equal(t, int64(100_00), tran.Amount.GrossCent)
+	require.True(t, tran.DueDate.Valid)
+	require.Equal(t, first, tran.DueDate.Time)
+
+	// the next installment can also be paid directly, once the open paylink is gone
+	_, err = i.invalidateTentativePayments(context.Background(), 10, "", "internal", "test")
+	require.NoError(t, err)
+
+	created, err := i.CreateTransaction(attendeeCtx(), partial())
+	require.NoError(t, err)
+	require.Equal(t, int64(100_00), created.Amount.GrossCent)
+}
+
+// racingPlans hides existing installment plans from the check before creating one,
+// like a concurrent request that attaches its plan in between.
+type racingPlans struct {
+	database.Repository
+}
+
+func (r *racingPlans) GetInstallmentPlans(ctx context.Context, debitorID int64, event string) ([]entities.InstallmentPlan, error) {
+	return nil, nil
+}
+
+func TestAttachInstallmentPlanConcurrently(t *testing.T) {
+	withInstallmentPlans(t, map[string]config.InstallmentPlanConfig{
+		"sponsor": {Installments: 3, IntervalDays: 30, MinimumDuesCent: 200_00},
+	})
+
+	db := &racingPlans{Repository: inmemory.NewInMemoryProvider()}
+	seedDB(db, []entities.Transaction{eurDue(10, "1", 300_00)})
+
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	_, err := i.attachInstallmentPlan(adminCtx(), 10, "sponsor", time.Time{}, "admin")
+	require.NoError(t, err)
+
+	_, err = i.attachInstallmentPlan(adminCtx(), 10, "sponsor", time.Time{}, "admin")
+	require.True(t, apierrors.IsConflictError(err), "unexpected error %v", err)
+}
@@ -0,0 +1,163 @@
+package interaction
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
+)
+
+const ledgerBatchSize = 500
+
+// LedgerBreak describes an entry at which the hash chain of a transaction log is broken
+type LedgerBreak struct {
+	TransactionID string
+	// LogID is 0 if the break is at the end of the chain
+	LogID  uint
+	Reason string
+}
+
+// LedgerReport is the result of verifying the transaction log
+type LedgerReport struct {
+	Entries int
+	Chains  int
+	Breaks  []LedgerBreak
+}
+
+// VerifyLedger walks the hash chains of all transaction log entries and reports every break.
+//
+// This is meant for the command line, it does not check permissions.
+func VerifyLedger(ctx context.Context, store database.Repository) (*LedgerReport, error) {
+	report := LedgerReport{Breaks: make([]LedgerBreak, 0)}
+	lastHash := make(map[string]string)
+	length := make(map[string]int64)
+
+	var afterID uint
+	for {
+		batch, err := store.GetTransactionLogs(ctx, afterID, ledgerBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tl := range batch {
+			if tl.PrevHash != lastHash[tl.TransactionID] {
+				report.Breaks = append(report.Breaks, LedgerBreak{
+					TransactionID: tl.TransactionID,
+					LogID:         tl.ID,
+					Reason:        "previous hash does not match the preceding entry, an entry was removed or inserted",
+				})
+			}
+			if tl.Hash != tl.ComputeHash() {
+				report.Breaks = append(report.Breaks, LedgerBreak{
+					TransactionID: tl.TransactionID,
+					LogID:         tl.ID,
+					Reason:        "content does not match the hash, the entry was modified",
+				})
+			}
+
+			// continue from the stored hash, so a single modified entry is only reported once
+			lastHash[tl.TransactionID] = tl.Hash
+			length[tl.TransactionID]++
+			report.Entries++
+			afterID = tl.ID
+		}
+
+		if len(batch) < ledgerBatchSize {
+			break
+		}
+	}
+
+	heads, err := store.GetHashChainHeads(ctx, entities.TransactionLogChainPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, head := range heads {
+		transactionID := strings.TrimPrefix(head.ChainName, entities.TransactionLogChainPrefix)
+		if lastHash[transactionID] != head.Hash || length[transactionID] != head.Length {
+			report.Breaks = append(report.Breaks, LedgerBreak{
+				TransactionID: transactionID,
+				Reason:        fmt.Sprintf("chain should end with entry %d of hash %s, entries were removed from the end", head.Length, head.Hash),
+			})
+		}
+		delete(length, transactionID)
+	}
+
+	for transactionID := range length {
+		report.Breaks = append(report.Breaks, LedgerBreak{
+			TransactionID: transactionID,
+			Reason:        "no chain head was recorded for this transaction",
+		})
+	}
+
+	report.Chains = len(heads)
+	return &report, nil
+}
+
+// GetLedgerCheckpoint returns the current heads of all hash chains, signed with the configured checkpoint key.
+//
+// Only admins may request a checkpoint.
+func (s *serviceInteractor) GetLedgerCheckpoint(ctx context.Context) (*entities.LedgerCheckpoint, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := s.getLedgerCheckpoint(ctx, mgr)
+	s.recordAudit(ctx, mgr, auditActionLedgerCheckpoint, 0, "", "", err)
+	return checkpoint, err
+}
+
+func (s *serviceInteractor) getLedgerCheckpoint(ctx context.Context, mgr *RBACValidator) (*entities.LedgerCheckpoint, error) {
+	if !mgr.IsAdmin() {
+		return nil, apierrors.NewForbidden("only admins may request ledger checkpoints")
+	}
+
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	key := appConfig.Security.Ledger.CheckpointKey
+	if key == "" {
+		logging.LoggerFromContext(ctx).Error("ledger checkpoint requested, but security.ledger.checkpoint_key is not configured")
+		return nil, apierrors.NewInternalServerError("ledger checkpoints are not configured")
+	}
+
+	heads, err := s.store.GetHashChainHeads(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := entities.LedgerCheckpoint{
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Heads:     heads,
+		Digest:    checkpointDigest(heads),
+	}
+	checkpoint.Signature = signCheckpoint(key, checkpoint)
+
+	return &checkpoint, nil
+}
+
+func checkpointDigest(heads []entities.HashChainHead) string {
+	h := sha256.New()
+	for _, head := range heads {
+		_, _ = fmt.Fprintf(h, "%s:%d:%s\n", head.ChainName, head.Length, head.Hash)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func signCheckpoint(key string, checkpoint entities.LedgerCheckpoint) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	_, _ = fmt.Fprintf(mac, "%s\n%s", checkpoint.CreatedAt.Format(time.RFC3339), checkpoint.Digest)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,189 @@
+package interaction
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+)
+
+func TestVerifyLedger(t *testing.T) {
+	db := inmemory.NewInMemoryProvider()
+	for _, id := range []string{"A", "B", "A", "A", "B"} {
+		require.NoError(t, db.CreateTransactionLog(context.Background(), entities.TransactionLog{
+			DebitorID:         1,
+			TransactionID:     id,
+			TransactionStatus: entities.TransactionStatusPending,
+		}))
+	}
+
+	report, err := VerifyLedger(context.Background(), db)
+	require.NoError(t, err)
+	require.Equal(t, 5, report.Entries)
+	require.Equal(t, 2, report.Chains)
+	require.Empty(t, report.Breaks)
+}
+
+func TestVerifyLedgerBreaks(t *testing.T) {
+	chain := tstLedgerChain("A", 3)
+
+	tests := []struct {
+		name           string
+		logs           []entities.TransactionLog
+		heads          []entities.HashChainHead
+		expectedBreaks []LedgerBreak
+	}{
+		{
+			name:  "should accept an intact chain",
+			logs:  chain,
+			heads: []entities.HashChainHead{tstLedgerHead(chain)},
+		},
+		{
+			name: "should detect a modified entry",
+			logs: func() []entities.TransactionLog {
+				logs := append([]entities.TransactionLog{}, chain...)
+				logs[1].Amount.GrossCent = 1
+				return logs
+			}(),
+			heads: []entities.HashChainHead{tstLedgerHead(chain)},
+			expectedBreaks: []LedgerBreak{
+				{TransactionID: "A", LogID: 2, Reason: "content does not match the hash, the entry was modified"},
+			},
+		},
+		{
+			name:  "should detect a removed entry",
+			logs:  []entities.TransactionLog{chain[0], chain[2]},
+			heads: []entities.HashChainHead{tstLedgerHead(chain)},
+			expectedBreaks: []LedgerBreak{
+				{TransactionID: "A", LogID: 3, Reason: "previous hash does not match the preceding entry, an entry was removed or inserted"},
+				{TransactionID: "A", Reason: "chain should end with entry 3 of hash " + chain[2].Hash + ", entries were removed from the end"},
+			},
+		},
+		{
+			name:  "should detect entries removed from the end",
+			logs:  chain[:2],
+			heads: []entities.HashChainHead{tstLedgerHead(chain)},
+			expectedBreaks: []LedgerBreak{
+				{TransactionID: "A", Reason: "chain should end with entry 3 of hash " + chain[2].Hash + ", entries were removed from the end"},
+			},
+		},
+		{
+			name: "should detect a missing chain head",
+			logs: chain,
+			expectedBreaks: []LedgerBreak{
+				{TransactionID: "A", Reason: "no chain head was recorded for this transaction"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &RepositoryMock{
+				GetTransactionLogsFunc: func(ctx context.Context, afterID uint, limit int) ([]entities.TransactionLog, error) {
+					result := make([]entities.TransactionLog, 0)
+					for _, tl := range tt.logs {
+						if tl.ID > afterID && len(result) < limit {
+							result = append(result, tl)
+						}
+					}
+					return result, nil
+				},
+				GetHashChainHeadsFunc: func(ctx context.Context, prefix string) ([]entities.HashChainHead, error) {
+					require.Equal(t, entities.TransactionLogChainPrefix, prefix)
+					return tt.heads, nil
+				},
+			}
+
+			report, err := VerifyLedger(context.Background(), repo)
+			require.NoError(t, err)
+
+			if tt.expectedBreaks == nil {
+				require.Empty(t, report.Breaks)
+			} else {
+				require.Equal(t, tt.expectedBreaks, report.Breaks)
+			}
+		})
+	}
+}
+
+func TestGetLedgerCheckpoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		expectedErr error
+	}{
+		{
+			name: "should return a signed checkpoint for admins",
+			ctx:  adminCtx(),
+		},
+		{
+			name:        "should not allow the api token",
+			ctx:         apiKeyCtx(),
+			expectedErr: apierrors.NewForbidden("only admins may request ledger checkpoints"),
+		},
+		{
+			name:        "should not allow users",
+			ctx:         attendeeCtx(),
+			expectedErr: apierrors.NewForbidden("only admins may request ledger checkpoints"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := inmemory.NewInMemoryProvider()
+			require.NoError(t, db.CreateTransactionLog(context.Background(), entities.TransactionLog{TransactionID: "A"}))
+			i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+			checkpoint, err := i.GetLedgerCheckpoint(tt.ctx)
+			if tt.expectedErr != nil {
+				require.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			// the request itself is audited only after the checkpoint was taken
+			require.Len(t, checkpoint.Heads, 1)
+			require.Equal(t, entities.TransactionLogChainName("A"), checkpoint.Heads[0].ChainName)
+			require.Equal(t, checkpointDigest(checkpoint.Heads), checkpoint.Digest)
+
+			mac := hmac.New(sha256.New, []byte("put_secure_random_string_here_for_checkpoints"))
+			_, _ = mac.Write([]byte(checkpoint.CreatedAt.Format(time.RFC3339) + "\n" + checkpoint.Digest))
+			require.Equal(t, hex.EncodeToString(mac.Sum(nil)), checkpoint.Signature)
+		})
+	}
+}
+
+func tstLedgerChain(transactionID string, length int) []entities.TransactionLog {
+	result := make([]entities.TransactionLog, length)
+	prevHash := ""
+	for n := range result {
+		tl := entities.TransactionLog{
+			ID:            uint(n + 1),
+			CreatedAt:     time.Date(2023, 1, 1, 0, 0, n, 0, time.UTC),
+			TransactionID: transactionID,
+			Amount:        entities.Amount{ISOCurrency: "EUR", GrossCent: 1000},
+			PrevHash:      prevHash,
+		}
+		tl.Hash = tl.ComputeHash()
+		prevHash = tl.Hash
+		result[n] = tl
+	}
+	return result
+}
+
+func tstLedgerHead(chain []entities.TransactionLog) entities.HashChainHead {
+	last := chain[len(chain)-1]
+	return entities.HashChainHead{
+		ChainName: entities.TransactionLogChainName(last.TransactionID),
+		Hash:      last.Hash,
+		Length:    int64(len(chain)),
+	}
+}
@@ -0,0 +1,141 @@
+package interaction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+)
+
+// Balance sums up the valid transactions of a debitor
+type Balance struct {
+	DebitorID    int64
+	Event        string
+	DuesCent     int64
+	PaymentsCent int64
+	// OutstandingCent is negative if the debitor paid more than they owe
+	OutstandingCent int64
+	// StoredOutstandingCent is the outstanding amount as calculated by the database, which is used for initiate-payment
+	StoredOutstandingCent int64
+}
+
+// Consistent is false if the database calculates a different outstanding amount than the transactions add up to.
+func (b *Balance) Consistent() bool {
+	return b.OutstandingCent == b.StoredOutstandingCent
+}
+
+// GetBalance recomputes the balance of the debitor for the event from the valid transactions.
+// If event is empty, the balance is computed for the current event.
+func (s *serviceInteractor) GetBalance(ctx context.Context, debitorID int64, event string) (*Balance, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mgr.IsAdmin() && !mgr.IsAPITokenCall() && !mgr.IsSystemCall() {
+		err := apierrors.NewForbidden("no permission to read balances")
+		s.recordAudit(ctx, mgr, auditActionReadBalance, debitorID, "", "", err)
+		return nil, err
+	}
+
+	balance, err := s.computeBalance(ctx, debitorID, event)
+	s.recordAudit(ctx, mgr, auditActionReadBalance, debitorID, "", "", err)
+	return balance, err
+}
+
+func (s *serviceInteractor) computeBalance(ctx context.Context, debitorID int64, event string) (*Balance, error) {
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if event == "" {
+		current, err := currentEvent(appConfig, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		event = current.Name
+	} else if _, ok := appConfig.Service.EventByName(event); !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("unknown event %s", event))
+	}
+
+	transactions, err := s.store.GetValidTransactionsForDebitor(ctx, debitorID, eventFilter(appConfig, event))
+	if err != nil {
+		return nil, err
+	}
+
+	balance := Balance{DebitorID: debitorID, Event: event}
+	for _, t := range transactions {
+		switch t.TransactionType {
+		case entities.TransactionTypeDue:
+			balance.DuesCent += t.Amount.GrossCent
+		case entities.TransactionTypePayment:
+			balance.PaymentsCent += t.Amount.GrossCent
+		}
+	}
+	balance.OutstandingCent = balance.DuesCent - balance.PaymentsCent
+
+	balance.StoredOutstandingCent, err = s.store.QueryOutstandingDuesForDebitor(ctx, debitorID, eventFilter(appConfig, event))
+	if err != nil {
+		return nil, err
+	}
+
+	if !balance.Consistent() {
+		logging.LoggerFromContext(ctx).Warn("balance of debitor %d for event %s is inconsistent: transactions add up to %d, database calculates %d",
+			debitorID, event, balance.OutstandingCent, balance.StoredOutstandingCent)
+	}
+
+	return &balance, nil
+}
+
+// VoidTentativePayments marks all paylinks of the debitor deleted, for all events, and returns the ids of their transactions.
+func (s *serviceInteractor) VoidTentativePayments(ctx context.Context, debitorID int64) ([]string, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mgr.IsAdmin() && !mgr.IsAPITokenCall() && !mgr.IsSystemCall() {
+		err := apierrors.NewForbidden("no permission to void paylinks")
+		s.recordAudit(ctx, mgr, auditActionVoidPaylinks, debitorID, "", "", err)
+		return nil, err
+	}
+
+	voided, err := s.invalidateTentativePayments(ctx, debitorID, "", mgr.Actor(), "voided paylink - manually voided")
+	for _, transactionID := range voided {
+		s.recordAudit(ctx, mgr, auditActionVoidPaylinks, debitorID, transactionID, "", nil)
+	}
+	if err != nil {
+		s.recordAudit(ctx, mgr, auditActionVoidPaylinks, debitorID, "", "", err)
+	}
+
+	return voided, err
+}
+
+// ResendPaymentsChanged notifies the attendee service about changed payments of the debitor,
+// e.g. after an earlier notification failed.
+func (s *serviceInteractor) ResendPaymentsChanged(ctx context.Context, debitorID int64) error {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !mgr.IsAdmin() && !mgr.IsAPITokenCall() && !mgr.IsSystemCall() {
+		err := apierrors.NewForbidden("no permission to send payment notifications")
+		s.recordAudit(ctx, mgr, auditActionPaymentsChanged, debitorID, "", "", err)
+		return err
+	}
+
+	err = s.attendeeClient.PaymentsChanged(ctx, uint(debitorID))
+	if err != nil {
+		logging.LoggerFromContext(ctx).Error("error when calling the attendee service webhook. [error]: %v", err)
+		err = apierrors.NewInternalServerError(fmt.Sprintf("attendee service webhook failed for debitor %d", debitorID))
+	}
+
+	s.recordAudit(ctx, mgr, auditActionPaymentsChanged, debitorID, "", "", err)
+	return err
+}
@@ -0,0 +1,139 @@
+package interaction
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+)
+
+func systemCtx() context.Context {
+	return WithSystemIdentity(context.Background(), "cli/test")
+}
+
+func tstOperationsTransactions() []entities.Transaction {
+	return []entities.Transaction{
+		{
+			DebitorID:         1,
+			TransactionID:     "1234567890",
+			TransactionType:   entities.TransactionTypeDue,
+			PaymentMethod:     entities.PaymentMethodCredit,
+			TransactionStatus: entities.TransactionStatusValid,
+			Amount:            entities.Amount{ISOCurrency: "EUR", GrossCent: 10000},
+		},
+		{
+			DebitorID:         1,
+			TransactionID:     "1234567891",
+			TransactionType:   entities.TransactionTypePayment,
+			PaymentMethod:     entities.PaymentMethodTransfer,
+			TransactionStatus: entities.TransactionStatusValid,
+			Amount:            entities.Amount{ISOCurrency: "EUR", GrossCent: 4000},
+		},
+		{
+			DebitorID:         1,
+			TransactionID:     "1234567892",
+			TransactionType:   entities.TransactionTypePayment,
+			PaymentMethod:     entities.PaymentMethodCredit,
+			TransactionStatus: entities.TransactionStatusTentative,
+			Amount:            entities.Amount{ISOCurrency: "EUR", GrossCent: 6000},
+		},
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, tstOperationsTransactions())
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	balance, err := i.GetBalance(systemCtx(), 1, "")
+	require.NoError(t, err)
+	require.Equal(t, &Balance{
+		DebitorID:             1,
+		Event:                 "EF2023",
+		DuesCent:              10000,
+		PaymentsCent:          4000,
+		OutstandingCent:       6000,
+		StoredOutstandingCent: 6000,
+	}, balance)
+	require.True(t, balance.Consistent())
+
+	_, err = i.GetBalance(attendeeCtx(), 1, "")
+	require.True(t, apierrors.IsForbiddenError(err))
+}
+
+func TestVoidTentativePayments(t *testing.T) {
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, tstOperationsTransactions())
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	voided, err := i.VoidTentativePayments(systemCtx(), 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1234567892"}, voided)
+
+	transactions, err := db.GetAdminTransactionsByFilter(context.Background(), entities.TransactionQuery{TransactionIdentifier: "1234567892"})
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	require.Equal(t, "system:cli/test", transactions[0].Deletion.By)
+
+	entries, err := db.GetAuditLogEntries(context.Background(), entities.AuditLogQuery{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, auditActionVoidPaylinks, entries[0].Action)
+	require.Equal(t, "1234567892", entries[0].TransactionID)
+	require.Equal(t, "system:cli/test", entries[0].Actor)
+
+	_, err = i.VoidTentativePayments(attendeeCtx(), 1)
+	require.True(t, apierrors.IsForbiddenError(err))
+}
+
+func TestResendPaymentsChanged(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		webhookErr  error
+		expectCalls int
+		expectErr   bool
+	}{
+		{
+			name:        "should notify the attendee service",
+			ctx:         systemCtx(),
+			expectCalls: 1,
+		},
+		{
+			name:        "should report a failed notification",
+			ctx:         systemCtx(),
+			webhookErr:  errors.New("downstream unavailable"),
+			expectCalls: 1,
+			expectErr:   true,
+		},
+		{
+			name:      "should not allow users",
+			ctx:       attendeeCtx(),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attMock := &AttendeeServiceMock{
+				PaymentsChangedFunc: func(ctx context.Context, debitorID uint) error {
+					return tt.webhookErr
+				},
+			}
+			i := tstServiceInteractor(inmemory.NewInMemoryProvider(), attMock, &CncrdAdapterMock{})
+
+			err := i.ResendPaymentsChanged(tt.ctx, 1)
+			if tt.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Len(t, attMock.PaymentsChangedCalls(), tt.expectCalls)
+		})
+	}
+}
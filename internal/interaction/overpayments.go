@@ -0,0 +1,408 @@
+package interaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
+	"github.com/eurofurence/reg-payment-service/internal/repository/downstreams/cncrdadapter"
+)
+
+// Overpayment is a debitor whose valid payments for an event exceed their valid dues.
+//
+// This happens when attendees pay twice, make a mistake in a bank transfer, or when dues
+// are lowered after payment, for example when an admin removes sponsor status.
+type Overpayment struct {
+	DebitorID    int64
+	Event        string
+	DuesCent     int64
+	PaymentsCent int64
+	CreditCent   int64
+}
+
+type OverpaymentAction string
+
+const (
+	// OverpaymentActionRefund pays the credit back, using the adapter for card payments
+	OverpaymentActionRefund OverpaymentAction = "refund"
+	// OverpaymentActionCarryForward moves the credit to a later event
+	OverpaymentActionCarryForward OverpaymentAction = "carry_forward"
+)
+
+// OverpaymentResolution describes how an admin resolves an overpayment.
+type OverpaymentResolution struct {
+	Action OverpaymentAction
+	// Event is the overpaid event, defaults to the current one
+	Event string
+	// AmountCent defaults to the full credit
+	AmountCent int64
+	// Method is the method to refund with, defaults to the method of the latest payment
+	Method entities.PaymentMethod
+	// TargetEvent is the event to carry the credit forward to, defaults to the one configured after Event
+	TargetEvent string
+	Comment     string
+}
+
+// ListOverpayments returns all debitors with a credit for the event, or the current event if empty.
+func (s *serviceInteractor) ListOverpayments(ctx context.Context, event string) ([]Overpayment, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mgr.IsAdmin() && !mgr.IsSystemCall() {
+		err := apierrors.NewForbidden("no permission to list overpayments")
+		s.recordAudit(ctx, mgr, auditActionReadOverpayments, 0, "", "", err)
+		return nil, err
+	}
+
+	result, err := s.listOverpayments(ctx, event)
+	s.recordAudit(ctx, mgr, auditActionReadOverpayments, 0, "", "", err)
+	return result, err
+}
+
+func (s *serviceInteractor) listOverpayments(ctx context.Context, event string) ([]Overpayment, error) {
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	eventConfig, err := overpaidEvent(appConfig, event)
+	if err != nil {
+		return nil, err
+	}
+
+	overpaid, err := s.store.QueryOverpaidDebitors(ctx, eventFilter(appConfig, eventConfig.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Overpayment, len(overpaid))
+	for i, o := range overpaid {
+		result[i] = Overpayment{
+			DebitorID:    o.DebitorID,
+			Event:        eventConfig.Name,
+			DuesCent:     o.DuesCent,
+			PaymentsCent: o.PaymentsCent,
+			CreditCent:   o.CreditCent(),
+		}
+	}
+	return result, nil
+}
+
+// ResolveOverpayment books the transactions that remove (part of) the credit of a debitor, and returns them.
+//
+// A refund is a negative payment of the refund method. Card payments are refunded through the
+// payment provider adapter, other methods are paid back manually, the refund only documents that.
+// Carrying the credit forward books a negative internal payment for the overpaid event, and a positive one
+// for the target event, sharing a reference.
+//
+// The resolution is stored as the reason of the transactions, and so becomes part of the transaction log.
+func (s *serviceInteractor) ResolveOverpayment(ctx context.Context, debitorID int64, resolution OverpaymentResolution) ([]entities.Transaction, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mgr.IsAdmin() && !mgr.IsSystemCall() {
+		err := apierrors.NewForbidden("no permission to resolve overpayments")
+		s.recordAudit(ctx, mgr, auditActionResolveOverpayment, debitorID, "", "", err)
+		return nil, err
+	}
+
+	booked, err := s.resolveOverpayment(ctx, debitorID, resolution)
+	if err != nil {
+		s.recordAudit(ctx, mgr, auditActionResolveOverpayment, debitorID, "", "", err)
+		return nil, err
+	}
+
+	for i := range booked {
+		s.recordAudit(ctx, mgr, auditActionResolveOverpayment, debitorID, booked[i].TransactionID, transactionDiff(nil, &booked[i]), nil)
+		transactionCreated(&booked[i])
+	}
+
+	if err := s.attendeeClient.PaymentsChanged(ctx, uint(debitorID)); err != nil {
+		// only log an error when the call was not successful but don't cause an internal server error
+		logging.LoggerFromContext(ctx).Error("error when calling the attendee service webhook. [error]: %v", err)
+	}
+
+	return booked, nil
+}
+
+func (s *serviceInteractor) resolveOverpayment(ctx context.Context, debitorID int64, resolution OverpaymentResolution) ([]entities.Transaction, error) {
+	fields := url.Values{}
+	if debitorID <= 0 {
+		fields.Add("debitor_id", "must be greater than zero")
+	}
+	if resolution.Action != OverpaymentActionRefund && resolution.Action != OverpaymentActionCarryForward {
+		fields.Add("action", fmt.Sprintf("must be one of %s, %s", OverpaymentActionRefund, OverpaymentActionCarryForward))
+	}
+	if resolution.AmountCent < 0 {
+		fields.Add("gross_cent", "must not be negative")
+	}
+	if len(fields) > 0 {
+		return nil, apierrors.NewUnprocessableEntity(fields)
+	}
+
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := overpaidEvent(appConfig, resolution.Event)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.computeBalance(ctx, debitorID, event.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	credit := -balance.OutstandingCent
+	if credit <= 0 {
+		return nil, apierrors.NewNotFound(fmt.Sprintf("debitor %d has no overpayment for event %s", debitorID, event.Name))
+	}
+
+	amount := resolution.AmountCent
+	if amount == 0 {
+		amount = credit
+	}
+	if amount > credit {
+		return nil, apierrors.NewConflict(fmt.Sprintf("debitor %d has a credit of %d, which does not cover %d", debitorID, credit, amount))
+	}
+
+	if resolution.Action == OverpaymentActionRefund {
+		return s.refundOverpayment(ctx, appConfig, event, debitorID, amount, resolution)
+	}
+	return s.carryOverpaymentForward(ctx, appConfig, event, debitorID, amount, resolution)
+}
+
+func (s *serviceInteractor) refundOverpayment(ctx context.Context, appConfig *config.Application, event config.EventConfig, debitorID int64, amount int64, resolution OverpaymentResolution) ([]entities.Transaction, error) {
+	filter := eventFilter(appConfig, event.Name)
+
+	method := resolution.Method
+	if method == "" {
+		latest, err := s.latestPayment(ctx, debitorID, filter, "")
+		if err != nil {
+			return nil, err
+		}
+		method = latest.PaymentMethod
+	}
+
+	if method == entities.PaymentMethodInternal || method == entities.PaymentMethodGift {
+		return nil, apierrors.NewUnprocessableEntity(url.Values{"method": {fmt.Sprintf("cannot refund with payment method %s", method)}})
+	}
+
+	// refunds go back the way the money came in, so the latest payment of that method decides currency and vat rate
+	payment, err := s.latestPayment(ctx, debitorID, filter, method)
+	if err != nil {
+		return nil, err
+	}
+
+	if method == entities.PaymentMethodCredit && payment.Amount.GrossCent < amount {
+		return nil, apierrors.NewConflict(fmt.Sprintf("the latest card payment %s of debitor %d is smaller than the refund of %d", payment.TransactionID, debitorID, amount))
+	}
+
+	// card refunds are booked pending until the payment provider has paid them out,
+	// so the credit is reserved while the provider is called
+	status := entities.TransactionStatusValid
+	if method == entities.PaymentMethodCredit {
+		status = entities.TransactionStatusPending
+	}
+
+	refund := entities.Transaction{
+		DebitorID:         debitorID,
+		TransactionType:   entities.TransactionTypePayment,
+		PaymentMethod:     method,
+		TransactionStatus: status,
+		Comment:           "refund of overpayment",
+		Reason:            fmt.Sprintf("overpayment refunded, original payment %s", payment.TransactionID),
+		Event:             event.Name,
+		EffectiveDate:     sql.NullTime{Time: time.Now(), Valid: true},
+		Amount: entities.Amount{
+			ISOCurrency: payment.Amount.ISOCurrency,
+			VatRate:     payment.Amount.VatRate,
+			GrossCent:   -amount,
+		},
+	}
+	if resolution.Comment != "" {
+		refund.Comment = resolution.Comment
+	}
+
+	// the credit is checked again while the transactions of the debitor are locked, so concurrent
+	// refunds or transfers cannot spend the same credit
+	booked := []entities.Transaction{refund}
+	err = s.createWithGeneratedIDs(ctx, booked, []string{event.Prefix()}, func(trs []entities.Transaction) error {
+		return s.store.CreateTransactionsWithinCredit(ctx, debitorID, filter, amount, trs)
+	})
+	refund = booked[0]
+	if errors.Is(err, database.ErrInsufficientCredit) {
+		return nil, apierrors.NewConflict(fmt.Sprintf("the credit of debitor %d no longer covers a refund of %d", debitorID, amount))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if method == entities.PaymentMethodCredit {
+		if err := s.payOutRefund(ctx, &refund, payment); err != nil {
+			return nil, err
+		}
+	}
+
+	return []entities.Transaction{refund}, nil
+}
+
+// payOutRefund asks the payment provider to pay back the pending card refund, and marks it valid once it was paid out.
+//
+// The transaction id of the refund is passed as its reference, so the adapter recognizes a repeated
+// request for the same refund. If the provider fails, the refund is voided, which releases the credit again.
+func (s *serviceInteractor) payOutRefund(ctx context.Context, refund *entities.Transaction, payment *entities.Transaction) error {
+	logger := logging.LoggerFromContext(ctx)
+
+	response, err := s.cncrdClient.CreateRefund(ctx, cncrdadapter.RefundRequestDto{
+		ReferenceId:        refund.TransactionID,
+		PaymentReferenceId: payment.TransactionID,
+		DebitorId:          refund.DebitorID,
+		Amount:             -refund.Amount.GrossCent,
+		Currency:           refund.Amount.ISOCurrency,
+	})
+	if err != nil {
+		voided := *refund
+		voided.Deletion = entities.Deletion{
+			Status:  voided.TransactionStatus,
+			Comment: voided.Comment,
+			By:      "internal",
+		}
+		voided.TransactionStatus = entities.TransactionStatusDeleted
+		voided.Comment = "voided refund - the payment provider failed"
+		if err := s.store.DeleteTransaction(ctx, voided); err != nil {
+			logger.Error("could not void refund %s after the payment provider failed - [error]: %v", refund.TransactionID, err)
+		}
+
+		return apierrors.NewInternalServerError(fmt.Sprintf("refund through the payment provider failed: %v", err))
+	}
+
+	refund.TransactionStatus = entities.TransactionStatusValid
+	if response.ProviderTransactionId != "" {
+		refund.ProcessorInfo = entities.PaymentProcessorInformation{
+			entities.ProcessorInfoProviderTransactionID: response.ProviderTransactionId,
+		}
+	}
+
+	if err := s.store.UpdateTransaction(ctx, *refund, true); err != nil {
+		// the money was paid out, the pending refund keeps the credit reserved until an admin books it
+		logger.Error("refund %s was paid out, but could not be marked valid - [error]: %v", refund.TransactionID, err)
+		return apierrors.NewInternalServerError(fmt.Sprintf("refund %s was paid out, but could not be marked valid - see log for details", refund.TransactionID))
+	}
+
+	return nil
+}
+
+func (s *serviceInteractor) carryOverpaymentForward(ctx context.Context, appConfig *config.Application, event config.EventConfig, debitorID int64, amount int64, resolution OverpaymentResolution) ([]entities.Transaction, error) {
+	target, err := carryForwardEvent(appConfig, event, resolution.TargetEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := s.latestPayment(ctx, debitorID, eventFilter(appConfig, event.Name), "")
+	if err != nil {
+		return nil, err
+	}
+
+	if !target.IsCurrencyAllowed(payment.Amount.ISOCurrency, appConfig.Service.AllowedCurrencies) || !target.IsVatRateAllowed(payment.Amount.VatRate) {
+		return nil, apierrors.NewConflict(fmt.Sprintf("event %s does not allow currency %s at vat rate %.2f", target.Name, payment.Amount.ISOCurrency, payment.Amount.VatRate))
+	}
+
+	reference := generateTransferReference(event.Prefix(), debitorID)
+	effective := sql.NullTime{Time: time.Now(), Valid: true}
+
+	booked := make([]entities.Transaction, 0, 2)
+	prefixes := make([]string, 0, 2)
+	for _, side := range []struct {
+		event  config.EventConfig
+		amount int64
+		reason string
+	}{
+		{event, -amount, fmt.Sprintf("overpayment carried forward to %s", target.Name)},
+		{target, amount, fmt.Sprintf("overpayment carried forward from %s", event.Name)},
+	} {
+		tran := entities.Transaction{
+			DebitorID:         debitorID,
+			TransactionType:   entities.TransactionTypePayment,
+			PaymentMethod:     entities.PaymentMethodInternal,
+			TransactionStatus: entities.TransactionStatusValid,
+			Comment:           side.reason,
+			Reason:            side.reason,
+			Event:             side.event.Name,
+			EffectiveDate:     effective,
+			GroupReference:    reference,
+			Amount: entities.Amount{
+				ISOCurrency: payment.Amount.ISOCurrency,
+				VatRate:     payment.Amount.VatRate,
+				GrossCent:   side.amount,
+			},
+		}
+		if resolution.Comment != "" {
+			tran.Comment = resolution.Comment
+		}
+
+		booked = append(booked, tran)
+		prefixes = append(prefixes, side.event.Prefix())
+	}
+
+	err = s.createWithGeneratedIDs(ctx, booked, prefixes, func(trs []entities.Transaction) error {
+		return s.store.CreateTransactionsWithinCredit(ctx, debitorID, eventFilter(appConfig, event.Name), amount, trs)
+	})
+	if errors.Is(err, database.ErrInsufficientCredit) {
+		return nil, apierrors.NewConflict(fmt.Sprintf("the credit of debitor %d no longer covers %d", debitorID, amount))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return booked, nil
+}
+
+// overpaidEvent returns the named event, or the current one if name is empty.
+func overpaidEvent(appConfig *config.Application, name string) (config.EventConfig, error) {
+	if name == "" {
+		return currentEvent(appConfig, time.Now())
+	}
+
+	event, ok := appConfig.Service.EventByName(name)
+	if !ok {
+		return config.EventConfig{}, apierrors.NewBadRequest(fmt.Sprintf("unknown event %s", name))
+	}
+	return event, nil
+}
+
+// carryForwardEvent returns the named event, or the event configured after the overpaid one if name is empty.
+func carryForwardEvent(appConfig *config.Application, overpaid config.EventConfig, name string) (config.EventConfig, error) {
+	if name != "" {
+		target, ok := appConfig.Service.EventByName(name)
+		if !ok {
+			return config.EventConfig{}, apierrors.NewUnprocessableEntity(url.Values{"target_event": {"must be one of the configured events"}})
+		}
+		if target.Name == overpaid.Name {
+			return config.EventConfig{}, apierrors.NewUnprocessableEntity(url.Values{"target_event": {"must differ from the overpaid event"}})
+		}
+		return target, nil
+	}
+
+	events := appConfig.Service.ConfiguredEvents()
+	for i, e := range events {
+		if e.Name == overpaid.Name && i+1 < len(events) {
+			return events[i+1], nil
+		}
+	}
+	return config.EventConfig{}, apierrors.NewConflict(fmt.Sprintf("no event is configured after %s to carry the credit forward to", overpaid.Name))
+}
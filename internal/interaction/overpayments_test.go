@@ -0,0 +1,301 @@
+package interaction
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+	"github.com/eurofurence/reg-payment-service/internal/repository/downstreams/cncrdadapter"
+)
+
+func overpaymentSeed(method entities.PaymentMethod) []entities.Transaction {
+	seed := []entities.Transaction{
+		eurDue(10, "EF2023-000010-0101-120000-0001", 100_00),
+		newTransaction(10, "EF2023-000010-0102-120000-0002", entities.TransactionTypePayment, method, entities.TransactionStatusValid, entities.Amount{
+			ISOCurrency: "EUR",
+			GrossCent:   150_00,
+			VatRate:     19.0,
+		}),
+		eurDue(11, "EF2023-000011-0101-120000-0003", 100_00),
+	}
+	for i := range seed {
+		seed[i].Event = "EF2023"
+	}
+	return seed
+}
+
+func TestListOverpayments(t *testing.T) {
+	withEvents(t)
+
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, overpaymentSeed(entities.PaymentMethodTransfer))
+
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	_, err := i.ListOverpayments(attendeeCtx(), "EF2023")
+	require.EqualError(t, err, apierrors.NewForbidden("no permission to list overpayments").Error())
+
+	overpayments, err := i.ListOverpayments(adminCtx(), "EF2023")
+	require.NoError(t, err)
+	require.Equal(t, []Overpayment{{DebitorID: 10, Event: "EF2023", DuesCent: 100_00, PaymentsCent: 150_00, CreditCent: 50_00}}, overpayments)
+
+	overpayments, err = i.ListOverpayments(adminCtx(), "EF2024")
+	require.NoError(t, err)
+	require.Empty(t, overpayments)
+}
+
+func TestResolveOverpayment(t *testing.T) {
+	withEvents(t)
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		method       entities.PaymentMethod
+		resolution   OverpaymentResolution
+		refundErr    error
+		err          error
+		expected     []int64
+		remaining    int64
+		expectRefund bool
+	}{
+		{
+			name:       "should not allow users to resolve overpayments",
+			ctx:        attendeeCtx(),
+			method:     entities.PaymentMethodTransfer,
+			resolution: OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023"},
+			err:        apierrors.NewForbidden("no permission to resolve overpayments"),
+		},
+		{
+			name:       "should reject an amount above the credit",
+			ctx:        adminCtx(),
+			method:     entities.PaymentMethodTransfer,
+			resolution: OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023", AmountCent: 50_01},
+			err:        apierrors.NewConflict("debitor 10 has a credit of 5000, which does not cover 5001"),
+		},
+		{
+			name:       "should book a refund of bank transfers without calling the adapter",
+			ctx:        adminCtx(),
+			method:     entities.PaymentMethodTransfer,
+			resolution: OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023"},
+			expected:   []int64{-50_00},
+		},
+		{
+			name:         "should refund card payments through the adapter",
+			ctx:          adminCtx(),
+			method:       entities.PaymentMethodCredit,
+			resolution:   OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023", AmountCent: 20_00},
+			expected:     []int64{-20_00},
+			remaining:    30_00,
+			expectRefund: true,
+		},
+		{
+			name:         "should not book a refund the adapter failed to make",
+			ctx:          adminCtx(),
+			method:       entities.PaymentMethodCredit,
+			resolution:   OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023"},
+			refundErr:    errors.New("provider unavailable"),
+			err:          apierrors.NewInternalServerError("refund through the payment provider failed: provider unavailable"),
+			expectRefund: true,
+		},
+		{
+			name:       "should carry the credit forward to the next event",
+			ctx:        adminCtx(),
+			method:     entities.PaymentMethodTransfer,
+			resolution: OverpaymentResolution{Action: OverpaymentActionCarryForward, Event: "EF2023"},
+			expected:   []int64{-50_00, 50_00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var notified []uint
+			asm := &AttendeeServiceMock{
+				PaymentsChangedFunc: func(ctx context.Context, debitorId uint) error {
+					notified = append(notified, debitorId)
+					return nil
+				},
+			}
+
+			var refunds []cncrdadapter.RefundRequestDto
+			ccm := &CncrdAdapterMock{
+				CreateRefundFunc: func(ctx context.Context, request cncrdadapter.RefundRequestDto) (cncrdadapter.RefundDto, error) {
+					refunds = append(refunds, request)
+					return cncrdadapter.RefundDto{ReferenceId: request.ReferenceId, ProviderTransactionId: "rf-4711"}, tt.refundErr
+				},
+			}
+
+			seed := overpaymentSeed(tt.method)
+			db := inmemory.NewInMemoryProvider()
+			seedDB(db, seed)
+
+			i := tstServiceInteractor(db, asm, ccm)
+
+			booked, err := i.ResolveOverpayment(tt.ctx, 10, tt.resolution)
+
+			if tt.expectRefund {
+				require.Len(t, refunds, 1)
+				require.Equal(t, seed[1].TransactionID, refunds[0].PaymentReferenceId)
+			} else {
+				require.Empty(t, refunds)
+			}
+
+			if tt.err != nil {
+				require.EqualError(t, err, tt.err.Error())
+				require.Empty(t, notified)
+
+				stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{})
+				require.NoError(t, err)
+				require.Len(t, stored, len(seed))
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, []uint{10}, notified)
+			require.Len(t, booked, len(tt.expected))
+			for n, tran := range booked {
+				require.Equal(t, tt.expected[n], tran.Amount.GrossCent)
+				require.Equal(t, entities.TransactionStatusValid, tran.TransactionStatus)
+				require.NotEmpty(t, tran.Reason)
+			}
+
+			if tt.expectRefund {
+				require.Equal(t, refunds[0].ReferenceId, booked[0].TransactionID)
+				require.Equal(t, "rf-4711", booked[0].ProcessorInfo[entities.ProcessorInfoProviderTransactionID])
+			}
+
+			if tt.resolution.Action == OverpaymentActionCarryForward {
+				require.Equal(t, "EF2023", booked[0].Event)
+				require.Equal(t, "EF2024", booked[1].Event)
+				require.Equal(t, entities.PaymentMethodInternal, booked[1].PaymentMethod)
+				require.Equal(t, booked[0].GroupReference, booked[1].GroupReference)
+
+				next, err := i.computeBalance(context.Background(), 10, "EF2024")
+				require.NoError(t, err)
+				require.Equal(t, int64(-50_00), next.OutstandingCent)
+			}
+
+			balance, err := i.computeBalance(context.Background(), 10, "EF2023")
+			require.NoError(t, err)
+			require.Equal(t, -tt.remaining, balance.OutstandingCent)
+		})
+	}
+}
+
+func TestRefundReservesCreditUntilPaidOut(t *testing.T) {
+	withEvents(t)
+
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, overpaymentSeed(entities.PaymentMethodCredit))
+
+	var i *serviceInteractor
+	var concurrentErr error
+	ccm := &CncrdAdapterMock{
+		CreateRefundFunc: func(ctx context.Context, request cncrdadapter.RefundRequestDto) (cncrdadapter.RefundDto, error) {
+			pending, err := db.GetTransactionsByFilter(ctx, entities.TransactionQuery{TransactionIdentifier: request.ReferenceId})
+			require.NoError(t, err)
+			require.Len(t, pending, 1)
+			require.Equal(t, entities.TransactionStatusPending, pending[0].TransactionStatus)
+
+			// a second refund while the provider pays out the first one
+			if concurrentErr == nil {
+				_, concurrentErr = i.ResolveOverpayment(adminCtx(), 10, OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023", AmountCent: 50_00})
+			}
+
+			return cncrdadapter.RefundDto{ReferenceId: request.ReferenceId}, nil
+		},
+	}
+	i = tstServiceInteractor(db, &AttendeeServiceMock{}, ccm)
+
+	booked, err := i.ResolveOverpayment(adminCtx(), 10, OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023", AmountCent: 50_00})
+	require.NoError(t, err)
+	require.Len(t, booked, 1)
+	require.Equal(t, entities.TransactionStatusValid, booked[0].TransactionStatus)
+
+	require.EqualError(t, concurrentErr, apierrors.NewConflict("the credit of debitor 10 no longer covers a refund of 5000").Error())
+
+	balance, err := i.computeBalance(context.Background(), 10, "EF2023")
+	require.NoError(t, err)
+	require.Zero(t, balance.OutstandingCent)
+}
+
+func TestResolveOverpaymentRetriesCollidingIDs(t *testing.T) {
+	withEvents(t)
+
+	tests := []struct {
+		name       string
+		method     entities.PaymentMethod
+		resolution OverpaymentResolution
+	}{
+		{
+			name:       "refund of bank transfers",
+			method:     entities.PaymentMethodTransfer,
+			resolution: OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023"},
+		},
+		{
+			name:       "refund of card payments",
+			method:     entities.PaymentMethodCredit,
+			resolution: OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023"},
+		},
+		{
+			name:       "carry forward",
+			method:     entities.PaymentMethodTransfer,
+			resolution: OverpaymentResolution{Action: OverpaymentActionCarryForward, Event: "EF2023"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &collidingCreates{Repository: inmemory.NewInMemoryProvider()}
+			seedDB(db, overpaymentSeed(tt.method))
+			db.collisions, db.attempts = 1, nil
+
+			asm := &AttendeeServiceMock{
+				PaymentsChangedFunc: func(ctx context.Context, debitorId uint) error {
+					return nil
+				},
+			}
+			ccm := &CncrdAdapterMock{
+				CreateRefundFunc: func(ctx context.Context, request cncrdadapter.RefundRequestDto) (cncrdadapter.RefundDto, error) {
+					return cncrdadapter.RefundDto{ReferenceId: request.ReferenceId}, nil
+				},
+			}
+			i := tstServiceInteractor(db, asm, ccm)
+
+			booked, err := i.ResolveOverpayment(adminCtx(), 10, tt.resolution)
+			require.NoError(t, err)
+			requireCreatedAfterCollision(t, db, booked)
+		})
+	}
+}
+
+func TestResolveOverpaymentErrors(t *testing.T) {
+	withEvents(t)
+
+	db := inmemory.NewInMemoryProvider()
+	seed := overpaymentSeed(entities.PaymentMethodTransfer)
+	for _, tran := range seed {
+		tran.Event = "EF2024"
+		tran.TransactionID = "EF24" + tran.TransactionID[len("EF2023"):]
+		seed = append(seed, tran)
+	}
+	seedDB(db, seed)
+
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	_, err := i.ResolveOverpayment(adminCtx(), 11, OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023"})
+	require.EqualError(t, err, apierrors.NewNotFound("debitor 11 has no overpayment for event EF2023").Error())
+
+	_, err = i.ResolveOverpayment(adminCtx(), 10, OverpaymentResolution{Action: OverpaymentActionCarryForward, Event: "EF2024"})
+	require.EqualError(t, err, apierrors.NewConflict("no event is configured after EF2024 to carry the credit forward to").Error())
+
+	_, err = i.ResolveOverpayment(adminCtx(), 10, OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023", Method: entities.PaymentMethodInternal})
+	require.True(t, apierrors.IsUnprocessableEntityError(err))
+
+	_, err = i.ResolveOverpayment(adminCtx(), 10, OverpaymentResolution{Action: OverpaymentActionRefund, Event: "EF2023", Method: entities.PaymentMethodCredit})
+	require.EqualError(t, err, apierrors.NewConflict("debitor 10 has no valid credit payments").Error())
+}
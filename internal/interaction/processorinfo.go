@@ -0,0 +1,41 @@
+package interaction
+
+import (
+	"fmt"
+	"net/url"
+	"unicode"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+)
+
+// maxVisiblePANDigits is the most digits of a card number that may be stored (first 6 and last 4)
+const maxVisiblePANDigits = 10
+
+// validateProcessorInfo makes sure no full card numbers end up in the database.
+func validateProcessorInfo(info entities.PaymentProcessorInformation) error {
+	pan, ok := info[entities.ProcessorInfoMaskedPAN]
+	if !ok {
+		return nil
+	}
+
+	field := "payment_processor_information." + entities.ProcessorInfoMaskedPAN
+	panStr, ok := pan.(string)
+	if !ok {
+		return apierrors.NewUnprocessableEntity(url.Values{field: {"must be a string"}})
+	}
+
+	digits := 0
+	for _, r := range panStr {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	if digits > maxVisiblePANDigits {
+		return apierrors.NewUnprocessableEntity(url.Values{
+			field: {fmt.Sprintf("must not show more than %d digits of the card number", maxVisiblePANDigits)},
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package interaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+)
+
+func TestValidateProcessorInfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    entities.PaymentProcessorInformation
+		invalid bool
+	}{
+		{name: "no information", info: nil},
+		{name: "no card number", info: entities.PaymentProcessorInformation{entities.ProcessorInfoCardBrand: "visa"}},
+		{name: "last four digits", info: entities.PaymentProcessorInformation{entities.ProcessorInfoMaskedPAN: "**** **** **** 4242"}},
+		{name: "first six and last four digits", info: entities.PaymentProcessorInformation{entities.ProcessorInfoMaskedPAN: "424242******4242"}},
+		{name: "full card number", info: entities.PaymentProcessorInformation{entities.ProcessorInfoMaskedPAN: "4242424242424242"}, invalid: true},
+		{name: "not a string", info: entities.PaymentProcessorInformation{entities.ProcessorInfoMaskedPAN: 4242}, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateProcessorInfo(tt.info)
+			if tt.invalid {
+				require.True(t, apierrors.IsUnprocessableEntityError(err))
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUpdateProcessorInfo(t *testing.T) {
+	stored := entities.PaymentProcessorInformation{entities.ProcessorInfoPaylinkID: "42"}
+	updated := entities.PaymentProcessorInformation{
+		entities.ProcessorInfoPaylinkID:             "42",
+		entities.ProcessorInfoProviderTransactionID: "pt-1234",
+		entities.ProcessorInfoCardBrand:             "visa",
+		entities.ProcessorInfoMaskedPAN:             "**** 4242",
+		entities.ProcessorInfoFeeCent:               float64(95),
+	}
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		status       entities.TransactionStatus
+		info         entities.PaymentProcessorInformation
+		expectedErr  func(error) bool
+		expectedInfo entities.PaymentProcessorInformation
+	}{
+		{
+			name:         "api token may set the information",
+			ctx:          apiKeyCtx(),
+			status:       entities.TransactionStatusPending,
+			info:         updated,
+			expectedInfo: updated,
+		},
+		{
+			name:         "admins may set the information",
+			ctx:          adminCtx(),
+			status:       entities.TransactionStatusPending,
+			info:         updated,
+			expectedInfo: updated,
+		},
+		{
+			name:         "leaving the information empty keeps it",
+			ctx:          apiKeyCtx(),
+			status:       entities.TransactionStatusPending,
+			info:         entities.PaymentProcessorInformation{},
+			expectedInfo: stored,
+		},
+		{
+			name:         "users cannot change the information",
+			ctx:          attendeeCtx(),
+			status:       entities.TransactionStatusPending,
+			info:         updated,
+			expectedInfo: stored,
+		},
+		{
+			name:         "full card numbers are rejected",
+			ctx:          apiKeyCtx(),
+			status:       entities.TransactionStatusPending,
+			info:         entities.PaymentProcessorInformation{entities.ProcessorInfoMaskedPAN: "4242424242424242"},
+			expectedErr:  apierrors.IsUnprocessableEntityError,
+			expectedInfo: stored,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seed := newTransaction(1, "EF-000001-1201-120000-1234", entities.TransactionTypePayment, entities.PaymentMethodCredit,
+				entities.TransactionStatusTentative, entities.Amount{ISOCurrency: "EUR", GrossCent: 100_00})
+			seed.ProcessorInfo = stored
+
+			db := inmemory.NewInMemoryProvider()
+			seedDB(db, []entities.Transaction{seed})
+
+			asm := &AttendeeServiceMock{
+				ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) { return []int64{1}, nil },
+			}
+			i, err := NewServiceInteractor(db, asm, &CncrdAdapterMock{})
+			require.NoError(t, err)
+
+			change := seed
+			change.TransactionStatus = tt.status
+			change.ProcessorInfo = tt.info
+
+			err = i.UpdateTransaction(tt.ctx, &change)
+			if tt.expectedErr != nil {
+				require.True(t, tt.expectedErr(err), "unexpected error %v", err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			result, err := db.GetTransactionByTransactionIDAndType(context.Background(), seed.TransactionID, seed.TransactionType)
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedInfo, result.ProcessorInfo)
+		})
+	}
+}
@@ -8,14 +8,35 @@ import (
 	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
 )
 
+const (
+	// apiTokenActor is recorded as the actor for requests made with the fixed api token
+	apiTokenActor = "api-token"
+	// systemActorPrefix is followed by the name of the system identity
+	systemActorPrefix = "system:"
+
+	roleAdmin  = "admin"
+	roleAPI    = "api"
+	roleSystem = "system"
+	roleUser   = "user"
+	roleNone   = "none"
+)
+
 type RBACValidator struct {
 	subject          string
 	groups           []string
+	systemIdentity   string
 	isAdmin          bool
 	isAPITokenCall   bool
+	isSystemCall     bool
 	isRegisteredUser bool
 }
 
+// WithSystemIdentity marks all calls made with the returned context as made by the service itself,
+// e.g. from the command line. System calls have the same permissions as the api token.
+func WithSystemIdentity(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, common.CtxKeySystemIdentity{}, name)
+}
+
 func (i *RBACValidator) IsAdmin() bool {
 	return i.isAdmin
 }
@@ -24,6 +45,10 @@ func (i *RBACValidator) IsAPITokenCall() bool {
 	return i.isAPITokenCall
 }
 
+func (i *RBACValidator) IsSystemCall() bool {
+	return i.isSystemCall
+}
+
 func (i *RBACValidator) IsRegisteredUser() bool {
 	return i.isRegisteredUser && i.subject != ""
 }
@@ -32,6 +57,33 @@ func (i *RBACValidator) Subject() string {
 	return i.subject
 }
 
+// Actor identifies who made the request, either the subject, the name of the api token or the system identity
+func (i *RBACValidator) Actor() string {
+	if i.isSystemCall {
+		return systemActorPrefix + i.systemIdentity
+	}
+	if i.isAPITokenCall {
+		return apiTokenActor
+	}
+	return i.subject
+}
+
+// Role names the permissions the request was made with
+func (i *RBACValidator) Role() string {
+	switch {
+	case i.isSystemCall:
+		return roleSystem
+	case i.isAPITokenCall:
+		return roleAPI
+	case i.isAdmin:
+		return roleAdmin
+	case i.IsRegisteredUser():
+		return roleUser
+	default:
+		return roleNone
+	}
+}
+
 func NewRBACValidator(ctx context.Context) (*RBACValidator, error) {
 	manager := &RBACValidator{}
 
@@ -40,6 +92,12 @@ func NewRBACValidator(ctx context.Context) (*RBACValidator, error) {
 		return nil, err
 	}
 
+	if name, ok := ctx.Value(common.CtxKeySystemIdentity{}).(string); ok {
+		manager.isSystemCall = true
+		manager.systemIdentity = name
+		return manager, nil
+	}
+
 	if _, ok := ctx.Value(common.CtxKeyAPIKey{}).(string); ok {
 		manager.isAPITokenCall = true
 		return manager, nil
@@ -0,0 +1,35 @@
+package interaction
+
+import (
+	"context"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+)
+
+// RegistrationsChanged is called by the attendee service when the registrations of a subject have changed.
+//
+// We cache the registration ids per subject, so we need to forget what we know.
+// An empty subject means we forget the registration ids for everyone.
+func (s *serviceInteractor) RegistrationsChanged(ctx context.Context, subject string) error {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !mgr.IsAPITokenCall() {
+		err := apierrors.NewForbidden("only the api token may notify about changed registrations")
+		s.recordAudit(ctx, mgr, auditActionRegistrationsChanged, 0, "", "", err)
+		return err
+	}
+
+	if subject == "" {
+		logging.LoggerFromContext(ctx).Info("clearing cached registrations for all subjects")
+	} else {
+		logging.LoggerFromContext(ctx).Debug("clearing cached registrations for subject %s", subject)
+	}
+
+	s.attendeeClient.RegistrationsChanged(ctx, subject)
+	s.recordAudit(ctx, mgr, auditActionRegistrationsChanged, 0, "", "", nil)
+	return nil
+}
@@ -0,0 +1,65 @@
+package interaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+)
+
+func TestRegistrationsChanged(t *testing.T) {
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		subject       string
+		expectedErr   error
+		expectedCalls int
+	}{
+		{
+			name:          "should clear the cache for a subject when called with the api token",
+			ctx:           apiKeyCtx(),
+			subject:       "1234567890",
+			expectedCalls: 1,
+		},
+		{
+			name:          "should clear the cache for all subjects when called with an empty subject",
+			ctx:           apiKeyCtx(),
+			expectedCalls: 1,
+		},
+		{
+			name:        "should not allow admins",
+			ctx:         adminCtx(),
+			subject:     "1234567890",
+			expectedErr: apierrors.NewForbidden("only the api token may notify about changed registrations"),
+		},
+		{
+			name:        "should not allow users",
+			ctx:         attendeeCtx(),
+			subject:     "1234567890",
+			expectedErr: apierrors.NewForbidden("only the api token may notify about changed registrations"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attMock := &AttendeeServiceMock{}
+			i := tstServiceInteractor(inmemory.NewInMemoryProvider(), attMock, &CncrdAdapterMock{})
+
+			err := i.RegistrationsChanged(tt.ctx, tt.subject)
+			if tt.expectedErr != nil {
+				require.EqualError(t, err, tt.expectedErr.Error())
+			} else {
+				require.NoError(t, err)
+			}
+
+			calls := attMock.RegistrationsChangedCalls()
+			require.Len(t, calls, tt.expectedCalls)
+			if tt.expectedCalls > 0 {
+				require.Equal(t, tt.subject, calls[0].Subject)
+			}
+		})
+	}
+}
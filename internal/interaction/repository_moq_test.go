@@ -20,39 +20,87 @@ var _ database.Repository = &RepositoryMock{}
 //
 //		// make and configure a mocked database.Repository
 //		mockedRepository := &RepositoryMock{
+//			CheckSchemaFunc: func(ctx context.Context) error {
+//				panic("mock out the CheckSchema method")
+//			},
+//			CloseFunc: func() error {
+//				panic("mock out the Close method")
+//			},
+//			CreateAuditLogEntryFunc: func(ctx context.Context, e entities.AuditLogEntry) error {
+//				panic("mock out the CreateAuditLogEntry method")
+//			},
+//			CreateInstallmentPlanFunc: func(ctx context.Context, plan entities.InstallmentPlan) error {
+//				panic("mock out the CreateInstallmentPlan method")
+//			},
 //			CreateTransactionFunc: func(ctx context.Context, tr entities.Transaction) error {
 //				panic("mock out the CreateTransaction method")
 //			},
 //			CreateTransactionLogFunc: func(ctx context.Context, h entities.TransactionLog) error {
 //				panic("mock out the CreateTransactionLog method")
 //			},
+//			CreateTransactionsFunc: func(ctx context.Context, trs []entities.Transaction) error {
+//				panic("mock out the CreateTransactions method")
+//			},
+//			CreateTransactionsWithinCreditFunc: func(ctx context.Context, debitorID int64, event string, creditCent int64, trs []entities.Transaction) error {
+//				panic("mock out the CreateTransactionsWithinCredit method")
+//			},
+//			DeleteInstallmentPlanFunc: func(ctx context.Context, id uint) error {
+//				panic("mock out the DeleteInstallmentPlan method")
+//			},
 //			DeleteTransactionFunc: func(ctx context.Context, tr entities.Transaction) error {
 //				panic("mock out the DeleteTransaction method")
 //			},
 //			GetAdminTransactionsByFilterFunc: func(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
 //				panic("mock out the GetAdminTransactionsByFilter method")
 //			},
+//			GetAuditLogEntriesFunc: func(ctx context.Context, query entities.AuditLogQuery) ([]entities.AuditLogEntry, error) {
+//				panic("mock out the GetAuditLogEntries method")
+//			},
+//			GetHashChainHeadsFunc: func(ctx context.Context, prefix string) ([]entities.HashChainHead, error) {
+//				panic("mock out the GetHashChainHeads method")
+//			},
+//			GetInstallmentPlansFunc: func(ctx context.Context, debitorID int64, event string) ([]entities.InstallmentPlan, error) {
+//				panic("mock out the GetInstallmentPlans method")
+//			},
 //			GetTransactionByTransactionIDAndTypeFunc: func(ctx context.Context, transactionID string, tType entities.TransactionType) (*entities.Transaction, error) {
 //				panic("mock out the GetTransactionByTransactionIDAndType method")
 //			},
 //			GetTransactionLogByIDFunc: func(ctx context.Context, id uint) (*entities.TransactionLog, error) {
 //				panic("mock out the GetTransactionLogByID method")
 //			},
+//			GetTransactionLogsFunc: func(ctx context.Context, afterID uint, limit int) ([]entities.TransactionLog, error) {
+//				panic("mock out the GetTransactionLogs method")
+//			},
+//			GetTransactionLogsForTransactionFunc: func(ctx context.Context, transactionID string) ([]entities.TransactionLog, error) {
+//				panic("mock out the GetTransactionLogsForTransaction method")
+//			},
 //			GetTransactionsByFilterFunc: func(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
 //				panic("mock out the GetTransactionsByFilter method")
 //			},
-//			GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64) ([]entities.Transaction, error) {
+//			GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
 //				panic("mock out the GetValidTransactionsForDebitor method")
 //			},
+//			IssueDocumentFunc: func(ctx context.Context, doc entities.IssuedDocument) (*entities.IssuedDocument, error) {
+//				panic("mock out the IssueDocument method")
+//			},
 //			MigrateFunc: func() error {
 //				panic("mock out the Migrate method")
 //			},
-//			QueryOutstandingDuesForDebitorFunc: func(ctx context.Context, debitorID int64) (int64, error) {
+//			PingFunc: func(ctx context.Context) error {
+//				panic("mock out the Ping method")
+//			},
+//			QueryOutstandingDuesForDebitorFunc: func(ctx context.Context, debitorID int64, event string) (int64, error) {
 //				panic("mock out the QueryOutstandingDuesForDebitor method")
 //			},
+//			QueryOverpaidDebitorsFunc: func(ctx context.Context, event string) ([]entities.Overpayment, error) {
+//				panic("mock out the QueryOverpaidDebitors method")
+//			},
 //			UpdateTransactionFunc: func(ctx context.Context, tr entities.Transaction, historize bool) error {
 //				panic("mock out the UpdateTransaction method")
 //			},
+//			UpdateTransactionsFunc: func(ctx context.Context, trs []entities.Transaction) error {
+//				panic("mock out the UpdateTransactions method")
+//			},
 //		}
 //
 //		// use mockedRepository in code that requires database.Repository
@@ -60,41 +108,111 @@ var _ database.Repository = &RepositoryMock{}
 //
 //	}
 type RepositoryMock struct {
+	// CheckSchemaFunc mocks the CheckSchema method.
+	CheckSchemaFunc func(ctx context.Context) error
+
+	// CloseFunc mocks the Close method.
+	CloseFunc func() error
+
+	// CreateAuditLogEntryFunc mocks the CreateAuditLogEntry method.
+	CreateAuditLogEntryFunc func(ctx context.Context, e entities.AuditLogEntry) error
+
+	// CreateInstallmentPlanFunc mocks the CreateInstallmentPlan method.
+	CreateInstallmentPlanFunc func(ctx context.Context, plan entities.InstallmentPlan) error
+
 	// CreateTransactionFunc mocks the CreateTransaction method.
 	CreateTransactionFunc func(ctx context.Context, tr entities.Transaction) error
 
 	// CreateTransactionLogFunc mocks the CreateTransactionLog method.
 	CreateTransactionLogFunc func(ctx context.Context, h entities.TransactionLog) error
 
+	// CreateTransactionsFunc mocks the CreateTransactions method.
+	CreateTransactionsFunc func(ctx context.Context, trs []entities.Transaction) error
+
+	// CreateTransactionsWithinCreditFunc mocks the CreateTransactionsWithinCredit method.
+	CreateTransactionsWithinCreditFunc func(ctx context.Context, debitorID int64, event string, creditCent int64, trs []entities.Transaction) error
+
+	// DeleteInstallmentPlanFunc mocks the DeleteInstallmentPlan method.
+	DeleteInstallmentPlanFunc func(ctx context.Context, id uint) error
+
 	// DeleteTransactionFunc mocks the DeleteTransaction method.
 	DeleteTransactionFunc func(ctx context.Context, tr entities.Transaction) error
 
 	// GetAdminTransactionsByFilterFunc mocks the GetAdminTransactionsByFilter method.
 	GetAdminTransactionsByFilterFunc func(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error)
 
+	// GetAuditLogEntriesFunc mocks the GetAuditLogEntries method.
+	GetAuditLogEntriesFunc func(ctx context.Context, query entities.AuditLogQuery) ([]entities.AuditLogEntry, error)
+
+	// GetHashChainHeadsFunc mocks the GetHashChainHeads method.
+	GetHashChainHeadsFunc func(ctx context.Context, prefix string) ([]entities.HashChainHead, error)
+
+	// GetInstallmentPlansFunc mocks the GetInstallmentPlans method.
+	GetInstallmentPlansFunc func(ctx context.Context, debitorID int64, event string) ([]entities.InstallmentPlan, error)
+
 	// GetTransactionByTransactionIDAndTypeFunc mocks the GetTransactionByTransactionIDAndType method.
 	GetTransactionByTransactionIDAndTypeFunc func(ctx context.Context, transactionID string, tType entities.TransactionType) (*entities.Transaction, error)
 
 	// GetTransactionLogByIDFunc mocks the GetTransactionLogByID method.
 	GetTransactionLogByIDFunc func(ctx context.Context, id uint) (*entities.TransactionLog, error)
 
+	// GetTransactionLogsFunc mocks the GetTransactionLogs method.
+	GetTransactionLogsFunc func(ctx context.Context, afterID uint, limit int) ([]entities.TransactionLog, error)
+
+	// GetTransactionLogsForTransactionFunc mocks the GetTransactionLogsForTransaction method.
+	GetTransactionLogsForTransactionFunc func(ctx context.Context, transactionID string) ([]entities.TransactionLog, error)
+
 	// GetTransactionsByFilterFunc mocks the GetTransactionsByFilter method.
 	GetTransactionsByFilterFunc func(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error)
 
 	// GetValidTransactionsForDebitorFunc mocks the GetValidTransactionsForDebitor method.
-	GetValidTransactionsForDebitorFunc func(ctx context.Context, debitorID int64) ([]entities.Transaction, error)
+	GetValidTransactionsForDebitorFunc func(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error)
+
+	// IssueDocumentFunc mocks the IssueDocument method.
+	IssueDocumentFunc func(ctx context.Context, doc entities.IssuedDocument) (*entities.IssuedDocument, error)
 
 	// MigrateFunc mocks the Migrate method.
 	MigrateFunc func() error
 
+	// PingFunc mocks the Ping method.
+	PingFunc func(ctx context.Context) error
+
 	// QueryOutstandingDuesForDebitorFunc mocks the QueryOutstandingDuesForDebitor method.
-	QueryOutstandingDuesForDebitorFunc func(ctx context.Context, debitorID int64) (int64, error)
+	QueryOutstandingDuesForDebitorFunc func(ctx context.Context, debitorID int64, event string) (int64, error)
+
+	// QueryOverpaidDebitorsFunc mocks the QueryOverpaidDebitors method.
+	QueryOverpaidDebitorsFunc func(ctx context.Context, event string) ([]entities.Overpayment, error)
 
 	// UpdateTransactionFunc mocks the UpdateTransaction method.
 	UpdateTransactionFunc func(ctx context.Context, tr entities.Transaction, historize bool) error
 
+	// UpdateTransactionsFunc mocks the UpdateTransactions method.
+	UpdateTransactionsFunc func(ctx context.Context, trs []entities.Transaction) error
+
 	// calls tracks calls to the methods.
 	calls struct {
+		// CheckSchema holds details about calls to the CheckSchema method.
+		CheckSchema []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// Close holds details about calls to the Close method.
+		Close []struct {
+		}
+		// CreateAuditLogEntry holds details about calls to the CreateAuditLogEntry method.
+		CreateAuditLogEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// E is the e argument value.
+			E entities.AuditLogEntry
+		}
+		// CreateInstallmentPlan holds details about calls to the CreateInstallmentPlan method.
+		CreateInstallmentPlan []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Plan is the plan argument value.
+			Plan entities.InstallmentPlan
+		}
 		// CreateTransaction holds details about calls to the CreateTransaction method.
 		CreateTransaction []struct {
 			// Ctx is the ctx argument value.
@@ -109,6 +227,33 @@ type RepositoryMock struct {
 			// H is the h argument value.
 			H entities.TransactionLog
 		}
+		// CreateTransactions holds details about calls to the CreateTransactions method.
+		CreateTransactions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Trs is the trs argument value.
+			Trs []entities.Transaction
+		}
+		// CreateTransactionsWithinCredit holds details about calls to the CreateTransactionsWithinCredit method.
+		CreateTransactionsWithinCredit []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// DebitorID is the debitorID argument value.
+			DebitorID int64
+			// Event is the event argument value.
+			Event string
+			// CreditCent is the creditCent argument value.
+			CreditCent int64
+			// Trs is the trs argument value.
+			Trs []entities.Transaction
+		}
+		// DeleteInstallmentPlan holds details about calls to the DeleteInstallmentPlan method.
+		DeleteInstallmentPlan []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// ID is the id argument value.
+			ID uint
+		}
 		// DeleteTransaction holds details about calls to the DeleteTransaction method.
 		DeleteTransaction []struct {
 			// Ctx is the ctx argument value.
@@ -123,6 +268,29 @@ type RepositoryMock struct {
 			// Query is the query argument value.
 			Query entities.TransactionQuery
 		}
+		// GetAuditLogEntries holds details about calls to the GetAuditLogEntries method.
+		GetAuditLogEntries []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Query is the query argument value.
+			Query entities.AuditLogQuery
+		}
+		// GetHashChainHeads holds details about calls to the GetHashChainHeads method.
+		GetHashChainHeads []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Prefix is the prefix argument value.
+			Prefix string
+		}
+		// GetInstallmentPlans holds details about calls to the GetInstallmentPlans method.
+		GetInstallmentPlans []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// DebitorID is the debitorID argument value.
+			DebitorID int64
+			// Event is the event argument value.
+			Event string
+		}
 		// GetTransactionByTransactionIDAndType holds details about calls to the GetTransactionByTransactionIDAndType method.
 		GetTransactionByTransactionIDAndType []struct {
 			// Ctx is the ctx argument value.
@@ -139,6 +307,22 @@ type RepositoryMock struct {
 			// ID is the id argument value.
 			ID uint
 		}
+		// GetTransactionLogs holds details about calls to the GetTransactionLogs method.
+		GetTransactionLogs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// AfterID is the afterID argument value.
+			AfterID uint
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// GetTransactionLogsForTransaction holds details about calls to the GetTransactionLogsForTransaction method.
+		GetTransactionLogsForTransaction []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// TransactionID is the transactionID argument value.
+			TransactionID string
+		}
 		// GetTransactionsByFilter holds details about calls to the GetTransactionsByFilter method.
 		GetTransactionsByFilter []struct {
 			// Ctx is the ctx argument value.
@@ -152,16 +336,39 @@ type RepositoryMock struct {
 			Ctx context.Context
 			// DebitorID is the debitorID argument value.
 			DebitorID int64
+			// Event is the event argument value.
+			Event string
+		}
+		// IssueDocument holds details about calls to the IssueDocument method.
+		IssueDocument []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Doc is the doc argument value.
+			Doc entities.IssuedDocument
 		}
 		// Migrate holds details about calls to the Migrate method.
 		Migrate []struct {
 		}
+		// Ping holds details about calls to the Ping method.
+		Ping []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
 		// QueryOutstandingDuesForDebitor holds details about calls to the QueryOutstandingDuesForDebitor method.
 		QueryOutstandingDuesForDebitor []struct {
 			// Ctx is the ctx argument value.
 			Ctx context.Context
 			// DebitorID is the debitorID argument value.
 			DebitorID int64
+			// Event is the event argument value.
+			Event string
+		}
+		// QueryOverpaidDebitors holds details about calls to the QueryOverpaidDebitors method.
+		QueryOverpaidDebitors []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Event is the event argument value.
+			Event string
 		}
 		// UpdateTransaction holds details about calls to the UpdateTransaction method.
 		UpdateTransaction []struct {
@@ -172,18 +379,184 @@ type RepositoryMock struct {
 			// Historize is the historize argument value.
 			Historize bool
 		}
+		// UpdateTransactions holds details about calls to the UpdateTransactions method.
+		UpdateTransactions []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Trs is the trs argument value.
+			Trs []entities.Transaction
+		}
 	}
+	lockCheckSchema                          sync.RWMutex
+	lockClose                                sync.RWMutex
+	lockCreateAuditLogEntry                  sync.RWMutex
+	lockCreateInstallmentPlan                sync.RWMutex
 	lockCreateTransaction                    sync.RWMutex
 	lockCreateTransactionLog                 sync.RWMutex
+	lockCreateTransactions                   sync.RWMutex
+	lockCreateTransactionsWithinCredit       sync.RWMutex
+	lockDeleteInstallmentPlan                sync.RWMutex
 	lockDeleteTransaction                    sync.RWMutex
 	lockGetAdminTransactionsByFilter         sync.RWMutex
+	lockGetAuditLogEntries                   sync.RWMutex
+	lockGetHashChainHeads                    sync.RWMutex
+	lockGetInstallmentPlans                  sync.RWMutex
 	lockGetTransactionByTransactionIDAndType sync.RWMutex
 	lockGetTransactionLogByID                sync.RWMutex
+	lockGetTransactionLogs                   sync.RWMutex
+	lockGetTransactionLogsForTransaction     sync.RWMutex
 	lockGetTransactionsByFilter              sync.RWMutex
 	lockGetValidTransactionsForDebitor       sync.RWMutex
+	lockIssueDocument                        sync.RWMutex
 	lockMigrate                              sync.RWMutex
+	lockPing                                 sync.RWMutex
 	lockQueryOutstandingDuesForDebitor       sync.RWMutex
+	lockQueryOverpaidDebitors                sync.RWMutex
 	lockUpdateTransaction                    sync.RWMutex
+	lockUpdateTransactions                   sync.RWMutex
+}
+
+// CheckSchema calls CheckSchemaFunc.
+func (mock *RepositoryMock) CheckSchema(ctx context.Context) error {
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockCheckSchema.Lock()
+	mock.calls.CheckSchema = append(mock.calls.CheckSchema, callInfo)
+	mock.lockCheckSchema.Unlock()
+	if mock.CheckSchemaFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.CheckSchemaFunc(ctx)
+}
+
+// CheckSchemaCalls gets all the calls that were made to CheckSchema.
+// Check the length with:
+//
+//	len(mockedRepository.CheckSchemaCalls())
+func (mock *RepositoryMock) CheckSchemaCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockCheckSchema.RLock()
+	calls = mock.calls.CheckSchema
+	mock.lockCheckSchema.RUnlock()
+	return calls
+}
+
+// Close calls CloseFunc.
+func (mock *RepositoryMock) Close() error {
+	callInfo := struct {
+	}{}
+	mock.lockClose.Lock()
+	mock.calls.Close = append(mock.calls.Close, callInfo)
+	mock.lockClose.Unlock()
+	if mock.CloseFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.CloseFunc()
+}
+
+// CloseCalls gets all the calls that were made to Close.
+// Check the length with:
+//
+//	len(mockedRepository.CloseCalls())
+func (mock *RepositoryMock) CloseCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockClose.RLock()
+	calls = mock.calls.Close
+	mock.lockClose.RUnlock()
+	return calls
+}
+
+// CreateAuditLogEntry calls CreateAuditLogEntryFunc.
+func (mock *RepositoryMock) CreateAuditLogEntry(ctx context.Context, e entities.AuditLogEntry) error {
+	callInfo := struct {
+		Ctx context.Context
+		E   entities.AuditLogEntry
+	}{
+		Ctx: ctx,
+		E:   e,
+	}
+	mock.lockCreateAuditLogEntry.Lock()
+	mock.calls.CreateAuditLogEntry = append(mock.calls.CreateAuditLogEntry, callInfo)
+	mock.lockCreateAuditLogEntry.Unlock()
+	if mock.CreateAuditLogEntryFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.CreateAuditLogEntryFunc(ctx, e)
+}
+
+// CreateAuditLogEntryCalls gets all the calls that were made to CreateAuditLogEntry.
+// Check the length with:
+//
+//	len(mockedRepository.CreateAuditLogEntryCalls())
+func (mock *RepositoryMock) CreateAuditLogEntryCalls() []struct {
+	Ctx context.Context
+	E   entities.AuditLogEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		E   entities.AuditLogEntry
+	}
+	mock.lockCreateAuditLogEntry.RLock()
+	calls = mock.calls.CreateAuditLogEntry
+	mock.lockCreateAuditLogEntry.RUnlock()
+	return calls
+}
+
+// CreateInstallmentPlan calls CreateInstallmentPlanFunc.
+func (mock *RepositoryMock) CreateInstallmentPlan(ctx context.Context, plan entities.InstallmentPlan) error {
+	callInfo := struct {
+		Ctx  context.Context
+		Plan entities.InstallmentPlan
+	}{
+		Ctx:  ctx,
+		Plan: plan,
+	}
+	mock.lockCreateInstallmentPlan.Lock()
+	mock.calls.CreateInstallmentPlan = append(mock.calls.CreateInstallmentPlan, callInfo)
+	mock.lockCreateInstallmentPlan.Unlock()
+	if mock.CreateInstallmentPlanFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.CreateInstallmentPlanFunc(ctx, plan)
+}
+
+// CreateInstallmentPlanCalls gets all the calls that were made to CreateInstallmentPlan.
+// Check the length with:
+//
+//	len(mockedRepository.CreateInstallmentPlanCalls())
+func (mock *RepositoryMock) CreateInstallmentPlanCalls() []struct {
+	Ctx  context.Context
+	Plan entities.InstallmentPlan
+} {
+	var calls []struct {
+		Ctx  context.Context
+		Plan entities.InstallmentPlan
+	}
+	mock.lockCreateInstallmentPlan.RLock()
+	calls = mock.calls.CreateInstallmentPlan
+	mock.lockCreateInstallmentPlan.RUnlock()
+	return calls
 }
 
 // CreateTransaction calls CreateTransactionFunc.
@@ -264,6 +637,135 @@ func (mock *RepositoryMock) CreateTransactionLogCalls() []struct {
 	return calls
 }
 
+// CreateTransactions calls CreateTransactionsFunc.
+func (mock *RepositoryMock) CreateTransactions(ctx context.Context, trs []entities.Transaction) error {
+	callInfo := struct {
+		Ctx context.Context
+		Trs []entities.Transaction
+	}{
+		Ctx: ctx,
+		Trs: trs,
+	}
+	mock.lockCreateTransactions.Lock()
+	mock.calls.CreateTransactions = append(mock.calls.CreateTransactions, callInfo)
+	mock.lockCreateTransactions.Unlock()
+	if mock.CreateTransactionsFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.CreateTransactionsFunc(ctx, trs)
+}
+
+// CreateTransactionsCalls gets all the calls that were made to CreateTransactions.
+// Check the length with:
+//
+//	len(mockedRepository.CreateTransactionsCalls())
+func (mock *RepositoryMock) CreateTransactionsCalls() []struct {
+	Ctx context.Context
+	Trs []entities.Transaction
+} {
+	var calls []struct {
+		Ctx context.Context
+		Trs []entities.Transaction
+	}
+	mock.lockCreateTransactions.RLock()
+	calls = mock.calls.CreateTransactions
+	mock.lockCreateTransactions.RUnlock()
+	return calls
+}
+
+// CreateTransactionsWithinCredit calls CreateTransactionsWithinCreditFunc.
+func (mock *RepositoryMock) CreateTransactionsWithinCredit(ctx context.Context, debitorID int64, event string, creditCent int64, trs []entities.Transaction) error {
+	callInfo := struct {
+		Ctx        context.Context
+		DebitorID  int64
+		Event      string
+		CreditCent int64
+		Trs        []entities.Transaction
+	}{
+		Ctx:        ctx,
+		DebitorID:  debitorID,
+		Event:      event,
+		CreditCent: creditCent,
+		Trs:        trs,
+	}
+	mock.lockCreateTransactionsWithinCredit.Lock()
+	mock.calls.CreateTransactionsWithinCredit = append(mock.calls.CreateTransactionsWithinCredit, callInfo)
+	mock.lockCreateTransactionsWithinCredit.Unlock()
+	if mock.CreateTransactionsWithinCreditFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.CreateTransactionsWithinCreditFunc(ctx, debitorID, event, creditCent, trs)
+}
+
+// CreateTransactionsWithinCreditCalls gets all the calls that were made to CreateTransactionsWithinCredit.
+// Check the length with:
+//
+//	len(mockedRepository.CreateTransactionsWithinCreditCalls())
+func (mock *RepositoryMock) CreateTransactionsWithinCreditCalls() []struct {
+	Ctx        context.Context
+	DebitorID  int64
+	Event      string
+	CreditCent int64
+	Trs        []entities.Transaction
+} {
+	var calls []struct {
+		Ctx        context.Context
+		DebitorID  int64
+		Event      string
+		CreditCent int64
+		Trs        []entities.Transaction
+	}
+	mock.lockCreateTransactionsWithinCredit.RLock()
+	calls = mock.calls.CreateTransactionsWithinCredit
+	mock.lockCreateTransactionsWithinCredit.RUnlock()
+	return calls
+}
+
+// DeleteInstallmentPlan calls DeleteInstallmentPlanFunc.
+func (mock *RepositoryMock) DeleteInstallmentPlan(ctx context.Context, id uint) error {
+	callInfo := struct {
+		Ctx context.Context
+		ID  uint
+	}{
+		Ctx: ctx,
+		ID:  id,
+	}
+	mock.lockDeleteInstallmentPlan.Lock()
+	mock.calls.DeleteInstallmentPlan = append(mock.calls.DeleteInstallmentPlan, callInfo)
+	mock.lockDeleteInstallmentPlan.Unlock()
+	if mock.DeleteInstallmentPlanFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.DeleteInstallmentPlanFunc(ctx, id)
+}
+
+// DeleteInstallmentPlanCalls gets all the calls that were made to DeleteInstallmentPlan.
+// Check the length with:
+//
+//	len(mockedRepository.DeleteInstallmentPlanCalls())
+func (mock *RepositoryMock) DeleteInstallmentPlanCalls() []struct {
+	Ctx context.Context
+	ID  uint
+} {
+	var calls []struct {
+		Ctx context.Context
+		ID  uint
+	}
+	mock.lockDeleteInstallmentPlan.RLock()
+	calls = mock.calls.DeleteInstallmentPlan
+	mock.lockDeleteInstallmentPlan.RUnlock()
+	return calls
+}
+
 // DeleteTransaction calls DeleteTransactionFunc.
 func (mock *RepositoryMock) DeleteTransaction(ctx context.Context, tr entities.Transaction) error {
 	callInfo := struct {
@@ -343,6 +845,130 @@ func (mock *RepositoryMock) GetAdminTransactionsByFilterCalls() []struct {
 	return calls
 }
 
+// GetAuditLogEntries calls GetAuditLogEntriesFunc.
+func (mock *RepositoryMock) GetAuditLogEntries(ctx context.Context, query entities.AuditLogQuery) ([]entities.AuditLogEntry, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Query entities.AuditLogQuery
+	}{
+		Ctx:   ctx,
+		Query: query,
+	}
+	mock.lockGetAuditLogEntries.Lock()
+	mock.calls.GetAuditLogEntries = append(mock.calls.GetAuditLogEntries, callInfo)
+	mock.lockGetAuditLogEntries.Unlock()
+	if mock.GetAuditLogEntriesFunc == nil {
+		var (
+			auditLogEntrysOut []entities.AuditLogEntry
+			errOut            error
+		)
+		return auditLogEntrysOut, errOut
+	}
+	return mock.GetAuditLogEntriesFunc(ctx, query)
+}
+
+// GetAuditLogEntriesCalls gets all the calls that were made to GetAuditLogEntries.
+// Check the length with:
+//
+//	len(mockedRepository.GetAuditLogEntriesCalls())
+func (mock *RepositoryMock) GetAuditLogEntriesCalls() []struct {
+	Ctx   context.Context
+	Query entities.AuditLogQuery
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Query entities.AuditLogQuery
+	}
+	mock.lockGetAuditLogEntries.RLock()
+	calls = mock.calls.GetAuditLogEntries
+	mock.lockGetAuditLogEntries.RUnlock()
+	return calls
+}
+
+// GetHashChainHeads calls GetHashChainHeadsFunc.
+func (mock *RepositoryMock) GetHashChainHeads(ctx context.Context, prefix string) ([]entities.HashChainHead, error) {
+	callInfo := struct {
+		Ctx    context.Context
+		Prefix string
+	}{
+		Ctx:    ctx,
+		Prefix: prefix,
+	}
+	mock.lockGetHashChainHeads.Lock()
+	mock.calls.GetHashChainHeads = append(mock.calls.GetHashChainHeads, callInfo)
+	mock.lockGetHashChainHeads.Unlock()
+	if mock.GetHashChainHeadsFunc == nil {
+		var (
+			hashChainHeadsOut []entities.HashChainHead
+			errOut            error
+		)
+		return hashChainHeadsOut, errOut
+	}
+	return mock.GetHashChainHeadsFunc(ctx, prefix)
+}
+
+// GetHashChainHeadsCalls gets all the calls that were made to GetHashChainHeads.
+// Check the length with:
+//
+//	len(mockedRepository.GetHashChainHeadsCalls())
+func (mock *RepositoryMock) GetHashChainHeadsCalls() []struct {
+	Ctx    context.Context
+	Prefix string
+} {
+	var calls []struct {
+		Ctx    context.Context
+		Prefix string
+	}
+	mock.lockGetHashChainHeads.RLock()
+	calls = mock.calls.GetHashChainHeads
+	mock.lockGetHashChainHeads.RUnlock()
+	return calls
+}
+
+// GetInstallmentPlans calls GetInstallmentPlansFunc.
+func (mock *RepositoryMock) GetInstallmentPlans(ctx context.Context, debitorID int64, event string) ([]entities.InstallmentPlan, error) {
+	callInfo := struct {
+		Ctx       context.Context
+		DebitorID int64
+		Event     string
+	}{
+		Ctx:       ctx,
+		DebitorID: debitorID,
+		Event:     event,
+	}
+	mock.lockGetInstallmentPlans.Lock()
+	mock.calls.GetInstallmentPlans = append(mock.calls.GetInstallmentPlans, callInfo)
+	mock.lockGetInstallmentPlans.Unlock()
+	if mock.GetInstallmentPlansFunc == nil {
+		var (
+			installmentPlansOut []entities.InstallmentPlan
+			errOut              error
+		)
+		return installmentPlansOut, errOut
+	}
+	return mock.GetInstallmentPlansFunc(ctx, debitorID, event)
+}
+
+// GetInstallmentPlansCalls gets all the calls that were made to GetInstallmentPlans.
+// Check the length with:
+//
+//	len(mockedRepository.GetInstallmentPlansCalls())
+func (mock *RepositoryMock) GetInstallmentPlansCalls() []struct {
+	Ctx       context.Context
+	DebitorID int64
+	Event     string
+} {
+	var calls []struct {
+		Ctx       context.Context
+		DebitorID int64
+		Event     string
+	}
+	mock.lockGetInstallmentPlans.RLock()
+	calls = mock.calls.GetInstallmentPlans
+	mock.lockGetInstallmentPlans.RUnlock()
+	return calls
+}
+
 // GetTransactionByTransactionIDAndType calls GetTransactionByTransactionIDAndTypeFunc.
 func (mock *RepositoryMock) GetTransactionByTransactionIDAndType(ctx context.Context, transactionID string, tType entities.TransactionType) (*entities.Transaction, error) {
 	callInfo := struct {
@@ -427,6 +1053,90 @@ func (mock *RepositoryMock) GetTransactionLogByIDCalls() []struct {
 	return calls
 }
 
+// GetTransactionLogs calls GetTransactionLogsFunc.
+func (mock *RepositoryMock) GetTransactionLogs(ctx context.Context, afterID uint, limit int) ([]entities.TransactionLog, error) {
+	callInfo := struct {
+		Ctx     context.Context
+		AfterID uint
+		Limit   int
+	}{
+		Ctx:     ctx,
+		AfterID: afterID,
+		Limit:   limit,
+	}
+	mock.lockGetTransactionLogs.Lock()
+	mock.calls.GetTransactionLogs = append(mock.calls.GetTransactionLogs, callInfo)
+	mock.lockGetTransactionLogs.Unlock()
+	if mock.GetTransactionLogsFunc == nil {
+		var (
+			transactionLogsOut []entities.TransactionLog
+			errOut             error
+		)
+		return transactionLogsOut, errOut
+	}
+	return mock.GetTransactionLogsFunc(ctx, afterID, limit)
+}
+
+// GetTransactionLogsCalls gets all the calls that were made to GetTransactionLogs.
+// Check the length with:
+//
+//	len(mockedRepository.GetTransactionLogsCalls())
+func (mock *RepositoryMock) GetTransactionLogsCalls() []struct {
+	Ctx     context.Context
+	AfterID uint
+	Limit   int
+} {
+	var calls []struct {
+		Ctx     context.Context
+		AfterID uint
+		Limit   int
+	}
+	mock.lockGetTransactionLogs.RLock()
+	calls = mock.calls.GetTransactionLogs
+	mock.lockGetTransactionLogs.RUnlock()
+	return calls
+}
+
+// GetTransactionLogsForTransaction calls GetTransactionLogsForTransactionFunc.
+func (mock *RepositoryMock) GetTransactionLogsForTransaction(ctx context.Context, transactionID string) ([]entities.TransactionLog, error) {
+	callInfo := struct {
+		Ctx           context.Context
+		TransactionID string
+	}{
+		Ctx:           ctx,
+		TransactionID: transactionID,
+	}
+	mock.lockGetTransactionLogsForTransaction.Lock()
+	mock.calls.GetTransactionLogsForTransaction = append(mock.calls.GetTransactionLogsForTransaction, callInfo)
+	mock.lockGetTransactionLogsForTransaction.Unlock()
+	if mock.GetTransactionLogsForTransactionFunc == nil {
+		var (
+			transactionLogsOut []entities.TransactionLog
+			errOut             error
+		)
+		return transactionLogsOut, errOut
+	}
+	return mock.GetTransactionLogsForTransactionFunc(ctx, transactionID)
+}
+
+// GetTransactionLogsForTransactionCalls gets all the calls that were made to GetTransactionLogsForTransaction.
+// Check the length with:
+//
+//	len(mockedRepository.GetTransactionLogsForTransactionCalls())
+func (mock *RepositoryMock) GetTransactionLogsForTransactionCalls() []struct {
+	Ctx           context.Context
+	TransactionID string
+} {
+	var calls []struct {
+		Ctx           context.Context
+		TransactionID string
+	}
+	mock.lockGetTransactionLogsForTransaction.RLock()
+	calls = mock.calls.GetTransactionLogsForTransaction
+	mock.lockGetTransactionLogsForTransaction.RUnlock()
+	return calls
+}
+
 // GetTransactionsByFilter calls GetTransactionsByFilterFunc.
 func (mock *RepositoryMock) GetTransactionsByFilter(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
 	callInfo := struct {
@@ -468,13 +1178,15 @@ func (mock *RepositoryMock) GetTransactionsByFilterCalls() []struct {
 }
 
 // GetValidTransactionsForDebitor calls GetValidTransactionsForDebitorFunc.
-func (mock *RepositoryMock) GetValidTransactionsForDebitor(ctx context.Context, debitorID int64) ([]entities.Transaction, error) {
+func (mock *RepositoryMock) GetValidTransactionsForDebitor(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
 	callInfo := struct {
 		Ctx       context.Context
 		DebitorID int64
+		Event     string
 	}{
 		Ctx:       ctx,
 		DebitorID: debitorID,
+		Event:     event,
 	}
 	mock.lockGetValidTransactionsForDebitor.Lock()
 	mock.calls.GetValidTransactionsForDebitor = append(mock.calls.GetValidTransactionsForDebitor, callInfo)
@@ -486,7 +1198,7 @@ func (mock *RepositoryMock) GetValidTransactionsForDebitor(ctx context.Context,
 		)
 		return transactionsOut, errOut
 	}
-	return mock.GetValidTransactionsForDebitorFunc(ctx, debitorID)
+	return mock.GetValidTransactionsForDebitorFunc(ctx, debitorID, event)
 }
 
 // GetValidTransactionsForDebitorCalls gets all the calls that were made to GetValidTransactionsForDebitor.
@@ -496,10 +1208,12 @@ func (mock *RepositoryMock) GetValidTransactionsForDebitor(ctx context.Context,
 func (mock *RepositoryMock) GetValidTransactionsForDebitorCalls() []struct {
 	Ctx       context.Context
 	DebitorID int64
+	Event     string
 } {
 	var calls []struct {
 		Ctx       context.Context
 		DebitorID int64
+		Event     string
 	}
 	mock.lockGetValidTransactionsForDebitor.RLock()
 	calls = mock.calls.GetValidTransactionsForDebitor
@@ -507,6 +1221,46 @@ func (mock *RepositoryMock) GetValidTransactionsForDebitorCalls() []struct {
 	return calls
 }
 
+// IssueDocument calls IssueDocumentFunc.
+func (mock *RepositoryMock) IssueDocument(ctx context.Context, doc entities.IssuedDocument) (*entities.IssuedDocument, error) {
+	callInfo := struct {
+		Ctx context.Context
+		Doc entities.IssuedDocument
+	}{
+		Ctx: ctx,
+		Doc: doc,
+	}
+	mock.lockIssueDocument.Lock()
+	mock.calls.IssueDocument = append(mock.calls.IssueDocument, callInfo)
+	mock.lockIssueDocument.Unlock()
+	if mock.IssueDocumentFunc == nil {
+		var (
+			issuedDocumentOut *entities.IssuedDocument
+			errOut            error
+		)
+		return issuedDocumentOut, errOut
+	}
+	return mock.IssueDocumentFunc(ctx, doc)
+}
+
+// IssueDocumentCalls gets all the calls that were made to IssueDocument.
+// Check the length with:
+//
+//	len(mockedRepository.IssueDocumentCalls())
+func (mock *RepositoryMock) IssueDocumentCalls() []struct {
+	Ctx context.Context
+	Doc entities.IssuedDocument
+} {
+	var calls []struct {
+		Ctx context.Context
+		Doc entities.IssuedDocument
+	}
+	mock.lockIssueDocument.RLock()
+	calls = mock.calls.IssueDocument
+	mock.lockIssueDocument.RUnlock()
+	return calls
+}
+
 // Migrate calls MigrateFunc.
 func (mock *RepositoryMock) Migrate() error {
 	callInfo := struct {
@@ -537,14 +1291,51 @@ func (mock *RepositoryMock) MigrateCalls() []struct {
 	return calls
 }
 
+// Ping calls PingFunc.
+func (mock *RepositoryMock) Ping(ctx context.Context) error {
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockPing.Lock()
+	mock.calls.Ping = append(mock.calls.Ping, callInfo)
+	mock.lockPing.Unlock()
+	if mock.PingFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.PingFunc(ctx)
+}
+
+// PingCalls gets all the calls that were made to Ping.
+// Check the length with:
+//
+//	len(mockedRepository.PingCalls())
+func (mock *RepositoryMock) PingCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockPing.RLock()
+	calls = mock.calls.Ping
+	mock.lockPing.RUnlock()
+	return calls
+}
+
 // QueryOutstandingDuesForDebitor calls QueryOutstandingDuesForDebitorFunc.
-func (mock *RepositoryMock) QueryOutstandingDuesForDebitor(ctx context.Context, debitorID int64) (int64, error) {
+func (mock *RepositoryMock) QueryOutstandingDuesForDebitor(ctx context.Context, debitorID int64, event string) (int64, error) {
 	callInfo := struct {
 		Ctx       context.Context
 		DebitorID int64
+		Event     string
 	}{
 		Ctx:       ctx,
 		DebitorID: debitorID,
+		Event:     event,
 	}
 	mock.lockQueryOutstandingDuesForDebitor.Lock()
 	mock.calls.QueryOutstandingDuesForDebitor = append(mock.calls.QueryOutstandingDuesForDebitor, callInfo)
@@ -556,7 +1347,7 @@ func (mock *RepositoryMock) QueryOutstandingDuesForDebitor(ctx context.Context,
 		)
 		return nOut, errOut
 	}
-	return mock.QueryOutstandingDuesForDebitorFunc(ctx, debitorID)
+	return mock.QueryOutstandingDuesForDebitorFunc(ctx, debitorID, event)
 }
 
 // QueryOutstandingDuesForDebitorCalls gets all the calls that were made to QueryOutstandingDuesForDebitor.
@@ -566,10 +1357,12 @@ func (mock *RepositoryMock) QueryOutstandingDuesForDebitor(ctx context.Context,
 func (mock *RepositoryMock) QueryOutstandingDuesForDebitorCalls() []struct {
 	Ctx       context.Context
 	DebitorID int64
+	Event     string
 } {
 	var calls []struct {
 		Ctx       context.Context
 		DebitorID int64
+		Event     string
 	}
 	mock.lockQueryOutstandingDuesForDebitor.RLock()
 	calls = mock.calls.QueryOutstandingDuesForDebitor
@@ -577,6 +1370,46 @@ func (mock *RepositoryMock) QueryOutstandingDuesForDebitorCalls() []struct {
 	return calls
 }
 
+// QueryOverpaidDebitors calls QueryOverpaidDebitorsFunc.
+func (mock *RepositoryMock) QueryOverpaidDebitors(ctx context.Context, event string) ([]entities.Overpayment, error) {
+	callInfo := struct {
+		Ctx   context.Context
+		Event string
+	}{
+		Ctx:   ctx,
+		Event: event,
+	}
+	mock.lockQueryOverpaidDebitors.Lock()
+	mock.calls.QueryOverpaidDebitors = append(mock.calls.QueryOverpaidDebitors, callInfo)
+	mock.lockQueryOverpaidDebitors.Unlock()
+	if mock.QueryOverpaidDebitorsFunc == nil {
+		var (
+			overpaymentsOut []entities.Overpayment
+			errOut          error
+		)
+		return overpaymentsOut, errOut
+	}
+	return mock.QueryOverpaidDebitorsFunc(ctx, event)
+}
+
+// QueryOverpaidDebitorsCalls gets all the calls that were made to QueryOverpaidDebitors.
+// Check the length with:
+//
+//	len(mockedRepository.QueryOverpaidDebitorsCalls())
+func (mock *RepositoryMock) QueryOverpaidDebitorsCalls() []struct {
+	Ctx   context.Context
+	Event string
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Event string
+	}
+	mock.lockQueryOverpaidDebitors.RLock()
+	calls = mock.calls.QueryOverpaidDebitors
+	mock.lockQueryOverpaidDebitors.RUnlock()
+	return calls
+}
+
 // UpdateTransaction calls UpdateTransactionFunc.
 func (mock *RepositoryMock) UpdateTransaction(ctx context.Context, tr entities.Transaction, historize bool) error {
 	callInfo := struct {
@@ -619,3 +1452,42 @@ func (mock *RepositoryMock) UpdateTransactionCalls() []struct {
 	mock.lockUpdateTransaction.RUnlock()
 	return calls
 }
+
+// UpdateTransactions calls UpdateTransactionsFunc.
+func (mock *RepositoryMock) UpdateTransactions(ctx context.Context, trs []entities.Transaction) error {
+	callInfo := struct {
+		Ctx context.Context
+		Trs []entities.Transaction
+	}{
+		Ctx: ctx,
+		Trs: trs,
+	}
+	mock.lockUpdateTransactions.Lock()
+	mock.calls.UpdateTransactions = append(mock.calls.UpdateTransactions, callInfo)
+	mock.lockUpdateTransactions.Unlock()
+	if mock.UpdateTransactionsFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.UpdateTransactionsFunc(ctx, trs)
+}
+
+// UpdateTransactionsCalls gets all the calls that were made to UpdateTransactions.
+// Check the length with:
+//
+//	len(mockedRepository.UpdateTransactionsCalls())
+func (mock *RepositoryMock) UpdateTransactionsCalls() []struct {
+	Ctx context.Context
+	Trs []entities.Transaction
+} {
+	var calls []struct {
+		Ctx context.Context
+		Trs []entities.Transaction
+	}
+	mock.lockUpdateTransactions.RLock()
+	calls = mock.calls.UpdateTransactions
+	mock.lockUpdateTransactions.RUnlock()
+	return calls
+}
@@ -0,0 +1,98 @@
+package interaction
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+)
+
+// epcMaxAmountCent is the largest amount the EPC QR code guidelines allow, 999999999.99 EUR
+const epcMaxAmountCent = 99999999999
+
+// GetSepaQRPayload returns the EPC QR code (GiroCode) payload of an open sepa transfer, which banking apps scan
+// to fill in beneficiary, amount and the transaction id as remittance information.
+//
+// The same rules as for GetTransactionsForDebitor apply, so registered users may only get payloads for their own registrations.
+func (s *serviceInteractor) GetSepaQRPayload(ctx context.Context, transactionID string) (string, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	sepa, err := configuredSepa(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// will not return deleted transactions
+	transactions, err := s.store.GetTransactionsByFilter(ctx, transactionQuery(transactionID))
+	if err != nil {
+		return "", err
+	}
+	if len(transactions) == 0 {
+		return "", apierrors.NewNotFound(fmt.Sprintf("transaction %s could not be found", transactionID))
+	}
+	tran := transactions[0]
+
+	err = s.authorizeDocument(ctx, mgr, tran.DebitorID)
+	if err == nil && !isOpenSepaTransfer(tran) {
+		err = apierrors.NewConflict(fmt.Sprintf("transaction %s is not an open sepa transfer in EUR, qr codes are only available for those", transactionID))
+	}
+	s.recordAudit(ctx, mgr, auditActionReadSepaQR, tran.DebitorID, transactionID, "", err)
+	if err != nil {
+		return "", err
+	}
+
+	return epcPayload(sepa, tran), nil
+}
+
+func isOpenSepaTransfer(tran entities.Transaction) bool {
+	return tran.TransactionType == entities.TransactionTypePayment &&
+		tran.PaymentMethod == entities.PaymentMethodTransfer &&
+		(tran.TransactionStatus == entities.TransactionStatusTentative || tran.TransactionStatus == entities.TransactionStatusPending) &&
+		tran.Amount.ISOCurrency == "EUR" &&
+		tran.Amount.GrossCent > 0 && tran.Amount.GrossCent <= epcMaxAmountCent
+}
+
+// epcPayload builds the payload following EPC069-12 version 002 in UTF-8. The creditor reference is used as
+// structured remittance information, the transaction id as unstructured remittance information for payments
+// without one, as only one of them may be given. The trailing optional lines are left out, as the guidelines permit.
+func epcPayload(sepa config.SepaConfig, tran entities.Transaction) string {
+	structured, unstructured := tran.CreditorReference.String, ""
+	if !tran.CreditorReference.Valid {
+		structured, unstructured = "", tran.TransactionID
+	}
+
+	return strings.TrimRight(strings.Join([]string{
+		"BCD",
+		"002",
+		"1", // UTF-8
+		"SCT",
+		sepa.BIC,
+		sepa.BeneficiaryName,
+		sepa.IBAN,
+		fmt.Sprintf("EUR%d.%02d", tran.Amount.GrossCent/100, tran.Amount.GrossCent%100),
+		"", // purpose
+		structured,
+		unstructured,
+	}, "\n"), "\n")
+}
+
+func configuredSepa(ctx context.Context) (config.SepaConfig, error) {
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return config.SepaConfig{}, err
+	}
+
+	if !appConfig.Service.Sepa.IsConfigured() {
+		logging.LoggerFromContext(ctx).Error("sepa qr code requested, but service.sepa.beneficiary_name is not configured")
+		return config.SepaConfig{}, apierrors.NewInternalServerError("sepa qr codes are not configured")
+	}
+
+	return appConfig.Service.Sepa, nil
+}
@@ -0,0 +1,87 @@
+package interaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+)
+
+func withSepa(t *testing.T) {
+	original, err := config.GetApplicationConfig()
+	require.NoError(t, err)
+
+	conf := *original
+	conf.Service.Sepa = config.SepaConfig{
+		BeneficiaryName: "Eurofurence e.V.",
+		IBAN:            "DE89370400440532013000",
+		BIC:             "COBADEFFXXX",
+	}
+	require.NoError(t, config.Reload(&conf, t.Logf))
+	t.Cleanup(func() {
+		require.NoError(t, config.Reload(original, t.Logf))
+	})
+}
+
+func TestGetSepaQRPayload(t *testing.T) {
+	withSepa(t)
+
+	db := inmemory.NewInMemoryProvider()
+	seed := documentSeed()
+	chf := newTransaction(10, "EF2023-000010-0102-120000-0008", entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusPending, entities.Amount{
+		ISOCurrency: "CHF",
+		GrossCent:   39_00,
+	})
+	referenced := newTransaction(10, "EF2023-000010-0102-120000-0009", entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusTentative, entities.Amount{
+		ISOCurrency: "EUR",
+		GrossCent:   1234_56,
+	})
+	referenced.CreditorReference = creditorReferenceFor(&referenced)
+	seedDB(db, append(seed, chf, referenced))
+
+	asm := &AttendeeServiceMock{
+		ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+			return []int64{10}, nil
+		},
+	}
+	i := tstServiceInteractor(db, asm, &CncrdAdapterMock{})
+
+	payload, err := i.GetSepaQRPayload(attendeeCtx(), "EF2023-000010-0102-120000-0004")
+	require.NoError(t, err)
+	require.Equal(t, "BCD\n002\n1\nSCT\nCOBADEFFXXX\nEurofurence e.V.\nDE89370400440532013000\nEUR39.00\n\n\nEF2023-000010-0102-120000-0004", payload)
+
+	// the creditor reference replaces the transaction id, and may be used to look up the transaction
+	payload, err = i.GetSepaQRPayload(adminCtx(), "RF69 EF20 2300 009U TYSG VMKP")
+	require.NoError(t, err)
+	require.Equal(t, "BCD\n002\n1\nSCT\nCOBADEFFXXX\nEurofurence e.V.\nDE89370400440532013000\nEUR1234.56\n\nRF69EF202300009UTYSGVMKP", payload)
+
+	_, err = i.GetSepaQRPayload(attendeeCtx(), "EF2023-000011-0101-120000-0006")
+	require.EqualError(t, err, apierrors.NewForbidden("subject 1234567890 may not retrieve documents for debitor 11").Error())
+
+	// already paid
+	_, err = i.GetSepaQRPayload(adminCtx(), "EF2023-000010-0102-120000-0003")
+	require.EqualError(t, err, apierrors.NewConflict("transaction EF2023-000010-0102-120000-0003 is not an open sepa transfer in EUR, qr codes are only available for those").Error())
+
+	_, err = i.GetSepaQRPayload(adminCtx(), "EF2023-000010-0102-120000-0008")
+	require.True(t, apierrors.IsConflictError(err))
+
+	_, err = i.GetSepaQRPayload(adminCtx(), "EF2023-000010-0101-120000-0001")
+	require.True(t, apierrors.IsConflictError(err))
+
+	_, err = i.GetSepaQRPayload(adminCtx(), "EF2023-000010-0101-120000-9999")
+	require.EqualError(t, err, apierrors.NewNotFound("transaction EF2023-000010-0101-120000-9999 could not be found").Error())
+}
+
+func TestGetSepaQRPayloadWithoutConfig(t *testing.T) {
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, documentSeed())
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	_, err := i.GetSepaQRPayload(adminCtx(), "EF2023-000010-0102-120000-0004")
+	require.EqualError(t, err, apierrors.NewInternalServerError("sepa qr codes are not configured").Error())
+}
@@ -3,6 +3,7 @@ package interaction
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/eurofurence/reg-payment-service/internal/entities"
 	"github.com/eurofurence/reg-payment-service/internal/repository/database"
@@ -14,9 +15,28 @@ var _ Interactor = (*serviceInteractor)(nil)
 
 type Interactor interface {
 	GetTransactionsForDebitor(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error)
+	GetTransaction(ctx context.Context, transactionID string) (*TransactionDetails, error)
 	CreateTransaction(ctx context.Context, tran *entities.Transaction) (*entities.Transaction, error)
 	CreateTransactionForOutstandingDues(ctx context.Context, debitorID int64, method entities.PaymentMethod) (*entities.Transaction, error)
+	CreateGroupPaymentForOutstandingDues(ctx context.Context, debitorIDs []int64, method entities.PaymentMethod) (*GroupPayment, error)
+	UpdateGroupPayment(ctx context.Context, groupReference string, status entities.TransactionStatus, comment string, info entities.PaymentProcessorInformation) ([]entities.Transaction, error)
 	UpdateTransaction(ctx context.Context, tran *entities.Transaction) error
+	RegistrationsChanged(ctx context.Context, subject string) error
+	GetAuditLog(ctx context.Context, query entities.AuditLogQuery) ([]entities.AuditLogEntry, error)
+	GetLedgerCheckpoint(ctx context.Context) (*entities.LedgerCheckpoint, error)
+	CheckReadiness(ctx context.Context) *HealthReport
+	GetBalance(ctx context.Context, debitorID int64, event string) (*Balance, error)
+	VoidTentativePayments(ctx context.Context, debitorID int64) ([]string, error)
+	ResendPaymentsChanged(ctx context.Context, debitorID int64) error
+	ListOverpayments(ctx context.Context, event string) ([]Overpayment, error)
+	ResolveOverpayment(ctx context.Context, debitorID int64, resolution OverpaymentResolution) ([]entities.Transaction, error)
+	TransferBalance(ctx context.Context, sourceDebitorID int64, targetDebitorID int64, amountCent int64, comment string) (*BalanceTransfer, error)
+	GetInstallmentPlan(ctx context.Context, debitorID int64) (*InstallmentSchedule, error)
+	AttachInstallmentPlan(ctx context.Context, debitorID int64, plan string, firstDueDate time.Time) (*InstallmentSchedule, error)
+	RemoveInstallmentPlan(ctx context.Context, debitorID int64) error
+	GetReceipt(ctx context.Context, transactionID string) (*Document, error)
+	GetInvoice(ctx context.Context, debitorID int64, event string) (*Document, error)
+	GetSepaQRPayload(ctx context.Context, transactionID string) (string, error)
 }
 
 type serviceInteractor struct {
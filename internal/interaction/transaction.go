@@ -4,9 +4,11 @@ import (
 	"context"
 	"crypto/rand"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math/big"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -15,16 +17,22 @@ import (
 	"github.com/eurofurence/reg-payment-service/internal/config"
 	"github.com/eurofurence/reg-payment-service/internal/entities"
 	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/metrics"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
 	"github.com/eurofurence/reg-payment-service/internal/repository/downstreams/cncrdadapter"
 )
 
 const (
 	transactionIDTimeFormat = "0102-150405" // MMDD-HHmmss
+
+	// maxTransactionIDAttempts limits how often a generated transaction id is replaced after a collision
+	maxTransactionIDAttempts = 3
 )
 
 var (
 	debRegex       = regexp.MustCompile(`^\d{6,}$`)
 	randDigitRegex = regexp.MustCompile(`^\d{4}$`)
+	allDigitsRegex = regexp.MustCompile(`^\d+$`)
 )
 
 func (s *serviceInteractor) GetTransactionsForDebitor(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
@@ -34,6 +42,12 @@ func (s *serviceInteractor) GetTransactionsForDebitor(ctx context.Context, query
 		return nil, err
 	}
 
+	// the transaction identifier may also be given as creditor reference
+	if ref := entities.NormalizeCreditorReference(query.TransactionIdentifier); ref != "" {
+		query.TransactionIdentifier = ""
+		query.CreditorReference = ref
+	}
+
 	if mgr.IsRegisteredUser() {
 		regIDs, err := s.attendeeClient.ListMyRegistrationIds(ctx)
 		if err != nil {
@@ -42,7 +56,9 @@ func (s *serviceInteractor) GetTransactionsForDebitor(ctx context.Context, query
 		}
 
 		if !containsDebitor(regIDs, query.DebitorID) {
-			return nil, apierrors.NewForbidden(fmt.Sprintf("subject %s may not retrieve transactions for debitor %d", mgr.Subject(), query.DebitorID))
+			err := apierrors.NewForbidden(fmt.Sprintf("subject %s may not retrieve transactions for debitor %d", mgr.Subject(), query.DebitorID))
+			s.recordAudit(ctx, mgr, auditActionReadTransactions, query.DebitorID, query.TransactionIdentifier, "", err)
+			return nil, err
 		}
 
 		// will not return deleted transactions
@@ -51,14 +67,97 @@ func (s *serviceInteractor) GetTransactionsForDebitor(ctx context.Context, query
 		return transactions, err
 	}
 
-	if mgr.IsAdmin() || mgr.IsAPITokenCall() {
+	if mgr.IsAdmin() || mgr.IsAPITokenCall() || mgr.IsSystemCall() {
 		// return transactions in any state
 		transactions, err := s.store.GetAdminTransactionsByFilter(ctx, query)
+		s.recordAudit(ctx, mgr, auditActionReadTransactions, query.DebitorID, query.TransactionIdentifier, "", err)
 		sortByTxID(transactions)
 		return transactions, err
 	}
 
-	return nil, apierrors.NewForbidden("unable to determine the request permissions")
+	err = apierrors.NewForbidden("unable to determine the request permissions")
+	s.recordAudit(ctx, mgr, auditActionReadTransactions, query.DebitorID, query.TransactionIdentifier, "", err)
+	return nil, err
+}
+
+// TransactionDetails is a single transaction together with its log entries, oldest first
+type TransactionDetails struct {
+	Transaction entities.Transaction
+	History     []entities.TransactionLog
+}
+
+// GetTransaction looks up a single transaction by its identifier.
+//
+// The identifier may also be the creditor reference of the transaction.
+//
+// Registered users may only read non-deleted transactions of their own registrations,
+// admins, the api token and the command line may read any transaction.
+func (s *serviceInteractor) GetTransaction(ctx context.Context, transactionID string) (*TransactionDetails, error) {
+	logger := logging.LoggerFromContext(ctx)
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := transactionQuery(transactionID)
+	notFound := apierrors.NewNotFound(fmt.Sprintf("transaction %s could not be found", transactionID))
+
+	if mgr.IsRegisteredUser() {
+		regIDs, err := s.attendeeClient.ListMyRegistrationIds(ctx)
+		if err != nil {
+			logger.Error("could not call the attendee service. [error]: %v", err)
+			return nil, apierrors.NewInternalServerError("attendee service error - see log for details")
+		}
+
+		// will not return deleted transactions
+		transactions, err := s.store.GetTransactionsByFilter(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		if len(transactions) == 0 {
+			return nil, notFound
+		}
+
+		if !containsDebitor(regIDs, transactions[0].DebitorID) {
+			err := apierrors.NewForbidden(fmt.Sprintf("subject %s may not retrieve transactions for debitor %d", mgr.Subject(), transactions[0].DebitorID))
+			s.recordAudit(ctx, mgr, auditActionReadTransactions, transactions[0].DebitorID, transactionID, "", err)
+			return nil, err
+		}
+
+		return s.transactionDetails(ctx, transactions[0])
+	}
+
+	if mgr.IsAdmin() || mgr.IsAPITokenCall() || mgr.IsSystemCall() {
+		// return the transaction in any state
+		transactions, err := s.store.GetAdminTransactionsByFilter(ctx, query)
+		if err == nil && len(transactions) == 0 {
+			err = notFound
+		}
+
+		var debitorID int64
+		if len(transactions) > 0 {
+			debitorID = transactions[0].DebitorID
+		}
+		s.recordAudit(ctx, mgr, auditActionReadTransactions, debitorID, transactionID, "", err)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.transactionDetails(ctx, transactions[0])
+	}
+
+	err = apierrors.NewForbidden("unable to determine the request permissions")
+	s.recordAudit(ctx, mgr, auditActionReadTransactions, 0, transactionID, "", err)
+	return nil, err
+}
+
+func (s *serviceInteractor) transactionDetails(ctx context.Context, tran entities.Transaction) (*TransactionDetails, error) {
+	history, err := s.store.GetTransactionLogsForTransaction(ctx, tran.TransactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionDetails{Transaction: tran, History: history}, nil
 }
 
 func sortByTxID(transactions []entities.Transaction) {
@@ -76,51 +175,82 @@ func (s *serviceInteractor) CreateTransaction(ctx context.Context, tran *entitie
 		return nil, err
 	}
 
-	// check if currency is allowed
-	if !isCurrencyAllowed(appConfig.Service.AllowedCurrencies, tran.Amount.ISOCurrency) {
+	// default for effective date
+	if !tran.EffectiveDate.Valid {
+		tran.EffectiveDate = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	event, err := resolveEvent(appConfig, tran)
+	if err != nil {
+		return nil, err
+	}
+	tran.Event = event.Name
+
+	// check if currency and vat rate are allowed for the event
+	if !event.IsCurrencyAllowed(tran.Amount.ISOCurrency, appConfig.Service.AllowedCurrencies) {
 		return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid currency %s provided", tran.Amount.ISOCurrency))
 	}
+	if !event.IsVatRateAllowed(tran.Amount.VatRate) {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid vat rate %.2f provided for event %s", tran.Amount.VatRate, event.Name))
+	}
+	for _, l := range tran.LineItems {
+		if !event.IsVatRateAllowed(l.VatRate) {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid vat rate %.2f provided for line item %d of event %s", l.VatRate, l.Position, event.Name))
+		}
+	}
 
 	// generate a transaction ID if none exists
+	idPrefix := ""
 	if tran.TransactionID == "" {
-		id, err := generateTransactionID(appConfig.Service.TransactionIDPrefix, tran)
+		idPrefix = event.Prefix()
+		id, err := generateTransactionID(idPrefix, tran)
 		if err != nil {
 			return nil, err
 		}
 
 		tran.TransactionID = id
 	} else {
-		// if a transaction ID is provided, it should be validated against the allowed format (starts with configured prefix, and has the correct number of segments etc.)
+		// if a transaction ID is provided, it should be validated against the allowed format (starts with the prefix of the event, and has the correct number of segments etc.)
 		// (because we allow create with transaction ID set)
-		if !validateTransactionID(appConfig.Service.TransactionIDPrefix, tran.TransactionID) {
+		if !validateTransactionID(event.Prefix(), tran.TransactionID) {
 			return nil, apierrors.NewBadRequest("Invalid format for `TransactionID`")
 		}
+		if id, ok := transactionIDFromCreditorReference(event.Prefix(), tran.TransactionID); ok {
+			tran.TransactionID = id
+		}
 	}
-
-	// default for effective date
-	if !tran.EffectiveDate.Valid {
-		tran.EffectiveDate = sql.NullTime{Time: time.Now(), Valid: true}
-	}
+	tran.CreditorReference = creditorReferenceFor(tran)
 
 	mgr, err := NewRBACValidator(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	if mgr.IsAdmin() || mgr.IsAPITokenCall() {
-		return s.createTransactionWithElevatedAccess(ctx, tran, mgr)
+	if mgr.IsAdmin() || mgr.IsAPITokenCall() || mgr.IsSystemCall() {
+		if err := validateProcessorInfo(tran.ProcessorInfo); err != nil {
+			return nil, err
+		}
+
+		created, err := s.createTransactionWithElevatedAccess(ctx, tran, mgr, idPrefix)
+		s.recordAudit(ctx, mgr, auditActionCreateTransaction, tran.DebitorID, tran.TransactionID, transactionDiff(nil, tran), err)
+		return created, err
 	}
 
 	if mgr.IsRegisteredUser() {
+		// only the payment provider adapters and admins provide payment processor information
+		tran.ProcessorInfo = nil
+
 		// check if attendee is permitted to create this transaction
 		if err := s.validateAttendeeTransaction(ctx, tran); err != nil {
+			s.recordAudit(ctx, mgr, auditActionCreateTransaction, tran.DebitorID, tran.TransactionID, transactionDiff(nil, tran), err)
 			return nil, err
 		}
 
 		// create a transaction in the database
-		if err := s.store.CreateTransaction(ctx, *tran); err != nil {
+		if err := s.storeNewTransaction(ctx, tran, idPrefix); err != nil {
 			return nil, err
 		}
+		transactionCreated(tran)
 
 		// generate a payment link
 		paymentLink, err := s.createPaymentLink(ctx, *tran)
@@ -142,7 +272,9 @@ func (s *serviceInteractor) CreateTransaction(ctx context.Context, tran *entitie
 		return tran, nil
 	}
 
-	return nil, apierrors.NewForbidden("unable to determine the request permissions")
+	err = apierrors.NewForbidden("unable to determine the request permissions")
+	s.recordAudit(ctx, mgr, auditActionCreateTransaction, tran.DebitorID, tran.TransactionID, transactionDiff(nil, tran), err)
+	return nil, err
 }
 
 func (s *serviceInteractor) CreateTransactionForOutstandingDues(ctx context.Context, debitorID int64, method entities.PaymentMethod) (*entities.Transaction, error) {
@@ -151,8 +283,13 @@ func (s *serviceInteractor) CreateTransactionForOutstandingDues(ctx context.Cont
 		return nil, err
 	}
 
-	logging.LoggerFromContext(ctx)
-	validTransactions, err := s.store.GetValidTransactionsForDebitor(ctx, debitorID)
+	// outstanding dues are paid for the current event
+	event, err := currentEvent(appConfig, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	validTransactions, err := s.store.GetValidTransactionsForDebitor(ctx, debitorID, eventFilter(appConfig, event.Name))
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +300,7 @@ func (s *serviceInteractor) CreateTransactionForOutstandingDues(ctx context.Cont
 
 	first := validTransactions[0]
 
-	dues, err := s.store.QueryOutstandingDuesForDebitor(ctx, debitorID)
+	dues, err := s.store.QueryOutstandingDuesForDebitor(ctx, debitorID, eventFilter(appConfig, event.Name))
 	if err != nil {
 		return nil, err
 	}
@@ -181,16 +318,30 @@ func (s *serviceInteractor) CreateTransactionForOutstandingDues(ctx context.Cont
 		return nil, apierrors.NewBadRequest("payment method not available for initiate-payment")
 	}
 
+	// with an installment plan, only the next installment is paid
+	amount := dues
+	var dueDate sql.NullTime
+	schedule, err := s.currentInstallmentSchedule(ctx, debitorID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule != nil && schedule.NextAmountCent > 0 {
+		amount = schedule.NextAmountCent
+		dueDate = sql.NullTime{Time: schedule.Next.DueDate, Valid: true}
+	}
+
 	return s.CreateTransaction(ctx, &entities.Transaction{
 		DebitorID:         debitorID,
 		TransactionType:   entities.TransactionTypePayment,
 		PaymentMethod:     method,
 		TransactionStatus: entities.TransactionStatusTentative,
 		Comment:           comment,
+		Event:             event.Name,
+		DueDate:           dueDate,
 		Amount: entities.Amount{
 			ISOCurrency: first.Amount.ISOCurrency,
 			VatRate:     first.Amount.VatRate,
-			GrossCent:   dues,
+			GrossCent:   amount,
 		},
 	})
 }
@@ -201,53 +352,119 @@ func (s *serviceInteractor) UpdateTransaction(ctx context.Context, tran *entitie
 		return err
 	}
 
+	before, after, err := s.updateTransaction(ctx, tran, mgr)
+	if tran == nil {
+		return err
+	}
+
+	action := auditActionUpdateTransaction
+	if tran.TransactionStatus == entities.TransactionStatusDeleted {
+		action = auditActionDeleteTransaction
+	}
+	s.recordAudit(ctx, mgr, action, tran.DebitorID, tran.TransactionID, transactionDiff(before, after), err)
+
+	return err
+}
+
+// updateTransaction returns the transaction as it was before the change (if it was found),
+// and as it should be after the change, for the audit log.
+func (s *serviceInteractor) updateTransaction(ctx context.Context, tran *entities.Transaction, mgr *RBACValidator) (*entities.Transaction, *entities.Transaction, error) {
+	update, err := s.checkTransactionUpdate(ctx, tran, mgr)
+	if err != nil {
+		return update.before, update.after, err
+	}
+
+	if update.delete {
+		err = s.store.DeleteTransaction(ctx, *update.after)
+	} else {
+		err = s.store.UpdateTransaction(ctx, *update.after, update.historize)
+	}
+	if err != nil {
+		return update.before, update.after, err
+	}
+
+	s.transactionUpdated(ctx, update)
+
+	return update.before, update.after, nil
+}
+
+// transactionUpdate is a change of a transaction that passed all checks and can be stored.
+type transactionUpdate struct {
+	before *entities.Transaction
+	after  *entities.Transaction
+	// delete flags a valid payment deleted, instead of updating it
+	delete    bool
+	historize bool
+}
+
+// checkTransactionUpdate checks if the change may be made, without storing anything.
+//
+// On error, the update still holds the transaction as it was before the change (if it was found),
+// and as it should be after the change, for the audit log.
+func (s *serviceInteractor) checkTransactionUpdate(ctx context.Context, tran *entities.Transaction, mgr *RBACValidator) (transactionUpdate, error) {
 	logger := logging.LoggerFromContext(ctx)
 
-	if mgr.IsAdmin() || mgr.IsAPITokenCall() {
+	if mgr.IsAdmin() || mgr.IsAPITokenCall() || mgr.IsSystemCall() {
 		// ok
 	} else if mgr.IsRegisteredUser() {
 		// registered users may update some of their own transactions, but only from tentative to pending
 		regIDs, err := s.attendeeClient.ListMyRegistrationIds(ctx)
 		if err != nil {
 			logger.Error("could not call the attendee service. [error]: %v", err)
-			return apierrors.NewInternalServerError("attendee service error - see log for details")
+			return transactionUpdate{after: tran}, apierrors.NewInternalServerError("attendee service error - see log for details")
 		}
 
 		if !containsDebitor(regIDs, tran.DebitorID) {
-			return apierrors.NewForbidden(fmt.Sprintf("subject %s may not access transactions for debitor %d", mgr.Subject(), tran.DebitorID))
+			return transactionUpdate{after: tran}, apierrors.NewForbidden(fmt.Sprintf("subject %s may not access transactions for debitor %d", mgr.Subject(), tran.DebitorID))
 		}
 	} else {
-		return apierrors.NewForbidden("no permission to update transaction")
+		return transactionUpdate{after: tran}, apierrors.NewForbidden("no permission to update transaction")
 	}
 
-	res, err := s.store.GetTransactionsByFilter(ctx, entities.TransactionQuery{
-		DebitorID:             tran.DebitorID,
-		TransactionIdentifier: tran.TransactionID,
-	})
+	query := transactionQuery(tran.TransactionID)
+	query.DebitorID = tran.DebitorID
+	res, err := s.store.GetTransactionsByFilter(ctx, query)
 
 	if err != nil {
-		return err
+		return transactionUpdate{after: tran}, err
 	}
 
 	if len(res) == 0 {
-		return apierrors.NewNotFound(
+		return transactionUpdate{after: tran}, apierrors.NewNotFound(
 			fmt.Sprintf("transaction %s for debitor %d could not be found", tran.TransactionID, tran.DebitorID),
 		)
 	}
 
 	curTran := res[0]
+	before := curTran
+
+	// the transaction may have been given by its creditor reference
+	tran.TransactionID = curTran.TransactionID
+
+	// transactions cannot move between events or group payments, and keep their creditor reference
+	tran.Event = curTran.Event
+	tran.GroupReference = curTran.GroupReference
+	tran.CreditorReference = curTran.CreditorReference
+
+	// only the payment provider adapters and admins may change the payment processor information,
+	// leaving it empty keeps it unchanged
+	if len(tran.ProcessorInfo) == 0 || !(mgr.IsAdmin() || mgr.IsAPITokenCall() || mgr.IsSystemCall()) {
+		tran.ProcessorInfo = curTran.ProcessorInfo
+	} else if err := validateProcessorInfo(tran.ProcessorInfo); err != nil {
+		return transactionUpdate{before: &before, after: tran}, err
+	}
 
 	if curTran.TransactionType == entities.TransactionTypeDue {
-		return apierrors.NewForbidden("cannot change transactions of type due")
+		return transactionUpdate{before: &before, after: tran}, apierrors.NewForbidden("cannot change transactions of type due")
 	}
 
-	if !mgr.IsAdmin() && !mgr.IsAPITokenCall() {
+	if !mgr.IsAdmin() && !mgr.IsAPITokenCall() && !mgr.IsSystemCall() {
 		// non-admin users may only change transactions in status Tentative to Pending
 		if curTran.TransactionStatus != entities.TransactionStatusTentative ||
 			tran.TransactionStatus != entities.TransactionStatusPending ||
 			tran.TransactionType != entities.TransactionTypePayment {
 			logger.Warn("forbidden attempt to change transaction %s to target status %s by subject %s", tran.TransactionID, tran.TransactionStatus, mgr.Subject())
-			return apierrors.NewForbidden(fmt.Sprintf("subject %s may not make this transaction change - the attempt has been logged", mgr.Subject()))
+			return transactionUpdate{before: &before, after: tran}, apierrors.NewForbidden(fmt.Sprintf("subject %s may not make this transaction change - the attempt has been logged", mgr.Subject()))
 		}
 	}
 
@@ -265,7 +482,7 @@ func (s *serviceInteractor) UpdateTransaction(ctx context.Context, tran *entitie
 		days := time.Now().UTC().Sub(curTran.CreatedAt.UTC()).Hours() / 24.0
 
 		if days > maxDaysForDeletion {
-			return apierrors.NewForbidden("unable to flag valid transaction as deleted after 3 days, please book a compensating transaction instead")
+			return transactionUpdate{before: &before, after: tran}, apierrors.NewForbidden("unable to flag valid transaction as deleted after 3 days, please book a compensating transaction instead")
 		}
 
 		// remember old values and who made the change
@@ -277,22 +494,7 @@ func (s *serviceInteractor) UpdateTransaction(ctx context.Context, tran *entitie
 		curTran.TransactionStatus = entities.TransactionStatusDeleted
 		curTran.Comment = tran.Comment
 
-		if err := s.store.DeleteTransaction(ctx, curTran); err != nil {
-			return err
-		}
-
-		// inform the attendee service that a transaction was deleted
-		if tran.TransactionType == entities.TransactionTypePayment {
-			if err := s.attendeeClient.PaymentsChanged(ctx, uint(tran.DebitorID)); err != nil {
-				// only log an error when the call was not successful but don't cause an internal server error
-				logger.Error("error when calling the attendee service webhook. [error]: %v", err)
-			}
-		}
-
-		logger.Warn("admin successfully deleted valid payment %s", tran.TransactionID)
-
-		return nil
-
+		return transactionUpdate{before: &before, after: &curTran, delete: true}, nil
 	}
 
 	requireHistorization := false
@@ -301,7 +503,7 @@ func (s *serviceInteractor) UpdateTransaction(ctx context.Context, tran *entitie
 	//    (The previous status is always historized, see the history field)
 	if tran.TransactionStatus != curTran.TransactionStatus {
 		if !isValidStatusChange(curTran, *tran) {
-			return apierrors.NewForbidden(
+			return transactionUpdate{before: &before, after: tran}, apierrors.NewForbidden(
 				fmt.Sprintf("cannot change status from %s to %s for transaction %s",
 					curTran.TransactionStatus,
 					tran.TransactionStatus,
@@ -312,25 +514,31 @@ func (s *serviceInteractor) UpdateTransaction(ctx context.Context, tran *entitie
 		requireHistorization = true
 	}
 
-	if err := s.store.UpdateTransaction(ctx, *tran, requireHistorization); err != nil {
-		return err
+	return transactionUpdate{before: &before, after: tran, historize: requireHistorization}, nil
+}
+
+// transactionUpdated informs the attendee service about a stored change of a payment.
+func (s *serviceInteractor) transactionUpdated(ctx context.Context, update transactionUpdate) {
+	logger := logging.LoggerFromContext(ctx)
+
+	if update.delete {
+		logger.Warn("admin successfully deleted valid payment %s", update.after.TransactionID)
 	}
 
-	if tran.TransactionType == entities.TransactionTypePayment {
-		// inform the attendee service that a transaction was updated
-		if err := s.attendeeClient.PaymentsChanged(ctx, uint(tran.DebitorID)); err != nil {
+	if update.after.TransactionType == entities.TransactionTypePayment {
+		// inform the attendee service that a transaction was updated or deleted
+		if err := s.attendeeClient.PaymentsChanged(ctx, uint(update.after.DebitorID)); err != nil {
 			// only log an error when the call was not successful but don't cause an internal server error
 			logger.Error("error when calling the attendee service webhook. [error]: %v", err)
 		}
 	}
-
-	return nil
 }
 
 func (s *serviceInteractor) createTransactionWithElevatedAccess(
 	ctx context.Context,
 	tran *entities.Transaction,
-	mgr *RBACValidator) (*entities.Transaction, error) {
+	mgr *RBACValidator,
+	idPrefix string) (*entities.Transaction, error) {
 
 	logger := logging.LoggerFromContext(ctx)
 
@@ -345,10 +553,11 @@ func (s *serviceInteractor) createTransactionWithElevatedAccess(
 
 		// We first make sure that we successfully persisted the transaction
 		// in the DB before requesting a payment link if applicable
-		err := s.store.CreateTransaction(ctx, *tran)
+		err := s.storeNewTransaction(ctx, tran, idPrefix)
 		if err != nil {
 			return nil, err
 		}
+		transactionCreated(tran)
 
 		// create payment link if
 		// transaction_type=payment, method=credit, status=tentative
@@ -378,16 +587,17 @@ func (s *serviceInteractor) createTransactionWithElevatedAccess(
 	} else {
 		// create new due transaction - must be created in status valid
 		tran.TransactionStatus = entities.TransactionStatusValid
-		err := s.store.CreateTransaction(ctx, *tran)
+		err := s.storeNewTransaction(ctx, tran, idPrefix)
 		if err != nil {
 			return tran, err
 		}
+		transactionCreated(tran)
 
 		// invalidate existing paylinks by marking their transactions deleted
 		//
 		// do not trigger payments changed webhook, because that may cause an update cycle
 		// (the only one adding dues is the attendee service anyway, and we're only changing tentative payments here, which do not count yet anyway)
-		err = s.invalidateTentativePayments(ctx, tran.DebitorID)
+		_, err = s.invalidateTentativePayments(ctx, tran.DebitorID, tran.Event, "internal", "voided paylink - dues have changed")
 		if err != nil {
 			return tran, err
 		}
@@ -396,12 +606,22 @@ func (s *serviceInteractor) createTransactionWithElevatedAccess(
 	}
 }
 
-func (s *serviceInteractor) invalidateTentativePayments(ctx context.Context, debitorID int64) error {
-	transactions, err := s.store.GetTransactionsByFilter(ctx, entities.TransactionQuery{DebitorID: debitorID})
+// invalidateTentativePayments marks the paylinks of the debitor for the event deleted, or for all events if event is empty,
+// and returns the ids of their transactions.
+func (s *serviceInteractor) invalidateTentativePayments(ctx context.Context, debitorID int64, event string, by string, comment string) ([]string, error) {
+	appConfig, err := config.GetApplicationConfig()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	transactions, err := s.store.GetTransactionsByFilter(ctx, entities.TransactionQuery{DebitorID: debitorID, Event: eventFilter(appConfig, event)})
+	if err != nil {
+		return nil, err
+	}
+
+	voided := make([]string, 0)
+	groups := make([]string, 0)
+
 	// delete existing transactions of type payment in status tentative (that is, paylinks)
 	for _, tt := range transactions {
 		if tt.TransactionType == entities.TransactionTypePayment && tt.TransactionStatus == entities.TransactionStatusTentative {
@@ -409,13 +629,17 @@ func (s *serviceInteractor) invalidateTentativePayments(ctx context.Context, deb
 			tt.Deletion = entities.Deletion{
 				Status:  tt.TransactionStatus, // previous status
 				Comment: tt.Comment,           // previous comment
-				By:      "internal",           // identity of deleting user
+				By:      by,                   // identity of deleting user
 			}
 			tt.TransactionStatus = entities.TransactionStatusDeleted
-			tt.Comment = "voided paylink - dues have changed"
+			tt.Comment = comment
 
 			if err := s.store.DeleteTransaction(ctx, tt); err != nil {
-				return err
+				return voided, err
+			}
+			voided = append(voided, tt.TransactionID)
+			if tt.GroupReference != "" {
+				groups = append(groups, tt.GroupReference)
 			}
 
 			logger := logging.LoggerFromContext(ctx)
@@ -423,7 +647,12 @@ func (s *serviceInteractor) invalidateTentativePayments(ctx context.Context, deb
 		}
 	}
 
-	return nil
+	// a group paylink no longer matches the dues once one of its payments is voided
+	for _, group := range groups {
+		voided = append(voided, s.voidGroupPayment(ctx, group, comment)...)
+	}
+
+	return voided, nil
 }
 
 func (s *serviceInteractor) validateAttendeeTransaction(ctx context.Context, newTransaction *entities.Transaction) error {
@@ -442,8 +671,14 @@ func (s *serviceInteractor) validateAttendeeTransaction(ctx context.Context, new
 		return apierrors.NewForbidden("transaction is not eligible for requesting a payment link")
 	}
 
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return err
+	}
+	event := eventFilter(appConfig, newTransaction.Event)
+
 	// Check if there are any pending or tentative transactions that block creation of the payment
-	pending, err := s.arePendingPaymentsPresent(ctx, newTransaction.DebitorID, newTransaction.PaymentMethod)
+	pending, err := s.arePendingPaymentsPresent(ctx, newTransaction.DebitorID, event, newTransaction.PaymentMethod)
 
 	if err != nil {
 		logger.Error("could not retrieve pending payments for debitor %d - [error]: %v", newTransaction.DebitorID, err)
@@ -456,14 +691,20 @@ func (s *serviceInteractor) validateAttendeeTransaction(ctx context.Context, new
 	}
 
 	// We defined, that we only query transactions in status valid.
-	currentTransactions, err := s.store.GetValidTransactionsForDebitor(ctx, newTransaction.DebitorID)
+	currentTransactions, err := s.store.GetValidTransactionsForDebitor(ctx, newTransaction.DebitorID, event)
+	if err != nil {
+		return err
+	}
+
+	// attendees with an installment plan may also pay the next installment
+	nextInstallment, err := s.nextInstallmentAmount(ctx, newTransaction.DebitorID, newTransaction.Event)
 	if err != nil {
 		return err
 	}
 
 	// in error case: 400
 	// if partial payment || no outstanding dues
-	if !s.isValidAttendeePayment(currentTransactions, newTransaction, logger) {
+	if !s.isValidAttendeePayment(currentTransactions, newTransaction, nextInstallment, logger) {
 		return apierrors.NewBadRequest("no outstanding dues or partial payment")
 	}
 
@@ -480,11 +721,17 @@ func containsDebitor(debIDs []int64, debID int64) bool {
 	return false
 }
 
+// validateTransactionID checks that the transaction id has the format of generated ids, with the given prefix.
+// The id may also be given as the creditor reference derived from it.
 func validateTransactionID(prefix, transactionID string) bool {
 	if transactionID == "" {
 		return false
 	}
 
+	if id, ok := transactionIDFromCreditorReference(prefix, transactionID); ok {
+		transactionID = id
+	}
+
 	segments := strings.Split(transactionID, "-")
 
 	// we expect 5 segments (Time also contains a dash `-`)
@@ -522,6 +769,124 @@ func generateTransactionID(prefix string, tran *entities.Transaction) (string, e
 
 }
 
+// creditorReferenceDigits is the number of characters the digits of a transaction id take in its creditor reference
+const creditorReferenceDigits = 14
+
+// creditorReferenceFor derives the creditor reference of a payment from its transaction id. It consists of the event
+// prefix, followed by the digits of the id as a base 36 number of fixed length, e.g. EF2024-000010-0102-120000-0003
+// becomes RF77EF202400009UTYSGVMKJ, and it can be turned back into the transaction id.
+//
+// Dues, transaction ids of another format, and prefixes longer than 7 characters or with underscores get none, as
+// the reference is limited to 21 letters or digits. So do debitor ids too large to fit, which is none up to 7 digits.
+func creditorReferenceFor(tran *entities.Transaction) sql.NullString {
+	if tran.TransactionType != entities.TransactionTypePayment {
+		return sql.NullString{}
+	}
+
+	prefix, rest, found := strings.Cut(tran.TransactionID, "-")
+	digits := strings.ReplaceAll(rest, "-", "")
+	if !found || !allDigitsRegex.MatchString(digits) {
+		return sql.NullString{}
+	}
+
+	number, _ := new(big.Int).SetString(digits, 10)
+	encoded := strings.ToUpper(number.Text(36))
+	if len(encoded) > creditorReferenceDigits {
+		return sql.NullString{}
+	}
+
+	ref := entities.NewCreditorReference(prefix + strings.Repeat("0", creditorReferenceDigits-len(encoded)) + encoded)
+	if id, ok := transactionIDFromCreditorReference(prefix, ref); !ok || id != tran.TransactionID {
+		// not a transaction id as generated, the reference would not lead back to it
+		return sql.NullString{}
+	}
+	return sql.NullString{String: ref, Valid: true}
+}
+
+// transactionIDFromCreditorReference turns a creditor reference in printed or electronic form back into the
+// transaction id it was derived from, if it belongs to the event with the given prefix.
+func transactionIDFromCreditorReference(prefix, value string) (string, bool) {
+	ref := entities.NormalizeCreditorReference(value)
+	if ref == "" || len(ref) != 4+len(prefix)+creditorReferenceDigits || !strings.EqualFold(ref[4:4+len(prefix)], prefix) {
+		return "", false
+	}
+
+	number, ok := new(big.Int).SetString(ref[4+len(prefix):], 36)
+	if !ok {
+		return "", false
+	}
+
+	// debitor id, MMDD, HHmmss and the random digits
+	digits := fmt.Sprintf("%020d", number)
+	n := len(digits)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", prefix, digits[:n-14], digits[n-14:n-10], digits[n-10:n-4], digits[n-4:]), true
+}
+
+// transactionQuery looks up a transaction by its transaction id, or by its creditor reference in printed or electronic form.
+func transactionQuery(identifier string) entities.TransactionQuery {
+	if ref := entities.NormalizeCreditorReference(identifier); ref != "" {
+		return entities.TransactionQuery{CreditorReference: ref}
+	}
+	return entities.TransactionQuery{TransactionIdentifier: identifier}
+}
+
+// storeNewTransaction creates the transaction in the database.
+//
+// If the transaction id was generated, which is marked by a non-empty idPrefix, it is generated anew when it or its
+// creditor reference collide with an existing transaction. This happens when two ids are generated in the same second.
+func (s *serviceInteractor) storeNewTransaction(ctx context.Context, tran *entities.Transaction, idPrefix string) error {
+	trs := []entities.Transaction{*tran}
+	err := s.createWithGeneratedIDs(ctx, trs, []string{idPrefix}, func(trs []entities.Transaction) error {
+		return s.store.CreateTransaction(ctx, trs[0])
+	})
+	*tran = trs[0]
+	return err
+}
+
+// createWithGeneratedIDs creates new transactions through create, which stores all of them or none.
+//
+// A non-empty prefix marks a transaction whose id is generated, prefixes[i] for trs[i]. Its id is generated if not
+// set yet, and generated anew for all of them when one of them or its creditor reference collides with an existing
+// transaction, before create is called again. Each payment gets the creditor reference derived from its id.
+func (s *serviceInteractor) createWithGeneratedIDs(ctx context.Context, trs []entities.Transaction, prefixes []string, create func([]entities.Transaction) error) error {
+	generate := func(regenerate bool) error {
+		for i := range trs {
+			if prefixes[i] != "" && (regenerate || trs[i].TransactionID == "") {
+				id, err := generateTransactionID(prefixes[i], &trs[i])
+				if err != nil {
+					return err
+				}
+				trs[i].TransactionID = id
+			}
+			trs[i].CreditorReference = creditorReferenceFor(&trs[i])
+		}
+		return nil
+	}
+
+	if err := generate(false); err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := create(trs)
+		if !errors.Is(err, database.ErrTransactionExists) {
+			return err
+		}
+		if !slices.ContainsFunc(prefixes, func(prefix string) bool { return prefix != "" }) || attempt == maxTransactionIDAttempts {
+			return apierrors.NewConflict(fmt.Sprintf("transaction %s already exists", trs[0].TransactionID))
+		}
+
+		ids := make([]string, len(trs))
+		for i := range trs {
+			ids[i] = trs[i].TransactionID
+		}
+		logging.LoggerFromContext(ctx).Warn("transaction ids %s collide with an existing transaction, generating new ones", strings.Join(ids, ", "))
+		if err := generate(true); err != nil {
+			return err
+		}
+	}
+}
+
 var digitRunes = []rune("0123456789")
 
 func randomDigits(count int) string {
@@ -544,8 +909,8 @@ func randomDigits(count int) string {
 	return string(res)
 }
 
-func (s *serviceInteractor) arePendingPaymentsPresent(ctx context.Context, debitorID int64, requestedPaymentMethod entities.PaymentMethod) (bool, error) {
-	transactions, err := s.store.GetTransactionsByFilter(ctx, entities.TransactionQuery{DebitorID: debitorID})
+func (s *serviceInteractor) arePendingPaymentsPresent(ctx context.Context, debitorID int64, event string, requestedPaymentMethod entities.PaymentMethod) (bool, error) {
+	transactions, err := s.store.GetTransactionsByFilter(ctx, entities.TransactionQuery{DebitorID: debitorID, Event: event})
 	if err != nil {
 		return false, err
 	}
@@ -569,7 +934,9 @@ func (s *serviceInteractor) arePendingPaymentsPresent(ctx context.Context, debit
 	return false, nil
 }
 
-func (s *serviceInteractor) isValidAttendeePayment(curTransactions []entities.Transaction, newTran *entities.Transaction, logger logging.Logger) bool {
+// isValidAttendeePayment checks that the payment pays off the outstanding dues, or exactly the next installment
+// if the debitor has an installment plan. Pass 0 for nextInstallment if there is no plan.
+func (s *serviceInteractor) isValidAttendeePayment(curTransactions []entities.Transaction, newTran *entities.Transaction, nextInstallment int64, logger logging.Logger) bool {
 	var allDues int64
 	var allPayments int64
 
@@ -596,8 +963,8 @@ func (s *serviceInteractor) isValidAttendeePayment(curTransactions []entities.Tr
 
 	remaining := allDues - allPayments
 
-	if remaining < 0 || newTran.Amount.GrossCent != remaining {
-		// we do not allow partial payments from attendees
+	if remaining < 0 || (newTran.Amount.GrossCent != remaining && (nextInstallment <= 0 || newTran.Amount.GrossCent != nextInstallment)) {
+		// we do not allow partial payments from attendees, except for installments according to their plan
 		// Admins or s2s calls will not use this validation logic
 		logger.Info("rejected partial payment for attendee %d", newTran.DebitorID)
 		return false
@@ -618,6 +985,7 @@ func (s *serviceInteractor) createPaymentLink(ctx context.Context, tran entities
 		})
 
 		if err != nil {
+			metrics.PaylinkFailed()
 			return "", apierrors.NewInternalServerError(err.Error())
 		}
 
@@ -644,14 +1012,8 @@ func (s *serviceInteractor) createPaymentLink(ctx context.Context, tran entities
 	return "", apierrors.NewInternalServerError("invalid payment method for paylink")
 }
 
-func isCurrencyAllowed(allowedCurrencies []string, isoCurrency string) bool {
-	for _, cur := range allowedCurrencies {
-		if strings.EqualFold(cur, isoCurrency) {
-			return true
-		}
-	}
-
-	return false
+func transactionCreated(tran *entities.Transaction) {
+	metrics.TransactionCreated(string(tran.TransactionType), string(tran.PaymentMethod), string(tran.TransactionStatus))
 }
 
 func shouldRequestPaymentLink(tran *entities.Transaction) bool {
@@ -2,6 +2,7 @@ package interaction
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"os"
@@ -1408,7 +1409,7 @@ func TestCreateTransactionForOutstandingDues(t *testing.T) {
 						GrossCent:   200_00,
 						VatRate:     19.0,
 					}),
-					newTransaction(10, "1234", entities.TransactionTypePayment, entities.PaymentMethodCredit, entities.TransactionStatusValid, entities.Amount{
+					newTransaction(10, "1235", entities.TransactionTypePayment, entities.PaymentMethodCredit, entities.TransactionStatusValid, entities.Amount{
 						ISOCurrency: "EUR",
 						GrossCent:   200_00,
 						VatRate:     19.0,
@@ -1446,7 +1447,7 @@ func TestCreateTransactionForOutstandingDues(t *testing.T) {
 						GrossCent:   200_00,
 						VatRate:     19.0,
 					}),
-					newTransaction(10, "1234", entities.TransactionTypePayment, entities.PaymentMethodCredit, entities.TransactionStatusPending, entities.Amount{
+					newTransaction(10, "1235", entities.TransactionTypePayment, entities.PaymentMethodCredit, entities.TransactionStatusPending, entities.Amount{
 						ISOCurrency: "EUR",
 						GrossCent:   200_00,
 						VatRate:     19.0,
@@ -1535,6 +1536,228 @@ func TestCreateTransactionForOutstandingDues(t *testing.T) {
 	}
 }
 
+func TestCreditorReferenceFor(t *testing.T) {
+	payment := func(tranID string) *entities.Transaction {
+		tran := newTransaction(10, tranID, entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusTentative, entities.Amount{})
+		return &tran
+	}
+	due := newTransaction(10, "EF2024-000010-0102-120000-0003", entities.TransactionTypeDue, entities.PaymentMethodTransfer, entities.TransactionStatusValid, entities.Amount{})
+
+	require.Equal(t, sql.NullString{String: "RF77EF202400009UTYSGVMKJ", Valid: true}, creditorReferenceFor(payment("EF2024-000010-0102-120000-0003")))
+	require.Equal(t, sql.NullString{String: "RF02EF2400009UTYSGVMKJ", Valid: true}, creditorReferenceFor(payment("EF24-000010-0102-120000-0003")))
+	require.Equal(t, sql.NullString{String: "RF02EF2400009UTYSGVMKJ", Valid: true}, creditorReferenceFor(payment("ef24-000010-0102-120000-0003")))
+	require.Equal(t, sql.NullString{String: "RF42EF20240Q1YS426GU78CJ", Valid: true}, creditorReferenceFor(payment("EF2024-1234567-0102-120000-0003")))
+	require.False(t, creditorReferenceFor(payment("EF2024-123456789-0102-120000-0003")).Valid)
+	require.False(t, creditorReferenceFor(payment("EF202456-1234567-0102-120000-0003")).Valid)
+	require.False(t, creditorReferenceFor(payment("EF_24-000010-0102-120000-0003")).Valid)
+	require.False(t, creditorReferenceFor(payment("EF2024-0000010-0102-120000-0003")).Valid)
+	require.False(t, creditorReferenceFor(payment("EF2024-000010-0102120000-0003")).Valid)
+	require.False(t, creditorReferenceFor(payment("1234")).Valid)
+	require.False(t, creditorReferenceFor(&due).Valid)
+}
+
+func TestTransactionIDFromCreditorReference(t *testing.T) {
+	id, ok := transactionIDFromCreditorReference("EF2024", "RF77EF202400009UTYSGVMKJ")
+	require.True(t, ok)
+	require.Equal(t, "EF2024-000010-0102-120000-0003", id)
+
+	id, ok = transactionIDFromCreditorReference("ef24", "rf02 ef24 0000 9uty sgvm kj")
+	require.True(t, ok)
+	require.Equal(t, "ef24-000010-0102-120000-0003", id)
+
+	id, ok = transactionIDFromCreditorReference("EF2024", "RF42EF20240Q1YS426GU78CJ")
+	require.True(t, ok)
+	require.Equal(t, "EF2024-1234567-0102-120000-0003", id)
+
+	// another event, wrong check digits, not a creditor reference
+	for _, value := range []string{"RF02EF2400009UTYSGVMKJ", "RF78EF202400009UTYSGVMKJ", "EF2024-000010-0102-120000-0003"} {
+		_, ok = transactionIDFromCreditorReference("EF2024", value)
+		require.False(t, ok, value)
+	}
+}
+
+func TestTransactionQuery(t *testing.T) {
+	require.Equal(t, entities.TransactionQuery{TransactionIdentifier: "EF2024-000010-0102-120000-0003"}, transactionQuery("EF2024-000010-0102-120000-0003"))
+	require.Equal(t, entities.TransactionQuery{CreditorReference: "RF77EF202400009UTYSGVMKJ"}, transactionQuery("RF77EF202400009UTYSGVMKJ"))
+	require.Equal(t, entities.TransactionQuery{CreditorReference: "RF77EF202400009UTYSGVMKJ"}, transactionQuery("rf77 ef20 2400 009u tysg vmkj"))
+	require.Equal(t, entities.TransactionQuery{CreditorReference: "RF18539007547034"}, transactionQuery("RF18 5390 0754 7034"))
+
+	// wrong check digits
+	require.Equal(t, entities.TransactionQuery{TransactionIdentifier: "RF78EF202400009UTYSGVMKJ"}, transactionQuery("RF78EF202400009UTYSGVMKJ"))
+}
+
+func TestStoreNewTransaction(t *testing.T) {
+	withEvents(t)
+
+	taken := newTransaction(10, "EF24-000010-0102-120000-0003", entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusTentative, entities.Amount{ISOCurrency: "EUR", GrossCent: 10_00})
+	taken.CreditorReference = creditorReferenceFor(&taken)
+
+	db := inmemory.NewInMemoryProvider()
+	seedDB(db, []entities.Transaction{taken})
+	i := &serviceInteractor{store: db}
+
+	// a provided transaction id is kept
+	duplicate := taken
+	err := i.storeNewTransaction(context.Background(), &duplicate, "")
+	require.EqualError(t, err, apierrors.NewConflict("transaction EF24-000010-0102-120000-0003 already exists").Error())
+
+	// the same digits for another event get another creditor reference
+	otherEvent := taken
+	otherEvent.TransactionID = "EF2023-000010-0102-120000-0003"
+	otherEvent.CreditorReference = creditorReferenceFor(&otherEvent)
+	err = i.storeNewTransaction(context.Background(), &otherEvent, "")
+	require.NoError(t, err)
+	require.NotEqual(t, taken.CreditorReference, otherEvent.CreditorReference)
+
+	// a generated transaction id is generated anew
+	generated := taken
+	err = i.storeNewTransaction(context.Background(), &generated, "EF24")
+	require.NoError(t, err)
+	require.NotEqual(t, taken.TransactionID, generated.TransactionID)
+	require.True(t, validateTransactionID("EF24", generated.TransactionID))
+	require.Equal(t, creditorReferenceFor(&generated), generated.CreditorReference)
+
+	stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{DebitorID: 10})
+	require.NoError(t, err)
+	require.Len(t, stored, 3)
+}
+
+// collidingCreates reports the first creates as colliding with an existing transaction, like an id generated
+// in the same second as another one, and records the transaction ids of each attempt.
+type collidingCreates struct {
+	database.Repository
+	collisions int
+	attempts   [][]string
+}
+
+func (c *collidingCreates) collide(trs ...entities.Transaction) bool {
+	ids := make([]string, len(trs))
+	for i := range trs {
+		ids[i] = trs[i].TransactionID
+	}
+	c.attempts = append(c.attempts, ids)
+
+	if c.collisions > 0 {
+		c.collisions--
+		return true
+	}
+	return false
+}
+
+func (c *collidingCreates) CreateTransaction(ctx context.Context, tr entities.Transaction) error {
+	if c.collide(tr) {
+		return database.ErrTransactionExists
+	}
+	return c.Repository.CreateTransaction(ctx, tr)
+}
+
+func (c *collidingCreates) CreateTransactions(ctx context.Context, trs []entities.Transaction) error {
+	if c.collide(trs...) {
+		return database.ErrTransactionExists
+	}
+	return c.Repository.CreateTransactions(ctx, trs)
+}
+
+func (c *collidingCreates) CreateTransactionsWithinCredit(ctx context.Context, debitorID int64, event string, creditCent int64, trs []entities.Transaction) error {
+	if c.collide(trs...) {
+		return database.ErrTransactionExists
+	}
+	return c.Repository.CreateTransactionsWithinCredit(ctx, debitorID, event, creditCent, trs)
+}
+
+// requireCreatedAfterCollision checks that the created payments were stored on the second attempt,
+// and that each got the creditor reference of its transaction id.
+func requireCreatedAfterCollision(t *testing.T, db *collidingCreates, created []entities.Transaction) {
+	t.Helper()
+
+	require.Len(t, db.attempts, 2)
+	require.Len(t, created, len(db.attempts[1]))
+	for n, tran := range created {
+		require.Equal(t, db.attempts[1][n], tran.TransactionID)
+		require.True(t, tran.CreditorReference.Valid, tran.TransactionID)
+		require.Equal(t, creditorReferenceFor(&tran), tran.CreditorReference)
+
+		stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{CreditorReference: tran.CreditorReference.String})
+		require.NoError(t, err)
+		require.Len(t, stored, 1)
+		require.Equal(t, tran.TransactionID, stored[0].TransactionID)
+	}
+}
+
+func TestCreateWithGeneratedIDs(t *testing.T) {
+	db := &collidingCreates{Repository: inmemory.NewInMemoryProvider(), collisions: maxTransactionIDAttempts}
+	i := &serviceInteractor{store: db}
+
+	trs := []entities.Transaction{
+		newTransaction(10, "", entities.TransactionTypePayment, entities.PaymentMethodInternal, entities.TransactionStatusValid, entities.Amount{ISOCurrency: "EUR", GrossCent: -10_00}),
+		newTransaction(11, "", entities.TransactionTypePayment, entities.PaymentMethodInternal, entities.TransactionStatusValid, entities.Amount{ISOCurrency: "EUR", GrossCent: 10_00}),
+	}
+	err := i.createWithGeneratedIDs(context.Background(), trs, []string{"EF24", "EF24"}, func(trs []entities.Transaction) error {
+		return db.CreateTransactions(context.Background(), trs)
+	})
+	require.True(t, apierrors.IsConflictError(err))
+	require.Len(t, db.attempts, maxTransactionIDAttempts)
+
+	stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{})
+	require.NoError(t, err)
+	require.Empty(t, stored)
+}
+
+func TestTransactionsByCreditorReference(t *testing.T) {
+	withEvents(t)
+
+	db := inmemory.NewInMemoryProvider()
+	asm := &AttendeeServiceMock{
+		ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+			return []int64{10}, nil
+		},
+	}
+	i := tstServiceInteractor(db, asm, &CncrdAdapterMock{})
+
+	tran := newTransaction(10, "", entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusTentative, entities.Amount{ISOCurrency: "EUR", GrossCent: 10_00})
+	created, err := i.CreateTransaction(apiKeyCtx(), &tran)
+	require.NoError(t, err)
+	require.True(t, created.CreditorReference.Valid)
+	require.Equal(t, creditorReferenceFor(created), created.CreditorReference)
+
+	ref := created.CreditorReference.String
+	printed := ref[:4] + " " + ref[4:8] + " " + ref[8:]
+
+	details, err := i.GetTransaction(adminCtx(), printed)
+	require.NoError(t, err)
+	require.Equal(t, created.TransactionID, details.Transaction.TransactionID)
+
+	found, err := i.GetTransactionsForDebitor(attendeeCtx(), entities.TransactionQuery{DebitorID: 10, TransactionIdentifier: ref})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, created.TransactionID, found[0].TransactionID)
+
+	update := *created
+	update.ID = 0
+	update.TransactionID = ref
+	update.CreditorReference = sql.NullString{}
+	update.TransactionStatus = entities.TransactionStatusPending
+	require.NoError(t, i.UpdateTransaction(attendeeCtx(), &update))
+	require.Equal(t, created.TransactionID, update.TransactionID)
+
+	updated, err := i.GetTransaction(adminCtx(), created.TransactionID)
+	require.NoError(t, err)
+	require.Equal(t, entities.TransactionStatusPending, updated.Transaction.TransactionStatus)
+	require.Equal(t, created.CreditorReference, updated.Transaction.CreditorReference)
+
+	due := newTransaction(10, "", entities.TransactionTypeDue, entities.PaymentMethodTransfer, entities.TransactionStatusValid, entities.Amount{ISOCurrency: "EUR", GrossCent: 10_00})
+	createdDue, err := i.CreateTransaction(apiKeyCtx(), &due)
+	require.NoError(t, err)
+	require.False(t, createdDue.CreditorReference.Valid)
+
+	// a transaction id may be provided as creditor reference, too
+	explicit := newTransaction(10, "RF02 EF24 0000 9UTY SGVM KJ", entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusTentative, entities.Amount{ISOCurrency: "EUR", GrossCent: 10_00})
+	createdExplicit, err := i.CreateTransaction(apiKeyCtx(), &explicit)
+	require.NoError(t, err)
+	require.Equal(t, "EF24-000010-0102-120000-0003", createdExplicit.TransactionID)
+	require.Equal(t, sql.NullString{String: "RF02EF2400009UTYSGVMKJ", Valid: true}, createdExplicit.CreditorReference)
+}
+
 func newTransaction(debID int64, tranID string,
 	pType entities.TransactionType,
 	method entities.PaymentMethod,
@@ -1742,7 +1965,7 @@ func TestValidateAttendeeTransaction(t *testing.T) {
 					GetTransactionsByFilterFunc: func(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
 						return []entities.Transaction{tstDefaultTransaction(nil)}, nil
 					},
-					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64) ([]entities.Transaction, error) {
+					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
 						return []entities.Transaction{tstDefaultTransaction(nil)}, nil
 					},
 				},
@@ -1787,7 +2010,7 @@ func TestValidateAttendeeTransaction(t *testing.T) {
 					GetTransactionsByFilterFunc: func(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
 						return []entities.Transaction{tstDefaultTransaction(nil)}, nil
 					},
-					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64) ([]entities.Transaction, error) {
+					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
 						return []entities.Transaction{tstDefaultTransaction(nil)}, nil
 					},
 				},
@@ -1813,7 +2036,7 @@ func TestValidateAttendeeTransaction(t *testing.T) {
 					GetTransactionsByFilterFunc: func(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
 						return nil, errors.New("test")
 					},
-					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64) ([]entities.Transaction, error) {
+					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
 						return []entities.Transaction{tstDefaultTransaction(nil)}, nil
 					},
 				},
@@ -1842,7 +2065,7 @@ func TestValidateAttendeeTransaction(t *testing.T) {
 							t.TransactionStatus = entities.TransactionStatusPending
 						})}, nil
 					},
-					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64) ([]entities.Transaction, error) {
+					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
 						return []entities.Transaction{tstDefaultTransaction(nil)}, nil
 					},
 				},
@@ -1868,7 +2091,7 @@ func TestValidateAttendeeTransaction(t *testing.T) {
 					GetTransactionsByFilterFunc: func(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
 						return []entities.Transaction{tstDefaultTransaction(nil)}, nil
 					},
-					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64) ([]entities.Transaction, error) {
+					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
 						return []entities.Transaction{tstDefaultTransaction(nil)}, nil
 					},
 				},
@@ -1895,7 +2118,7 @@ func TestValidateAttendeeTransaction(t *testing.T) {
 					GetTransactionsByFilterFunc: func(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
 						return []entities.Transaction{tstDefaultTransaction(nil)}, nil
 					},
-					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64) ([]entities.Transaction, error) {
+					GetValidTransactionsForDebitorFunc: func(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
 						return []entities.Transaction{tstDefaultTransaction(nil), tstDefaultTransaction(func(t *entities.Transaction) {
 							t.TransactionType = entities.TransactionTypePayment
 							t.TransactionStatus = entities.TransactionStatusValid
@@ -2023,6 +2246,18 @@ func TestValidateTransactionID(t *testing.T) {
 			inputID:  "abc123-000302-0101-163055-12345", // 01.01T16:61:00 <- Minute 61 should fail
 			expected: false,
 		},
+		{
+			name:     "should succeed with creditor reference",
+			prefix:   "EF2024",
+			inputID:  "RF77 EF20 2400 009U TYSG VMKJ",
+			expected: true,
+		},
+		{
+			name:     "should fail with creditor reference of another event",
+			prefix:   "EF2024",
+			inputID:  "RF02EF2400009UTYSGVMKJ",
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
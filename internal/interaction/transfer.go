@@ -0,0 +1,184 @@
+package interaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
+)
+
+// BalanceTransfer is a negative payment on the source debitor and a positive one on the target,
+// sharing the same GroupReference.
+type BalanceTransfer struct {
+	Reference string
+	Source    entities.Transaction
+	Target    entities.Transaction
+}
+
+// TransferBalance moves credit of the current event from one debitor to another, for example when
+// someone gives their paid membership to a friend, or when two registrations are merged.
+//
+// Both payments use the internal payment method, and are booked together or not at all.
+// The source must have at least amountCent more valid payments than dues. Only admins and the
+// command line may transfer balances.
+func (s *serviceInteractor) TransferBalance(ctx context.Context, sourceDebitorID int64, targetDebitorID int64, amountCent int64, comment string) (*BalanceTransfer, error) {
+	mgr, err := NewRBACValidator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !mgr.IsAdmin() && !mgr.IsSystemCall() {
+		err := apierrors.NewForbidden("only admins may transfer balances")
+		s.recordAudit(ctx, mgr, auditActionTransferBalance, sourceDebitorID, "", "", err)
+		return nil, err
+	}
+
+	transfer, err := s.transferBalance(ctx, sourceDebitorID, targetDebitorID, amountCent, comment)
+	if err != nil {
+		s.recordAudit(ctx, mgr, auditActionTransferBalance, sourceDebitorID, "", "", err)
+		return nil, err
+	}
+
+	logger := logging.LoggerFromContext(ctx)
+	for _, tran := range []*entities.Transaction{&transfer.Source, &transfer.Target} {
+		s.recordAudit(ctx, mgr, auditActionTransferBalance, tran.DebitorID, tran.TransactionID, transactionDiff(nil, tran), nil)
+		transactionCreated(tran)
+
+		if err := s.attendeeClient.PaymentsChanged(ctx, uint(tran.DebitorID)); err != nil {
+			// only log an error when the call was not successful but don't cause an internal server error
+			logger.Error("error when calling the attendee service webhook. [error]: %v", err)
+		}
+	}
+
+	return transfer, nil
+}
+
+func (s *serviceInteractor) transferBalance(ctx context.Context, sourceDebitorID int64, targetDebitorID int64, amountCent int64, comment string) (*BalanceTransfer, error) {
+	fields := url.Values{}
+	if sourceDebitorID <= 0 {
+		fields.Add("source_debitor_id", "must be greater than zero")
+	}
+	if targetDebitorID <= 0 {
+		fields.Add("target_debitor_id", "must be greater than zero")
+	} else if targetDebitorID == sourceDebitorID {
+		fields.Add("target_debitor_id", "must differ from the source debitor")
+	}
+	if amountCent <= 0 {
+		fields.Add("gross_cent", "must be greater than zero")
+	}
+	if len(fields) > 0 {
+		return nil, apierrors.NewUnprocessableEntity(fields)
+	}
+
+	appConfig, err := config.GetApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := currentEvent(appConfig, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := s.computeBalance(ctx, sourceDebitorID, event.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	credit := -balance.OutstandingCent
+	if credit < amountCent {
+		return nil, apierrors.NewConflict(fmt.Sprintf("debitor %d has a credit of %d, which does not cover a transfer of %d", sourceDebitorID, max(credit, 0), amountCent))
+	}
+
+	// the credit is moved in the currency it was paid in
+	payment, err := s.latestPayment(ctx, sourceDebitorID, eventFilter(appConfig, event.Name), "")
+	if err != nil {
+		return nil, err
+	}
+
+	reference := generateTransferReference(event.Prefix(), sourceDebitorID)
+	effective := sql.NullTime{Time: time.Now(), Valid: true}
+
+	transfer := BalanceTransfer{Reference: reference}
+	for _, side := range []struct {
+		tran      *entities.Transaction
+		debitorID int64
+		amount    int64
+		comment   string
+	}{
+		{&transfer.Source, sourceDebitorID, -amountCent, fmt.Sprintf("balance transfer to debitor %d", targetDebitorID)},
+		{&transfer.Target, targetDebitorID, amountCent, fmt.Sprintf("balance transfer from debitor %d", sourceDebitorID)},
+	} {
+		*side.tran = entities.Transaction{
+			DebitorID:         side.debitorID,
+			TransactionType:   entities.TransactionTypePayment,
+			PaymentMethod:     entities.PaymentMethodInternal,
+			TransactionStatus: entities.TransactionStatusValid,
+			Comment:           side.comment,
+			Event:             event.Name,
+			EffectiveDate:     effective,
+			GroupReference:    reference,
+			Amount: entities.Amount{
+				ISOCurrency: payment.Amount.ISOCurrency,
+				VatRate:     payment.Amount.VatRate,
+				GrossCent:   side.amount,
+			},
+		}
+		if comment != "" {
+			side.tran.Comment = comment
+		}
+	}
+
+	// the credit is checked again while the transactions of the source are locked, so concurrent
+	// transfers or refunds cannot spend the same credit
+	sides := []entities.Transaction{transfer.Source, transfer.Target}
+	err = s.createWithGeneratedIDs(ctx, sides, []string{event.Prefix(), event.Prefix()}, func(trs []entities.Transaction) error {
+		return s.store.CreateTransactionsWithinCredit(ctx, sourceDebitorID, eventFilter(appConfig, event.Name), amountCent, trs)
+	})
+	transfer.Source, transfer.Target = sides[0], sides[1]
+	if errors.Is(err, database.ErrInsufficientCredit) {
+		return nil, apierrors.NewConflict(fmt.Sprintf("the credit of debitor %d no longer covers a transfer of %d", sourceDebitorID, amountCent))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &transfer, nil
+}
+
+// latestPayment returns the latest valid payment of the debitor that paid money in,
+// optionally only considering the given payment method.
+func (s *serviceInteractor) latestPayment(ctx context.Context, debitorID int64, event string, method entities.PaymentMethod) (*entities.Transaction, error) {
+	transactions, err := s.store.GetValidTransactionsForDebitor(ctx, debitorID, event)
+	if err != nil {
+		return nil, err
+	}
+	sortByTxID(transactions)
+
+	for i := len(transactions) - 1; i >= 0; i-- {
+		tran := transactions[i]
+		if tran.TransactionType == entities.TransactionTypePayment && tran.Amount.GrossCent > 0 && (method == "" || tran.PaymentMethod == method) {
+			return &tran, nil
+		}
+	}
+
+	if method != "" {
+		return nil, apierrors.NewConflict(fmt.Sprintf("debitor %d has no valid %s payments", debitorID, method))
+	}
+	return nil, apierrors.NewConflict(fmt.Sprintf("debitor %d has no valid payments", debitorID))
+}
+
+// generateTransferReference works like generateGroupReference, the debitor segment starting with T.
+// It is shared by both sides of a transfer of credit, between debitors or between events.
+func generateTransferReference(prefix string, debitorID int64) string {
+	parsedTime := time.Now().UTC().Format(transactionIDTimeFormat)
+	return fmt.Sprintf("%s-T%06d-%s-%s", prefix, debitorID, parsedTime, randomDigits(4))
+}
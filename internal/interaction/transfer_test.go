@@ -0,0 +1,197 @@
+package interaction
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database/inmemory"
+)
+
+func TestTransferBalance(t *testing.T) {
+	eurPayment := func(debitorID int64, tranID string, grossCent int64) entities.Transaction {
+		return newTransaction(debitorID, tranID, entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusValid, entities.Amount{
+			ISOCurrency: "EUR",
+			GrossCent:   grossCent,
+			VatRate:     19.0,
+		})
+	}
+
+	seed := []entities.Transaction{
+		eurDue(10, "1", 100_00),
+		eurPayment(10, "2", 150_00),
+		eurDue(11, "3", 50_00),
+	}
+
+	type args struct {
+		ctx    context.Context
+		source int64
+		target int64
+		amount int64
+	}
+
+	tests := []struct {
+		name string
+		args args
+		err  error
+	}{
+		{
+			name: "should not allow users to transfer balances",
+			args: args{ctx: attendeeCtx(), source: 10, target: 11, amount: 50_00},
+			err:  apierrors.NewForbidden("only admins may transfer balances"),
+		},
+		{
+			name: "should not allow api token calls to transfer balances",
+			args: args{ctx: apiKeyCtx(), source: 10, target: 11, amount: 50_00},
+			err:  apierrors.NewForbidden("only admins may transfer balances"),
+		},
+		{
+			name: "should reject a transfer to the source debitor",
+			args: args{ctx: adminCtx(), source: 10, target: 10, amount: 0},
+			err: apierrors.NewUnprocessableEntity(url.Values{
+				"target_debitor_id": {"must differ from the source debitor"},
+				"gross_cent":        {"must be greater than zero"},
+			}),
+		},
+		{
+			name: "should reject a transfer exceeding the credit of the source",
+			args: args{ctx: adminCtx(), source: 10, target: 11, amount: 50_01},
+			err:  apierrors.NewConflict("debitor 10 has a credit of 5000, which does not cover a transfer of 5001"),
+		},
+		{
+			name: "should reject a transfer from a debitor without credit",
+			args: args{ctx: adminCtx(), source: 11, target: 10, amount: 10_00},
+			err:  apierrors.NewConflict("debitor 11 has a credit of 0, which does not cover a transfer of 1000"),
+		},
+		{
+			name: "should book the transfer on both debitors",
+			args: args{ctx: adminCtx(), source: 10, target: 11, amount: 50_00},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var notified []uint
+			asm := &AttendeeServiceMock{
+				PaymentsChangedFunc: func(ctx context.Context, debitorId uint) error {
+					notified = append(notified, debitorId)
+					return nil
+				},
+			}
+
+			db := inmemory.NewInMemoryProvider()
+			seedDB(db, seed)
+
+			i := tstServiceInteractor(db, asm, &CncrdAdapterMock{})
+
+			transfer, err := i.TransferBalance(tt.args.ctx, tt.args.source, tt.args.target, tt.args.amount, "")
+
+			if tt.err != nil {
+				require.EqualError(t, err, tt.err.Error())
+				require.Nil(t, transfer)
+				require.Empty(t, notified)
+
+				stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{})
+				require.NoError(t, err)
+				require.Len(t, stored, len(seed))
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotEmpty(t, transfer.Reference)
+			require.Equal(t, []uint{10, 11}, notified)
+
+			for debitorID, gross := range map[int64]int64{10: -50_00, 11: 50_00} {
+				stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{
+					DebitorID:      debitorID,
+					GroupReference: transfer.Reference,
+				})
+				require.NoError(t, err)
+				require.Len(t, stored, 1)
+				require.Equal(t, entities.PaymentMethodInternal, stored[0].PaymentMethod)
+				require.Equal(t, entities.TransactionStatusValid, stored[0].TransactionStatus)
+				require.Equal(t, gross, stored[0].Amount.GrossCent)
+				require.Equal(t, "EUR", stored[0].Amount.ISOCurrency)
+			}
+
+			source, err := i.computeBalance(context.Background(), 10, "")
+			require.NoError(t, err)
+			require.Zero(t, source.OutstandingCent)
+
+			target, err := i.computeBalance(context.Background(), 11, "")
+			require.NoError(t, err)
+			require.Zero(t, target.OutstandingCent)
+		})
+	}
+}
+
+// concurrentSpending books a payment spending the credit of the debitor right before the transactions
+// are created, like a concurrent transfer or refund that finished after the credit was checked.
+type concurrentSpending struct {
+	database.Repository
+	spending entities.Transaction
+}
+
+func (c *concurrentSpending) CreateTransactionsWithinCredit(ctx context.Context, debitorID int64, event string, creditCent int64, trs []entities.Transaction) error {
+	if err := c.Repository.CreateTransaction(ctx, c.spending); err != nil {
+		return err
+	}
+	return c.Repository.CreateTransactionsWithinCredit(ctx, debitorID, event, creditCent, trs)
+}
+
+func TestTransferBalanceRechecksCredit(t *testing.T) {
+	db := &concurrentSpending{
+		Repository: inmemory.NewInMemoryProvider(),
+		spending: newTransaction(10, "3", entities.TransactionTypePayment, entities.PaymentMethodInternal, entities.TransactionStatusValid, entities.Amount{
+			ISOCurrency: "EUR",
+			GrossCent:   -30_00,
+			VatRate:     19.0,
+		}),
+	}
+	seedDB(db, []entities.Transaction{
+		eurDue(10, "1", 100_00),
+		newTransaction(10, "2", entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusValid, entities.Amount{
+			ISOCurrency: "EUR",
+			GrossCent:   150_00,
+			VatRate:     19.0,
+		}),
+	})
+
+	i := tstServiceInteractor(db, &AttendeeServiceMock{}, &CncrdAdapterMock{})
+
+	transfer, err := i.TransferBalance(adminCtx(), 10, 11, 50_00, "")
+	require.EqualError(t, err, apierrors.NewConflict("the credit of debitor 10 no longer covers a transfer of 5000").Error())
+	require.Nil(t, transfer)
+
+	stored, err := db.GetTransactionsByFilter(context.Background(), entities.TransactionQuery{DebitorID: 11})
+	require.NoError(t, err)
+	require.Empty(t, stored)
+}
+
+func TestTransferBalanceRetriesCollidingIDs(t *testing.T) {
+	db := &collidingCreates{Repository: inmemory.NewInMemoryProvider()}
+	seedDB(db, []entities.Transaction{
+		eurDue(10, "1", 100_00),
+		newTransaction(10, "2", entities.TransactionTypePayment, entities.PaymentMethodTransfer, entities.TransactionStatusValid, entities.Amount{
+			ISOCurrency: "EUR",
+			GrossCent:   150_00,
+			VatRate:     19.0,
+		}),
+	})
+	db.collisions, db.attempts = 1, nil
+
+	i := tstServiceInteractor(db, &AttendeeServiceMock{
+		PaymentsChangedFunc: func(ctx context.Context, debitorId uint) error {
+			return nil
+		},
+	}, &CncrdAdapterMock{})
+
+	transfer, err := i.TransferBalance(adminCtx(), 10, 11, 50_00, "")
+	require.NoError(t, err)
+	requireCreatedAfterCollision(t, db, []entities.Transaction{transfer.Source, transfer.Target})
+}
@@ -0,0 +1,102 @@
+// Package metrics holds all prometheus metrics of the service.
+//
+// The metrics are registered with the default registry, which also provides the go runtime and process metrics.
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "reg_payment"
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of http requests, by route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of http requests, by route pattern, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	circuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "downstream",
+		Name:      "circuit_breaker_state",
+		Help:      "State of the circuit breaker for a downstream service: 0 = closed, 1 = half-open, 2 = open.",
+	}, []string{"name"})
+
+	transactionsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "business",
+		Name:      "transactions_created_total",
+		Help:      "Number of transactions created, by type, method and status.",
+	}, []string{"type", "method", "status"})
+
+	paylinkFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "business",
+		Name:      "paylink_failures_total",
+		Help:      "Number of failed attempts to create a paylink at the payment provider adapter.",
+	})
+
+	paymentsChangedFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "business",
+		Name:      "payments_changed_failures_total",
+		Help:      "Number of failed calls to the payments changed webhook of the attendee service.",
+	})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of database queries, by operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"operation", "table"})
+)
+
+var circuitBreakerStates = map[string]float64{
+	"closed":    0,
+	"half-open": 1,
+	"open":      2,
+}
+
+func HTTPRequestServed(route string, method string, status string, duration time.Duration) {
+	httpRequests.WithLabelValues(route, method, status).Inc()
+	httpRequestDuration.WithLabelValues(route, method, status).Observe(duration.Seconds())
+}
+
+// CircuitBreakerStateChanged takes the state as named by gobreaker.
+func CircuitBreakerStateChanged(name string, state string) {
+	value, ok := circuitBreakerStates[state]
+	if !ok {
+		return
+	}
+	circuitBreakerState.WithLabelValues(name).Set(value)
+}
+
+func TransactionCreated(transactionType string, method string, status string) {
+	transactionsCreated.WithLabelValues(transactionType, method, status).Inc()
+}
+
+func PaylinkFailed() {
+	paylinkFailures.Inc()
+}
+
+func PaymentsChangedFailed() {
+	paymentsChangedFailures.Inc()
+}
+
+func DBQueryFinished(operation string, table string, duration time.Duration) {
+	dbQueryDuration.WithLabelValues(operation, table).Observe(duration.Seconds())
+}
@@ -0,0 +1,54 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// quietZone is the light border around the symbol required by the standard, in modules
+const quietZone = 4
+
+// SVG draws the code with a quiet zone, each module scale pixels wide.
+func (c *Code) SVG(scale int) []byte {
+	full := c.Size + 2*quietZone
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		full*scale, full*scale, full, full)
+	out.WriteString(`<rect width="100%" height="100%" fill="#ffffff"/><path fill="#000000" d="`)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Dark(x, y) {
+				fmt.Fprintf(&out, "M%d,%dh1v1h-1z", x+quietZone, y+quietZone)
+			}
+		}
+	}
+	out.WriteString(`"/></svg>`)
+	out.WriteByte('\n')
+
+	return out.Bytes()
+}
+
+// PNG draws the code with a quiet zone, each module scale pixels wide.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	full := (c.Size + 2*quietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, full, full), color.Palette{color.White, color.Black})
+	for y := 0; y < full; y++ {
+		for x := 0; x < full; x++ {
+			mx := x/scale - quietZone
+			my := y/scale - quietZone
+			if mx >= 0 && mx < c.Size && my >= 0 && my < c.Size && c.Dark(mx, my) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,275 @@
+package qrcode
+
+type matrix struct {
+	version    int
+	size       int
+	modules    [][]bool
+	isFunction [][]bool // finder, timing and alignment patterns, and format and version information, which are not masked
+}
+
+func newMatrix(version int) *matrix {
+	size := version*4 + 17
+	m := &matrix{version: version, size: size}
+	m.modules = make([][]bool, size)
+	m.isFunction = make([][]bool, size)
+	for y := range m.modules {
+		m.modules[y] = make([]bool, size)
+		m.isFunction[y] = make([]bool, size)
+	}
+	return m
+}
+
+func (m *matrix) setFunction(x, y int, dark bool) {
+	m.modules[y][x] = dark
+	m.isFunction[y][x] = true
+}
+
+func (m *matrix) drawFunctionPatterns(mask int) {
+	for i := 0; i < m.size; i++ {
+		m.setFunction(6, i, i%2 == 0)
+		m.setFunction(i, 6, i%2 == 0)
+	}
+
+	m.drawFinderPattern(3, 3)
+	m.drawFinderPattern(m.size-4, 3)
+	m.drawFinderPattern(3, m.size-4)
+
+	positions := alignmentPositions[m.version]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// these overlap the finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			m.drawAlignmentPattern(x, y)
+		}
+	}
+
+	m.drawFormatBits(mask)
+	m.drawVersionBits()
+}
+
+// drawFinderPattern draws the pattern centered at x, y, including its separator.
+func (m *matrix) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= m.size || yy < 0 || yy >= m.size {
+				continue
+			}
+			distance := max(abs(dx), abs(dy))
+			m.setFunction(xx, yy, distance != 2 && distance != 4)
+		}
+	}
+}
+
+func (m *matrix) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			m.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits draws both copies of the error correction level and mask, protected by a BCH code.
+func (m *matrix) drawFormatBits(mask int) {
+	bits := formatBits(mask)
+
+	// around the top left finder pattern
+	for i := 0; i <= 5; i++ {
+		m.setFunction(8, i, bit(bits, i))
+	}
+	m.setFunction(8, 7, bit(bits, 6))
+	m.setFunction(8, 8, bit(bits, 7))
+	m.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		m.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	// split between the other two finder patterns
+	for i := 0; i < 8; i++ {
+		m.setFunction(m.size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		m.setFunction(8, m.size-15+i, bit(bits, i))
+	}
+	m.setFunction(8, m.size-8, true) // always dark
+}
+
+// drawVersionBits draws both copies of the version, protected by a BCH code, which is only present from version 7.
+func (m *matrix) drawVersionBits() {
+	if m.version < 7 {
+		return
+	}
+
+	bits := versionBits(m.version)
+	for i := 0; i < 18; i++ {
+		a := m.size - 11 + i%3
+		b := i / 3
+		m.setFunction(a, b, bit(bits, i))
+		m.setFunction(b, a, bit(bits, i))
+	}
+}
+
+func formatBits(mask int) int {
+	data := 0b00<<3 | mask // 00 is error correction level M
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+func versionBits(version int) int {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return version<<12 | rem
+}
+
+// drawCodewords fills the modules that are not part of a function pattern in the zigzag order of the standard,
+// two columns at a time from the right, alternating upwards and downwards.
+func (m *matrix) drawCodewords(codewords []byte) {
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// skip the vertical timing pattern
+			right = 5
+		}
+		for vertical := 0; vertical < m.size; vertical++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vertical
+				if (right+1)&2 == 0 {
+					y = m.size - 1 - vertical
+				}
+				if !m.isFunction[y][x] && i < len(codewords)*8 {
+					m.modules[y][x] = bit(int(codewords[i/8]), 7-i%8)
+					i++
+				}
+				// the remainder bits left over are light
+			}
+		}
+	}
+}
+
+func (m *matrix) applyMask(mask int) {
+	for y := 0; y < m.size; y++ {
+		for x := 0; x < m.size; x++ {
+			if m.isFunction[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			m.modules[y][x] = m.modules[y][x] != invert
+		}
+	}
+}
+
+var finderLike = [][]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty rates how hard the masked symbol is to read, following the four rules of the standard.
+func (m *matrix) penalty() int {
+	result := 0
+
+	line := make([]bool, m.size)
+	for _, horizontal := range []bool{true, false} {
+		for a := 0; a < m.size; a++ {
+			for b := 0; b < m.size; b++ {
+				if horizontal {
+					line[b] = m.modules[a][b]
+				} else {
+					line[b] = m.modules[b][a]
+				}
+			}
+
+			// runs of five or more modules of the same color
+			run := 1
+			for b := 1; b <= m.size; b++ {
+				if b < m.size && line[b] == line[b-1] {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += 3 + run - 5
+				}
+				run = 1
+			}
+
+			// patterns looking like a finder pattern
+			for b := 0; b+len(finderLike[0]) <= m.size; b++ {
+				for _, pattern := range finderLike {
+					if matches(line[b:], pattern) {
+						result += 40
+					}
+				}
+			}
+		}
+	}
+
+	// blocks of 2x2 modules of the same color
+	for y := 0; y < m.size-1; y++ {
+		for x := 0; x < m.size-1; x++ {
+			c := m.modules[y][x]
+			if c == m.modules[y][x+1] && c == m.modules[y+1][x] && c == m.modules[y+1][x+1] {
+				result += 3
+			}
+		}
+	}
+
+	// deviation from an equal share of dark modules, in steps of 5%
+	dark := 0
+	for _, row := range m.modules {
+		for _, c := range row {
+			if c {
+				dark++
+			}
+		}
+	}
+	total := m.size * m.size
+	result += abs(dark*20-total*10) / total * 10
+
+	return result
+}
+
+func matches(line []bool, pattern []bool) bool {
+	for i, p := range pattern {
+		if line[i] != p {
+			return false
+		}
+	}
+	return true
+}
+
+func bit(value int, i int) bool {
+	return (value>>i)&1 == 1
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
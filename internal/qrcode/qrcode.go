@@ -0,0 +1,216 @@
+// Package qrcode encodes data as QR code (ISO/IEC 18004) in byte mode with error correction level M.
+//
+// It supports versions 1 to 13, which holds up to 331 bytes. That is the limit the EPC QR code
+// guidelines set for SEPA credit transfers, which is what this service needs QR codes for.
+package qrcode
+
+import (
+	"errors"
+	"math"
+)
+
+// Code is an encoded QR code without quiet zone
+type Code struct {
+	Size    int // the width and height in modules
+	modules [][]bool
+}
+
+// Dark reports whether the module in column x and row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+type blockLayout struct {
+	ecPerBlock int
+	groups     [][2]int // pairs of block count and data codewords per block
+}
+
+// layouts holds the error correction blocks of level M, indexed by version
+var layouts = []blockLayout{
+	{},
+	{10, [][2]int{{1, 16}}},
+	{16, [][2]int{{1, 28}}},
+	{26, [][2]int{{1, 44}}},
+	{18, [][2]int{{2, 32}}},
+	{24, [][2]int{{2, 43}}},
+	{16, [][2]int{{4, 27}}},
+	{18, [][2]int{{4, 31}}},
+	{22, [][2]int{{2, 38}, {2, 39}}},
+	{22, [][2]int{{3, 36}, {2, 37}}},
+	{26, [][2]int{{4, 43}, {1, 44}}},
+	{30, [][2]int{{1, 50}, {4, 51}}},
+	{22, [][2]int{{6, 36}, {2, 37}}},
+	{22, [][2]int{{8, 37}, {1, 38}}},
+}
+
+// alignmentPositions holds the centers of the alignment patterns in both directions, indexed by version
+var alignmentPositions = [][]int{
+	{}, {},
+	{6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34},
+	{6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50}, {6, 30, 54}, {6, 32, 58}, {6, 34, 62},
+}
+
+const maxVersion = 13
+
+// ErrTooLong is returned if the data does not fit into the largest supported version.
+var ErrTooLong = errors.New("data too long for a qr code of version 13 with error correction level M")
+
+func (l blockLayout) dataCodewords() int {
+	total := 0
+	for _, g := range l.groups {
+		total += g[0] * g[1]
+	}
+	return total
+}
+
+// Encode creates the smallest QR code holding data, choosing the mask with the lowest penalty.
+func Encode(data []byte) (*Code, error) {
+	version := 0
+	for v := 1; v <= maxVersion; v++ {
+		if 4+countBits(v)+8*len(data) <= 8*layouts[v].dataCodewords() {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	codewords := addErrorCorrection(version, dataCodewords(version, data))
+
+	var best *Code
+	bestPenalty := math.MaxInt
+	for mask := 0; mask < 8; mask++ {
+		m := drawMatrix(version, codewords, mask)
+		if penalty := m.penalty(); penalty < bestPenalty {
+			best = &Code{Size: m.size, modules: m.modules}
+			bestPenalty = penalty
+		}
+	}
+
+	return best, nil
+}
+
+// drawMatrix places the codewords in a matrix of the given version, masked with the given mask.
+func drawMatrix(version int, codewords []byte, mask int) *matrix {
+	m := newMatrix(version)
+	m.drawFunctionPatterns(mask)
+	m.drawCodewords(codewords)
+	m.applyMask(mask)
+	return m
+}
+
+// countBits is the length of the character count indicator of byte mode
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// dataCodewords encodes data in byte mode, followed by terminator and padding up to the capacity of the version.
+func dataCodewords(version int, data []byte) []byte {
+	capacity := layouts[version].dataCodewords()
+
+	var bits bitBuffer
+	bits.append(0b0100, 4) // byte mode
+	bits.append(len(data), countBits(version))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+
+	bits.append(0, min(4, capacity*8-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	for pad := 0xEC; len(bits) < capacity*8; pad ^= 0xEC ^ 0x11 {
+		bits.append(pad, 8)
+	}
+
+	result := make([]byte, capacity)
+	for i, bit := range bits {
+		if bit {
+			result[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return result
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value int, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+// addErrorCorrection splits the data into blocks, and interleaves them together with their error correction codewords.
+func addErrorCorrection(version int, data []byte) []byte {
+	layout := layouts[version]
+	divisor := reedSolomonDivisor(layout.ecPerBlock)
+
+	var blocks, ecBlocks [][]byte
+	for _, g := range layout.groups {
+		for i := 0; i < g[0]; i++ {
+			block := data[:g[1]]
+			data = data[g[1]:]
+			blocks = append(blocks, block)
+			ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+		}
+	}
+
+	var result []byte
+	longest := layout.groups[len(layout.groups)-1][1]
+	for i := 0; i < longest; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ecPerBlock; i++ {
+		for _, ec := range ecBlocks {
+			result = append(result, ec[i])
+		}
+	}
+	return result
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree, highest coefficient first, without the leading 1.
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func reedSolomonRemainder(data []byte, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMultiply(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
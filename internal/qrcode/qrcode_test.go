@@ -0,0 +1,243 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReedSolomon(t *testing.T) {
+	// HELLO WORLD as 1-M, the example from the standard
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	require.Equal(t, []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}, reedSolomonRemainder(data, reedSolomonDivisor(10)))
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	require.Equal(t, 0b101010000010010, formatBits(0))
+	require.Equal(t, 0b100000011001110, formatBits(5))
+	require.Equal(t, 0b000111110010010100, versionBits(7))
+	require.Equal(t, 0b001101100001000111, versionBits(13))
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		version int
+	}{
+		{name: "smallest version", length: 14, version: 1},
+		{name: "next version", length: 15, version: 2},
+		{name: "16 bit character count", length: 200, version: 10},
+		{name: "largest supported version", length: 331, version: 13},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(strings.Repeat("BCD\n002\n1\nSCT\n", 30))[:tt.length]
+
+			code, err := Encode(data)
+			require.NoError(t, err)
+			require.Equal(t, tt.version*4+17, code.Size)
+
+			// finder pattern in the top left corner
+			for i := 0; i < 7; i++ {
+				require.True(t, code.Dark(i, 0))
+				require.True(t, code.Dark(0, i))
+			}
+			require.False(t, code.Dark(1, 1))
+			require.True(t, code.Dark(3, 3))
+
+			require.Equal(t, dataCodewords(tt.version, data), readDataCodewords(t, code, tt.version))
+		})
+	}
+
+	_, err := Encode(make([]byte, 332))
+	require.Equal(t, ErrTooLong, err)
+}
+
+// TestEncodeMatchesReference compares an EPC payload with the matrix rsc.io/qr v0.2.0 produces for it.
+// That encoder also uses byte mode and error correction level M, but always applies mask 0.
+func TestEncodeMatchesReference(t *testing.T) {
+	payload := "BCD\n002\n1\nSCT\nCOBADEFFXXX\nEurofurence e.V.\nDE89370400440532013000\nEUR1234.56\n\nRF2500001001021200000009"
+	expected := []string{
+		"#######..####.###.#.#..##......##.#######",
+		"#.....#.#.#....###.#.#.##..###.##.#.....#",
+		"#.###.#..##.....#..##.###.#...###.#.###.#",
+		"#.###.#...#..##..#..#..##..##..#..#.###.#",
+		"#.###.#.####.#.#.#.##..###.....#..#.###.#",
+		"#.....#..###.#...#.#.####..#.#.#..#.....#",
+		"#######.#.#.#.#.#.#.#.#.#.#.#.#.#.#######",
+		".........#.##...##.#.#.###..###.#........",
+		"#.#.#.#..##..#.##.......#..##.##....#..#.",
+		"..##......####...####..###.#.#....##.#..#",
+		"...####.#.#.##.#....#.#..#..###.#.##....#",
+		"####....#####.#.##.#.#.#.#.####..#.#...##",
+		"################.######..##..#.#..##..#.#",
+		".....#..#...#.##..#####..#...#....#.....#",
+		"###.####.....#.######...#.....#.#.#..##.#",
+		"##..##..#..#######..##.#.###.#.#.#.....#.",
+		"###...#.#.#.##..#..####.....###...##.#.##",
+		"..#..#.....#####..##.#...######...##....#",
+		"###.#.####.#.#.#.#..#.#.#.#.#.#..##...###",
+		"............##.#....##...#...###...###...",
+		"..##.#####.#..###....##.#######.####.#.##",
+		".##.#....##.###.#....######...#.#.##...#.",
+		".#.##.##.###..##..#.....###..##...#.#####",
+		"#.##.#.##.#......#..##########.#####.#.##",
+		"#....##..##.#..#..#.....#.......#.##.#.##",
+		"#.#......#.###..##.##..##..#####..##.#..#",
+		".#..####...#.##...#.#.#.##......#.####..#",
+		".####..###..###..#..###..#...##..#.....##",
+		"###..##..###.#..###.###...#..##...#...###",
+		".##.##..###..#..####..#..#...#....#..#..#",
+		"#.#####.#.##.#.#.##..#..###.#.#...##....#",
+		".##..#...##..#..##.#.#.#.#...#.#.#..#..#.",
+		"#.##..#....#..##...#.##..##.###.#####...#",
+		"........#.##.#...#...##..###.##.#...##.#.",
+		"#######..#..#.....#...#...#.#.###.#.#.###",
+		"#.....#...#.##.#.#####.#.#.#.#.##...##.##",
+		"#.###.#.#.#.#.#...#..##.###.###.######...",
+		"#.###.#..###.#..#.#..####.#.####.#.#.#.##",
+		"#.###.#.##.....###...#..##..###.##..#.###",
+		"#.....#..#.#..#...#..#...###.#####.#.#.#.",
+		"#######.##.....###..#..##...#..##..###.##",
+	}
+
+	m := drawMatrix(6, addErrorCorrection(6, dataCodewords(6, []byte(payload))), 0)
+	actual := make([]string, m.size)
+	for y, row := range m.modules {
+		for _, module := range row {
+			if module {
+				actual[y] += "#"
+			} else {
+				actual[y] += "."
+			}
+		}
+	}
+	require.Equal(t, expected, actual)
+
+	code, err := Encode([]byte(payload))
+	require.NoError(t, err)
+	require.Equal(t, len(expected), code.Size)
+}
+
+func TestImages(t *testing.T) {
+	code, err := Encode([]byte("EF2024-000010-0102-120000-0003"))
+	require.NoError(t, err)
+
+	svg := string(code.SVG(4))
+	require.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="148" height="148" viewBox="0 0 37 37"`))
+	require.Contains(t, svg, "M4,4h1v1h-1z")
+
+	encoded, err := code.PNG(4)
+	require.NoError(t, err)
+	img, err := png.Decode(bytes.NewReader(encoded))
+	require.NoError(t, err)
+	require.Equal(t, 148, img.Bounds().Dx())
+
+	r, _, _, _ := img.At(0, 0).RGBA()
+	require.Equal(t, uint32(0xffff), r)
+	r, _, _, _ = img.At(16, 16).RGBA()
+	require.Equal(t, uint32(0), r)
+}
+
+// readDataCodewords decodes the code the way a scanner would, checking the error correction codewords on the way.
+func readDataCodewords(t *testing.T, code *Code, version int) []byte {
+	m := newMatrix(version)
+	m.drawFunctionPatterns(0)
+
+	// the first copy of the format bits
+	format := 0
+	for i := 0; i <= 5; i++ {
+		format |= dark(code, 8, i) << i
+	}
+	format |= dark(code, 8, 7)<<6 | dark(code, 8, 8)<<7 | dark(code, 7, 8)<<8
+	for i := 9; i < 15; i++ {
+		format |= dark(code, 14-i, 8) << i
+	}
+	mask := -1
+	for candidate := 0; candidate < 8; candidate++ {
+		if formatBits(candidate) == format {
+			mask = candidate
+		}
+	}
+	require.NotEqual(t, -1, mask, "format bits %015b", format)
+
+	for y := 0; y < code.Size; y++ {
+		for x := 0; x < code.Size; x++ {
+			if !m.isFunction[y][x] {
+				m.modules[y][x] = code.Dark(x, y)
+			}
+		}
+	}
+	m.applyMask(mask)
+
+	layout := layouts[version]
+	total := layout.dataCodewords()
+	for _, g := range layout.groups {
+		total += g[0] * layout.ecPerBlock
+	}
+
+	codewords := make([]byte, total)
+	i := 0
+	for right := m.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vertical := 0; vertical < m.size; vertical++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vertical
+				if (right+1)&2 == 0 {
+					y = m.size - 1 - vertical
+				}
+				if !m.isFunction[y][x] && i < total*8 {
+					if m.modules[y][x] {
+						codewords[i/8] |= 1 << (7 - i%8)
+					}
+					i++
+				}
+			}
+		}
+	}
+
+	// undo the interleaving
+	var blocks [][]byte
+	for _, g := range layout.groups {
+		for n := 0; n < g[0]; n++ {
+			blocks = append(blocks, make([]byte, 0, g[1]))
+		}
+	}
+	next := 0
+	for len(blocks[len(blocks)-1]) < cap(blocks[len(blocks)-1]) {
+		for b := range blocks {
+			if len(blocks[b]) < cap(blocks[b]) {
+				blocks[b] = append(blocks[b], codewords[next])
+				next++
+			}
+		}
+	}
+
+	var data []byte
+	divisor := reedSolomonDivisor(layout.ecPerBlock)
+	for b, block := range blocks {
+		ec := make([]byte, layout.ecPerBlock)
+		for k := range ec {
+			ec[k] = codewords[next+k*len(blocks)+b]
+		}
+		require.Equal(t, reedSolomonRemainder(block, divisor), ec, "error correction of block %d", b)
+		data = append(data, block...)
+	}
+
+	return data
+}
+
+func dark(code *Code, x, y int) int {
+	if code.Dark(x, y) {
+		return 1
+	}
+	return 0
+}
@@ -1,6 +1,8 @@
 package inmemory
 
 import (
+	"context"
+
 	"github.com/eurofurence/reg-payment-service/internal/entities"
 	"github.com/eurofurence/reg-payment-service/internal/repository/database"
 )
@@ -10,6 +12,10 @@ var _ database.Repository = (*inmemoryProvider)(nil)
 type inmemoryProvider struct {
 	transactions    map[uint]entities.Transaction
 	transactionLogs map[uint]entities.TransactionLog
+	auditLog        []entities.AuditLogEntry
+	chainHeads      map[string]entities.HashChainHead
+	plans           map[uint]entities.InstallmentPlan
+	documents       []entities.IssuedDocument
 	idSequence      uint32
 }
 
@@ -17,6 +23,8 @@ func NewInMemoryProvider() database.Repository {
 	return &inmemoryProvider{
 		transactions:    make(map[uint]entities.Transaction),
 		transactionLogs: make(map[uint]entities.TransactionLog),
+		chainHeads:      make(map[string]entities.HashChainHead),
+		plans:           make(map[uint]entities.InstallmentPlan),
 	}
 }
 
@@ -24,3 +32,15 @@ func (i *inmemoryProvider) Migrate() error {
 	// Nothing to do here
 	return nil
 }
+
+func (i *inmemoryProvider) Close() error {
+	return nil
+}
+
+func (i *inmemoryProvider) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (i *inmemoryProvider) CheckSchema(ctx context.Context) error {
+	return nil
+}
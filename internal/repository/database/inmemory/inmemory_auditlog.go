@@ -0,0 +1,55 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+)
+
+const auditLogChainName = "audit_log"
+
+func (m *inmemoryProvider) CreateAuditLogEntry(ctx context.Context, e entities.AuditLogEntry) error {
+	if e.ID != 0 {
+		return errors.New("create needs a new audit log entry")
+	}
+	e.ID = uint(atomic.AddUint32(&m.idSequence, 1))
+	e.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	e.PrevHash = m.chainHeads[auditLogChainName].Hash
+	e.Hash = e.ComputeHash()
+
+	m.auditLog = append(m.auditLog, e)
+	m.advanceChainHead(auditLogChainName, e.Hash)
+	return nil
+}
+
+func (m *inmemoryProvider) GetAuditLogEntries(ctx context.Context, query entities.AuditLogQuery) ([]entities.AuditLogEntry, error) {
+	result := make([]entities.AuditLogEntry, 0)
+	for _, e := range m.auditLog {
+		if query.Actor != "" && e.Actor != query.Actor {
+			continue
+		}
+		if query.DebitorID != 0 && e.DebitorID != query.DebitorID {
+			continue
+		}
+		if query.TransactionIdentifier != "" && e.TransactionID != query.TransactionIdentifier {
+			continue
+		}
+		if query.Outcome != "" && e.Outcome != query.Outcome {
+			continue
+		}
+		if !query.CreatedFrom.IsZero() && e.CreatedAt.Before(query.CreatedFrom) {
+			continue
+		}
+		if !query.CreatedBefore.IsZero() && !e.CreatedAt.Before(query.CreatedBefore) {
+			continue
+		}
+
+		result = append(result, e)
+	}
+
+	return result, nil
+}
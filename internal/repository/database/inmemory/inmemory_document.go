@@ -0,0 +1,38 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+)
+
+func (m *inmemoryProvider) IssueDocument(ctx context.Context, doc entities.IssuedDocument) (*entities.IssuedDocument, error) {
+	if doc.ID != 0 || doc.Sequence != 0 {
+		return nil, errors.New("issue needs a new document")
+	}
+
+	var last int64
+	for _, existing := range m.documents {
+		if existing.Kind != doc.Kind {
+			continue
+		}
+		if existing.Subject == doc.Subject {
+			return &existing, nil
+		}
+		if existing.Sequence > last {
+			last = existing.Sequence
+		}
+	}
+
+	doc.ID = uint(atomic.AddUint32(&m.idSequence, 1))
+	doc.Sequence = last + 1
+	if doc.CreatedAt.IsZero() {
+		doc.CreatedAt = time.Now()
+	}
+
+	m.documents = append(m.documents, doc)
+	return &doc, nil
+}
@@ -0,0 +1,33 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+)
+
+func (m *inmemoryProvider) GetHashChainHeads(ctx context.Context, prefix string) ([]entities.HashChainHead, error) {
+	result := make([]entities.HashChainHead, 0)
+	for name, head := range m.chainHeads {
+		if strings.HasPrefix(name, prefix) {
+			result = append(result, head)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ChainName < result[j].ChainName
+	})
+	return result, nil
+}
+
+func (m *inmemoryProvider) advanceChainHead(chainName string, hash string) {
+	head := m.chainHeads[chainName]
+	head.ChainName = chainName
+	head.Hash = hash
+	head.Length++
+	head.UpdatedAt = time.Now().UTC()
+	m.chainHeads[chainName] = head
+}
@@ -0,0 +1,63 @@
+package inmemory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
+)
+
+func (m *inmemoryProvider) CreateInstallmentPlan(ctx context.Context, plan entities.InstallmentPlan) error {
+	if plan.ID != 0 {
+		return errors.New("create needs a new installment plan")
+	}
+	for _, existing := range m.plans {
+		if existing.DebitorID == plan.DebitorID && existing.Event == plan.Event {
+			return database.ErrInstallmentPlanExists
+		}
+	}
+
+	plan.ID = uint(atomic.AddUint32(&m.idSequence, 1))
+	if plan.CreatedAt.IsZero() {
+		plan.CreatedAt = time.Now()
+	}
+
+	installments := make([]entities.Installment, len(plan.Installments))
+	for i, inst := range plan.Installments {
+		inst.ID = uint(atomic.AddUint32(&m.idSequence, 1))
+		inst.PlanID = plan.ID
+		installments[i] = inst
+	}
+	plan.Installments = installments
+
+	m.plans[plan.ID] = plan
+	return nil
+}
+
+func (m *inmemoryProvider) GetInstallmentPlans(ctx context.Context, debitorID int64, event string) ([]entities.InstallmentPlan, error) {
+	result := make([]entities.InstallmentPlan, 0)
+	for _, plan := range m.plans {
+		if plan.DebitorID == debitorID && (event == "" || plan.Event == event) {
+			installments := append([]entities.Installment(nil), plan.Installments...)
+			sort.Slice(installments, func(i, j int) bool {
+				return installments[i].Number < installments[j].Number
+			})
+			plan.Installments = installments
+			result = append(result, plan)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Event < result[j].Event
+	})
+	return result, nil
+}
+
+func (m *inmemoryProvider) DeleteInstallmentPlan(ctx context.Context, id uint) error {
+	delete(m.plans, id)
+	return nil
+}
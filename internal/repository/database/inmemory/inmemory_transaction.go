@@ -4,18 +4,23 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"sort"
 	"sync/atomic"
 	"time"
 
 	"gorm.io/gorm"
 
 	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
 )
 
 func (m *inmemoryProvider) CreateTransaction(ctx context.Context, tr entities.Transaction) error {
 	if tr.ID != 0 {
 		return errors.New("create needs a new transaction")
 	}
+	if m.transactionExists(tr) {
+		return database.ErrTransactionExists
+	}
 	tr.ID = uint(atomic.AddUint32(&m.idSequence, 1))
 
 	// set a creation date if none was provided beforehand
@@ -27,6 +32,68 @@ func (m *inmemoryProvider) CreateTransaction(ctx context.Context, tr entities.Tr
 	return nil
 }
 
+func (m *inmemoryProvider) CreateTransactions(ctx context.Context, trs []entities.Transaction) error {
+	for i, tr := range trs {
+		if tr.ID != 0 {
+			return errors.New("create needs new transactions")
+		}
+		if m.transactionExists(tr) {
+			return database.ErrTransactionExists
+		}
+		for _, other := range trs[:i] {
+			if sameTransaction(tr, other) {
+				return database.ErrTransactionExists
+			}
+		}
+	}
+
+	for _, tr := range trs {
+		if err := m.CreateTransaction(ctx, tr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *inmemoryProvider) CreateTransactionsWithinCredit(ctx context.Context, debitorID int64, event string, creditCent int64, trs []entities.Transaction) error {
+	var credit int64
+	for _, t := range m.transactions {
+		if t.DebitorID != debitorID || (event != "" && t.Event != event) || !t.DeletedAt.Time.IsZero() {
+			continue
+		}
+
+		switch {
+		case t.TransactionStatus == entities.TransactionStatusValid && t.TransactionType == entities.TransactionTypePayment:
+			credit += t.Amount.GrossCent
+		case t.TransactionStatus == entities.TransactionStatusValid && t.TransactionType == entities.TransactionTypeDue:
+			credit -= t.Amount.GrossCent
+		case (t.TransactionStatus == entities.TransactionStatusTentative || t.TransactionStatus == entities.TransactionStatusPending) &&
+			t.TransactionType == entities.TransactionTypePayment && t.Amount.GrossCent < 0:
+			credit += t.Amount.GrossCent
+		}
+	}
+
+	if credit < creditCent {
+		return database.ErrInsufficientCredit
+	}
+
+	return m.CreateTransactions(ctx, trs)
+}
+
+// transactionExists checks the unique indexes of the database, on the transaction id and the creditor reference
+func (m *inmemoryProvider) transactionExists(tr entities.Transaction) bool {
+	for _, t := range m.transactions {
+		if sameTransaction(t, tr) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameTransaction(a, b entities.Transaction) bool {
+	return a.TransactionID == b.TransactionID || (a.CreditorReference.Valid && a.CreditorReference == b.CreditorReference)
+}
+
 func (m *inmemoryProvider) UpdateTransaction(ctx context.Context, tr entities.Transaction, _ bool) error {
 	if tr.ID == 0 {
 		found, err := m.GetTransactionByTransactionIDAndType(ctx, tr.TransactionID, tr.TransactionType)
@@ -46,6 +113,27 @@ func (m *inmemoryProvider) UpdateTransaction(ctx context.Context, tr entities.Tr
 	return nil
 }
 
+func (m *inmemoryProvider) UpdateTransactions(ctx context.Context, trs []entities.Transaction) error {
+	for _, tr := range trs {
+		if _, err := m.GetTransactionByTransactionIDAndType(ctx, tr.TransactionID, tr.TransactionType); err != nil {
+			return err
+		}
+	}
+
+	for _, tr := range trs {
+		var err error
+		if reflect.ValueOf(tr.Deletion).IsZero() {
+			err = m.UpdateTransaction(ctx, tr, true)
+		} else {
+			err = m.DeleteTransaction(ctx, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (m *inmemoryProvider) GetTransactionByTransactionIDAndType(ctx context.Context, transactionID string, tType entities.TransactionType) (*entities.Transaction, error) {
 	for _, t := range m.transactions {
 		if t.TransactionID == transactionID && t.TransactionType == tType {
@@ -69,6 +157,15 @@ func (m *inmemoryProvider) GetTransactionsByFilter(ctx context.Context, query en
 		if query.TransactionIdentifier != "" && t.TransactionID != query.TransactionIdentifier {
 			continue
 		}
+		if query.CreditorReference != "" && t.CreditorReference.String != query.CreditorReference {
+			continue
+		}
+		if query.Event != "" && t.Event != query.Event {
+			continue
+		}
+		if query.GroupReference != "" && t.GroupReference != query.GroupReference {
+			continue
+		}
 
 		if !query.EffectiveFrom.IsZero() && query.EffectiveFrom.After(t.EffectiveDate.Time) {
 			continue
@@ -92,6 +189,15 @@ func (m *inmemoryProvider) GetAdminTransactionsByFilter(ctx context.Context, que
 		if query.TransactionIdentifier != "" && t.TransactionID != query.TransactionIdentifier {
 			continue
 		}
+		if query.CreditorReference != "" && t.CreditorReference.String != query.CreditorReference {
+			continue
+		}
+		if query.Event != "" && t.Event != query.Event {
+			continue
+		}
+		if query.GroupReference != "" && t.GroupReference != query.GroupReference {
+			continue
+		}
 
 		if !query.EffectiveFrom.IsZero() && query.EffectiveFrom.After(t.EffectiveDate.Time) {
 			continue
@@ -107,10 +213,10 @@ func (m *inmemoryProvider) GetAdminTransactionsByFilter(ctx context.Context, que
 	return result, nil
 }
 
-func (m *inmemoryProvider) GetValidTransactionsForDebitor(ctx context.Context, debitorID int64) ([]entities.Transaction, error) {
+func (m *inmemoryProvider) GetValidTransactionsForDebitor(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
 	result := make([]entities.Transaction, 0)
 	for _, t := range m.transactions {
-		if t.DebitorID == debitorID && t.TransactionStatus == entities.TransactionStatusValid {
+		if t.DebitorID == debitorID && (event == "" || t.Event == event) && t.TransactionStatus == entities.TransactionStatusValid {
 			result = append(result, t)
 		}
 	}
@@ -118,12 +224,12 @@ func (m *inmemoryProvider) GetValidTransactionsForDebitor(ctx context.Context, d
 	return result, nil
 }
 
-func (m *inmemoryProvider) QueryOutstandingDuesForDebitor(ctx context.Context, debutorID int64) (int64, error) {
+func (m *inmemoryProvider) QueryOutstandingDuesForDebitor(ctx context.Context, debitorID int64, event string) (int64, error) {
 	dues := int64(0)
 	payments := int64(0)
 
 	for _, tr := range m.transactions {
-		if reflect.ValueOf(tr.Deletion).IsZero() && tr.TransactionStatus == entities.TransactionStatusValid {
+		if tr.DebitorID == debitorID && reflect.ValueOf(tr.Deletion).IsZero() && (event == "" || tr.Event == event) && tr.TransactionStatus == entities.TransactionStatusValid {
 			if tr.TransactionType == entities.TransactionTypeDue {
 				dues += tr.Amount.GrossCent
 			}
@@ -137,7 +243,50 @@ func (m *inmemoryProvider) QueryOutstandingDuesForDebitor(ctx context.Context, d
 	return (dues - payments), nil
 }
 
+func (m *inmemoryProvider) QueryOverpaidDebitors(ctx context.Context, event string) ([]entities.Overpayment, error) {
+	balances := make(map[int64]*entities.Overpayment)
+
+	for _, tr := range m.transactions {
+		if reflect.ValueOf(tr.Deletion).IsZero() && (event == "" || tr.Event == event) && tr.TransactionStatus == entities.TransactionStatusValid {
+			balance, ok := balances[tr.DebitorID]
+			if !ok {
+				balance = &entities.Overpayment{DebitorID: tr.DebitorID}
+				balances[tr.DebitorID] = balance
+			}
+
+			if tr.TransactionType == entities.TransactionTypeDue {
+				balance.DuesCent += tr.Amount.GrossCent
+			}
+
+			if tr.TransactionType == entities.TransactionTypePayment {
+				balance.PaymentsCent += tr.Amount.GrossCent
+			}
+		}
+	}
+
+	result := make([]entities.Overpayment, 0)
+	for _, balance := range balances {
+		if balance.CreditCent() > 0 {
+			result = append(result, *balance)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DebitorID < result[j].DebitorID
+	})
+
+	return result, nil
+}
+
 func (m *inmemoryProvider) DeleteTransaction(ctx context.Context, tr entities.Transaction) error {
+	if tr.ID == 0 {
+		found, err := m.GetTransactionByTransactionIDAndType(ctx, tr.TransactionID, tr.TransactionType)
+		if err != nil {
+			return err
+		}
+
+		tr.ID = found.ID
+	}
+
 	if cur, e := m.transactions[tr.ID]; e {
 		cur.DeletedAt = gorm.DeletedAt{Time: time.Now().UTC(), Valid: true}
 		cur.Deletion = entities.Deletion{
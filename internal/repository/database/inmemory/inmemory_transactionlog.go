@@ -3,7 +3,9 @@ package inmemory
 import (
 	"context"
 	"errors"
+	"sort"
 	"sync/atomic"
+	"time"
 
 	"github.com/eurofurence/reg-payment-service/internal/entities"
 )
@@ -13,7 +15,14 @@ func (m *inmemoryProvider) CreateTransactionLog(ctx context.Context, tl entities
 		return errors.New("create needs a new transaction log entry")
 	}
 	tl.ID = uint(atomic.AddUint32(&m.idSequence, 1))
+	tl.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	chainName := entities.TransactionLogChainName(tl.TransactionID)
+	tl.PrevHash = m.chainHeads[chainName].Hash
+	tl.Hash = tl.ComputeHash()
+
 	m.transactionLogs[tl.ID] = tl
+	m.advanceChainHead(chainName, tl.Hash)
 	return nil
 }
 
@@ -24,3 +33,36 @@ func (m *inmemoryProvider) GetTransactionLogByID(ctx context.Context, id uint) (
 	}
 	return &tl, nil
 }
+
+func (m *inmemoryProvider) GetTransactionLogs(ctx context.Context, afterID uint, limit int) ([]entities.TransactionLog, error) {
+	result := make([]entities.TransactionLog, 0)
+	for _, tl := range m.transactionLogs {
+		if tl.ID > afterID {
+			result = append(result, tl)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *inmemoryProvider) GetTransactionLogsForTransaction(ctx context.Context, transactionID string) ([]entities.TransactionLog, error) {
+	result := make([]entities.TransactionLog, 0)
+	for _, tl := range m.transactionLogs {
+		if tl.TransactionID == transactionID {
+			result = append(result, tl)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
@@ -3,6 +3,7 @@ package mysql
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	gormlogger "gorm.io/gorm/logger"
@@ -20,9 +21,27 @@ import (
 type mysqlConnector struct {
 	logger logging.Logger
 	db     *gorm.DB
+	// used to assign transactions from before events existed to the event of their transaction id prefix
+	events []config.EventConfig
+	// the schema only changes through Migrate, so it need not be checked again once it was complete
+	schemaChecked atomic.Bool
 }
 
-func NewMySQLConnector(conf config.DatabaseConfig, logger logging.Logger) (database.Repository, error) {
+func migratedModels() []interface{} {
+	return []interface{}{
+		&entities.Transaction{},
+		&entities.TransactionLog{},
+		&entities.AuditLogEntry{},
+		&entities.HashChainHead{},
+		&entities.InstallmentPlan{},
+		&entities.Installment{},
+		&entities.LineItem{},
+		&entities.DocumentSequence{},
+		&entities.IssuedDocument{},
+	}
+}
+
+func NewMySQLConnector(conf config.DatabaseConfig, events []config.EventConfig, logger logging.Logger) (database.Repository, error) {
 	dsn, err := buildMySQLDSN(conf.Username, conf.Password, conf.Database, conf.Parameters)
 	if err != nil {
 		return nil, err
@@ -33,12 +52,18 @@ func NewMySQLConnector(conf config.DatabaseConfig, logger logging.Logger) (datab
 			TablePrefix: "pay_",
 		},
 		Logger: gormlogger.Default.LogMode(gormlogger.Silent),
+		// reports violations of unique indexes as gorm.ErrDuplicatedKey
+		TranslateError: true,
 	}
 	db, err := gorm.Open(mysql.Open(dsn), &gormConfig)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := registerMetricsCallbacks(db); err != nil {
+		return nil, err
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
@@ -48,23 +73,46 @@ func NewMySQLConnector(conf config.DatabaseConfig, logger logging.Logger) (datab
 	sqlDB.SetMaxIdleConns(50)
 	sqlDB.SetConnMaxLifetime(time.Minute * 10)
 
-	return &mysqlConnector{
-		logger: logger,
-		db:     db,
+	return &tracedConnector{
+		mysqlConnector: &mysqlConnector{
+			logger: logger,
+			db:     db,
+			events: events,
+		},
 	}, nil
 
 }
 
 func (i *mysqlConnector) Migrate() error {
-	err := i.db.AutoMigrate(
-		&entities.Transaction{},
-		&entities.TransactionLog{},
-	)
+	dropTriggers, createTriggers := i.appendOnlyTriggers()
+
+	// the triggers would also reject the changes made by the migration
+	for _, stmt := range dropTriggers {
+		if err := i.db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
+	err := i.db.AutoMigrate(migratedModels()...)
 
 	if err != nil {
 		return err
 	}
 
+	if err := i.sealLegacyTransactionLogs(); err != nil {
+		return err
+	}
+
+	if err := i.assignLegacyTransactionEvents(); err != nil {
+		return err
+	}
+
+	for _, stmt := range createTriggers {
+		if err := i.db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
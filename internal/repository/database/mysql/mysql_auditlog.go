@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+)
+
+const auditLogChainName = "audit_log"
+
+func (m *mysqlConnector) CreateAuditLogEntry(ctx context.Context, e entities.AuditLogEntry) error {
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	return m.db.WithContext(tCtx).Transaction(func(tx *gorm.DB) error {
+		head, err := lockChainHead(tx, auditLogChainName)
+		if err != nil {
+			return err
+		}
+
+		e.CreatedAt = time.Now().UTC().Truncate(time.Second)
+		e.PrevHash = head.Hash
+		e.Hash = e.ComputeHash()
+
+		if err := tx.Create(&e).Error; err != nil {
+			return err
+		}
+
+		return advanceChainHead(tx, head, e.Hash)
+	})
+}
+
+func (m *mysqlConnector) GetAuditLogEntries(ctx context.Context, query entities.AuditLogQuery) ([]entities.AuditLogEntry, error) {
+	var result []entities.AuditLogEntry
+
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	db := m.db.WithContext(tCtx).
+		Where(&entities.AuditLogEntry{
+			Actor:         query.Actor,
+			DebitorID:     query.DebitorID,
+			TransactionID: query.TransactionIdentifier,
+			Outcome:       query.Outcome,
+		})
+
+	if !query.CreatedFrom.IsZero() {
+		db.Where("created_at >= ?", query.CreatedFrom)
+	}
+
+	if !query.CreatedBefore.IsZero() {
+		db.Where("created_at < ?", query.CreatedBefore)
+	}
+
+	res := db.Order("id").Find(&result)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+)
+
+func (m *mysqlConnector) IssueDocument(ctx context.Context, doc entities.IssuedDocument) (*entities.IssuedDocument, error) {
+	if doc.ID != 0 || doc.Sequence != 0 {
+		return nil, errors.New("issue needs a new document")
+	}
+
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	var issued entities.IssuedDocument
+	err := m.db.WithContext(tCtx).Transaction(func(tx *gorm.DB) error {
+		sequence, err := lockDocumentSequence(tx, doc.Kind)
+		if err != nil {
+			return err
+		}
+
+		// looked up after locking, so a concurrent request for the same subject waits for this one and then finds its document
+		res := tx.Where(&entities.IssuedDocument{Kind: doc.Kind, Subject: doc.Subject}).Limit(1).Find(&issued)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			return nil
+		}
+
+		sequence.Last++
+		if err := tx.Save(sequence).Error; err != nil {
+			return err
+		}
+
+		doc.Sequence = sequence.Last
+		if err := tx.Create(&doc).Error; err != nil {
+			return err
+		}
+
+		issued = doc
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &issued, nil
+}
+
+// lockDocumentSequence must be called inside a database transaction. It returns the sequence of the kind,
+// and keeps its row locked until the transaction ends.
+func lockDocumentSequence(tx *gorm.DB, kind entities.DocumentKind) (*entities.DocumentSequence, error) {
+	sequence := entities.DocumentSequence{Kind: kind}
+
+	// make sure the row exists, so there is something to lock
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&sequence)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	res = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(&entities.DocumentSequence{Kind: kind}).
+		First(&sequence)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return &sequence, nil
+}
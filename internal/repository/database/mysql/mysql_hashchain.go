@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+)
+
+// lockChainHead must be called inside a database transaction. It returns the current head of the chain,
+// and keeps the head row locked until the transaction ends.
+func lockChainHead(tx *gorm.DB, chainName string) (*entities.HashChainHead, error) {
+	head := entities.HashChainHead{ChainName: chainName}
+
+	// make sure the row exists, so there is something to lock
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&head)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	res = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(&entities.HashChainHead{ChainName: chainName}).
+		First(&head)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return &head, nil
+}
+
+func advanceChainHead(tx *gorm.DB, head *entities.HashChainHead, hash string) error {
+	head.Hash = hash
+	head.Length++
+	return tx.Save(head).Error
+}
+
+func (m *mysqlConnector) GetHashChainHeads(ctx context.Context, prefix string) ([]entities.HashChainHead, error) {
+	var result []entities.HashChainHead
+
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	res := m.db.WithContext(tCtx).
+		Where("chain_name LIKE ?", escapeLike(prefix)+"%").
+		Order("chain_name").
+		Find(&result)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return result, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// appendOnlyTriggers lists the statements that make the database reject updates and deletes on the append only tables.
+func (m *mysqlConnector) appendOnlyTriggers() (drop []string, create []string) {
+	tables := []string{
+		m.db.NamingStrategy.TableName("TransactionLog"),
+		m.db.NamingStrategy.TableName("AuditLogEntry"),
+		// line items never change once written with their due
+		m.db.NamingStrategy.TableName("LineItem"),
+	}
+
+	for _, table := range tables {
+		for _, op := range []string{"UPDATE", "DELETE"} {
+			name := fmt.Sprintf("%s_no_%s", table, strings.ToLower(op))
+			drop = append(drop, fmt.Sprintf("DROP TRIGGER IF EXISTS %s", name))
+			create = append(create, fmt.Sprintf(
+				"CREATE TRIGGER %s BEFORE %s ON %s FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = '%s is append only'",
+				name, op, table, table))
+		}
+	}
+
+	return drop, create
+}
@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+)
+
+func (m *mysqlConnector) Ping(ctx context.Context) error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.PingContext(ctx)
+}
+
+func (m *mysqlConnector) Close() error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
+}
+
+func (m *mysqlConnector) CheckSchema(ctx context.Context) error {
+	if m.schemaChecked.Load() {
+		return nil
+	}
+
+	db := m.db.WithContext(ctx)
+	migrator := db.Migrator()
+	for _, model := range migratedModels() {
+		stmt := db.Model(model).Statement
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("table %s is missing, the database needs to be migrated", stmt.Schema.Table)
+		}
+
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+				return fmt.Errorf("column %s.%s is missing, the database needs to be migrated", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+
+	m.schemaChecked.Store(true)
+	return nil
+}
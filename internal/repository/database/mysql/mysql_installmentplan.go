@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
+)
+
+func (m *mysqlConnector) CreateInstallmentPlan(ctx context.Context, plan entities.InstallmentPlan) error {
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	// also creates the installments
+	err := m.db.WithContext(tCtx).Create(&plan).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return database.ErrInstallmentPlanExists
+	}
+	return err
+}
+
+func (m *mysqlConnector) GetInstallmentPlans(ctx context.Context, debitorID int64, event string) ([]entities.InstallmentPlan, error) {
+	var plans []entities.InstallmentPlan
+
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	res := m.db.WithContext(tCtx).
+		Preload("Installments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("number")
+		}).
+		Where(&entities.InstallmentPlan{
+			DebitorID: debitorID,
+			Event:     event,
+		}).
+		Order("event").
+		Find(&plans)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return plans, nil
+}
+
+func (m *mysqlConnector) DeleteInstallmentPlan(ctx context.Context, id uint) error {
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	return m.db.WithContext(tCtx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(&entities.Installment{PlanID: id}).Delete(&entities.Installment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entities.InstallmentPlan{}, id).Error
+	})
+}
@@ -0,0 +1,184 @@
+package mysql
+
+import (
+	"context"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/tracing"
+)
+
+// tracedConnector records a span for each repository method of the mysqlConnector.
+type tracedConnector struct {
+	*mysqlConnector
+}
+
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return tracing.StartSpan(ctx, "mysql."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMySQL),
+	)
+}
+
+func (t *tracedConnector) CreateTransaction(ctx context.Context, tr entities.Transaction) error {
+	ctx, span := startSpan(ctx, "CreateTransaction")
+	err := t.mysqlConnector.CreateTransaction(ctx, tr)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (t *tracedConnector) CreateTransactions(ctx context.Context, trs []entities.Transaction) error {
+	ctx, span := startSpan(ctx, "CreateTransactions")
+	err := t.mysqlConnector.CreateTransactions(ctx, trs)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (t *tracedConnector) CreateTransactionsWithinCredit(ctx context.Context, debitorID int64, event string, creditCent int64, trs []entities.Transaction) error {
+	ctx, span := startSpan(ctx, "CreateTransactionsWithinCredit")
+	err := t.mysqlConnector.CreateTransactionsWithinCredit(ctx, debitorID, event, creditCent, trs)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (t *tracedConnector) GetTransactionByTransactionIDAndType(ctx context.Context, transactionID string, tType entities.TransactionType) (*entities.Transaction, error) {
+	ctx, span := startSpan(ctx, "GetTransactionByTransactionIDAndType")
+	tr, err := t.mysqlConnector.GetTransactionByTransactionIDAndType(ctx, transactionID, tType)
+	tracing.EndSpan(span, err)
+	return tr, err
+}
+
+func (t *tracedConnector) GetTransactionsByFilter(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
+	ctx, span := startSpan(ctx, "GetTransactionsByFilter")
+	transactions, err := t.mysqlConnector.GetTransactionsByFilter(ctx, query)
+	tracing.EndSpan(span, err)
+	return transactions, err
+}
+
+func (t *tracedConnector) GetAdminTransactionsByFilter(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error) {
+	ctx, span := startSpan(ctx, "GetAdminTransactionsByFilter")
+	transactions, err := t.mysqlConnector.GetAdminTransactionsByFilter(ctx, query)
+	tracing.EndSpan(span, err)
+	return transactions, err
+}
+
+func (t *tracedConnector) GetValidTransactionsForDebitor(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
+	ctx, span := startSpan(ctx, "GetValidTransactionsForDebitor")
+	transactions, err := t.mysqlConnector.GetValidTransactionsForDebitor(ctx, debitorID, event)
+	tracing.EndSpan(span, err)
+	return transactions, err
+}
+
+func (t *tracedConnector) QueryOutstandingDuesForDebitor(ctx context.Context, debitorID int64, event string) (int64, error) {
+	ctx, span := startSpan(ctx, "QueryOutstandingDuesForDebitor")
+	amount, err := t.mysqlConnector.QueryOutstandingDuesForDebitor(ctx, debitorID, event)
+	tracing.EndSpan(span, err)
+	return amount, err
+}
+
+func (t *tracedConnector) QueryOverpaidDebitors(ctx context.Context, event string) ([]entities.Overpayment, error) {
+	ctx, span := startSpan(ctx, "QueryOverpaidDebitors")
+	result, err := t.mysqlConnector.QueryOverpaidDebitors(ctx, event)
+	tracing.EndSpan(span, err)
+	return result, err
+}
+
+func (t *tracedConnector) UpdateTransaction(ctx context.Context, tr entities.Transaction, historize bool) error {
+	ctx, span := startSpan(ctx, "UpdateTransaction")
+	err := t.mysqlConnector.UpdateTransaction(ctx, tr, historize)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (t *tracedConnector) UpdateTransactions(ctx context.Context, trs []entities.Transaction) error {
+	ctx, span := startSpan(ctx, "UpdateTransactions")
+	err := t.mysqlConnector.UpdateTransactions(ctx, trs)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (t *tracedConnector) DeleteTransaction(ctx context.Context, tr entities.Transaction) error {
+	ctx, span := startSpan(ctx, "DeleteTransaction")
+	err := t.mysqlConnector.DeleteTransaction(ctx, tr)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (t *tracedConnector) CreateTransactionLog(ctx context.Context, h entities.TransactionLog) error {
+	ctx, span := startSpan(ctx, "CreateTransactionLog")
+	err := t.mysqlConnector.CreateTransactionLog(ctx, h)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (t *tracedConnector) GetTransactionLogByID(ctx context.Context, id uint) (*entities.TransactionLog, error) {
+	ctx, span := startSpan(ctx, "GetTransactionLogByID")
+	tl, err := t.mysqlConnector.GetTransactionLogByID(ctx, id)
+	tracing.EndSpan(span, err)
+	return tl, err
+}
+
+func (t *tracedConnector) GetTransactionLogs(ctx context.Context, afterID uint, limit int) ([]entities.TransactionLog, error) {
+	ctx, span := startSpan(ctx, "GetTransactionLogs")
+	logs, err := t.mysqlConnector.GetTransactionLogs(ctx, afterID, limit)
+	tracing.EndSpan(span, err)
+	return logs, err
+}
+
+func (t *tracedConnector) GetTransactionLogsForTransaction(ctx context.Context, transactionID string) ([]entities.TransactionLog, error) {
+	ctx, span := startSpan(ctx, "GetTransactionLogsForTransaction")
+	logs, err := t.mysqlConnector.GetTransactionLogsForTransaction(ctx, transactionID)
+	tracing.EndSpan(span, err)
+	return logs, err
+}
+
+func (t *tracedConnector) CreateAuditLogEntry(ctx context.Context, e entities.AuditLogEntry) error {
+	ctx, span := startSpan(ctx, "CreateAuditLogEntry")
+	err := t.mysqlConnector.CreateAuditLogEntry(ctx, e)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (t *tracedConnector) GetAuditLogEntries(ctx context.Context, query entities.AuditLogQuery) ([]entities.AuditLogEntry, error) {
+	ctx, span := startSpan(ctx, "GetAuditLogEntries")
+	entries, err := t.mysqlConnector.GetAuditLogEntries(ctx, query)
+	tracing.EndSpan(span, err)
+	return entries, err
+}
+
+func (t *tracedConnector) GetHashChainHeads(ctx context.Context, prefix string) ([]entities.HashChainHead, error) {
+	ctx, span := startSpan(ctx, "GetHashChainHeads")
+	heads, err := t.mysqlConnector.GetHashChainHeads(ctx, prefix)
+	tracing.EndSpan(span, err)
+	return heads, err
+}
+
+func (t *tracedConnector) CreateInstallmentPlan(ctx context.Context, plan entities.InstallmentPlan) error {
+	ctx, span := startSpan(ctx, "CreateInstallmentPlan")
+	err := t.mysqlConnector.CreateInstallmentPlan(ctx, plan)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (t *tracedConnector) GetInstallmentPlans(ctx context.Context, debitorID int64, event string) ([]entities.InstallmentPlan, error) {
+	ctx, span := startSpan(ctx, "GetInstallmentPlans")
+	plans, err := t.mysqlConnector.GetInstallmentPlans(ctx, debitorID, event)
+	tracing.EndSpan(span, err)
+	return plans, err
+}
+
+func (t *tracedConnector) DeleteInstallmentPlan(ctx context.Context, id uint) error {
+	ctx, span := startSpan(ctx, "DeleteInstallmentPlan")
+	err := t.mysqlConnector.DeleteInstallmentPlan(ctx, id)
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func (t *tracedConnector) IssueDocument(ctx context.Context, doc entities.IssuedDocument) (*entities.IssuedDocument, error) {
+	ctx, span := startSpan(ctx, "IssueDocument")
+	issued, err := t.mysqlConnector.IssueDocument(ctx, doc)
+	tracing.EndSpan(span, err)
+	return issued, err
+}
@@ -7,7 +7,10 @@ import (
 	"reflect"
 	"time"
 
+	"gorm.io/gorm"
+
 	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/repository/database"
 )
 
 var allowedFieldsForUpdate = []string{
@@ -16,17 +19,12 @@ var allowedFieldsForUpdate = []string{
 	"VatRate",
 	"TransactionStatus",
 	"comment",
-	// TODO Missing payment processor information,
+	"ProcessorInfo",
 	"PaymentStartUrl",
 	"EffectiveDate",
 	"DueDate",
 }
 
-var (
-	// ErrTransactionExists is returned when a transaction already exists in the database
-	ErrTransactionExists = errors.New("the transaction already exists")
-)
-
 func (m *mysqlConnector) CreateTransaction(ctx context.Context, tr entities.Transaction) error {
 	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
@@ -34,17 +32,96 @@ func (m *mysqlConnector) CreateTransaction(ctx context.Context, tr entities.Tran
 	result := m.db.WithContext(tCtx).Create(&tr)
 
 	if result.Error != nil {
-		return result.Error
+		return translateDuplicate(result.Error)
 	}
 
 	return m.CreateTransactionLog(ctx, tr.ToTransactionLog())
 }
 
+// translateDuplicate reports a violation of the unique indexes on transaction id and creditor reference as ErrTransactionExists.
+func translateDuplicate(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return database.ErrTransactionExists
+	}
+	return err
+}
+
+func (m *mysqlConnector) CreateTransactions(ctx context.Context, trs []entities.Transaction) error {
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	return m.db.WithContext(tCtx).Transaction(func(tx *gorm.DB) error {
+		return createTransactions(tx, trs)
+	})
+}
+
+func (m *mysqlConnector) CreateTransactionsWithinCredit(ctx context.Context, debitorID int64, event string, creditCent int64, trs []entities.Transaction) error {
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	return m.db.WithContext(tCtx).Transaction(func(tx *gorm.DB) error {
+		credit, err := lockCredit(tx, debitorID, event)
+		if err != nil {
+			return err
+		}
+		if credit < creditCent {
+			return database.ErrInsufficientCredit
+		}
+
+		return createTransactions(tx, trs)
+	})
+}
+
+// createTransactions must be called inside a database transaction.
+func createTransactions(tx *gorm.DB, trs []entities.Transaction) error {
+	for _, tr := range trs {
+		if err := tx.Create(&tr).Error; err != nil {
+			return translateDuplicate(err)
+		}
+
+		if err := createTransactionLog(tx, tr.ToTransactionLog()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockCredit locks the transactions of the debitor until the end of the database transaction,
+// and returns their credit. It must be called inside a database transaction.
+func lockCredit(tx *gorm.DB, debitorID int64, event string) (int64, error) {
+	stmt := `SELECT
+COALESCE(SUM(CASE
+	WHEN p.transaction_status = "valid" AND p.transaction_type = "payment" THEN p.gross_cent
+	WHEN p.transaction_status = "valid" AND p.transaction_type = "due" THEN -p.gross_cent
+	WHEN p.transaction_status IN ("tentative", "pending") AND p.transaction_type = "payment" AND p.gross_cent < 0 THEN p.gross_cent
+	ELSE 0 END),0)
+FROM
+	pay_transactions p
+WHERE
+p.debitor_id = @debitorID
+AND (@event = "" OR p.event = @event)
+FOR UPDATE`
+
+	var credit int64
+
+	res := tx.Raw(stmt, sql.Named("debitorID", debitorID), sql.Named("event", event)).
+		Find(&credit)
+
+	return credit, res.Error
+}
+
 func (m *mysqlConnector) UpdateTransaction(ctx context.Context, tr entities.Transaction, historize bool) error {
 	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
 
-	res := m.db.WithContext(tCtx).
+	return m.db.WithContext(tCtx).Transaction(func(tx *gorm.DB) error {
+		return updateTransaction(tx, tr, historize)
+	})
+}
+
+// updateTransaction must be called inside a database transaction.
+func updateTransaction(tx *gorm.DB, tr entities.Transaction, historize bool) error {
+	res := tx.
 		Model(&entities.Transaction{}).
 		Select(allowedFieldsForUpdate).
 		Where(&entities.Transaction{
@@ -57,7 +134,9 @@ func (m *mysqlConnector) UpdateTransaction(ctx context.Context, tr entities.Tran
 		return res.Error
 	}
 
-	res = m.db.WithContext(tCtx).
+	// reloaded with the line items, so the log entry carries their digest
+	res = tx.
+		Scopes(preloadLineItems).
 		Where(&entities.Transaction{
 			TransactionID:   tr.TransactionID,
 			TransactionType: tr.TransactionType,
@@ -68,18 +147,38 @@ func (m *mysqlConnector) UpdateTransaction(ctx context.Context, tr entities.Tran
 	}
 
 	if historize {
-		return m.CreateTransactionLog(ctx, tr.ToTransactionLog())
+		return createTransactionLog(tx, tr.ToTransactionLog())
 	}
 
 	return nil
 }
 
+func (m *mysqlConnector) UpdateTransactions(ctx context.Context, trs []entities.Transaction) error {
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	return m.db.WithContext(tCtx).Transaction(func(tx *gorm.DB) error {
+		for _, tr := range trs {
+			var err error
+			if reflect.ValueOf(tr.Deletion).IsZero() {
+				err = updateTransaction(tx, tr, true)
+			} else {
+				err = deleteTransaction(tx, tr)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (m *mysqlConnector) GetTransactionByTransactionIDAndType(ctx context.Context, transactionID string, tType entities.TransactionType) (*entities.Transaction, error) {
 	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
 
 	var tr entities.Transaction
-	res := m.db.WithContext(tCtx).Where(&entities.Transaction{
+	res := m.db.WithContext(tCtx).Scopes(preloadLineItems).Where(&entities.Transaction{
 		TransactionID:   transactionID,
 		TransactionType: tType,
 	}).First(&tr)
@@ -98,9 +197,16 @@ func (m *mysqlConnector) GetTransactionsByFilter(ctx context.Context, query enti
 	defer cancel()
 
 	db := m.db.WithContext(tCtx).
+		Scopes(preloadLineItems).
 		Where(&entities.Transaction{
 			DebitorID:     query.DebitorID,
 			TransactionID: query.TransactionIdentifier,
+			CreditorReference: sql.NullString{
+				String: query.CreditorReference,
+				Valid:  query.CreditorReference != "",
+			},
+			Event:          query.Event,
+			GroupReference: query.GroupReference,
 		})
 
 	if !query.EffectiveFrom.IsZero() {
@@ -126,9 +232,16 @@ func (m *mysqlConnector) GetAdminTransactionsByFilter(ctx context.Context, query
 	defer cancel()
 
 	db := m.db.WithContext(tCtx).
+		Scopes(preloadLineItems).
 		Where(&entities.Transaction{
 			DebitorID:     query.DebitorID,
 			TransactionID: query.TransactionIdentifier,
+			CreditorReference: sql.NullString{
+				String: query.CreditorReference,
+				Valid:  query.CreditorReference != "",
+			},
+			Event:          query.Event,
+			GroupReference: query.GroupReference,
 		})
 
 	if !query.EffectiveFrom.IsZero() {
@@ -147,15 +260,24 @@ func (m *mysqlConnector) GetAdminTransactionsByFilter(ctx context.Context, query
 	return transactions, nil
 }
 
-func (m *mysqlConnector) GetValidTransactionsForDebitor(ctx context.Context, debitorID int64) ([]entities.Transaction, error) {
+// preloadLineItems loads the line items of itemized dues, in order.
+func preloadLineItems(db *gorm.DB) *gorm.DB {
+	return db.Preload("LineItems", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	})
+}
+
+func (m *mysqlConnector) GetValidTransactionsForDebitor(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error) {
 	var transactions []entities.Transaction
 
 	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
 
 	res := m.db.WithContext(tCtx).
+		Scopes(preloadLineItems).
 		Where(&entities.Transaction{
 			DebitorID:         debitorID,
+			Event:             event,
 			TransactionStatus: entities.TransactionStatusValid,
 		}).Find(&transactions)
 
@@ -166,7 +288,7 @@ func (m *mysqlConnector) GetValidTransactionsForDebitor(ctx context.Context, deb
 	return transactions, nil
 }
 
-func (m *mysqlConnector) QueryOutstandingDuesForDebitor(ctx context.Context, debitorID int64) (int64, error) {
+func (m *mysqlConnector) QueryOutstandingDuesForDebitor(ctx context.Context, debitorID int64, event string) (int64, error) {
 	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
 
@@ -178,21 +300,53 @@ COALESCE(SUM(p.gross_cent),0) - (
 		pay_transactions psub
 	WHERE
 		psub.debitor_id = @debitorID AND psub.transaction_type = "payment" AND psub.transaction_status = "valid"
+		AND (@event = "" OR psub.event = @event)
 	)
 FROM
 	pay_transactions p
 WHERE
-p.debitor_id = @debitorID AND p.transaction_type = "due" AND p.transaction_status = "valid"`
+p.debitor_id = @debitorID AND p.transaction_type = "due" AND p.transaction_status = "valid"
+AND (@event = "" OR p.event = @event)`
 
 	var amount int64
 
 	res := m.db.WithContext(tCtx).
-		Raw(stmt, sql.Named("debitorID", debitorID)).
+		Raw(stmt, sql.Named("debitorID", debitorID), sql.Named("event", event)).
 		Find(&amount)
 
 	return amount, res.Error
 }
 
+func (m *mysqlConnector) QueryOverpaidDebitors(ctx context.Context, event string) ([]entities.Overpayment, error) {
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	// same calculation as QueryOutstandingDuesForDebitor, for all debitors at once
+	stmt := `SELECT
+	p.debitor_id AS debitor_id,
+	COALESCE(SUM(CASE WHEN p.transaction_type = "due" THEN p.gross_cent ELSE 0 END),0) AS dues_cent,
+	COALESCE(SUM(CASE WHEN p.transaction_type = "payment" THEN p.gross_cent ELSE 0 END),0) AS payments_cent
+FROM
+	pay_transactions p
+WHERE
+	p.transaction_status = "valid"
+	AND (@event = "" OR p.event = @event)
+GROUP BY
+	p.debitor_id
+HAVING
+	payments_cent > dues_cent
+ORDER BY
+	p.debitor_id`
+
+	var result []entities.Overpayment
+
+	res := m.db.WithContext(tCtx).
+		Raw(stmt, sql.Named("event", event)).
+		Scan(&result)
+
+	return result, res.Error
+}
+
 func (m *mysqlConnector) DeleteTransaction(ctx context.Context, tr entities.Transaction) error {
 	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
@@ -202,7 +356,14 @@ func (m *mysqlConnector) DeleteTransaction(ctx context.Context, tr entities.Tran
 		return errors.New("no deletion information was provided. Transaction cannot be flagged as deleted without")
 	}
 
-	res := m.db.WithContext(tCtx).
+	return m.db.WithContext(tCtx).Transaction(func(tx *gorm.DB) error {
+		return deleteTransaction(tx, tr)
+	})
+}
+
+// deleteTransaction must be called inside a database transaction.
+func deleteTransaction(tx *gorm.DB, tr entities.Transaction) error {
+	res := tx.
 		Model(&entities.Transaction{}).
 		Select([]string{"deleted_at", "deleted_status", "deleted_comment", "deleted_by", "transaction_status", "comment"}).
 		Where(&entities.Transaction{
@@ -214,7 +375,9 @@ func (m *mysqlConnector) DeleteTransaction(ctx context.Context, tr entities.Tran
 		return res.Error
 	}
 
-	res = m.db.WithContext(tCtx).
+	// reloaded with the line items, so the log entry carries their digest
+	res = tx.
+		Scopes(preloadLineItems).
 		Where(&entities.Transaction{
 			TransactionID:   tr.TransactionID,
 			TransactionType: tr.TransactionType,
@@ -224,5 +387,32 @@ func (m *mysqlConnector) DeleteTransaction(ctx context.Context, tr entities.Tran
 		return res.Error
 	}
 
-	return m.CreateTransactionLog(ctx, tr.ToTransactionLog())
+	return createTransactionLog(tx, tr.ToTransactionLog())
+}
+
+// assignLegacyTransactionEvents assigns transactions created before events existed to the configured event
+// whose transaction id prefix matches the first segment of their transaction id.
+//
+// Transactions whose prefix matches no configured event are left unassigned.
+func (m *mysqlConnector) assignLegacyTransactionEvents() error {
+	for _, event := range m.events {
+		res := m.db.Exec("UPDATE pay_transactions SET event = ? WHERE event = '' AND SUBSTRING_INDEX(transaction_id, '-', 1) = ?",
+			event.Name, event.Prefix())
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected > 0 {
+			m.logger.Info("assigned %d transactions with prefix %s to event %s", res.RowsAffected, event.Prefix(), event.Name)
+		}
+	}
+
+	var unassigned int64
+	if err := m.db.Model(&entities.Transaction{}).Where("event = ''").Count(&unassigned).Error; err != nil {
+		return err
+	}
+	if unassigned > 0 {
+		m.logger.Warn("%d transactions have a transaction id prefix that matches no configured event", unassigned)
+	}
+	return nil
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"gorm.io/gorm"
+
 	"github.com/eurofurence/reg-payment-service/internal/entities"
 )
 
@@ -11,11 +13,113 @@ func (m *mysqlConnector) CreateTransactionLog(ctx context.Context, tl entities.T
 	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
 	defer cancel()
 
-	res := m.db.WithContext(tCtx).Create(&tl)
-	return res.Error
+	return m.db.WithContext(tCtx).Transaction(func(tx *gorm.DB) error {
+		return createTransactionLog(tx, tl)
+	})
+}
+
+// createTransactionLog must be called inside a database transaction.
+func createTransactionLog(tx *gorm.DB, tl entities.TransactionLog) error {
+	head, err := lockChainHead(tx, entities.TransactionLogChainName(tl.TransactionID))
+	if err != nil {
+		return err
+	}
+
+	tl.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	tl.PrevHash = head.Hash
+	tl.Hash = tl.ComputeHash()
+
+	if err := tx.Create(&tl).Error; err != nil {
+		return err
+	}
+
+	return advanceChainHead(tx, head, tl.Hash)
 }
 
 func (m *mysqlConnector) GetTransactionLogByID(ctx context.Context, id uint) (*entities.TransactionLog, error) {
 	// TODO evaluate if needed
 	return nil, nil
 }
+
+func (m *mysqlConnector) GetTransactionLogs(ctx context.Context, afterID uint, limit int) ([]entities.TransactionLog, error) {
+	var result []entities.TransactionLog
+
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	res := m.db.WithContext(tCtx).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&result)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return result, nil
+}
+
+func (m *mysqlConnector) GetTransactionLogsForTransaction(ctx context.Context, transactionID string) ([]entities.TransactionLog, error) {
+	var result []entities.TransactionLog
+
+	tCtx, cancel := context.WithTimeout(ctx, time.Second*20)
+	defer cancel()
+
+	res := m.db.WithContext(tCtx).
+		Where(&entities.TransactionLog{TransactionID: transactionID}).
+		Order("id").
+		Find(&result)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	return result, nil
+}
+
+// sealLegacyTransactionLogs adds the hash chain to entries written before the transaction log was chained.
+//
+// Entries are chained in the order they were written, so the result is the same as if
+// they had been chained from the start.
+func (m *mysqlConnector) sealLegacyTransactionLogs() error {
+	const batchSize = 500
+
+	for {
+		var batch []entities.TransactionLog
+		res := m.db.
+			Where("hash IS NULL OR hash = ''").
+			Order("id").
+			Limit(batchSize).
+			Find(&batch)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		for _, tl := range batch {
+			err := m.db.Transaction(func(tx *gorm.DB) error {
+				head, err := lockChainHead(tx, entities.TransactionLogChainName(tl.TransactionID))
+				if err != nil {
+					return err
+				}
+
+				tl.PrevHash = head.Hash
+				tl.Hash = tl.ComputeHash()
+
+				err = tx.Model(&entities.TransactionLog{}).
+					Where("id = ?", tl.ID).
+					Updates(map[string]interface{}{"prev_hash": tl.PrevHash, "hash": tl.Hash}).Error
+				if err != nil {
+					return err
+				}
+
+				return advanceChainHead(tx, head, tl.Hash)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < batchSize {
+			return nil
+		}
+	}
+}
@@ -2,28 +2,99 @@ package database
 
 import (
 	"context"
+	"errors"
 
 	"github.com/eurofurence/reg-payment-service/internal/entities"
 )
 
+// ErrTransactionExists is returned when a transaction id or creditor reference is already taken by another transaction
+var ErrTransactionExists = errors.New("the transaction already exists")
+
+// ErrInsufficientCredit is returned when the credit of a debitor no longer covers the transactions to create
+var ErrInsufficientCredit = errors.New("the credit of the debitor does not cover the transactions")
+
+// ErrInstallmentPlanExists is returned when the debitor already has an installment plan for the event
+var ErrInstallmentPlanExists = errors.New("the debitor already has an installment plan for the event")
+
 type Repository interface {
 	Migrate() error
+	// Close releases the connections to the database.
+	Close() error
+	HealthRepository
 	TransactionRepository
 	TransactionLogRepository
+	AuditLogRepository
+	HashChainRepository
+	InstallmentPlanRepository
+	DocumentRepository
+}
+
+type HealthRepository interface {
+	// Ping checks that the database can be reached.
+	Ping(ctx context.Context) error
+	// CheckSchema checks that all tables and columns created by Migrate exist.
+	CheckSchema(ctx context.Context) error
 }
 
 type TransactionRepository interface {
 	CreateTransaction(ctx context.Context, tr entities.Transaction) error
+	// CreateTransactions creates all of the transactions, or none of them.
+	CreateTransactions(ctx context.Context, trs []entities.Transaction) error
+	// CreateTransactionsWithinCredit creates all of the transactions, or none of them, if the credit of the debitor for the event,
+	// or for all events if event is empty, still covers creditCent. The credit is the valid payments minus the valid dues,
+	// less the outgoing payments that are not valid yet. The transactions of the debitor are locked while checking,
+	// so concurrent calls for the same debitor cannot spend the same credit. Returns ErrInsufficientCredit otherwise.
+	CreateTransactionsWithinCredit(ctx context.Context, debitorID int64, event string, creditCent int64, trs []entities.Transaction) error
 	GetTransactionByTransactionIDAndType(ctx context.Context, transactionID string, tType entities.TransactionType) (*entities.Transaction, error)
 	GetTransactionsByFilter(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error)
 	GetAdminTransactionsByFilter(ctx context.Context, query entities.TransactionQuery) ([]entities.Transaction, error)
-	GetValidTransactionsForDebitor(ctx context.Context, debitorID int64) ([]entities.Transaction, error)
-	QueryOutstandingDuesForDebitor(ctx context.Context, debitorID int64) (int64, error)
+	// GetValidTransactionsForDebitor returns the valid transactions of the debitor for the event, or for all events if event is empty.
+	GetValidTransactionsForDebitor(ctx context.Context, debitorID int64, event string) ([]entities.Transaction, error)
+	// QueryOutstandingDuesForDebitor returns the valid dues minus the valid payments of the debitor for the event, or for all events if event is empty.
+	QueryOutstandingDuesForDebitor(ctx context.Context, debitorID int64, event string) (int64, error)
+	// QueryOverpaidDebitors returns the debitors whose valid payments exceed their valid dues for the event,
+	// or for all events if event is empty, ordered by debitor id.
+	QueryOverpaidDebitors(ctx context.Context, event string) ([]entities.Overpayment, error)
 	UpdateTransaction(ctx context.Context, tr entities.Transaction, historize bool) error
 	DeleteTransaction(ctx context.Context, tr entities.Transaction) error
+	// UpdateTransactions updates and historizes all of the transactions, or none of them.
+	// Transactions that carry deletion information are flagged as deleted, like DeleteTransaction does.
+	UpdateTransactions(ctx context.Context, trs []entities.Transaction) error
 }
 
 type TransactionLogRepository interface {
+	// CreateTransactionLog appends an entry to the log of its transaction. Timestamp and hash chain are filled in by the repository.
 	CreateTransactionLog(ctx context.Context, h entities.TransactionLog) error
 	GetTransactionLogByID(ctx context.Context, id uint) (*entities.TransactionLog, error)
+	// GetTransactionLogs returns up to limit entries with an id greater than afterID, ordered by id.
+	GetTransactionLogs(ctx context.Context, afterID uint, limit int) ([]entities.TransactionLog, error)
+	// GetTransactionLogsForTransaction returns all entries for the transaction, oldest first.
+	GetTransactionLogsForTransaction(ctx context.Context, transactionID string) ([]entities.TransactionLog, error)
+}
+
+type AuditLogRepository interface {
+	// CreateAuditLogEntry appends an entry to the audit log. Timestamp and hash chain are filled in by the repository.
+	CreateAuditLogEntry(ctx context.Context, e entities.AuditLogEntry) error
+	GetAuditLogEntries(ctx context.Context, query entities.AuditLogQuery) ([]entities.AuditLogEntry, error)
+}
+
+type HashChainRepository interface {
+	// GetHashChainHeads returns the heads of all chains whose name starts with prefix, ordered by name.
+	GetHashChainHeads(ctx context.Context, prefix string) ([]entities.HashChainHead, error)
+}
+
+type InstallmentPlanRepository interface {
+	// CreateInstallmentPlan stores the plan together with its installments.
+	// Returns ErrInstallmentPlanExists if the debitor already has a plan for the event.
+	CreateInstallmentPlan(ctx context.Context, plan entities.InstallmentPlan) error
+	// GetInstallmentPlans returns the plans of the debitor for the event, or for all events if event is empty, installments ordered by number.
+	GetInstallmentPlans(ctx context.Context, debitorID int64, event string) ([]entities.InstallmentPlan, error)
+	// DeleteInstallmentPlan removes the plan and its installments.
+	DeleteInstallmentPlan(ctx context.Context, id uint) error
+}
+
+type DocumentRepository interface {
+	// IssueDocument returns the document of the same kind and subject if there is one. Otherwise, it allocates the next
+	// number of the kind and stores the document, both in one database transaction, so no number is skipped or used twice.
+	IssueDocument(ctx context.Context, doc entities.IssuedDocument) (*entities.IssuedDocument, error)
 }
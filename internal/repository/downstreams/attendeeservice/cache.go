@@ -0,0 +1,131 @@
+package attendeeservice
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// loadTimeout limits the shared lookup, which runs detached from the request that started it. It matches the
+// timeout of the circuit breaker in front of the attendee service.
+const loadTimeout = 15 * time.Second
+
+// registrationCache holds the registration ids per subject for a short time.
+//
+// Concurrent lookups for the same subject are de-duplicated, so only one request
+// per subject is in flight towards the attendee service at any time. That request does
+// not belong to any single caller, so it keeps running when the caller that started it
+// goes away, while every caller stops waiting when its own context is done.
+//
+// Errors are never cached. This matters when the circuit breaker is open: every caller
+// receives the error from the breaker, and the next call after the breaker closes
+// again goes through to the attendee service.
+type registrationCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	entries  map[string]registrationCacheEntry
+	inFlight map[string]*registrationCall
+	now      func() time.Time
+}
+
+type registrationCacheEntry struct {
+	ids     []int64
+	expires time.Time
+}
+
+type registrationCall struct {
+	done chan struct{}
+	ids  []int64
+	err  error
+	// set when the cache was invalidated while this call was in flight, so its result must not be stored
+	stale bool
+}
+
+func newRegistrationCache(ttl time.Duration) *registrationCache {
+	return &registrationCache{
+		ttl:      ttl,
+		entries:  make(map[string]registrationCacheEntry),
+		inFlight: make(map[string]*registrationCall),
+		now:      time.Now,
+	}
+}
+
+// get returns the cached ids for subject, or calls load exactly once for all concurrent callers.
+//
+// load receives a context that carries the values of ctx, but is not cancelled with it.
+// An empty subject or a ttl of zero disables caching, but load is still called.
+func (c *registrationCache) get(ctx context.Context, subject string, load func(ctx context.Context) ([]int64, error)) ([]int64, error) {
+	if subject == "" || c.ttl <= 0 {
+		return load(ctx)
+	}
+
+	c.mu.Lock()
+	if entry, ok := c.entries[subject]; ok {
+		if c.now().Before(entry.expires) {
+			c.mu.Unlock()
+			return copyIds(entry.ids), nil
+		}
+		delete(c.entries, subject)
+	}
+
+	call, ok := c.inFlight[subject]
+	if !ok {
+		call = &registrationCall{done: make(chan struct{})}
+		c.inFlight[subject] = call
+		go c.load(ctx, subject, call, load)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return copyIds(call.ids), call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *registrationCache) load(ctx context.Context, subject string, call *registrationCall, load func(ctx context.Context) ([]int64, error)) {
+	loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+	defer cancel()
+
+	call.ids, call.err = load(loadCtx)
+
+	c.mu.Lock()
+	delete(c.inFlight, subject)
+	if call.err == nil && !call.stale {
+		c.entries[subject] = registrationCacheEntry{
+			ids:     copyIds(call.ids),
+			expires: c.now().Add(c.ttl),
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// invalidate removes the entry for subject, or all entries if subject is empty.
+func (c *registrationCache) invalidate(subject string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if subject == "" {
+		c.entries = make(map[string]registrationCacheEntry)
+		for _, call := range c.inFlight {
+			call.stale = true
+		}
+		return
+	}
+
+	delete(c.entries, subject)
+	if call, ok := c.inFlight[subject]; ok {
+		call.stale = true
+	}
+}
+
+func copyIds(ids []int64) []int64 {
+	if ids == nil {
+		return nil
+	}
+	result := make([]int64, len(ids))
+	copy(result, ids)
+	return result
+}
@@ -0,0 +1,177 @@
+package attendeeservice
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+	"github.com/stretchr/testify/require"
+)
+
+func tstCache(ttl time.Duration, now *time.Time) *registrationCache {
+	c := newRegistrationCache(ttl)
+	c.now = func() time.Time {
+		return *now
+	}
+	return c
+}
+
+func TestRegistrationCacheServesFromCacheWithinTTL(t *testing.T) {
+	now := time.Now()
+	c := tstCache(30*time.Second, &now)
+
+	calls := 0
+	load := func(context.Context) ([]int64, error) {
+		calls++
+		return []int64{1, 2}, nil
+	}
+
+	ids, err := c.get(context.Background(), "subject", load)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, ids)
+
+	now = now.Add(29 * time.Second)
+	ids, err = c.get(context.Background(), "subject", load)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, ids)
+	require.Equal(t, 1, calls)
+
+	now = now.Add(2 * time.Second)
+	_, err = c.get(context.Background(), "subject", load)
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+}
+
+func TestRegistrationCacheKeysBySubject(t *testing.T) {
+	now := time.Now()
+	c := tstCache(30*time.Second, &now)
+
+	ids, _ := c.get(context.Background(), "a", func(context.Context) ([]int64, error) { return []int64{1}, nil })
+	require.Equal(t, []int64{1}, ids)
+
+	ids, _ = c.get(context.Background(), "b", func(context.Context) ([]int64, error) { return []int64{2}, nil })
+	require.Equal(t, []int64{2}, ids)
+}
+
+func TestRegistrationCacheDoesNotCacheWithoutSubjectOrTTL(t *testing.T) {
+	now := time.Now()
+	calls := 0
+	load := func(context.Context) ([]int64, error) {
+		calls++
+		return []int64{1}, nil
+	}
+
+	c := tstCache(30*time.Second, &now)
+	_, _ = c.get(context.Background(), "", load)
+	_, _ = c.get(context.Background(), "", load)
+	require.Equal(t, 2, calls)
+
+	c = tstCache(0, &now)
+	_, _ = c.get(context.Background(), "subject", load)
+	_, _ = c.get(context.Background(), "subject", load)
+	require.Equal(t, 4, calls)
+}
+
+func TestRegistrationCacheDoesNotCacheErrors(t *testing.T) {
+	now := time.Now()
+	c := tstCache(30*time.Second, &now)
+
+	// this is what the circuit breaker returns while it is open
+	_, err := c.get(context.Background(), "subject", func(context.Context) ([]int64, error) { return nil, gobreaker.ErrOpenState })
+	require.ErrorIs(t, err, gobreaker.ErrOpenState)
+
+	ids, err := c.get(context.Background(), "subject", func(context.Context) ([]int64, error) { return []int64{3}, nil })
+	require.NoError(t, err)
+	require.Equal(t, []int64{3}, ids)
+}
+
+func TestRegistrationCacheInvalidate(t *testing.T) {
+	now := time.Now()
+	c := tstCache(30*time.Second, &now)
+
+	_, _ = c.get(context.Background(), "a", func(context.Context) ([]int64, error) { return []int64{1}, nil })
+	_, _ = c.get(context.Background(), "b", func(context.Context) ([]int64, error) { return []int64{2}, nil })
+
+	c.invalidate("a")
+	ids, _ := c.get(context.Background(), "a", func(context.Context) ([]int64, error) { return []int64{10}, nil })
+	require.Equal(t, []int64{10}, ids)
+	ids, _ = c.get(context.Background(), "b", func(context.Context) ([]int64, error) { return []int64{20}, nil })
+	require.Equal(t, []int64{2}, ids)
+
+	c.invalidate("")
+	ids, _ = c.get(context.Background(), "b", func(context.Context) ([]int64, error) { return []int64{20}, nil })
+	require.Equal(t, []int64{20}, ids)
+}
+
+func TestRegistrationCacheDeduplicatesConcurrentCalls(t *testing.T) {
+	c := newRegistrationCache(30 * time.Second)
+
+	var calls int32
+	release := make(chan struct{})
+	load := func(context.Context) ([]int64, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []int64{1}, nil
+	}
+
+	const callers = 10
+	var started, finished sync.WaitGroup
+	results := make([][]int64, callers)
+	for n := 0; n < callers; n++ {
+		started.Add(1)
+		finished.Add(1)
+		go func(n int) {
+			defer finished.Done()
+			started.Done()
+			results[n], _ = c.get(context.Background(), "subject", load)
+		}(n)
+	}
+	started.Wait()
+
+	// give the goroutines a chance to queue up behind the first call, late ones are served from the cache
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	finished.Wait()
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, ids := range results {
+		require.Equal(t, []int64{1}, ids)
+	}
+}
+
+func TestRegistrationCacheWaitersStopOnTheirOwnContext(t *testing.T) {
+	c := newRegistrationCache(30 * time.Second)
+
+	release := make(chan struct{})
+	var loadErr error
+	load := func(ctx context.Context) ([]int64, error) {
+		<-release
+		loadErr = ctx.Err()
+		return []int64{1}, nil
+	}
+
+	// the first caller goes away while the lookup is in flight
+	first, cancel := context.WithCancel(context.Background())
+	firstDone := make(chan error)
+	go func() {
+		_, err := c.get(first, "subject", load)
+		firstDone <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-firstDone, context.Canceled)
+
+	// the lookup it started still completes for the next caller
+	secondDone := make(chan []int64)
+	go func() {
+		ids, _ := c.get(context.Background(), "subject", load)
+		secondDone <- ids
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	require.Equal(t, []int64{1}, <-secondDone)
+	require.NoError(t, loadErr)
+}
@@ -5,18 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/eurofurence/reg-payment-service/internal/config"
 
 	aurestclientapi "github.com/StephanHCB/go-autumn-restclient/api"
 
+	"github.com/eurofurence/reg-payment-service/internal/metrics"
 	"github.com/eurofurence/reg-payment-service/internal/repository/downstreams"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
 )
 
 type Impl struct {
 	paymentsChangedClient     aurestclientapi.Client
 	listMyRegistrationsClient aurestclientapi.Client
 	baseUrl                   string
+	registrations             *registrationCache
 }
 
 func New(attendeeServiceBaseUrl string) (AttendeeService, error) {
@@ -48,6 +52,7 @@ func New(attendeeServiceBaseUrl string) (AttendeeService, error) {
 		paymentsChangedClient:     paymentsChangedClient,
 		listMyRegistrationsClient: listMyRegistrationsClient,
 		baseUrl:                   attendeeServiceBaseUrl,
+		registrations:             newRegistrationCache(time.Duration(conf.Service.AttendeeServiceCacheSeconds) * time.Second),
 	}, nil
 }
 
@@ -59,10 +64,22 @@ func (i *Impl) PaymentsChanged(ctx context.Context, debitorId uint) error {
 	url := fmt.Sprintf("%s/api/rest/v1/attendees/%d/payments-changed", i.baseUrl, debitorId)
 	response := aurestclientapi.ParsedResponse{}
 	err := i.paymentsChangedClient.Perform(ctx, http.MethodPost, url, nil, &response)
-	return downstreams.ErrByStatus(err, response.Status)
+	err = downstreams.ErrByStatus(err, response.Status)
+	if err != nil {
+		metrics.PaymentsChangedFailed()
+	}
+	return err
 }
 
 func (i *Impl) ListMyRegistrationIds(ctx context.Context) ([]int64, error) {
+	return i.registrations.get(ctx, subjectFromContext(ctx), i.listMyRegistrationIds)
+}
+
+func (i *Impl) RegistrationsChanged(_ context.Context, subject string) {
+	i.registrations.invalidate(subject)
+}
+
+func (i *Impl) listMyRegistrationIds(ctx context.Context) ([]int64, error) {
 	url := fmt.Sprintf("%s/api/rest/v1/attendees", i.baseUrl)
 	bodyDto := AttendeeIdList{
 		Ids: make([]int64, 0),
@@ -77,3 +94,11 @@ func (i *Impl) ListMyRegistrationIds(ctx context.Context) ([]int64, error) {
 	}
 	return bodyDto.Ids, downstreams.ErrByStatus(err, response.Status)
 }
+
+func subjectFromContext(ctx context.Context) string {
+	if claims, ok := ctx.Value(common.CtxKeyClaims{}).(*common.AllClaims); ok && claims != nil {
+		return claims.Subject
+	}
+
+	return ""
+}
@@ -15,4 +15,9 @@ type AttendeeService interface {
 	//
 	// Forwards the jwt from the request.
 	ListMyRegistrationIds(ctx context.Context) ([]int64, error)
+
+	// RegistrationsChanged clears the cached result of ListMyRegistrationIds for a subject.
+	//
+	// An empty subject clears the cache for all subjects.
+	RegistrationsChanged(ctx context.Context, subject string)
 }
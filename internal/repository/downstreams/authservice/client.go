@@ -42,9 +42,10 @@ func newClient() (AuthService, error) {
 		30*time.Second,
 		15*time.Second,
 	)
+	downstreams.InstrumentCircuitBreaker(circuitBreakerClient, "auth-service-breaker")
 
 	return &Impl{
-		client:  circuitBreakerClient,
+		client:  downstreams.WithTracing(circuitBreakerClient, "auth-service"),
 		baseUrl: conf.Security.Oidc.AuthService,
 	}, nil
 }
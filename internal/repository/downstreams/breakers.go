@@ -0,0 +1,64 @@
+package downstreams
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	aurestbreaker "github.com/StephanHCB/go-autumn-restclient-circuitbreaker/implementation/breaker"
+	aurestclientapi "github.com/StephanHCB/go-autumn-restclient/api"
+
+	"github.com/eurofurence/reg-payment-service/internal/metrics"
+)
+
+const (
+	CircuitBreakerClosed   = "closed"
+	CircuitBreakerHalfOpen = "half-open"
+	CircuitBreakerOpen     = "open"
+)
+
+// CircuitBreakerStatus is the last known state of a circuit breaker, as named by gobreaker.
+type CircuitBreakerStatus struct {
+	Name  string
+	State string
+}
+
+var (
+	breakerStatesMu sync.RWMutex
+	breakerStates   = make(map[string]string)
+)
+
+// InstrumentCircuitBreaker tracks the state of a circuit breaker for the readiness check and the metrics.
+func InstrumentCircuitBreaker(client aurestclientapi.Client, circuitBreakerName string) {
+	aurestbreaker.Instrument(client, circuitBreakerStateChanged, nil)
+	circuitBreakerStateChanged(circuitBreakerName, CircuitBreakerClosed)
+}
+
+func circuitBreakerStateChanged(circuitBreakerName string, state string) {
+	breakerStatesMu.Lock()
+	breakerStates[circuitBreakerName] = state
+	breakerStatesMu.Unlock()
+
+	metrics.CircuitBreakerStateChanged(circuitBreakerName, state)
+}
+
+// CircuitBreakerStates returns the states of all instrumented circuit breakers, ordered by name.
+//
+// The names are given without the -breaker suffix, so they name the downstream service.
+func CircuitBreakerStates() []CircuitBreakerStatus {
+	breakerStatesMu.RLock()
+	defer breakerStatesMu.RUnlock()
+
+	result := make([]CircuitBreakerStatus, 0, len(breakerStates))
+	for name, state := range breakerStates {
+		result = append(result, CircuitBreakerStatus{
+			Name:  strings.TrimSuffix(name, "-breaker"),
+			State: state,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
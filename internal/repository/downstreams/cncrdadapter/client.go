@@ -54,3 +54,13 @@ func (i *Impl) GetPaylinkById(ctx context.Context, id uint) (PaymentLinkDto, err
 	err := i.client.Perform(ctx, http.MethodGet, url, nil, &response)
 	return bodyDto, downstreams.ErrByStatus(err, response.Status)
 }
+
+func (i *Impl) CreateRefund(ctx context.Context, request RefundRequestDto) (RefundDto, error) {
+	url := fmt.Sprintf("%s/api/rest/v1/refunds", i.baseUrl)
+	bodyDto := RefundDto{}
+	response := aurestclientapi.ParsedResponse{
+		Body: &bodyDto,
+	}
+	err := i.client.Perform(ctx, http.MethodPost, url, request, &response)
+	return bodyDto, downstreams.ErrByStatus(err, response.Status)
+}
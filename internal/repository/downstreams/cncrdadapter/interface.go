@@ -7,6 +7,7 @@ import (
 type CncrdAdapter interface {
 	CreatePaylink(ctx context.Context, request PaymentLinkRequestDto) (PaymentLinkDto, error)
 	GetPaylinkById(ctx context.Context, id uint) (PaymentLinkDto, error)
+	CreateRefund(ctx context.Context, request RefundRequestDto) (RefundDto, error)
 }
 
 type PaymentLinkRequestDto struct {
@@ -28,3 +29,19 @@ type PaymentLinkDto struct {
 	VatRate     float64 `json:"vat_rate"`
 	Link        string  `json:"link"`
 }
+
+// RefundRequestDto asks the provider to pay back (part of) a card payment.
+type RefundRequestDto struct {
+	ReferenceId        string `json:"reference_id"`         // the transaction id of the refund, booked before the call, so it identifies repeated requests
+	PaymentReferenceId string `json:"payment_reference_id"` // the transaction id of the refunded payment
+	DebitorId          int64  `json:"debitor_id"`
+	Amount             int64  `json:"amount"`
+	Currency           string `json:"currency"`
+}
+
+type RefundDto struct {
+	ReferenceId           string `json:"reference_id"`
+	ProviderTransactionId string `json:"provider_transaction_id"`
+	Amount                int64  `json:"amount"`
+	Currency              string `json:"currency"`
+}
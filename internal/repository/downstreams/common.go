@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/eurofurence/reg-payment-service/internal/config"
@@ -39,6 +40,7 @@ func ApiTokenRequestManipulator(fixedApiToken string) aurestclientapi.RequestMan
 	return func(ctx context.Context, r *http.Request) {
 		r.Header.Add(apiKeyHeader, fixedApiToken)
 		r.Header.Add(middleware.RequestIDHeader, requestIDFromContext(ctx))
+		injectTraceContext(ctx, r)
 	}
 }
 
@@ -49,12 +51,14 @@ func AccessTokenForwardingRequestManipulator() aurestclientapi.RequestManipulato
 			r.Header.Add(headers.Authorization, "Bearer "+accessToken)
 		}
 		r.Header.Add(middleware.RequestIDHeader, requestIDFromContext(ctx))
+		injectTraceContext(ctx, r)
 	}
 }
 
 func CookiesOrAuthHeaderForwardingRequestManipulator(conf config.SecurityConfig) aurestclientapi.RequestManipulatorCallback {
 	return func(ctx context.Context, r *http.Request) {
 		r.Header.Add(middleware.RequestIDHeader, requestIDFromContext(ctx))
+		injectTraceContext(ctx, r)
 
 		idToken, ok2 := ctx.Value(common.CtxKeyIdToken{}).(string)
 		accessToken, ok3 := ctx.Value(common.CtxKeyAccessToken{}).(string)
@@ -103,7 +107,9 @@ func ClientWith(requestManipulator aurestclientapi.RequestManipulatorCallback, c
 		15*time.Second,
 	)
 
-	return circuitBreakerClient, nil
+	InstrumentCircuitBreaker(circuitBreakerClient, circuitBreakerName)
+
+	return WithTracing(circuitBreakerClient, strings.TrimSuffix(circuitBreakerName, "-breaker")), nil
 }
 
 func ErrByStatus(err error, status int) error {
@@ -0,0 +1,51 @@
+package downstreams
+
+import (
+	"context"
+	"net/http"
+
+	aurestclientapi "github.com/StephanHCB/go-autumn-restclient/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/eurofurence/reg-payment-service/internal/tracing"
+)
+
+type tracingClient struct {
+	wrapped aurestclientapi.Client
+	name    string
+}
+
+// WithTracing wraps a client so each call is recorded as a client span.
+//
+// The traceparent header is added by the request manipulators, which see the context of the span.
+func WithTracing(client aurestclientapi.Client, name string) aurestclientapi.Client {
+	return &tracingClient{
+		wrapped: client,
+		name:    name,
+	}
+}
+
+func (c *tracingClient) Perform(ctx context.Context, method string, url string, requestBody interface{}, response *aurestclientapi.ParsedResponse) error {
+	ctx, span := tracing.StartSpan(ctx, c.name+" "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.URLFull(url),
+		),
+	)
+
+	err := c.wrapped.Perform(ctx, method, url, requestBody, response)
+	if response != nil && response.Status != 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(response.Status))
+	}
+
+	tracing.EndSpan(span, err)
+	return err
+}
+
+func injectTraceContext(ctx context.Context, r *http.Request) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+}
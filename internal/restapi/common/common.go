@@ -1,12 +1,18 @@
 package common
 
 import (
+	"context"
 	"encoding/json"
+	"math"
 	"net/http"
 	"net/url"
+	"strconv"
+	"time"
 
+	"github.com/go-http-utils/headers"
 	"github.com/golang-jwt/jwt/v4"
 
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
 	"github.com/eurofurence/reg-payment-service/internal/logging"
 )
 
@@ -15,12 +21,26 @@ type (
 	CtxKeyAccessToken struct{}
 	CtxKeyAPIKey      struct{}
 	CtxKeyClaims      struct{}
+	CtxKeyRequestInfo struct{}
+
+	// CtxKeySystemIdentity is only ever set by the command line, never for requests
+	CtxKeySystemIdentity struct{}
 
 	// TODO Remove after legacy system was replaced with 2FA
 	// See reference https://github.com/eurofurence/reg-payment-service/issues/57
 	CtxKeyAdminHeader struct{}
 )
 
+// RequestInfo describes where a request came from and which endpoint it was made to
+type RequestInfo struct {
+	ClientIP     string
+	ForwardedFor string
+	Method       string
+	Path         string
+	// Accept is the Accept header of the request, used to choose the format of error responses
+	Accept string
+}
+
 type CustomClaims struct {
 	EMail         string   `json:"email"`
 	EMailVerified bool     `json:"email_verified"`
@@ -45,43 +65,123 @@ func EncodeToJSON(w http.ResponseWriter, obj interface{}, logger logging.Logger)
 	}
 }
 
-func SendUnauthorizedResponse(w http.ResponseWriter, reqID string, logger logging.Logger, details string) {
-	SendResponseWithStatusAndMessage(w, http.StatusUnauthorized, reqID, AuthUnauthorizedMessage, logger, details)
+func SendUnauthorizedResponse(ctx context.Context, w http.ResponseWriter, logger logging.Logger, details string) {
+	SendResponseWithStatusAndMessage(ctx, w, http.StatusUnauthorized, AuthUnauthorizedMessage, logger, details)
+}
+
+func SendBadRequestResponse(ctx context.Context, w http.ResponseWriter, logger logging.Logger, details string) {
+	SendResponseWithStatusAndMessage(ctx, w, http.StatusBadRequest, RequestParseErrorMessage, logger, details)
 }
 
-func SendBadRequestResponse(w http.ResponseWriter, reqID string, logger logging.Logger, details string) {
-	SendResponseWithStatusAndMessage(w, http.StatusBadRequest, reqID, RequestParseErrorMessage, logger, details)
+func SendStatusNotFoundResponse(ctx context.Context, w http.ResponseWriter, logger logging.Logger, details string) {
+	SendResponseWithStatusAndMessage(ctx, w, http.StatusNotFound, TransactionIDNotFoundMessage, logger, details)
 }
 
-func SendStatusNotFoundResponse(w http.ResponseWriter, reqID string, logger logging.Logger, details string) {
-	SendResponseWithStatusAndMessage(w, http.StatusNotFound, reqID, TransactionIDNotFoundMessage, logger, details)
+func SendForbiddenResponse(ctx context.Context, w http.ResponseWriter, logger logging.Logger, details string) {
+	SendResponseWithStatusAndMessage(ctx, w, http.StatusForbidden, AuthForbiddenMessage, logger, details)
 }
 
-func SendForbiddenResponse(w http.ResponseWriter, reqID string, logger logging.Logger, details string) {
-	SendResponseWithStatusAndMessage(w, http.StatusForbidden, reqID, AuthForbiddenMessage, logger, details)
+func SendConflictResponse(ctx context.Context, w http.ResponseWriter, logger logging.Logger, details string) {
+	SendResponseWithStatusAndMessage(ctx, w, http.StatusConflict, RequestConflictMessage, logger, details)
 }
 
-func SendConflictResponse(w http.ResponseWriter, reqID string, logger logging.Logger, details string) {
-	SendResponseWithStatusAndMessage(w, http.StatusConflict, reqID, RequestConflictMessage, logger, details)
+// SendTooManyRequestsResponse also sets the Retry-After header, which must happen before the status is written.
+func SendTooManyRequestsResponse(ctx context.Context, w http.ResponseWriter, logger logging.Logger, details string, retryAfter time.Duration) {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	SendResponseWithStatusAndMessage(ctx, w, http.StatusTooManyRequests, RequestRateLimitedMessage, logger, details)
 }
 
-func SendInternalServerError(w http.ResponseWriter, reqID string, logger logging.Logger, details string) {
-	SendResponseWithStatusAndMessage(w, http.StatusInternalServerError, reqID, InternalErrorMessage, logger, details)
+func SendInternalServerError(ctx context.Context, w http.ResponseWriter, logger logging.Logger, details string) {
+	SendResponseWithStatusAndMessage(ctx, w, http.StatusInternalServerError, InternalErrorMessage, logger, details)
 }
 
-func SendResponseWithStatusAndMessage(w http.ResponseWriter, status int, reqID string, message APIErrorMessage, logger logging.Logger, details string) {
-	if reqID == "" {
-		logger.Debug("request id is empty")
+// SendUnprocessableEntityResponse reports field values that failed to validate, keyed by field name.
+func SendUnprocessableEntityResponse(ctx context.Context, w http.ResponseWriter, logger logging.Logger, fields url.Values) {
+	SendResponseWithStatusMessageAndDetails(ctx, w, http.StatusUnprocessableEntity, TransactionDataInvalidMessage, logger, fields)
+}
+
+type statusResponse struct {
+	status  int
+	message APIErrorMessage
+}
+
+// statusResponses maps every known reason to the http status and message sent to the client.
+var statusResponses = map[apierrors.KnownReason]statusResponse{
+	apierrors.KnownReasonBadRequest:          {http.StatusBadRequest, RequestParseErrorMessage},
+	apierrors.KnownReasonUnauthorized:        {http.StatusUnauthorized, AuthUnauthorizedMessage},
+	apierrors.KnownReasonForbidden:           {http.StatusForbidden, AuthForbiddenMessage},
+	apierrors.KnownReasonNotFound:            {http.StatusNotFound, TransactionIDNotFoundMessage},
+	apierrors.KnownReasonConflict:            {http.StatusConflict, RequestConflictMessage},
+	apierrors.KnownReasonUnprocessableEntity: {http.StatusUnprocessableEntity, TransactionDataInvalidMessage},
+	apierrors.KnownReasonInternalServerError: {http.StatusInternalServerError, InternalErrorMessage},
+	apierrors.KnownReasonUnknown:             {http.StatusInternalServerError, UnknownErrorMessage},
+}
+
+// SendStatusErrorResponse sends the response matching the reason of the status, including
+// its details and field level problems.
+//
+// Statuses without a known reason are matched by their http status code instead.
+func SendStatusErrorResponse(ctx context.Context, w http.ResponseWriter, logger logging.Logger, status apierrors.Status) {
+	resp, ok := statusResponses[status.Reason]
+	if !ok || status.Reason == apierrors.KnownReasonUnknown {
+		resp = statusResponses[apierrors.KnownReasonUnknown]
+		for reason, candidate := range statusResponses {
+			if reason != apierrors.KnownReasonUnknown && candidate.status == status.Code {
+				resp = candidate
+				break
+			}
+		}
 	}
 
-	w.WriteHeader(status)
+	details := url.Values{}
+	for field, messages := range status.Fields {
+		details[field] = append([]string(nil), messages...)
+	}
+	if status.Details != "" {
+		details.Add("details", status.Details)
+	}
+
+	SendResponseWithStatusMessageAndDetails(ctx, w, resp.status, resp.message, logger, details)
+}
 
+func SendResponseWithStatusAndMessage(ctx context.Context, w http.ResponseWriter, status int, message APIErrorMessage, logger logging.Logger, details string) {
 	var detailValues url.Values
 	if details != "" {
-		logger.Debug("Request was not successful: [error]: %s", details)
 		detailValues = url.Values{"details": []string{details}}
 	}
 
-	apiErr := NewAPIError(reqID, message, detailValues)
-	EncodeToJSON(w, apiErr, logger)
+	SendResponseWithStatusMessageAndDetails(ctx, w, status, message, logger, detailValues)
+}
+
+// SendResponseWithStatusMessageAndDetails renders the error as application/problem+json if the client prefers it,
+// and in the legacy APIError format otherwise.
+func SendResponseWithStatusMessageAndDetails(ctx context.Context, w http.ResponseWriter, status int, message APIErrorMessage, logger logging.Logger, details url.Values) {
+	reqID := logging.GetRequestID(ctx)
+	if reqID == "" {
+		logger.Debug("request id is empty")
+	}
+
+	if len(details) == 0 {
+		details = nil
+	} else {
+		logger.Debug("Request was not successful: [error]: %v", details)
+	}
+
+	info, _ := ctx.Value(CtxKeyRequestInfo{}).(RequestInfo)
+
+	w.Header().Add(headers.Vary, headers.Accept)
+	if PrefersProblemJSON(info.Accept) {
+		w.Header().Set(headers.ContentType, ProblemJSONContentType)
+		w.WriteHeader(status)
+		EncodeToJSON(w, NewProblemDetails(reqID, status, message, info.Path, details), logger)
+		return
+	}
+
+	w.Header().Set(headers.ContentType, "application/json")
+	w.WriteHeader(status)
+	EncodeToJSON(w, NewAPIError(reqID, message, details), logger)
 }
@@ -17,7 +17,6 @@ func CreateHandler[Req, Res any](endpoint Endpoint[Req, Res],
 	responseHandler ResponseHandler[Res]) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		reqID := logging.GetRequestID(ctx)
 		logger := logging.LoggerFromContext(ctx)
 
 		defer func() {
@@ -29,20 +28,26 @@ func CreateHandler[Req, Res any](endpoint Endpoint[Req, Res],
 
 		if requestHandler == nil {
 			logger.Error("No request handler supplied")
-			SendInternalServerError(w, reqID, logger, "")
+			SendInternalServerError(ctx, w, logger, "")
 			return
 		}
 
 		if responseHandler == nil {
 			logger.Error("No response handler supplied")
-			SendInternalServerError(w, reqID, logger, "")
+			SendInternalServerError(ctx, w, logger, "")
 			return
 		}
 
 		request, err := requestHandler(r)
 		if err != nil {
 			logger.Error("An error occurred while parsing the request. [error]: %v", err)
-			SendBadRequestResponse(w, reqID, logger, "")
+
+			if status := apierrors.AsAPIStatus(err); status != nil {
+				SendStatusErrorResponse(ctx, w, logger, status.Status())
+				return
+			}
+
+			SendBadRequestResponse(ctx, w, logger, requestParseDetails)
 			return
 		}
 
@@ -53,40 +58,20 @@ func CreateHandler[Req, Res any](endpoint Endpoint[Req, Res],
 
 			// check if the error is a `StatusError`
 			if status := apierrors.AsAPIStatus(err); status != nil {
-
-				// TODO enhance
-				switch {
-				case apierrors.IsBadRequestError(err):
-					SendBadRequestResponse(w, reqID, logger, status.Status().Details)
-				case apierrors.IsUnauthorizedError(err):
-					SendUnauthorizedResponse(w, reqID, logger, status.Status().Details)
-				case apierrors.IsForbiddenError(err):
-					SendForbiddenResponse(w, reqID, logger, status.Status().Details)
-				case apierrors.IsNotFoundError(err):
-					SendStatusNotFoundResponse(w, reqID, logger, status.Status().Details)
-				case apierrors.IsConflictError(err):
-					SendConflictResponse(w, reqID, logger, status.Status().Details)
-				case apierrors.IsInternalServerError(err):
-					SendInternalServerError(w,
-						reqID,
-						logger,
-						status.Status().Details,
-					)
-				}
-
+				SendStatusErrorResponse(ctx, w, logger, status.Status())
 				return
 			}
 
 			// do not propagate internal errors to the client.
 			// check the logs for errors - and use metrics later on
 			logger.Error("Service reported internal error: [error]: %v", err)
-			SendInternalServerError(w, reqID, logger, "")
+			SendInternalServerError(ctx, w, logger, "")
 			return
 		}
 
 		if err := responseHandler(ctx, response, w); err != nil {
 			logger.Error("An error occurred during the handling of the response. [error]: %v", err)
-			SendInternalServerError(w, reqID, logger, "")
+			SendInternalServerError(ctx, w, logger, "")
 			return
 		}
 
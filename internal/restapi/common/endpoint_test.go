@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -328,6 +329,38 @@ func TestStatusErrors(t *testing.T) {
 			expectedError:          apierrors.NewConflict("conflict"),
 			expectedStatus:         http.StatusConflict,
 		},
+		{
+			name: "Should return unprocessable entity if business logic returns StatusError",
+			endpoint: func(ctx context.Context, request *testRequest, logger logging.Logger) (*testResponse, error) {
+				return nil, apierrors.NewUnprocessableEntity(url.Values{"counter": {"too small"}})
+			},
+			reqHandler: func(r *http.Request) (*testRequest, error) {
+				tReq.Counter++
+				return tReq, nil
+			},
+			respHandler: func(ctx context.Context, res *testResponse, w http.ResponseWriter) error {
+				res.Counter++
+				return nil
+			},
+			expectedRequestCounter: 1,
+			expectedStatus:         http.StatusUnprocessableEntity,
+		},
+		{
+			name: "Should return internal server error if business logic returns StatusError with unknown reason",
+			endpoint: func(ctx context.Context, request *testRequest, logger logging.Logger) (*testResponse, error) {
+				return nil, &apierrors.StatusError{ErrStatus: apierrors.Status{Reason: apierrors.KnownReasonUnknown}}
+			},
+			reqHandler: func(r *http.Request) (*testRequest, error) {
+				tReq.Counter++
+				return tReq, nil
+			},
+			respHandler: func(ctx context.Context, res *testResponse, w http.ResponseWriter) error {
+				res.Counter++
+				return nil
+			},
+			expectedRequestCounter: 1,
+			expectedStatus:         http.StatusInternalServerError,
+		},
 		{
 			name: "Should return internal server error if business logic returns StatusError",
 			endpoint: func(ctx context.Context, request *testRequest, logger logging.Logger) (*testResponse, error) {
@@ -385,3 +418,84 @@ func TestStatusErrors(t *testing.T) {
 	}
 
 }
+
+func TestErrorResponseDetails(t *testing.T) {
+	tests := []struct {
+		name            string
+		endpointErr     error
+		reqHandlerErr   error
+		expectedStatus  int
+		expectedMessage APIErrorMessage
+		expectedDetails url.Values
+	}{
+		{
+			name:            "Should report field level validation errors from the request handler",
+			reqHandlerErr:   apierrors.NewUnprocessableEntity(url.Values{"debitor_id": {"must be greater than zero"}, "method": {"invalid payment method Kevin"}}),
+			expectedStatus:  http.StatusUnprocessableEntity,
+			expectedMessage: TransactionDataInvalidMessage,
+			expectedDetails: url.Values{
+				"debitor_id": {"must be greater than zero"},
+				"method":     {"invalid payment method Kevin"},
+				"details":    {"invalid values for fields: debitor_id, method"},
+			},
+		},
+		{
+			name:            "Should report plain request handler errors as parse failures without echoing them",
+			reqHandlerErr:   errors.New("invalid character '}' looking for beginning of value"),
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: RequestParseErrorMessage,
+			expectedDetails: url.Values{"details": {"request body could not be parsed"}},
+		},
+		{
+			name:            "Should report field level errors from the business logic",
+			endpointErr:     apierrors.NewBadRequest("invalid currency USD provided").WithField("amount.currency", "not allowed"),
+			expectedStatus:  http.StatusBadRequest,
+			expectedMessage: RequestParseErrorMessage,
+			expectedDetails: url.Values{
+				"amount.currency": {"not allowed"},
+				"details":         {"invalid currency USD provided"},
+			},
+		},
+		{
+			name:            "Should match statuses without a known reason by code",
+			endpointErr:     &apierrors.StatusError{ErrStatus: apierrors.Status{Reason: apierrors.KnownReasonUnknown, Code: http.StatusNotFound}},
+			expectedStatus:  http.StatusNotFound,
+			expectedMessage: TransactionIDNotFoundMessage,
+		},
+		{
+			name:            "Should report statuses without a known reason or code as unknown",
+			endpointErr:     &apierrors.StatusError{ErrStatus: apierrors.Status{Reason: apierrors.KnownReasonUnknown}},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedMessage: UnknownErrorMessage,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := setupHandler(
+				func(ctx context.Context, request *testRequest, logger logging.Logger) (*testResponse, error) {
+					return nil, tc.endpointErr
+				},
+				func(r *http.Request) (*testRequest, error) {
+					if tc.reqHandlerErr != nil {
+						return nil, tc.reqHandlerErr
+					}
+					return &testRequest{}, nil
+				},
+				func(ctx context.Context, res *testResponse, w http.ResponseWriter) error {
+					return nil
+				},
+			)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			require.Equal(t, tc.expectedStatus, w.Code)
+
+			var apiErr APIError
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&apiErr))
+			require.Equal(t, tc.expectedMessage, apiErr.Message)
+			require.Equal(t, tc.expectedDetails, apiErr.Details)
+		})
+	}
+}
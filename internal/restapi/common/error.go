@@ -41,6 +41,8 @@ const (
 	RequestParseErrorMessage APIErrorMessage = "request.parse.failed"
 	// Request created a conflict
 	RequestConflictMessage APIErrorMessage = "request.conflict"
+	// Too many requests by the same caller
+	RequestRateLimitedMessage APIErrorMessage = "request.rate.limited"
 	// Internal error
 	InternalErrorMessage APIErrorMessage = "http.error.internal"
 	// Unknown error
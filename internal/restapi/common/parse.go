@@ -0,0 +1,61 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+)
+
+// requestParseDetails is sent for every request that could not be parsed. The underlying error
+// is only logged, it may echo parts of the request body.
+const requestParseDetails = "request body could not be parsed"
+
+// Field level messages for values that are not one of the allowed values. They name the allowed values
+// instead of echoing the rejected one.
+const (
+	InvalidPaymentMethodMessage     = "must be one of credit, paypal, transfer, internal, gift, cash"
+	InvalidTransactionTypeMessage   = "must be one of due, payment"
+	InvalidTransactionStatusMessage = "must be one of tentative, pending, valid, deleted"
+	InvalidUndeletedStatusMessage   = "must be one of tentative, pending, valid"
+	InvalidEffectiveDateMessage     = "must be a date in the format YYYY-MM-DD"
+)
+
+// BodyParseError reports a request body that is not valid json or does not match the expected structure.
+//
+// Type mismatches are reported for the offending field, so clients can tell which value was wrong.
+func BodyParseError(err error) error {
+	parseErr := apierrors.NewBadRequest(requestParseDetails)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		parseErr.WithField(typeErr.Field, "must be of type "+jsonTypeName(typeErr.Type))
+	}
+	return parseErr
+}
+
+// jsonTypeName names the json type a value must have to be decoded into t.
+func jsonTypeName(t reflect.Type) string {
+	if t == nil {
+		return "value"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	}
+	return "value"
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProblemJSONContentType is the media type of RFC 9457 problem details.
+const ProblemJSONContentType = "application/problem+json"
+
+// ProblemDetails is the RFC 9457 rendering of an APIError, sent to clients that prefer application/problem+json.
+//
+// The request id, the APIErrorMessage code and any field level details are added as extension members.
+type ProblemDetails struct {
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Status    int             `json:"status"`
+	Detail    string          `json:"detail,omitempty"`
+	Instance  string          `json:"instance,omitempty"`
+	RequestID string          `json:"requestid"`
+	Code      APIErrorMessage `json:"code"`
+	Timestamp int64           `json:"timestamp"`
+	Fields    url.Values      `json:"fields,omitempty"`
+}
+
+// NewProblemDetails creates the problem details for an error response.
+//
+// The "details" entry of details becomes the detail member, all other entries are field level problems.
+func NewProblemDetails(reqID string, status int, message APIErrorMessage, instance string, details url.Values) *ProblemDetails {
+	problem := &ProblemDetails{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    strings.Join(details["details"], "; "),
+		Instance:  instance,
+		RequestID: reqID,
+		Code:      message,
+		Timestamp: time.Now().Unix(),
+	}
+
+	for field, messages := range details {
+		if field == "details" {
+			continue
+		}
+		if problem.Fields == nil {
+			problem.Fields = url.Values{}
+		}
+		problem.Fields[field] = messages
+	}
+
+	return problem
+}
+
+// PrefersProblemJSON reports whether the Accept header asks for application/problem+json
+// with at least the same quality as application/json.
+//
+// Wildcards match both formats equally, so they keep the legacy format.
+func PrefersProblemJSON(accept string) bool {
+	problemQ, jsonQ := 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if qStr, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qStr, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case ProblemJSONContentType:
+			problemQ = max(problemQ, q)
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+
+	return problemQ > 0 && problemQ >= jsonQ
+}
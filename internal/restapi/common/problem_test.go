@@ -0,0 +1,86 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+)
+
+func TestPrefersProblemJSON(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected bool
+	}{
+		{accept: "", expected: false},
+		{accept: "*/*", expected: false},
+		{accept: "application/json", expected: false},
+		{accept: "application/problem+json", expected: true},
+		{accept: "application/problem+json, application/json", expected: true},
+		{accept: "application/json, application/problem+json;q=0.9", expected: false},
+		{accept: "application/json;q=0.5, application/problem+json", expected: true},
+		{accept: "application/problem+json;q=0", expected: false},
+		{accept: "application/problem+json;q=nonsense", expected: false},
+		{accept: "text/html, application/problem+json;q=0.8, */*;q=0.1", expected: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.accept, func(t *testing.T) {
+			require.Equal(t, tc.expected, PrefersProblemJSON(tc.accept))
+		})
+	}
+}
+
+func TestSendStatusErrorResponseFormat(t *testing.T) {
+	status := apierrors.NewUnprocessableEntity(url.Values{"debitor_id": {"must be greater than zero"}}).Status()
+
+	sendWithAccept := func(accept string) *httptest.ResponseRecorder {
+		ctx := logging.ChildCtxWithRequestID(context.Background(), "a8b7c6d5")
+		ctx = context.WithValue(ctx, CtxKeyRequestInfo{}, RequestInfo{Path: "/api/rest/v1/transactions", Accept: accept})
+
+		w := httptest.NewRecorder()
+		SendStatusErrorResponse(ctx, w, logging.NewNoopLogger(), status)
+		return w
+	}
+
+	t.Run("problem+json when preferred", func(t *testing.T) {
+		w := sendWithAccept("application/problem+json")
+
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		require.Equal(t, ProblemJSONContentType, w.Header().Get("Content-Type"))
+
+		var problem ProblemDetails
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+		require.Equal(t, "about:blank", problem.Type)
+		require.Equal(t, "Unprocessable Entity", problem.Title)
+		require.Equal(t, http.StatusUnprocessableEntity, problem.Status)
+		require.Equal(t, "invalid values for fields: debitor_id", problem.Detail)
+		require.Equal(t, "/api/rest/v1/transactions", problem.Instance)
+		require.Equal(t, "a8b7c6d5", problem.RequestID)
+		require.Equal(t, TransactionDataInvalidMessage, problem.Code)
+		require.Equal(t, url.Values{"debitor_id": {"must be greater than zero"}}, problem.Fields)
+	})
+
+	t.Run("legacy format otherwise", func(t *testing.T) {
+		w := sendWithAccept("application/json")
+
+		require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var apiErr APIError
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&apiErr))
+		require.Equal(t, "a8b7c6d5", apiErr.RequestID)
+		require.Equal(t, TransactionDataInvalidMessage, apiErr.Message)
+		require.Equal(t, url.Values{
+			"debitor_id": {"must be greater than zero"},
+			"details":    {"invalid values for fields: debitor_id"},
+		}, apiErr.Details)
+	})
+}
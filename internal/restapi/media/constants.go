@@ -2,3 +2,6 @@ package media
 
 const ContentTypeApplicationJson = "application/json"
 const ContentTypeTextPlain = "text/plain; charset=utf-8"
+const ContentTypeApplicationPdf = "application/pdf"
+const ContentTypeImagePng = "image/png"
+const ContentTypeImageSvg = "image/svg+xml"
@@ -153,10 +153,14 @@ func TestCheckRequestAuthorization_ParsePEMs(t *testing.T) {
 type statusCodeResponseWriter struct {
 	statusCode int
 	called     bool
+	header     http.Header
 }
 
 func (s *statusCodeResponseWriter) Header() http.Header {
-	return nil
+	if s.header == nil {
+		s.header = http.Header{}
+	}
+	return s.header
 }
 
 func (s *statusCodeResponseWriter) Write(b []byte) (int, error) {
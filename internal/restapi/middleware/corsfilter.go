@@ -1,45 +1,267 @@
 package middleware
 
 import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
 	"github.com/go-http-utils/headers"
 
 	"github.com/eurofurence/reg-payment-service/internal/config"
 	"github.com/eurofurence/reg-payment-service/internal/logging"
+)
 
-	"log"
-	"net/http"
+var (
+	defaultCorsMethods        = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+	defaultCorsHeaders        = []string{"Content-Type", "Authorization", adminRequestHeader}
+	defaultCorsExposedHeaders = []string{"Location", RequestIDHeader}
 )
 
-func createCorsHeadersHandler(next http.Handler, config *config.SecurityConfig) func(w http.ResponseWriter, r *http.Request) {
+type corsPolicy struct {
+	origins          []originPattern
+	methods          []string
+	headers          []string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           int
+	// warn on every request, because the policy was configured for local development
+	warn bool
+	// reload counts the configuration reloads before the policy was built
+	reload uint64
+}
+
+var (
+	// reloadedCorsPolicy is built from the most recent configuration reload. A single reload listener
+	// serves all middlewares, which prefer it over their own policy if it is newer.
+	reloadedCorsPolicy atomic.Pointer[corsPolicy]
+	corsReloads        atomic.Uint64
+	registerCorsReload sync.Once
+)
+
+// originPattern matches either exactly one origin, or all subdomains of a host if wildcard is set
+type originPattern struct {
+	any      bool
+	scheme   string
+	host     string
+	port     string
+	wildcard bool
+}
+
+func newCorsPolicy(conf *config.CorsConfig) *corsPolicy {
+	if conf == nil {
+		return &corsPolicy{}
+	}
+
+	if conf.DisableCors {
+		policy := &corsPolicy{
+			methods:          defaultCorsMethods,
+			headers:          defaultCorsHeaders,
+			exposedHeaders:   strings.Join(defaultCorsExposedHeaders, ", "),
+			allowCredentials: true,
+			warn:             true,
+		}
+		for _, origin := range strings.Split(conf.AllowOrigin, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				policy.origins = append(policy.origins, parseOriginPattern(origin))
+			}
+		}
+		return policy
+	}
+
+	policy := &corsPolicy{
+		methods:          withDefault(conf.AllowedMethods, defaultCorsMethods),
+		headers:          withDefault(conf.AllowedHeaders, defaultCorsHeaders),
+		exposedHeaders:   strings.Join(withDefault(conf.ExposedHeaders, defaultCorsExposedHeaders), ", "),
+		allowCredentials: conf.AllowCredentials,
+		maxAge:           conf.MaxAgeSeconds,
+	}
+	for _, origin := range conf.AllowedOrigins {
+		policy.origins = append(policy.origins, parseOriginPattern(origin))
+	}
+	return policy
+}
+
+func withDefault(values []string, defaults []string) []string {
+	if len(values) == 0 {
+		return defaults
+	}
+	return values
+}
+
+func parseOriginPattern(origin string) originPattern {
+	if origin == "*" {
+		return originPattern{any: true}
+	}
+
+	scheme, host, port := splitOrigin(origin)
+	p := originPattern{scheme: scheme, host: host, port: port}
+	if strings.HasPrefix(host, "*.") {
+		p.wildcard = true
+		p.host = strings.TrimPrefix(host, "*")
+	}
+	return p
+}
+
+// splitOrigin splits scheme://host[:port] into its lower case parts. The port is left empty if not given.
+func splitOrigin(origin string) (scheme string, host string, port string) {
+	scheme, rest, found := strings.Cut(strings.ToLower(origin), "://")
+	if !found {
+		return "", "", ""
+	}
+
+	host = rest
+	if i := strings.LastIndex(rest, ":"); i >= 0 {
+		host, port = rest[:i], rest[i+1:]
+	}
+	return scheme, host, port
+}
+
+func (p originPattern) matches(origin string) bool {
+	if p.any {
+		return true
+	}
+
+	scheme, host, port := splitOrigin(origin)
+	if scheme != p.scheme || port != p.port {
+		return false
+	}
+
+	if p.wildcard {
+		// p.host starts with a dot, so the bare domain and look-alikes such as evilexample.com do not match
+		return len(host) > len(p.host) && strings.HasSuffix(host, p.host)
+	}
+	return host == p.host
+}
+
+func (c *corsPolicy) allowsOrigin(origin string) bool {
+	for _, p := range c.origins {
+		if p.matches(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *corsPolicy) allowsMethod(method string) bool {
+	for _, m := range c.methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *corsPolicy) allowsHeaders(requested string) bool {
+	for _, h := range strings.Split(requested, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+
+		allowed := false
+		for _, a := range c.headers {
+			if strings.EqualFold(a, h) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func createCorsHeadersHandler(next http.Handler, initial *corsPolicy) func(w http.ResponseWriter, r *http.Request) {
 	handlerFunc := func(w http.ResponseWriter, r *http.Request) {
+		policy := initial
+		if reloaded := reloadedCorsPolicy.Load(); reloaded != nil && reloaded.reload > initial.reload {
+			policy = reloaded
+		}
 		ctx := r.Context()
+		logger := logging.LoggerFromContext(ctx)
+
+		if policy.warn {
+			logger.Warn("sending headers to disable CORS. This configuration is not intended for production use, only for local development!")
+		}
+
+		origin := r.Header.Get(headers.Origin)
+		isPreflight := r.Method == http.MethodOptions && r.Header.Get(headers.AccessControlRequestMethod) != ""
 
-		// Example for cors middleware
-		if config != nil && config.Cors.DisableCors {
-			logging.LoggerFromContext(ctx).Warn("sending headers to disable CORS. This configuration is not intended for production use, only for local development!")
-			w.Header().Set(headers.AccessControlAllowOrigin, config.Cors.AllowOrigin)
-			w.Header().Set(headers.AccessControlAllowMethods, "POST, GET, OPTIONS, PUT, DELETE")
-			w.Header().Set(headers.AccessControlAllowHeaders, "content-type")
-			w.Header().Set(headers.AccessControlAllowCredentials, "true")
-			w.Header().Set(headers.AccessControlExposeHeaders, "Location, X-B3-TraceId")
+		// the response depends on the origin, so caches must not serve it for other origins
+		w.Header().Add(headers.Vary, headers.Origin)
+		if isPreflight {
+			w.Header().Add(headers.Vary, headers.AccessControlRequestMethod)
+			w.Header().Add(headers.Vary, headers.AccessControlRequestHeaders)
+		}
+
+		originAllowed := origin != "" && policy.allowsOrigin(origin)
+		if originAllowed {
+			w.Header().Set(headers.AccessControlAllowOrigin, origin)
+			if policy.allowCredentials {
+				w.Header().Set(headers.AccessControlAllowCredentials, "true")
+			}
+		} else if origin != "" {
+			logger.Debug("origin %s is not allowed by the CORS policy", origin)
 		}
 
 		if r.Method == http.MethodOptions {
-			log.Println("INFO received OPTIONS request. Responding with OK.")
-			w.WriteHeader(http.StatusOK)
+			// preflight requests carry no credentials, so they must be answered before authorization
+			if isPreflight && originAllowed {
+				requestedMethod := r.Header.Get(headers.AccessControlRequestMethod)
+				requestedHeaders := r.Header.Get(headers.AccessControlRequestHeaders)
+
+				if policy.allowsMethod(requestedMethod) && policy.allowsHeaders(requestedHeaders) {
+					w.Header().Set(headers.AccessControlAllowMethods, strings.Join(policy.methods, ", "))
+					w.Header().Set(headers.AccessControlAllowHeaders, strings.Join(policy.headers, ", "))
+					if policy.maxAge > 0 {
+						w.Header().Set(headers.AccessControlMaxAge, strconv.Itoa(policy.maxAge))
+					}
+				} else {
+					logger.Debug("preflight for %s with headers [%s] is not allowed by the CORS policy", requestedMethod, requestedHeaders)
+				}
+			}
+
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
+		if originAllowed && policy.exposedHeaders != "" {
+			w.Header().Set(headers.AccessControlExposeHeaders, policy.exposedHeaders)
+		}
+
 		next.ServeHTTP(w, r)
 	}
 	return handlerFunc
 }
 
-// would not need this extra layer in the absence of parameters
+// CorsHeadersMiddleware applies the configured CORS policy, and answers all OPTIONS requests.
+//
+// Allowed origins are reflected in Access-Control-Allow-Origin rather than answered with *,
+// because * is not accepted by browsers for requests with cookies.
+//
+// The policy is rebuilt when the configuration is reloaded.
+func CorsHeadersMiddleware(conf *config.SecurityConfig) func(http.Handler) http.Handler {
+	var policy *corsPolicy
+	if conf != nil {
+		policy = newCorsPolicy(&conf.Cors)
+	} else {
+		policy = newCorsPolicy(nil)
+	}
+	policy.reload = corsReloads.Load()
+
+	registerCorsReload.Do(func() {
+		config.OnReload(func(reloaded *config.Application) {
+			next := newCorsPolicy(&reloaded.Security.Cors)
+			next.reload = corsReloads.Add(1)
+			reloadedCorsPolicy.Store(next)
+		})
+	})
 
-func CorsHeadersMiddleware(config *config.SecurityConfig) func(http.Handler) http.Handler {
 	middlewareCreator := func(next http.Handler) http.Handler {
-		return http.HandlerFunc(createCorsHeadersHandler(next, config))
+		return http.HandlerFunc(createCorsHeadersHandler(next, policy))
 	}
 	return middlewareCreator
 }
@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/go-http-utils/headers"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/docs"
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/repository/downstreams/authservice"
+)
+
+var corsConfig = config.CorsConfig{
+	AllowedOrigins:   []string{"https://regsys.example.com", "https://*.staging.example.com", "http://localhost:8000"},
+	AllowCredentials: true,
+	MaxAgeSeconds:    600,
+}
+
+func TestOriginPattern(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		origin   string
+		expected bool
+	}{
+		{pattern: "https://regsys.example.com", origin: "https://regsys.example.com", expected: true},
+		{pattern: "https://regsys.example.com", origin: "https://REGSYS.example.com", expected: true},
+		{pattern: "https://regsys.example.com", origin: "http://regsys.example.com", expected: false},
+		{pattern: "https://regsys.example.com", origin: "https://regsys.example.com:8443", expected: false},
+		{pattern: "https://regsys.example.com", origin: "https://regsys.example.com.evil.com", expected: false},
+		{pattern: "http://localhost:8000", origin: "http://localhost:8000", expected: true},
+		{pattern: "http://localhost:8000", origin: "http://localhost:8001", expected: false},
+		{pattern: "https://*.example.com", origin: "https://portal.example.com", expected: true},
+		{pattern: "https://*.example.com", origin: "https://a.b.example.com", expected: true},
+		{pattern: "https://*.example.com", origin: "https://example.com", expected: false},
+		{pattern: "https://*.example.com", origin: "https://evilexample.com", expected: false},
+		{pattern: "https://*.example.com", origin: "null", expected: false},
+		{pattern: "*", origin: "https://anything.example.org", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.origin, func(t *testing.T) {
+			require.Equal(t, tt.expected, parseOriginPattern(tt.pattern).matches(tt.origin))
+		})
+	}
+}
+
+func TestCorsHeaders(t *testing.T) {
+	tests := []struct {
+		name                string
+		method              string
+		origin              string
+		requestMethod       string
+		requestHeaders      string
+		expectedStatus      int
+		expectedAllowOrigin string
+		expectedAllowMethod string
+		expectedVary        []string
+	}{
+		{
+			name:                "should answer an allowed preflight",
+			method:              http.MethodOptions,
+			origin:              "https://regsys.example.com",
+			requestMethod:       http.MethodPut,
+			requestHeaders:      "content-type, x-admin-request",
+			expectedStatus:      http.StatusNoContent,
+			expectedAllowOrigin: "https://regsys.example.com",
+			expectedAllowMethod: "GET, POST, PUT, DELETE",
+			expectedVary:        []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:                "should answer a preflight from a wildcard subdomain",
+			method:              http.MethodOptions,
+			origin:              "https://portal.staging.example.com",
+			requestMethod:       http.MethodPost,
+			expectedStatus:      http.StatusNoContent,
+			expectedAllowOrigin: "https://portal.staging.example.com",
+			expectedAllowMethod: "GET, POST, PUT, DELETE",
+			expectedVary:        []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:           "should not allow a preflight from an unknown origin",
+			method:         http.MethodOptions,
+			origin:         "https://evil.example.org",
+			requestMethod:  http.MethodPost,
+			expectedStatus: http.StatusNoContent,
+			expectedVary:   []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:                "should not allow a preflight for a header that is not listed",
+			method:              http.MethodOptions,
+			origin:              "https://regsys.example.com",
+			requestMethod:       http.MethodPost,
+			requestHeaders:      "x-something-else",
+			expectedStatus:      http.StatusNoContent,
+			expectedAllowOrigin: "https://regsys.example.com",
+			expectedVary:        []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:                "should not allow a preflight for a method that is not listed",
+			method:              http.MethodOptions,
+			origin:              "https://regsys.example.com",
+			requestMethod:       http.MethodPatch,
+			expectedStatus:      http.StatusNoContent,
+			expectedAllowOrigin: "https://regsys.example.com",
+			expectedVary:        []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"},
+		},
+		{
+			name:                "should send cors headers on requests from allowed origins",
+			method:              http.MethodGet,
+			origin:              "http://localhost:8000",
+			expectedStatus:      http.StatusOK,
+			expectedAllowOrigin: "http://localhost:8000",
+			expectedVary:        []string{"Origin"},
+		},
+		{
+			name:           "should not send cors headers on requests from unknown origins",
+			method:         http.MethodGet,
+			origin:         "http://localhost:9999",
+			expectedStatus: http.StatusOK,
+			expectedVary:   []string{"Origin"},
+		},
+		{
+			name:           "should set vary on requests without origin",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+			expectedVary:   []string{"Origin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CorsHeadersMiddleware(&config.SecurityConfig{Cors: corsConfig})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(tt.method, "/api/rest/v1/transactions", nil)
+			if tt.origin != "" {
+				r.Header.Set(headers.Origin, tt.origin)
+			}
+			if tt.requestMethod != "" {
+				r.Header.Set(headers.AccessControlRequestMethod, tt.requestMethod)
+			}
+			if tt.requestHeaders != "" {
+				r.Header.Set(headers.AccessControlRequestHeaders, tt.requestHeaders)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			require.Equal(t, tt.expectedStatus, w.Code)
+			require.Equal(t, tt.expectedAllowOrigin, w.Header().Get(headers.AccessControlAllowOrigin))
+			require.Equal(t, tt.expectedAllowMethod, w.Header().Get(headers.AccessControlAllowMethods))
+			require.Equal(t, tt.expectedVary, w.Header().Values(headers.Vary))
+
+			if tt.expectedAllowOrigin != "" {
+				require.Equal(t, "true", w.Header().Get(headers.AccessControlAllowCredentials))
+			} else {
+				require.Empty(t, w.Header().Get(headers.AccessControlAllowCredentials))
+			}
+			if tt.expectedAllowMethod != "" {
+				require.Equal(t, "Content-Type, Authorization, X-Admin-Request", w.Header().Get(headers.AccessControlAllowHeaders))
+				require.Equal(t, "600", w.Header().Get(headers.AccessControlMaxAge))
+			}
+		})
+	}
+}
+
+func TestCorsDisabledForDevelopment(t *testing.T) {
+	handler := CorsHeadersMiddleware(&config.SecurityConfig{Cors: config.CorsConfig{
+		DisableCors: true,
+		AllowOrigin: "http://localhost:8000, http://localhost:8001",
+	}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/api/rest/v1/transactions", nil)
+	r.Header.Set(headers.Origin, "http://localhost:8001")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	require.Equal(t, "http://localhost:8001", w.Header().Get(headers.AccessControlAllowOrigin))
+	require.Equal(t, "true", w.Header().Get(headers.AccessControlAllowCredentials))
+	require.Equal(t, "Location, X-Request-Id", w.Header().Get(headers.AccessControlExposeHeaders))
+}
+
+func TestCorsWithCookieAuthentication(t *testing.T) {
+	docs.Description("cross origin requests with the authentication cookies work from allowed origins")
+	authServiceMock.Reset()
+	authServiceMock.Enable()
+	authServiceMock.SetupResponse(valid_JWT_id_is_admin_sub1234567890, valid_access_token, authservice.UserInfoResponse{
+		Subject: "1234567890",
+		Groups:  []string{"admin"},
+	})
+
+	conf := securityConfig256
+	conf.Cors = corsConfig
+
+	handler := CorsHeadersMiddleware(&conf)(CheckRequestAuthorization(&conf)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	// the preflight carries no cookies, and must not be rejected by the authorization
+	preflight := httptest.NewRequest(http.MethodOptions, "/api/rest/v1/transactions", nil)
+	preflight.Header.Set(headers.Origin, "https://regsys.example.com")
+	preflight.Header.Set(headers.AccessControlRequestMethod, http.MethodPost)
+	preflight.Header.Set(headers.AccessControlRequestHeaders, "content-type")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, preflight)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, "https://regsys.example.com", w.Header().Get(headers.AccessControlAllowOrigin))
+	require.Equal(t, "true", w.Header().Get(headers.AccessControlAllowCredentials))
+
+	// the actual request carries the cookies
+	r := httptest.NewRequest(http.MethodPost, "/api/rest/v1/transactions", nil)
+	r.Header.Set(headers.Origin, "https://regsys.example.com")
+	r.AddCookie(&http.Cookie{Name: conf.Oidc.IdTokenCookieName, Value: valid_JWT_id_is_admin_sub1234567890})
+	r.AddCookie(&http.Cookie{Name: conf.Oidc.AccessTokenCookieName, Value: valid_access_token})
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "https://regsys.example.com", w.Header().Get(headers.AccessControlAllowOrigin))
+	require.Equal(t, "true", w.Header().Get(headers.AccessControlAllowCredentials))
+	require.Equal(t, []string{"Origin"}, w.Header().Values(headers.Vary))
+
+	// without cookies, the browser must still be able to read the 401
+	r = httptest.NewRequest(http.MethodPost, "/api/rest/v1/transactions", nil)
+	r.Header.Set(headers.Origin, "https://regsys.example.com")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, "https://regsys.example.com", w.Header().Get(headers.AccessControlAllowOrigin))
+}
+
+func TestCorsPolicyFollowsReload(t *testing.T) {
+	docs.Description("a configuration reload replaces the cors policy of all middlewares created before it")
+	f, err := os.Open("../../../docs/config.example.yaml")
+	require.NoError(t, err)
+	defer f.Close()
+	conf, err := config.UnmarshalFromYamlConfiguration(f)
+	require.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	allowedOrigin := func(handler http.Handler) string {
+		r := httptest.NewRequest(http.MethodGet, "/api/rest/v1/transactions", nil)
+		r.Header.Set(headers.Origin, "https://reloaded.example.com")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Header().Get(headers.AccessControlAllowOrigin)
+	}
+
+	first := CorsHeadersMiddleware(&config.SecurityConfig{Cors: corsConfig})(ok)
+	second := CorsHeadersMiddleware(&config.SecurityConfig{Cors: corsConfig})(ok)
+	require.Equal(t, "", allowedOrigin(first))
+
+	reloaded := *conf
+	reloaded.Security.Cors = config.CorsConfig{AllowedOrigins: []string{"https://reloaded.example.com"}}
+	require.NoError(t, config.Reload(&reloaded, t.Logf))
+
+	require.Equal(t, "https://reloaded.example.com", allowedOrigin(first))
+	require.Equal(t, "https://reloaded.example.com", allowedOrigin(second))
+
+	// created after the reload, so its own policy is the newer one
+	third := CorsHeadersMiddleware(&config.SecurityConfig{Cors: corsConfig})(ok)
+	require.Equal(t, "", allowedOrigin(third))
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/eurofurence/reg-payment-service/internal/metrics"
+)
+
+// unmatchedRoute is used for requests that were answered before routing, e.g. because authorization failed,
+// so arbitrary paths do not create new time series.
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware counts requests and measures their duration, by route pattern, method and status.
+//
+// Must be placed before all middlewares that may answer a request themselves.
+func MetricsMiddleware(next http.Handler) http.Handler {
+	handlerFunc := func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		metrics.HTTPRequestServed(route, r.Method, strconv.Itoa(status), time.Since(start))
+	}
+	return http.HandlerFunc(handlerFunc)
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(MetricsMiddleware)
+	router.Get("/api/rest/v1/transactions/{debitor_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for _, path := range []string{"/api/rest/v1/transactions/1", "/api/rest/v1/transactions/2", "/does/not/exist"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	expected := `
+# HELP reg_payment_http_requests_total Number of http requests, by route pattern, method and status.
+# TYPE reg_payment_http_requests_total counter
+reg_payment_http_requests_total{method="GET",route="/api/rest/v1/transactions/{debitor_id}",status="404"} 2
+reg_payment_http_requests_total{method="GET",route="unmatched",status="404"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(prometheus.DefaultGatherer, strings.NewReader(expected), "reg_payment_http_requests_total"))
+}
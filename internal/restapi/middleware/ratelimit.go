@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+)
+
+const apiTokenRateLimitKey = "api-token"
+
+// rateLimiter is a token bucket per key.
+//
+// Each bucket holds up to burst tokens and refills at rate tokens per second. A request takes one token.
+type rateLimiter struct {
+	mu          sync.Mutex
+	rate        float64
+	burst       float64
+	buckets     map[string]*tokenBucket
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(limit config.RateLimit) *rateLimiter {
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		rate:    float64(limit.RequestsPerMinute) / 60.0,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from the bucket for key. If none is left, it returns how long until the next one is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.cleanup(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// cleanup removes the buckets that have refilled completely, they are no different from new ones.
+//
+// It runs at most once a minute, so the map does not grow with every caller ever seen.
+func (l *rateLimiter) cleanup(now time.Time) {
+	if now.Sub(l.lastCleanup) < time.Minute {
+		return
+	}
+	l.lastCleanup = now
+
+	fullAfter := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, bucket := range l.buckets {
+		if now.Sub(bucket.last) >= fullAfter {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type routeRateLimit struct {
+	method   string
+	segments []string // the segments of the configured path, {name} matches any single segment
+	users    *rateLimiter
+	apiToken *rateLimiter // nil if api token calls are exempt
+}
+
+// matches reports whether the request is for this route, and how many path parameters it took to match.
+func (l *routeRateLimit) matches(method string, segments []string) (bool, int) {
+	if method != l.method || len(segments) != len(l.segments) {
+		return false, 0
+	}
+
+	params := 0
+	for i, segment := range l.segments {
+		if isPathParameter(segment) {
+			if segments[i] == "" {
+				return false, 0
+			}
+			params++
+		} else if segment != segments[i] {
+			return false, 0
+		}
+	}
+	return true, params
+}
+
+func isPathParameter(segment string) bool {
+	return len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+// findRouteRateLimit returns the limit of the route the request is for, or nil.
+//
+// Like the router, it prefers fixed segments over parameters, so /transactions/initiate-payment
+// takes precedence over /transactions/{id}.
+func findRouteRateLimit(limits []*routeRateLimit, r *http.Request) *routeRateLimit {
+	segments := strings.Split(r.URL.Path, "/")
+
+	var found *routeRateLimit
+	foundParams := 0
+	for _, limit := range limits {
+		if ok, params := limit.matches(r.Method, segments); ok && (found == nil || params < foundParams) {
+			found, foundParams = limit, params
+		}
+	}
+	return found
+}
+
+// RateLimitMiddleware limits how often a single caller may call the configured routes, and responds
+// with status 429 and a Retry-After header once the limit is reached.
+//
+// Routes are configured by method and path, where a segment such as {id} matches any value, so all
+// transactions share the budget of /api/rest/v1/transactions/{id}/receipt.
+//
+// Callers are identified by the subject of their token. Requests without a token are identified by
+// client ip. Calls with the api token share a separate budget, or are not limited at all.
+//
+// Must be placed after CheckRequestAuthorization, so the token has been checked.
+func RateLimitMiddleware(conf *config.SecurityConfig) func(http.Handler) http.Handler {
+	limits := make([]*routeRateLimit, 0, len(conf.RateLimit.Routes))
+	for _, route := range conf.RateLimit.Routes {
+		limit := &routeRateLimit{
+			method:   route.Method,
+			segments: strings.Split(route.Path, "/"),
+			users:    newRateLimiter(route.RateLimit),
+		}
+		if conf.RateLimit.ApiToken.RequestsPerMinute > 0 {
+			limit.apiToken = newRateLimiter(conf.RateLimit.ApiToken)
+		}
+		limits = append(limits, limit)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := findRouteRateLimit(limits, r)
+			if limit == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			limiter, key := limit.users, rateLimitKey(r)
+			if key == apiTokenRateLimitKey {
+				if limit.apiToken == nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+				limiter = limit.apiToken
+			}
+
+			if allowed, retryAfter := limiter.allow(key); !allowed {
+				logger := logging.LoggerFromContext(ctx)
+				logger.Warn("rate limit exceeded for %s on %s %s", key, r.Method, r.URL.Path)
+				common.SendTooManyRequestsResponse(ctx, w, logger, "too many requests, please try again later", retryAfter)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func rateLimitKey(r *http.Request) string {
+	ctx := r.Context()
+
+	if _, ok := ctx.Value(common.CtxKeyAPIKey{}).(string); ok {
+		return apiTokenRateLimitKey
+	}
+
+	if claims, ok := ctx.Value(common.CtxKeyClaims{}).(*common.AllClaims); ok && claims.Subject != "" {
+		return "subject:" + claims.Subject
+	}
+
+	return "ip:" + clientIP(r)
+}
@@ -0,0 +1,223 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter(config.RateLimit{RequestsPerMinute: 6, Burst: 2})
+	l.now = func() time.Time { return now }
+
+	allowed, _ := l.allow("a")
+	require.True(t, allowed)
+	allowed, _ = l.allow("a")
+	require.True(t, allowed)
+
+	allowed, retryAfter := l.allow("a")
+	require.False(t, allowed)
+	require.Equal(t, 10*time.Second, retryAfter)
+
+	// other keys have their own bucket
+	allowed, _ = l.allow("b")
+	require.True(t, allowed)
+
+	now = now.Add(10 * time.Second)
+	allowed, _ = l.allow("a")
+	require.True(t, allowed)
+	allowed, _ = l.allow("a")
+	require.False(t, allowed)
+}
+
+func TestRateLimiterCleanup(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter(config.RateLimit{RequestsPerMinute: 60})
+	l.now = func() time.Time { return now }
+
+	l.allow("a")
+	l.allow("b")
+	require.Len(t, l.buckets, 2)
+
+	now = now.Add(2 * time.Minute)
+	l.allow("c")
+	require.Len(t, l.buckets, 1)
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	conf := &config.SecurityConfig{
+		RateLimit: config.RateLimitConfig{
+			Routes: []config.RouteRateLimitConfig{
+				{
+					Method:    http.MethodPost,
+					Path:      "/api/rest/v1/transactions",
+					RateLimit: config.RateLimit{RequestsPerMinute: 1},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		ctx              context.Context
+		apiTokenLimit    int
+		expectedStatuses []int
+	}{
+		{
+			name:             "should limit logged in users",
+			method:           http.MethodPost,
+			path:             "/api/rest/v1/transactions",
+			ctx:              tstClaimsCtx("1234567890"),
+			expectedStatuses: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:             "should limit requests without a token",
+			method:           http.MethodPost,
+			path:             "/api/rest/v1/transactions",
+			ctx:              context.Background(),
+			expectedStatuses: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name:             "should not limit other routes",
+			method:           http.MethodGet,
+			path:             "/api/rest/v1/transactions",
+			ctx:              tstClaimsCtx("1234567890"),
+			expectedStatuses: []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name:             "should not limit the api token by default",
+			method:           http.MethodPost,
+			path:             "/api/rest/v1/transactions",
+			ctx:              context.WithValue(context.Background(), common.CtxKeyAPIKey{}, valid_api_token),
+			expectedStatuses: []int{http.StatusOK, http.StatusOK, http.StatusOK},
+		},
+		{
+			name:             "should limit the api token with its own budget",
+			method:           http.MethodPost,
+			path:             "/api/rest/v1/transactions",
+			ctx:              context.WithValue(context.Background(), common.CtxKeyAPIKey{}, valid_api_token),
+			apiTokenLimit:    1,
+			expectedStatuses: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := *conf
+			c.RateLimit.ApiToken = config.RateLimit{RequestsPerMinute: tt.apiTokenLimit}
+
+			handler := RateLimitMiddleware(&c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			for _, expected := range tt.expectedStatuses {
+				r := httptest.NewRequest(tt.method, tt.path, nil).WithContext(tt.ctx)
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				require.Equal(t, expected, w.Code)
+				if expected == http.StatusTooManyRequests {
+					require.Equal(t, "60", w.Header().Get("Retry-After"))
+					require.Contains(t, w.Body.String(), string(common.RequestRateLimitedMessage))
+				}
+			}
+		})
+	}
+}
+
+func TestRateLimitMiddlewareKeysBySubject(t *testing.T) {
+	conf := &config.SecurityConfig{
+		RateLimit: config.RateLimitConfig{
+			Routes: []config.RouteRateLimitConfig{
+				{
+					Method:    http.MethodPost,
+					Path:      "/api/rest/v1/transactions",
+					RateLimit: config.RateLimit{RequestsPerMinute: 1},
+				},
+			},
+		},
+	}
+
+	handler := RateLimitMiddleware(conf)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, subject := range []string{"1", "2"} {
+		r := httptest.NewRequest(http.MethodPost, "/api/rest/v1/transactions", nil).WithContext(tstClaimsCtx(subject))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+}
+
+func TestRateLimitMiddlewareRouteParameters(t *testing.T) {
+	conf := &config.SecurityConfig{
+		RateLimit: config.RateLimitConfig{
+			Routes: []config.RouteRateLimitConfig{
+				{
+					Method:    http.MethodGet,
+					Path:      "/api/rest/v1/transactions/{id}/receipt",
+					RateLimit: config.RateLimit{RequestsPerMinute: 1},
+				},
+				{
+					Method:    http.MethodPost,
+					Path:      "/api/rest/v1/transactions/{id}",
+					RateLimit: config.RateLimit{RequestsPerMinute: 1},
+				},
+				{
+					Method:    http.MethodPost,
+					Path:      "/api/rest/v1/transactions/initiate-payment",
+					RateLimit: config.RateLimit{RequestsPerMinute: 1, Burst: 2},
+				},
+			},
+		},
+	}
+
+	handler := RateLimitMiddleware(conf)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method   string
+		path     string
+		expected int
+	}{
+		// all values of the parameter share the budget
+		{method: http.MethodGet, path: "/api/rest/v1/transactions/EF2024-000010-0102-120000-0003/receipt", expected: http.StatusOK},
+		{method: http.MethodGet, path: "/api/rest/v1/transactions/EF2024-000010-0102-120000-0004/receipt", expected: http.StatusTooManyRequests},
+		// a parameter matches a single segment only
+		{method: http.MethodGet, path: "/api/rest/v1/transactions/EF2024-000010-0102-120000-0003", expected: http.StatusOK},
+		{method: http.MethodGet, path: "/api/rest/v1/transactions//receipt", expected: http.StatusOK},
+		{method: http.MethodGet, path: "/api/rest/v1/transactions/a/b/receipt", expected: http.StatusOK},
+		// fixed segments take precedence over parameters
+		{method: http.MethodPost, path: "/api/rest/v1/transactions/initiate-payment", expected: http.StatusOK},
+		{method: http.MethodPost, path: "/api/rest/v1/transactions/initiate-payment", expected: http.StatusOK},
+		{method: http.MethodPost, path: "/api/rest/v1/transactions/initiate-payment", expected: http.StatusTooManyRequests},
+		{method: http.MethodPost, path: "/api/rest/v1/transactions/initiate-group-payment", expected: http.StatusOK},
+		{method: http.MethodPost, path: "/api/rest/v1/transactions/initiate-group-payment", expected: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil).WithContext(tstClaimsCtx("1234567890"))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		require.Equal(t, tt.expected, w.Code, "%s %s", tt.method, tt.path)
+	}
+}
+
+func tstClaimsCtx(subject string) context.Context {
+	return context.WithValue(context.Background(), common.CtxKeyClaims{}, &common.AllClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+	})
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/go-http-utils/headers"
+
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+)
+
+const forwardedForHeader = "X-Forwarded-For"
+
+// RequestInfoMiddleware places the client address and the requested endpoint in the request context,
+// so they are available for the audit log. The Accept header is kept as well, so error responses can be
+// rendered in the format the client asked for.
+//
+// The X-Forwarded-For header is kept separately, because any client can set it.
+func RequestInfoMiddleware(next http.Handler) http.Handler {
+	handlerFunc := func(w http.ResponseWriter, r *http.Request) {
+		info := common.RequestInfo{
+			ClientIP:     clientIP(r),
+			ForwardedFor: r.Header.Get(forwardedForHeader),
+			Method:       r.Method,
+			Path:         r.URL.EscapedPath(),
+			Accept:       r.Header.Get(headers.Accept),
+		}
+
+		ctx := context.WithValue(r.Context(), common.CtxKeyRequestInfo{}, info)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+	return http.HandlerFunc(handlerFunc)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
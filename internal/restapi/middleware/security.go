@@ -175,8 +175,8 @@ func checkAllAuthentication(ctx context.Context, method string, urlPath string,
 	var success bool
 	var err error
 
-	// health check on / is allowed through
-	if method == http.MethodGet && urlPath == "/" {
+	// health checks are allowed through, so they can be used as probes
+	if method == http.MethodGet && (urlPath == "/" || urlPath == "/info/health/live" || urlPath == "/info/health/ready") {
 		return ctx, "", nil
 	}
 
@@ -235,7 +235,6 @@ func CheckRequestAuthorization(conf *config.SecurityConfig) func(http.Handler) h
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
-			reqID := logging.GetRequestID(ctx)
 			logger := logging.LoggerFromContext(ctx)
 
 			ctx = storeAdminRequestHeaderIfAvailable(ctx, r)
@@ -247,7 +246,7 @@ func CheckRequestAuthorization(conf *config.SecurityConfig) func(http.Handler) h
 			ctx, userFacingErrorMessage, err := checkAllAuthentication(ctx, r.Method, r.URL.Path, conf, apiTokenHeaderValue, authHeaderValue, idTokenCookieValue, accessTokenCookieValue)
 			if err != nil {
 				logger.Warn("authorization failed: %s: %s", userFacingErrorMessage, err.Error())
-				common.SendUnauthorizedResponse(w, reqID, logger, userFacingErrorMessage)
+				common.SendUnauthorizedResponse(ctx, w, logger, userFacingErrorMessage)
 				return
 			}
 
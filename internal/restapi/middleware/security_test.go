@@ -125,6 +125,15 @@ func TestHealthCheck(t *testing.T) {
 	require.Nil(t, ctx.Value(common.CtxKeyClaims{}))
 }
 
+func TestHealthProbes(t *testing.T) {
+	docs.Description("liveness and readiness probes are allowed through")
+	for _, path := range []string{"/info/health/live", "/info/health/ready"} {
+		ctx, actualMsg, actualErr := checkAllAuthentication(context.Background(), http.MethodGet, path, &securityConfig256, "", "", "", "")
+		tstRequire(t, actualMsg, actualErr, "", "")
+		require.Nil(t, ctx.Value(common.CtxKeyClaims{}))
+	}
+}
+
 func TestNothingProvided(t *testing.T) {
 	docs.Description("not providing any authorization fails for this service")
 	ctx := tstNothingTestCase(t, "you need to supply authorization", "no authorization presented")
@@ -0,0 +1,133 @@
+package v1auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+)
+
+func Create(router chi.Router, i interaction.Interactor) {
+	router.Get("/audit-log",
+		common.CreateHandler(
+			MakeGetAuditLogEndpoint(i),
+			getAuditLogRequestHandler,
+			getAuditLogResponseHandler),
+	)
+}
+
+func MakeGetAuditLogEndpoint(i interaction.Interactor) common.Endpoint[GetAuditLogRequest, GetAuditLogResponse] {
+	return func(ctx context.Context, request *GetAuditLogRequest, logger logging.Logger) (*GetAuditLogResponse, error) {
+		entries, err := i.GetAuditLog(ctx, entities.AuditLogQuery{
+			Actor:                 request.Actor,
+			DebitorID:             request.DebitorID,
+			TransactionIdentifier: request.TransactionIdentifier,
+			Outcome:               request.Outcome,
+			CreatedFrom:           request.CreatedFrom,
+			CreatedBefore:         request.CreatedBefore,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		response := GetAuditLogResponse{Payload: make([]AuditLogEntry, len(entries))}
+		for i, e := range entries {
+			response.Payload[i] = ToV1AuditLogEntry(e)
+		}
+		return &response, nil
+	}
+}
+
+func getAuditLogRequestHandler(r *http.Request) (*GetAuditLogRequest, error) {
+	query := r.URL.Query()
+	req := GetAuditLogRequest{
+		Actor:                 query.Get("actor"),
+		TransactionIdentifier: query.Get("transaction_identifier"),
+		Outcome:               entities.AuditOutcome(query.Get("outcome")),
+	}
+
+	fields := url.Values{}
+	if debIDStr := query.Get("debitor_id"); debIDStr != "" {
+		debID, err := strconv.ParseInt(debIDStr, 10, 64)
+		if err != nil {
+			fields.Add("debitor_id", "must be an integer")
+		}
+		req.DebitorID = debID
+	}
+
+	switch req.Outcome {
+	case "", entities.AuditOutcomeSuccess, entities.AuditOutcomeForbidden, entities.AuditOutcomeFailed:
+	default:
+		fields.Add("outcome", fmt.Sprintf("must be one of %s, %s, %s", entities.AuditOutcomeSuccess, entities.AuditOutcomeForbidden, entities.AuditOutcomeFailed))
+	}
+
+	var err error
+	if req.CreatedFrom, err = parseTimestamp(query.Get("created_from")); err != nil {
+		fields.Add("created_from", "must be a date in the format YYYY-MM-DD or an RFC 3339 timestamp")
+	}
+	if req.CreatedBefore, err = parseTimestamp(query.Get("created_before")); err != nil {
+		fields.Add("created_before", "must be a date in the format YYYY-MM-DD or an RFC 3339 timestamp")
+	}
+
+	if len(fields) > 0 {
+		return nil, apierrors.NewUnprocessableEntity(fields)
+	}
+
+	return &req, nil
+}
+
+func getAuditLogResponseHandler(ctx context.Context, res *GetAuditLogResponse, w http.ResponseWriter) error {
+	return json.NewEncoder(w).Encode(res)
+}
+
+func ToV1AuditLogEntry(e entities.AuditLogEntry) AuditLogEntry {
+	result := AuditLogEntry{
+		ID:                    e.ID,
+		Timestamp:             e.CreatedAt,
+		RequestID:             e.RequestID,
+		Actor:                 e.Actor,
+		Role:                  e.Role,
+		ClientIP:              e.ClientIP,
+		ForwardedFor:          e.ForwardedFor,
+		Endpoint:              e.Endpoint,
+		Action:                e.Action,
+		DebitorID:             e.DebitorID,
+		TransactionIdentifier: e.TransactionID,
+		Outcome:               e.Outcome,
+		Details:               e.Details,
+		PrevHash:              e.PrevHash,
+		Hash:                  e.Hash,
+	}
+
+	if e.Diff != "" {
+		result.Diff = json.RawMessage(e.Diff)
+	}
+
+	return result
+}
+
+// parseTimestamp accepts either a date (yyyy-mm-dd) or a full RFC 3339 timestamp.
+//
+// If `value` is empty, we will return a zero time instead
+func parseTimestamp(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if parsed, err := time.Parse("2006-01-02", value); err == nil {
+		return parsed, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
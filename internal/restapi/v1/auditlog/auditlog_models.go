@@ -0,0 +1,46 @@
+package v1auditlog
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+)
+
+type (
+	// GetAuditLogRequest contains the filter criteria for reading the audit log
+	GetAuditLogRequest struct {
+		Actor                 string
+		DebitorID             int64
+		TransactionIdentifier string
+		Outcome               entities.AuditOutcome
+		// filter by creation time (inclusive) lower bound
+		CreatedFrom time.Time
+		// filter by creation time (exclusive) upper bound
+		CreatedBefore time.Time
+	}
+
+	// GetAuditLogResponse contains the matching audit log entries, oldest first
+	GetAuditLogResponse struct {
+		Payload []AuditLogEntry `json:"payload"`
+	}
+)
+
+type AuditLogEntry struct {
+	ID                    uint                  `json:"id"`
+	Timestamp             time.Time             `json:"timestamp"`
+	RequestID             string                `json:"request_id"`
+	Actor                 string                `json:"actor"`
+	Role                  string                `json:"role"`
+	ClientIP              string                `json:"client_ip"`
+	ForwardedFor          string                `json:"forwarded_for,omitempty"`
+	Endpoint              string                `json:"endpoint"`
+	Action                string                `json:"action"`
+	DebitorID             int64                 `json:"debitor_id,omitempty"`
+	TransactionIdentifier string                `json:"transaction_identifier,omitempty"`
+	Diff                  json.RawMessage       `json:"diff,omitempty"`
+	Outcome               entities.AuditOutcome `json:"outcome"`
+	Details               string                `json:"details,omitempty"`
+	PrevHash              string                `json:"prev_hash"`
+	Hash                  string                `json:"hash"`
+}
@@ -0,0 +1,87 @@
+package v1documents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-http-utils/headers"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/documents"
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/media"
+)
+
+func Create(router chi.Router, i interaction.Interactor) {
+	router.Get("/transactions/{id}/receipt",
+		common.CreateHandler(
+			MakeGetReceiptEndpoint(i),
+			getReceiptRequestHandler,
+			documentResponseHandler),
+	)
+
+	router.Get("/debitors/{debitor_id}/invoice",
+		common.CreateHandler(
+			MakeGetInvoiceEndpoint(i),
+			getInvoiceRequestHandler,
+			documentResponseHandler),
+	)
+}
+
+func MakeGetReceiptEndpoint(i interaction.Interactor) common.Endpoint[GetReceiptRequest, DocumentResponse] {
+	return func(ctx context.Context, request *GetReceiptRequest, logger logging.Logger) (*DocumentResponse, error) {
+		doc, err := i.GetReceipt(ctx, request.TransactionID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &DocumentResponse{Filename: documents.Filename(*doc), Content: documents.Render(*doc)}, nil
+	}
+}
+
+func MakeGetInvoiceEndpoint(i interaction.Interactor) common.Endpoint[GetInvoiceRequest, DocumentResponse] {
+	return func(ctx context.Context, request *GetInvoiceRequest, logger logging.Logger) (*DocumentResponse, error) {
+		doc, err := i.GetInvoice(ctx, request.DebitorID, request.Event)
+		if err != nil {
+			return nil, err
+		}
+
+		return &DocumentResponse{Filename: documents.Filename(*doc), Content: documents.Render(*doc)}, nil
+	}
+}
+
+func getReceiptRequestHandler(r *http.Request) (*GetReceiptRequest, error) {
+	transactionID := chi.URLParam(r, "id")
+	if transactionID == "" {
+		return nil, apierrors.NewBadRequest("expected transaction id in url parameter, but received empty value")
+	}
+
+	return &GetReceiptRequest{TransactionID: transactionID}, nil
+}
+
+func getInvoiceRequestHandler(r *http.Request) (*GetInvoiceRequest, error) {
+	debitorID, err := strconv.ParseInt(chi.URLParam(r, "debitor_id"), 10, 64)
+	if err != nil || debitorID <= 0 {
+		return nil, apierrors.NewUnprocessableEntity(url.Values{"debitor_id": {"must be an integer greater than zero"}})
+	}
+
+	return &GetInvoiceRequest{DebitorID: debitorID, Event: r.URL.Query().Get("event")}, nil
+}
+
+func documentResponseHandler(ctx context.Context, res *DocumentResponse, w http.ResponseWriter) error {
+	if res == nil {
+		return errors.New("invalid response - cannot provide document")
+	}
+
+	w.Header().Set(headers.ContentType, media.ContentTypeApplicationPdf)
+	w.Header().Set(headers.ContentDisposition, fmt.Sprintf(`inline; filename="%s"`, res.Filename))
+	_, err := w.Write(res.Content)
+	return err
+}
@@ -0,0 +1,20 @@
+package v1documents
+
+type (
+	// GetReceiptRequest identifies the payment a receipt is requested for
+	GetReceiptRequest struct {
+		TransactionID string
+	}
+
+	// GetInvoiceRequest identifies the debitor and event an invoice is requested for
+	GetInvoiceRequest struct {
+		DebitorID int64
+		Event     string // the current event if empty
+	}
+
+	// DocumentResponse is a rendered invoice or receipt
+	DocumentResponse struct {
+		Filename string
+		Content  []byte
+	}
+)
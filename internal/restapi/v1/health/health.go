@@ -9,15 +9,20 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-http-utils/headers"
 
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
 	"github.com/eurofurence/reg-payment-service/internal/logging"
 	"github.com/eurofurence/reg-payment-service/internal/restapi/media"
 )
 
-func Create(server chi.Router) {
+// Create adds the health checks. Once shuttingDown returns true, the readiness check fails.
+func Create(server chi.Router, i interaction.Interactor, shuttingDown func() bool) {
 	server.Get("/info/health", healthGet)
+	server.Get("/info/health/live", healthGet)
+	server.Get("/info/health/ready", readyGet(i, shuttingDown))
 	server.Get("/", healthGet)
 }
 
+// healthGet is the liveness check. It does not check any dependencies, a restart would not fix them.
 func healthGet(w http.ResponseWriter, r *http.Request) {
 	dto := HealthResultDto{Status: "up"}
 
@@ -26,6 +31,47 @@ func healthGet(w http.ResponseWriter, r *http.Request) {
 	writeJson(r.Context(), w, dto)
 }
 
+// readyGet is the readiness check. It responds with status 503 if a required dependency is down,
+// so no traffic is routed to this instance.
+func readyGet(i interaction.Interactor, shuttingDown func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if shuttingDown() {
+			w.Header().Add(headers.ContentType, media.ContentTypeApplicationJson)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			writeJson(r.Context(), w, HealthResultDto{
+				Status: string(interaction.HealthDown),
+				Components: map[string]ComponentHealthDto{
+					"service": {Status: string(interaction.HealthDown), Details: "shutting down"},
+				},
+			})
+			return
+		}
+
+		report := i.CheckReadiness(r.Context())
+
+		dto := HealthResultDto{
+			Status:     string(report.Status),
+			Components: make(map[string]ComponentHealthDto),
+		}
+		for _, c := range report.Components {
+			dto.Components[c.Name] = ComponentHealthDto{
+				Status:   string(c.Status),
+				Optional: c.Optional,
+				Details:  c.Details,
+			}
+		}
+
+		status := http.StatusOK
+		if report.Status == interaction.HealthDown {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Add(headers.ContentType, media.ContentTypeApplicationJson)
+		w.WriteHeader(status)
+		writeJson(r.Context(), w, dto)
+	}
+}
+
 func writeJson(ctx context.Context, w http.ResponseWriter, v interface{}) {
 	encoder := json.NewEncoder(w)
 	encoder.SetEscapeHTML(false)
@@ -3,5 +3,12 @@
 package v1health
 
 type HealthResultDto struct {
-	Status string `json:"status"`
+	Status     string                        `json:"status"`
+	Components map[string]ComponentHealthDto `json:"components,omitempty"`
+}
+
+type ComponentHealthDto struct {
+	Status   string `json:"status"`
+	Optional bool   `json:"optional,omitempty"`
+	Details  string `json:"details,omitempty"`
 }
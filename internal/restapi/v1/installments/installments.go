@@ -0,0 +1,182 @@
+package v1installments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+)
+
+const isoDateFormat = "2006-01-02"
+
+func Create(router chi.Router, i interaction.Interactor) {
+	router.Get("/debitors/{debitor_id}/installment-plan",
+		common.CreateHandler(
+			MakeGetInstallmentPlanEndpoint(i),
+			getInstallmentPlanRequestHandler,
+			getInstallmentPlanResponseHandler),
+	)
+
+	router.Put("/debitors/{debitor_id}/installment-plan",
+		common.CreateHandler(
+			MakeAttachInstallmentPlanEndpoint(i),
+			attachInstallmentPlanRequestHandler,
+			attachInstallmentPlanResponseHandler),
+	)
+
+	router.Delete("/debitors/{debitor_id}/installment-plan",
+		common.CreateHandler(
+			MakeRemoveInstallmentPlanEndpoint(i),
+			removeInstallmentPlanRequestHandler,
+			removeInstallmentPlanResponseHandler),
+	)
+}
+
+func MakeGetInstallmentPlanEndpoint(i interaction.Interactor) common.Endpoint[GetInstallmentPlanRequest, GetInstallmentPlanResponse] {
+	return func(ctx context.Context, request *GetInstallmentPlanRequest, logger logging.Logger) (*GetInstallmentPlanResponse, error) {
+		schedule, err := i.GetInstallmentPlan(ctx, request.DebitorID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &GetInstallmentPlanResponse{InstallmentPlan: ToV1InstallmentPlan(*schedule)}, nil
+	}
+}
+
+func MakeAttachInstallmentPlanEndpoint(i interaction.Interactor) common.Endpoint[AttachInstallmentPlanRequest, AttachInstallmentPlanResponse] {
+	return func(ctx context.Context, request *AttachInstallmentPlanRequest, logger logging.Logger) (*AttachInstallmentPlanResponse, error) {
+		// validated by the request handler
+		firstDueDate, _ := parseDate(request.Body.FirstDueDate)
+
+		schedule, err := i.AttachInstallmentPlan(ctx, request.DebitorID, request.Body.Plan, firstDueDate)
+		if err != nil {
+			return nil, err
+		}
+
+		return &AttachInstallmentPlanResponse{InstallmentPlan: ToV1InstallmentPlan(*schedule)}, nil
+	}
+}
+
+func MakeRemoveInstallmentPlanEndpoint(i interaction.Interactor) common.Endpoint[RemoveInstallmentPlanRequest, RemoveInstallmentPlanResponse] {
+	return func(ctx context.Context, request *RemoveInstallmentPlanRequest, logger logging.Logger) (*RemoveInstallmentPlanResponse, error) {
+		return nil, i.RemoveInstallmentPlan(ctx, request.DebitorID)
+	}
+}
+
+func ToV1InstallmentPlan(schedule interaction.InstallmentSchedule) InstallmentPlan {
+	result := InstallmentPlan{
+		DebitorID:    schedule.DebitorID,
+		Event:        schedule.Event,
+		Plan:         schedule.Plan,
+		DuesCent:     schedule.DuesCent,
+		PaymentsCent: schedule.PaymentsCent,
+		Installments: make([]Installment, len(schedule.Installments)),
+	}
+
+	for i, inst := range schedule.Installments {
+		result.Installments[i] = Installment{
+			Number:    inst.Number,
+			DueDate:   inst.DueDate.Format(isoDateFormat),
+			GrossCent: inst.AmountCent,
+			OpenCent:  inst.OpenCent,
+		}
+	}
+
+	if schedule.Next != nil {
+		result.NextInstallment = &NextInstallment{
+			Number:    schedule.Next.Number,
+			DueDate:   schedule.Next.DueDate.Format(isoDateFormat),
+			GrossCent: schedule.NextAmountCent,
+		}
+	}
+
+	return result
+}
+
+func debitorIDFromURL(r *http.Request) (int64, error) {
+	debitorID, err := strconv.ParseInt(chi.URLParam(r, "debitor_id"), 10, 64)
+	if err != nil || debitorID <= 0 {
+		return 0, apierrors.NewUnprocessableEntity(url.Values{"debitor_id": {"must be an integer greater than zero"}})
+	}
+	return debitorID, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(isoDateFormat, value)
+}
+
+func getInstallmentPlanRequestHandler(r *http.Request) (*GetInstallmentPlanRequest, error) {
+	debitorID, err := debitorIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetInstallmentPlanRequest{DebitorID: debitorID}, nil
+}
+
+func getInstallmentPlanResponseHandler(ctx context.Context, res *GetInstallmentPlanResponse, w http.ResponseWriter) error {
+	if res == nil {
+		return errors.New("invalid response - cannot provide installment plan information")
+	}
+	return json.NewEncoder(w).Encode(res)
+}
+
+func attachInstallmentPlanRequestHandler(r *http.Request) (*AttachInstallmentPlanRequest, error) {
+	debitorID, err := debitorIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	request := AttachInstallmentPlanRequest{DebitorID: debitorID}
+	if err := json.NewDecoder(r.Body).Decode(&request.Body); err != nil {
+		return nil, common.BodyParseError(err)
+	}
+
+	fields := url.Values{}
+	if request.Body.Plan == "" {
+		fields.Add("plan", "must not be empty")
+	}
+	if _, err := parseDate(request.Body.FirstDueDate); err != nil {
+		fields.Add("first_due_date", "must be a date in the format YYYY-MM-DD")
+	}
+
+	if len(fields) > 0 {
+		return nil, apierrors.NewUnprocessableEntity(fields)
+	}
+
+	return &request, nil
+}
+
+func attachInstallmentPlanResponseHandler(ctx context.Context, res *AttachInstallmentPlanResponse, w http.ResponseWriter) error {
+	if res == nil {
+		return errors.New("invalid response - cannot provide installment plan information")
+	}
+	return json.NewEncoder(w).Encode(res)
+}
+
+func removeInstallmentPlanRequestHandler(r *http.Request) (*RemoveInstallmentPlanRequest, error) {
+	debitorID, err := debitorIDFromURL(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RemoveInstallmentPlanRequest{DebitorID: debitorID}, nil
+}
+
+func removeInstallmentPlanResponseHandler(ctx context.Context, _ *RemoveInstallmentPlanResponse, w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
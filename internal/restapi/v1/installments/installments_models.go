@@ -0,0 +1,63 @@
+package v1installments
+
+type (
+	// GetInstallmentPlanRequest identifies the debitor whose plan for the current event is requested
+	GetInstallmentPlanRequest struct {
+		DebitorID int64
+	}
+
+	// GetInstallmentPlanResponse is the plan with the amounts worked out from the current dues
+	GetInstallmentPlanResponse struct {
+		InstallmentPlan
+	}
+
+	// AttachInstallmentPlanRequest attaches a configured plan to the debitor for the current event
+	AttachInstallmentPlanRequest struct {
+		DebitorID int64
+		Body      AttachInstallmentPlan
+	}
+
+	// AttachInstallmentPlanResponse is the newly attached plan
+	AttachInstallmentPlanResponse struct {
+		InstallmentPlan
+	}
+
+	// RemoveInstallmentPlanRequest identifies the debitor whose plan for the current event is removed
+	RemoveInstallmentPlanRequest struct {
+		DebitorID int64
+	}
+
+	// RemoveInstallmentPlanResponse is an empty response as this endpoint yields no response
+	RemoveInstallmentPlanResponse struct{}
+)
+
+type AttachInstallmentPlan struct {
+	Plan string `json:"plan"`
+	// FirstDueDate is optional and defaults to today
+	FirstDueDate string `json:"first_due_date"`
+}
+
+type InstallmentPlan struct {
+	DebitorID    int64         `json:"debitor_id"`
+	Event        string        `json:"event"`
+	Plan         string        `json:"plan"`
+	DuesCent     int64         `json:"dues_cent"`
+	PaymentsCent int64         `json:"payments_cent"`
+	Installments []Installment `json:"installments"`
+	// NextInstallment is omitted once the dues are paid
+	NextInstallment *NextInstallment `json:"next_installment,omitempty"`
+}
+
+type Installment struct {
+	Number    int    `json:"number"`
+	DueDate   string `json:"due_date"`
+	GrossCent int64  `json:"gross_cent"`
+	OpenCent  int64  `json:"open_cent"`
+}
+
+type NextInstallment struct {
+	Number  int    `json:"number"`
+	DueDate string `json:"due_date"`
+	// GrossCent is the amount a paylink for the next installment is created for
+	GrossCent int64 `json:"gross_cent"`
+}
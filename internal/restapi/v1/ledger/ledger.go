@@ -0,0 +1,58 @@
+package v1ledger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+)
+
+const signatureAlgorithm = "HMAC-SHA256"
+
+func Create(router chi.Router, i interaction.Interactor) {
+	router.Get("/ledger/checkpoint",
+		common.CreateHandler(
+			MakeGetCheckpointEndpoint(i),
+			getCheckpointRequestHandler,
+			getCheckpointResponseHandler),
+	)
+}
+
+func MakeGetCheckpointEndpoint(i interaction.Interactor) common.Endpoint[GetCheckpointRequest, GetCheckpointResponse] {
+	return func(ctx context.Context, request *GetCheckpointRequest, logger logging.Logger) (*GetCheckpointResponse, error) {
+		checkpoint, err := i.GetLedgerCheckpoint(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		response := GetCheckpointResponse{
+			Timestamp: checkpoint.CreatedAt,
+			Chains:    make([]ChainHead, len(checkpoint.Heads)),
+			Digest:    checkpoint.Digest,
+			Algorithm: signatureAlgorithm,
+			Signature: checkpoint.Signature,
+		}
+		for i, head := range checkpoint.Heads {
+			response.Chains[i] = ChainHead{
+				Chain:  head.ChainName,
+				Length: head.Length,
+				Hash:   head.Hash,
+			}
+		}
+
+		return &response, nil
+	}
+}
+
+func getCheckpointRequestHandler(r *http.Request) (*GetCheckpointRequest, error) {
+	return &GetCheckpointRequest{}, nil
+}
+
+func getCheckpointResponseHandler(ctx context.Context, res *GetCheckpointResponse, w http.ResponseWriter) error {
+	return json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,23 @@
+package v1ledger
+
+import "time"
+
+type (
+	// GetCheckpointRequest has no parameters, the checkpoint always covers all chains
+	GetCheckpointRequest struct{}
+
+	// GetCheckpointResponse is a signed snapshot of the heads of all hash chains
+	GetCheckpointResponse struct {
+		Timestamp time.Time   `json:"timestamp"`
+		Chains    []ChainHead `json:"chains"`
+		Digest    string      `json:"digest"`
+		Algorithm string      `json:"algorithm"`
+		Signature string      `json:"signature"`
+	}
+)
+
+type ChainHead struct {
+	Chain  string `json:"chain"`
+	Length int64  `json:"length"`
+	Hash   string `json:"hash"`
+}
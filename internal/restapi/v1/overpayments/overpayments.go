@@ -0,0 +1,139 @@
+package v1overpayments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+	v1transactions "github.com/eurofurence/reg-payment-service/internal/restapi/v1/transactions"
+)
+
+func Create(router chi.Router, i interaction.Interactor) {
+	router.Get("/overpayments",
+		common.CreateHandler(
+			MakeListOverpaymentsEndpoint(i),
+			listOverpaymentsRequestHandler,
+			listOverpaymentsResponseHandler),
+	)
+
+	router.Post("/overpayments/{debitor_id}/resolve",
+		common.CreateHandler(
+			MakeResolveOverpaymentEndpoint(i),
+			resolveOverpaymentRequestHandler,
+			resolveOverpaymentResponseHandler),
+	)
+}
+
+func MakeListOverpaymentsEndpoint(i interaction.Interactor) common.Endpoint[ListOverpaymentsRequest, ListOverpaymentsResponse] {
+	return func(ctx context.Context, request *ListOverpaymentsRequest, logger logging.Logger) (*ListOverpaymentsResponse, error) {
+		overpayments, err := i.ListOverpayments(ctx, request.Event)
+		if err != nil {
+			return nil, err
+		}
+
+		response := ListOverpaymentsResponse{Payload: make([]Overpayment, len(overpayments))}
+		for i, o := range overpayments {
+			response.Payload[i] = Overpayment{
+				DebitorID:    o.DebitorID,
+				Event:        o.Event,
+				DuesCent:     o.DuesCent,
+				PaymentsCent: o.PaymentsCent,
+				CreditCent:   o.CreditCent,
+			}
+		}
+		return &response, nil
+	}
+}
+
+func MakeResolveOverpaymentEndpoint(i interaction.Interactor) common.Endpoint[ResolveOverpaymentRequest, ResolveOverpaymentResponse] {
+	return func(ctx context.Context, request *ResolveOverpaymentRequest, logger logging.Logger) (*ResolveOverpaymentResponse, error) {
+		body := request.Body
+		logger.Debug("resolving overpayment of debitor %d with %s", request.DebitorID, body.Action)
+
+		booked, err := i.ResolveOverpayment(ctx, request.DebitorID, interaction.OverpaymentResolution{
+			Action:      interaction.OverpaymentAction(body.Action),
+			Event:       body.Event,
+			AmountCent:  body.GrossCent,
+			Method:      body.Method,
+			TargetEvent: body.TargetEvent,
+			Comment:     body.Comment,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		response := ResolveOverpaymentResponse{Transactions: make([]v1transactions.Transaction, len(booked))}
+		for i, tran := range booked {
+			response.Transactions[i] = v1transactions.ToV1Transaction(tran)
+		}
+		return &response, nil
+	}
+}
+
+func listOverpaymentsRequestHandler(r *http.Request) (*ListOverpaymentsRequest, error) {
+	return &ListOverpaymentsRequest{Event: r.URL.Query().Get("event")}, nil
+}
+
+func listOverpaymentsResponseHandler(ctx context.Context, res *ListOverpaymentsResponse, w http.ResponseWriter) error {
+	if res == nil {
+		return errors.New("invalid response - cannot provide overpayment information")
+	}
+	return json.NewEncoder(w).Encode(res)
+}
+
+func resolveOverpaymentRequestHandler(r *http.Request) (*ResolveOverpaymentRequest, error) {
+	debitorID, err := strconv.ParseInt(chi.URLParam(r, "debitor_id"), 10, 64)
+	if err != nil || debitorID <= 0 {
+		return nil, apierrors.NewUnprocessableEntity(url.Values{"debitor_id": {"must be an integer greater than zero"}})
+	}
+
+	request := ResolveOverpaymentRequest{DebitorID: debitorID}
+	if err := json.NewDecoder(r.Body).Decode(&request.Body); err != nil {
+		return nil, common.BodyParseError(err)
+	}
+
+	fields := url.Values{}
+	switch interaction.OverpaymentAction(request.Body.Action) {
+	case interaction.OverpaymentActionRefund:
+		if request.Body.TargetEvent != "" {
+			fields.Add("target_event", "only allowed when carrying the credit forward")
+		}
+	case interaction.OverpaymentActionCarryForward:
+		if request.Body.Method != "" {
+			fields.Add("method", "only allowed for refunds")
+		}
+	default:
+		fields.Add("action", fmt.Sprintf("must be one of %s, %s", interaction.OverpaymentActionRefund, interaction.OverpaymentActionCarryForward))
+	}
+	if request.Body.GrossCent < 0 {
+		fields.Add("gross_cent", "must not be negative")
+	}
+	if request.Body.Method != "" && !request.Body.Method.IsValid() {
+		fields.Add("method", common.InvalidPaymentMethodMessage)
+	}
+
+	if len(fields) > 0 {
+		return nil, apierrors.NewUnprocessableEntity(fields)
+	}
+
+	return &request, nil
+}
+
+func resolveOverpaymentResponseHandler(ctx context.Context, res *ResolveOverpaymentResponse, w http.ResponseWriter) error {
+	if res == nil {
+		return errors.New("invalid response - cannot provide the booked transactions")
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,50 @@
+package v1overpayments
+
+import (
+	"github.com/eurofurence/reg-payment-service/internal/entities"
+	v1transactions "github.com/eurofurence/reg-payment-service/internal/restapi/v1/transactions"
+)
+
+type (
+	// ListOverpaymentsRequest selects the event to list overpayments for, the current one if empty
+	ListOverpaymentsRequest struct {
+		Event string
+	}
+
+	// ListOverpaymentsResponse lists all debitors whose payments exceed their dues
+	ListOverpaymentsResponse struct {
+		Payload []Overpayment `json:"payload"`
+	}
+
+	// ResolveOverpaymentRequest books a refund or carries the credit of the debitor forward
+	ResolveOverpaymentRequest struct {
+		DebitorID int64
+		Body      OverpaymentResolution
+	}
+
+	// ResolveOverpaymentResponse contains the transactions that were booked
+	ResolveOverpaymentResponse struct {
+		Transactions []v1transactions.Transaction `json:"transactions"`
+	}
+)
+
+type Overpayment struct {
+	DebitorID    int64  `json:"debitor_id"`
+	Event        string `json:"event"`
+	DuesCent     int64  `json:"dues_cent"`
+	PaymentsCent int64  `json:"payments_cent"`
+	CreditCent   int64  `json:"credit_cent"`
+}
+
+type OverpaymentResolution struct {
+	Action string `json:"action"`
+	// Event is the overpaid event, defaults to the current one
+	Event string `json:"event"`
+	// GrossCent defaults to the full credit
+	GrossCent int64 `json:"gross_cent"`
+	// Method is only used for refunds, and defaults to the method of the latest payment
+	Method entities.PaymentMethod `json:"method"`
+	// TargetEvent is only used when carrying forward, and defaults to the next configured event
+	TargetEvent string `json:"target_event"`
+	Comment     string `json:"comment"`
+}
@@ -0,0 +1,93 @@
+package v1sepaqr
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-http-utils/headers"
+
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/qrcode"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/media"
+)
+
+const (
+	formatSVG  = "svg"
+	formatPNG  = "png"
+	formatText = "text"
+
+	// moduleScale is the size of a module in pixels, large enough to scan the png off a screen
+	moduleScale = 8
+)
+
+func Create(router chi.Router, i interaction.Interactor) {
+	router.Get("/transactions/{id}/sepa-qr",
+		common.CreateHandler(
+			MakeGetSepaQREndpoint(i),
+			getSepaQRRequestHandler,
+			sepaQRResponseHandler),
+	)
+}
+
+func MakeGetSepaQREndpoint(i interaction.Interactor) common.Endpoint[GetSepaQRRequest, SepaQRResponse] {
+	return func(ctx context.Context, request *GetSepaQRRequest, logger logging.Logger) (*SepaQRResponse, error) {
+		payload, err := i.GetSepaQRPayload(ctx, request.TransactionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if request.Format == formatText {
+			return &SepaQRResponse{ContentType: media.ContentTypeTextPlain, Content: []byte(payload)}, nil
+		}
+
+		code, err := qrcode.Encode([]byte(payload))
+		if err != nil {
+			logger.Error("could not encode sepa qr code for transaction %s. [error]: %v", request.TransactionID, err)
+			return nil, apierrors.NewInternalServerError("could not encode qr code - see log for details")
+		}
+
+		if request.Format == formatPNG {
+			content, err := code.PNG(moduleScale)
+			if err != nil {
+				return nil, err
+			}
+			return &SepaQRResponse{ContentType: media.ContentTypeImagePng, Content: content}, nil
+		}
+
+		return &SepaQRResponse{ContentType: media.ContentTypeImageSvg, Content: code.SVG(moduleScale)}, nil
+	}
+}
+
+func getSepaQRRequestHandler(r *http.Request) (*GetSepaQRRequest, error) {
+	transactionID := chi.URLParam(r, "id")
+	if transactionID == "" {
+		return nil, apierrors.NewBadRequest("expected transaction id in url parameter, but received empty value")
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "":
+		format = formatSVG
+	case formatSVG, formatPNG, formatText:
+	default:
+		return nil, apierrors.NewUnprocessableEntity(url.Values{"format": {"must be one of svg, png or text"}})
+	}
+
+	return &GetSepaQRRequest{TransactionID: transactionID, Format: format}, nil
+}
+
+func sepaQRResponseHandler(ctx context.Context, res *SepaQRResponse, w http.ResponseWriter) error {
+	if res == nil {
+		return errors.New("invalid response - cannot provide qr code")
+	}
+
+	w.Header().Set(headers.ContentType, res.ContentType)
+	_, err := w.Write(res.Content)
+	return err
+}
@@ -0,0 +1,15 @@
+package v1sepaqr
+
+type (
+	// GetSepaQRRequest identifies the sepa transfer a qr code is requested for
+	GetSepaQRRequest struct {
+		TransactionID string
+		Format        string // svg, png or text
+	}
+
+	// SepaQRResponse is the qr code in the requested format
+	SepaQRResponse struct {
+		ContentType string
+		Content     []byte
+	}
+)
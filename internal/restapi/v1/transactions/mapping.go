@@ -11,6 +11,8 @@ func ToV1Transaction(tran entities.Transaction) Transaction {
 	result := Transaction{
 		DebitorID:             tran.DebitorID,
 		TransactionIdentifier: tran.TransactionID,
+		CreditorReference:     tran.CreditorReference.String,
+		Event:                 tran.Event,
 		TransactionType:       tran.TransactionType,
 		Method:                tran.PaymentMethod,
 		Amount: Amount{
@@ -20,10 +22,35 @@ func ToV1Transaction(tran entities.Transaction) Transaction {
 		},
 		Comment:         tran.Comment,
 		Status:          tran.TransactionStatus,
-		Info:            make(map[string]interface{}), // TODO (no field)
+		Info:            make(map[string]interface{}),
 		PaymentStartUrl: tran.PaymentStartUrl,
 		EffectiveDate:   tran.EffectiveDate.Time.Format("2006-01-02"),
 		Reason:          tran.Reason,
+		GroupReference:  tran.GroupReference,
+	}
+
+	for k, v := range tran.ProcessorInfo {
+		result.Info[k] = v
+	}
+
+	for _, l := range tran.LineItems {
+		result.LineItems = append(result.LineItems, LineItem{
+			Description: l.Description,
+			Quantity:    l.Quantity,
+			UnitCent:    l.UnitCent,
+			VatRate:     l.VatRate,
+		})
+	}
+
+	if tran.TransactionType == entities.TransactionTypeDue {
+		for _, v := range tran.VatTotals() {
+			result.VatTotals = append(result.VatTotals, VatTotal{
+				VatRate:   v.VatRate,
+				GrossCent: v.GrossCent,
+				NetCent:   v.NetCent,
+				VatCent:   v.VatCent,
+			})
+		}
 	}
 
 	if !tran.CreatedAt.IsZero() {
@@ -38,6 +65,32 @@ func ToV1Transaction(tran entities.Transaction) Transaction {
 
 }
 
+// ToV1StatusHistory lists the status changes found in the transaction log, oldest first.
+//
+// Log entries that did not change the status are skipped.
+func ToV1StatusHistory(logs []entities.TransactionLog) []StatusHistory {
+	result := make([]StatusHistory, 0)
+	for _, tl := range logs {
+		if len(result) > 0 && result[len(result)-1].Status == tl.TransactionStatus {
+			continue
+		}
+
+		entry := StatusHistory{
+			Status:     tl.TransactionStatus,
+			Comment:    tl.Comment,
+			ChangeDate: tl.CreatedAt,
+		}
+		if tl.TransactionStatus == entities.TransactionStatusDeleted {
+			entry.Comment = tl.Deletion.Comment
+			entry.ChangedBy = tl.Deletion.By
+		}
+
+		result = append(result, entry)
+	}
+
+	return result
+}
+
 func ToTransactionEntity(tr Transaction) (*entities.Transaction, error) {
 	effDate, err := parseEffectiveDate(tr.EffectiveDate)
 	if err != nil {
@@ -47,6 +100,7 @@ func ToTransactionEntity(tr Transaction) (*entities.Transaction, error) {
 	tran := &entities.Transaction{
 		DebitorID:         tr.DebitorID,
 		TransactionID:     tr.TransactionIdentifier,
+		Event:             tr.Event,
 		TransactionType:   tr.TransactionType,
 		PaymentMethod:     tr.Method,
 		PaymentStartUrl:   tr.PaymentStartUrl,
@@ -64,6 +118,20 @@ func ToTransactionEntity(tr Transaction) (*entities.Transaction, error) {
 		Reason: tr.Reason,
 	}
 
+	if len(tr.Info) > 0 {
+		tran.ProcessorInfo = entities.PaymentProcessorInformation(tr.Info)
+	}
+
+	for i, l := range tr.LineItems {
+		tran.LineItems = append(tran.LineItems, entities.LineItem{
+			Position:    i + 1,
+			Description: l.Description,
+			Quantity:    l.Quantity,
+			UnitCent:    l.UnitCent,
+			VatRate:     l.VatRate,
+		})
+	}
+
 	if tr.DueDate != "" {
 		dueDate, err := parseEffectiveDate(tr.DueDate)
 		if err != nil {
@@ -33,6 +33,10 @@ type (
 		DebitorID int64
 		// filter by transaction_identifier
 		TransactionIdentifier string
+		// filter by the event the transactions belong to
+		Event string
+		// filter by the group reference of a group payment
+		GroupReference string
 		// filter by effective date (inclusive) lower bound
 		EffectiveFrom time.Time
 		// filter by effective date (exclusive) upper bound - this makes it easy to get everything in a given month
@@ -45,6 +49,20 @@ type (
 		Payload []Transaction `json:"payload"`
 	}
 
+	// GetTransactionRequest identifies a single transaction
+	GetTransactionRequest struct {
+		TransactionIdentifier string
+		// IfNoneMatch is the If-None-Match header, if the client already has a version of the transaction
+		IfNoneMatch string
+	}
+
+	// GetTransactionResponse contains a single transaction including its status history
+	GetTransactionResponse struct {
+		Transaction Transaction `json:"transaction"`
+
+		ifNoneMatch string
+	}
+
 	// CreateTrasactionRequest contains all information to create a new transaction for a given debitor
 	CreateTransactionRequest struct {
 		Transaction Transaction `json:"transaction"`
@@ -73,11 +91,45 @@ type (
 	InitiatePaymentResponse struct {
 		Transaction Transaction `json:"transaction"`
 	}
+
+	// InitiateGroupPaymentRequest is used to create a single paylink for the outstanding dues of several debitors.
+	InitiateGroupPaymentRequest struct {
+		GroupPaymentInitiator GroupPaymentInitiator
+	}
+
+	// InitiateGroupPaymentResponse contains the paylink and the payment transactions created for each debitor
+	InitiateGroupPaymentResponse struct {
+		GroupReference  string        `json:"group_reference"`
+		PaymentStartUrl string        `json:"payment_start_url"`
+		Transactions    []Transaction `json:"transactions"`
+	}
+
+	// UpdateGroupPaymentRequest changes the status of all payments of a group payment
+	UpdateGroupPaymentRequest struct {
+		GroupReference     string
+		GroupPaymentUpdate GroupPaymentUpdate
+	}
+
+	// UpdateGroupPaymentResponse is an empty response as this endpoint yields no response
+	UpdateGroupPaymentResponse struct{}
+
+	// TransferBalanceRequest moves credit from one debitor to another
+	TransferBalanceRequest struct {
+		BalanceTransfer BalanceTransfer
+	}
+
+	// TransferBalanceResponse contains the payments booked on the source and the target debitor
+	TransferBalanceResponse struct {
+		Reference    string        `json:"reference"`
+		Transactions []Transaction `json:"transactions"`
+	}
 )
 
 type Transaction struct {
 	DebitorID             int64                       `json:"debitor_id"`
 	TransactionIdentifier string                      `json:"transaction_identifier"`
+	CreditorReference     string                      `json:"creditor_reference,omitempty"` // read only
+	Event                 string                      `json:"event"`
 	TransactionType       entities.TransactionType    `json:"transaction_type"`
 	Method                entities.PaymentMethod      `json:"method"`
 	Amount                Amount                      `json:"amount"`
@@ -90,9 +142,44 @@ type Transaction struct {
 	CreationDate          *time.Time                  `json:"creation_date,omitempty"`
 	StatusHistory         []StatusHistory             `json:"status_history"`
 	Reason                string                      `json:"reason"`
+	GroupReference        string                      `json:"group_reference,omitempty"`
+	LineItems             []LineItem                  `json:"line_items,omitempty"`
+	VatTotals             []VatTotal                  `json:"vat_totals,omitempty"`
+}
+
+type LineItem struct {
+	Description string  `json:"description"`
+	Quantity    int64   `json:"quantity"`
+	UnitCent    int64   `json:"unit_cent"`
+	VatRate     float64 `json:"vat_rate"`
+}
+
+type VatTotal struct {
+	VatRate   float64 `json:"vat_rate"`
+	GrossCent int64   `json:"gross_cent"`
+	NetCent   int64   `json:"net_cent"`
+	VatCent   int64   `json:"vat_cent"`
 }
 
 type TransactionInitiator struct {
 	DebitorID int64                  `json:"debitor_id"`
 	Method    entities.PaymentMethod `json:"method"`
 }
+
+type GroupPaymentInitiator struct {
+	DebitorIDs []int64                `json:"debitor_ids"`
+	Method     entities.PaymentMethod `json:"method"`
+}
+
+type GroupPaymentUpdate struct {
+	Status  entities.TransactionStatus  `json:"status"`
+	Comment string                      `json:"comment"`
+	Info    PaymentProcessorInformation `json:"payment_processor_information"`
+}
+
+type BalanceTransfer struct {
+	SourceDebitorID int64  `json:"source_debitor_id"`
+	TargetDebitorID int64  `json:"target_debitor_id"`
+	GrossCent       int64  `json:"gross_cent"`
+	Comment         string `json:"comment"`
+}
@@ -2,17 +2,22 @@ package v1transactions
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-http-utils/headers"
 
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
 	"github.com/eurofurence/reg-payment-service/internal/entities"
 	"github.com/eurofurence/reg-payment-service/internal/interaction"
 	"github.com/eurofurence/reg-payment-service/internal/logging"
@@ -34,6 +39,13 @@ func Create(router chi.Router, i interaction.Interactor) {
 			createTransactionResponseHandler),
 	)
 
+	router.Get("/transactions/{id}",
+		common.CreateHandler(
+			MakeGetTransactionEndpoint(i),
+			getTransactionRequestHandler,
+			getTransactionResponseHandler),
+	)
+
 	router.Put("/transactions/{id}",
 		common.CreateHandler(
 			MakeUpdateTransactionEndpoint(i),
@@ -47,6 +59,27 @@ func Create(router chi.Router, i interaction.Interactor) {
 			initiatePaymentRequestHandler,
 			initiatePaymentResponseHandler,
 		))
+
+	router.Post("/transactions/initiate-group-payment",
+		common.CreateHandler(
+			MakeInitiateGroupPaymentEndpoint(i),
+			initiateGroupPaymentRequestHandler,
+			initiateGroupPaymentResponseHandler,
+		))
+
+	router.Put("/transactions/groups/{group_reference}",
+		common.CreateHandler(
+			MakeUpdateGroupPaymentEndpoint(i),
+			updateGroupPaymentRequestHandler,
+			updateGroupPaymentResponseHandler,
+		))
+
+	router.Post("/transactions/transfer-balance",
+		common.CreateHandler(
+			MakeTransferBalanceEndpoint(i),
+			transferBalanceRequestHandler,
+			transferBalanceResponseHandler,
+		))
 }
 
 func MakeGetTransactionsEndpoint(i interaction.Interactor) common.Endpoint[GetTransactionsRequest, GetTransactionsResponse] {
@@ -54,6 +87,8 @@ func MakeGetTransactionsEndpoint(i interaction.Interactor) common.Endpoint[GetTr
 		txList, err := i.GetTransactionsForDebitor(ctx, entities.TransactionQuery{
 			DebitorID:             request.DebitorID,
 			TransactionIdentifier: request.TransactionIdentifier,
+			Event:                 request.Event,
+			GroupReference:        request.GroupReference,
 			EffectiveFrom:         request.EffectiveFrom,
 			EffectiveBefore:       request.EffectiveBefore,
 		})
@@ -71,6 +106,20 @@ func MakeGetTransactionsEndpoint(i interaction.Interactor) common.Endpoint[GetTr
 	}
 }
 
+func MakeGetTransactionEndpoint(i interaction.Interactor) common.Endpoint[GetTransactionRequest, GetTransactionResponse] {
+	return func(ctx context.Context, request *GetTransactionRequest, logger logging.Logger) (*GetTransactionResponse, error) {
+		details, err := i.GetTransaction(ctx, request.TransactionIdentifier)
+		if err != nil {
+			return nil, err
+		}
+
+		tran := ToV1Transaction(details.Transaction)
+		tran.StatusHistory = ToV1StatusHistory(details.History)
+
+		return &GetTransactionResponse{Transaction: tran, ifNoneMatch: request.IfNoneMatch}, nil
+	}
+}
+
 func MakeCreateTransactionEndpoint(i interaction.Interactor) common.Endpoint[CreateTransactionRequest, CreateTransactionResponse] {
 	return func(ctx context.Context, request *CreateTransactionRequest, logger logging.Logger) (*CreateTransactionResponse, error) {
 
@@ -117,37 +166,90 @@ func MakeInitiatePaymentEndpoint(i interaction.Interactor) common.Endpoint[Initi
 	}
 }
 
+func MakeInitiateGroupPaymentEndpoint(i interaction.Interactor) common.Endpoint[InitiateGroupPaymentRequest, InitiateGroupPaymentResponse] {
+	return func(ctx context.Context, request *InitiateGroupPaymentRequest, logger logging.Logger) (*InitiateGroupPaymentResponse, error) {
+		logger.Debug("initiating group payment for debitors %v", request.GroupPaymentInitiator.DebitorIDs)
+		res, err := i.CreateGroupPaymentForOutstandingDues(ctx, request.GroupPaymentInitiator.DebitorIDs, request.GroupPaymentInitiator.Method)
+		if err != nil {
+			return nil, err
+		}
+
+		response := InitiateGroupPaymentResponse{
+			GroupReference:  res.GroupReference,
+			PaymentStartUrl: res.PaymentStartUrl,
+			Transactions:    make([]Transaction, len(res.Transactions)),
+		}
+		for i, tx := range res.Transactions {
+			response.Transactions[i] = ToV1Transaction(tx)
+		}
+		return &response, nil
+	}
+}
+
+func MakeUpdateGroupPaymentEndpoint(i interaction.Interactor) common.Endpoint[UpdateGroupPaymentRequest, UpdateGroupPaymentResponse] {
+	return func(ctx context.Context, request *UpdateGroupPaymentRequest, logger logging.Logger) (*UpdateGroupPaymentResponse, error) {
+		update := request.GroupPaymentUpdate
+
+		var info entities.PaymentProcessorInformation
+		if len(update.Info) > 0 {
+			info = entities.PaymentProcessorInformation(update.Info)
+		}
+
+		_, err := i.UpdateGroupPayment(ctx, request.GroupReference, update.Status, update.Comment, info)
+		return nil, err
+	}
+}
+
+func MakeTransferBalanceEndpoint(i interaction.Interactor) common.Endpoint[TransferBalanceRequest, TransferBalanceResponse] {
+	return func(ctx context.Context, request *TransferBalanceRequest, logger logging.Logger) (*TransferBalanceResponse, error) {
+		transfer := request.BalanceTransfer
+		logger.Debug("transferring %d from debitor %d to debitor %d", transfer.GrossCent, transfer.SourceDebitorID, transfer.TargetDebitorID)
+		res, err := i.TransferBalance(ctx, transfer.SourceDebitorID, transfer.TargetDebitorID, transfer.GrossCent, transfer.Comment)
+		if err != nil {
+			return nil, err
+		}
+
+		return &TransferBalanceResponse{
+			Reference:    res.Reference,
+			Transactions: []Transaction{ToV1Transaction(res.Source), ToV1Transaction(res.Target)},
+		}, nil
+	}
+}
+
 func getTransactionsRequestHandler(r *http.Request) (*GetTransactionsRequest, error) {
 	var req GetTransactionsRequest
 
 	// debID is not required, because accounting will want to list transactions for all debitors for a certain period
+	fields := url.Values{}
 	debIDStr := r.URL.Query().Get("debitor_id")
-	var debID int
-	var err error
 	if debIDStr != "" {
-		debID, err = strconv.Atoi(debIDStr)
+		debID, err := strconv.Atoi(debIDStr)
 		if err != nil {
-			return nil, err
+			fields.Add("debitor_id", "must be an integer")
 		}
+		req.DebitorID = int64(debID)
 	}
-	req.DebitorID = int64(debID)
 
 	req.TransactionIdentifier = r.URL.Query().Get("transaction_identifier")
+	req.Event = r.URL.Query().Get("event")
+	req.GroupReference = r.URL.Query().Get("group_reference")
 
 	efFrom, err := parseEffectiveDate(r.URL.Query().Get("effective_from"))
 	if err != nil {
-		return nil, err
+		fields.Add("effective_from", "must be a date in the format YYYY-MM-DD")
 	}
-
 	req.EffectiveFrom = efFrom
 
 	efBef, err := parseEffectiveDate(r.URL.Query().Get("effective_before"))
 	if err != nil {
-		return nil, err
+		fields.Add("effective_before", "must be a date in the format YYYY-MM-DD")
 	}
-
 	req.EffectiveBefore = efBef
 
+	if len(fields) > 0 {
+		return nil, apierrors.NewUnprocessableEntity(fields)
+	}
+
 	return &req, nil
 }
 
@@ -157,21 +259,69 @@ func getTransactionsResponseHandler(ctx context.Context, res *GetTransactionsRes
 	}
 
 	if len(res.Payload) == 0 {
-		reqID := logging.GetRequestID(ctx)
 		logger := logging.LoggerFromContext(ctx)
-		common.SendStatusNotFoundResponse(w, reqID, logger, "")
+		common.SendStatusNotFoundResponse(ctx, w, logger, "")
 		return nil
 	}
 
 	return json.NewEncoder(w).Encode(res)
 }
 
+func getTransactionRequestHandler(r *http.Request) (*GetTransactionRequest, error) {
+	transactionID := chi.URLParam(r, "id")
+	if transactionID == "" {
+		return nil, apierrors.NewBadRequest("expected transaction id in url parameter, but received empty value")
+	}
+
+	return &GetTransactionRequest{
+		TransactionIdentifier: transactionID,
+		IfNoneMatch:           r.Header.Get(headers.IfNoneMatch),
+	}, nil
+}
+
+// getTransactionResponseHandler sets a strong ETag calculated from the response body,
+// and answers with 304 if the client already has this version.
+func getTransactionResponseHandler(ctx context.Context, res *GetTransactionResponse, w http.ResponseWriter) error {
+	if res == nil {
+		return common.ErrorFromMessage(common.TransactionReadErrorMessage)
+	}
+
+	body, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	w.Header().Set(headers.ETag, etag)
+
+	if etagMatches(res.ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Header().Set(headers.ContentType, "application/json")
+	_, err = w.Write(append(body, '\n'))
+	return err
+}
+
+// etagMatches implements the weak comparison If-None-Match asks for.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
 var nowFunc = time.Now // needed for tests
 
 func createTransactionRequestHandler(r *http.Request) (*CreateTransactionRequest, error) {
 	var request CreateTransactionRequest
 	if err := json.NewDecoder(r.Body).Decode(&request.Transaction); err != nil {
-		return nil, err
+		return nil, common.BodyParseError(err)
 	}
 
 	if request.Transaction.EffectiveDate == "" {
@@ -199,19 +349,21 @@ func createTransactionResponseHandler(ctx context.Context, res *CreateTransactio
 func updateTransactionRequestHandler(r *http.Request) (*UpdateTransactionRequest, error) {
 	transactionID := chi.URLParam(r, "id")
 	if transactionID == "" {
-		return nil, errors.New("expected transaction id in url parameter, but received empty value")
+		return nil, apierrors.NewBadRequest("expected transaction id in url parameter, but received empty value")
 	}
 
 	var request UpdateTransactionRequest
 	if err := json.NewDecoder(r.Body).Decode(&request.Transaction); err != nil {
-		return nil, err
+		return nil, common.BodyParseError(err)
 	}
 
 	if request.Transaction.TransactionIdentifier == "" {
 		request.Transaction.TransactionIdentifier = transactionID
 	}
 	if request.Transaction.TransactionIdentifier != transactionID {
-		return nil, errors.New("transaction id in payload must match URL parameter")
+		return nil, apierrors.NewUnprocessableEntity(url.Values{
+			"transaction_identifier": {"must match the transaction id in the url"},
+		})
 	}
 
 	if err := validateTransaction(&request.Transaction, false); err != nil {
@@ -231,11 +383,13 @@ func initiatePaymentRequestHandler(r *http.Request) (*InitiatePaymentRequest, er
 	var payReq InitiatePaymentRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&payReq.TransactionInitiator); err != nil {
-		return nil, err
+		return nil, common.BodyParseError(err)
 	}
 
 	if payReq.TransactionInitiator.DebitorID <= 0 {
-		return nil, fmt.Errorf("invalid value %d for debitor. Value must be greater than zero", payReq.TransactionInitiator.DebitorID)
+		return nil, apierrors.NewUnprocessableEntity(url.Values{
+			"debitor_id": {"must be greater than zero"},
+		})
 	}
 
 	return &payReq, nil
@@ -251,35 +405,194 @@ func initiatePaymentResponseHandler(ctx context.Context, res *InitiatePaymentRes
 	return json.NewEncoder(w).Encode(res)
 }
 
+func initiateGroupPaymentRequestHandler(r *http.Request) (*InitiateGroupPaymentRequest, error) {
+	var payReq InitiateGroupPaymentRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&payReq.GroupPaymentInitiator); err != nil {
+		return nil, common.BodyParseError(err)
+	}
+
+	fields := url.Values{}
+	if len(payReq.GroupPaymentInitiator.DebitorIDs) == 0 {
+		fields.Add("debitor_ids", "must not be empty")
+	}
+	if payReq.GroupPaymentInitiator.Method != "" && !payReq.GroupPaymentInitiator.Method.IsValid() {
+		fields.Add("method", common.InvalidPaymentMethodMessage)
+	}
+
+	if len(fields) > 0 {
+		return nil, apierrors.NewUnprocessableEntity(fields)
+	}
+
+	return &payReq, nil
+}
+
+func initiateGroupPaymentResponseHandler(ctx context.Context, res *InitiateGroupPaymentResponse, w http.ResponseWriter) error {
+	if res == nil {
+		return errors.New("invalid response - cannot provide group payment information")
+	}
+	w.Header().Add(headers.Location, fmt.Sprintf("api/rest/v1/transactions?group_reference=%s", url.QueryEscape(res.GroupReference)))
+
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(res)
+}
+
+func updateGroupPaymentRequestHandler(r *http.Request) (*UpdateGroupPaymentRequest, error) {
+	groupReference := chi.URLParam(r, "group_reference")
+	if groupReference == "" {
+		return nil, apierrors.NewBadRequest("expected group reference in url parameter, but received empty value")
+	}
+
+	request := UpdateGroupPaymentRequest{GroupReference: groupReference}
+	if err := json.NewDecoder(r.Body).Decode(&request.GroupPaymentUpdate); err != nil {
+		return nil, common.BodyParseError(err)
+	}
+
+	if !request.GroupPaymentUpdate.Status.IsValid() {
+		return nil, apierrors.NewUnprocessableEntity(url.Values{
+			"status": {common.InvalidTransactionStatusMessage},
+		})
+	}
+
+	return &request, nil
+}
+
+func updateGroupPaymentResponseHandler(ctx context.Context, _ *UpdateGroupPaymentResponse, w http.ResponseWriter) error {
+	// Write status header without content here
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func transferBalanceRequestHandler(r *http.Request) (*TransferBalanceRequest, error) {
+	var request TransferBalanceRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&request.BalanceTransfer); err != nil {
+		return nil, common.BodyParseError(err)
+	}
+
+	transfer := request.BalanceTransfer
+	fields := url.Values{}
+	if transfer.SourceDebitorID <= 0 {
+		fields.Add("source_debitor_id", "must be greater than zero")
+	}
+	if transfer.TargetDebitorID <= 0 {
+		fields.Add("target_debitor_id", "must be greater than zero")
+	}
+	if transfer.GrossCent <= 0 {
+		fields.Add("gross_cent", "must be greater than zero")
+	}
+
+	if len(fields) > 0 {
+		return nil, apierrors.NewUnprocessableEntity(fields)
+	}
+
+	return &request, nil
+}
+
+func transferBalanceResponseHandler(ctx context.Context, res *TransferBalanceResponse, w http.ResponseWriter) error {
+	if res == nil {
+		return errors.New("invalid response - cannot provide balance transfer information")
+	}
+	w.Header().Add(headers.Location, fmt.Sprintf("api/rest/v1/transactions?group_reference=%s", url.QueryEscape(res.Reference)))
+
+	w.WriteHeader(http.StatusCreated)
+	return json.NewEncoder(w).Encode(res)
+}
+
+// validateTransaction checks all fields and reports every problem found, keyed by json field name.
 func validateTransaction(t *Transaction, forbidDeleted bool) error {
+	fields := url.Values{}
+
 	if t.DebitorID <= 0 {
-		return fmt.Errorf("invalid debitor id supplied - DebitorID: %d", t.DebitorID)
+		fields.Add("debitor_id", "must be greater than zero")
 	}
 
 	if !t.TransactionType.IsValid() {
-		return fmt.Errorf("invalid transaction type - TransactionType: %s", url.QueryEscape(string(t.TransactionType)))
+		fields.Add("transaction_type", common.InvalidTransactionTypeMessage)
 	}
 
 	if !t.Method.IsValid() {
-		return fmt.Errorf("invalid payment method - Method: %s", url.QueryEscape(string(t.Method)))
+		fields.Add("method", common.InvalidPaymentMethodMessage)
 	}
 
-	if !t.Status.IsValid() || t.Status == entities.TransactionStatusDeleted && forbidDeleted {
-		return fmt.Errorf("invalid transaction status - Status: %s", url.QueryEscape(string(t.Status)))
+	if forbidDeleted {
+		if !t.Status.IsValid() || t.Status == entities.TransactionStatusDeleted {
+			fields.Add("status", common.InvalidUndeletedStatusMessage)
+		}
+	} else if !t.Status.IsValid() {
+		fields.Add("status", common.InvalidTransactionStatusMessage)
 	}
 
 	_, err := parseEffectiveDate(t.EffectiveDate)
 	if t.EffectiveDate == "" || err != nil {
-		return fmt.Errorf("invalid effective date - EffectiveDate: %s", url.QueryEscape(t.EffectiveDate))
+		fields.Add("effective_date", common.InvalidEffectiveDateMessage)
 	}
 
 	if t.Amount.Currency == "" {
-		return errors.New("empty currency is not allowed")
+		fields.Add("amount.currency", "must not be empty")
 	}
 
 	if t.Amount.GrossCent == 0 {
-		return errors.New("GrossCent cannot be 0, use delete instead")
+		fields.Add("amount.gross_cent", "must not be 0, use delete instead")
+	}
+
+	validateLineItems(t, fields)
+
+	if len(fields) > 0 {
+		return apierrors.NewUnprocessableEntity(fields)
 	}
 
 	return nil
 }
+
+// validateLineItems checks the optional itemization of a due, the line items must add up to its gross amount.
+func validateLineItems(t *Transaction, fields url.Values) {
+	if len(t.LineItems) == 0 {
+		return
+	}
+
+	if t.TransactionType != entities.TransactionTypeDue {
+		fields.Add("line_items", "only allowed for transactions of type due")
+		return
+	}
+
+	var sum int64
+	overflow := false
+	for i, l := range t.LineItems {
+		if strings.TrimSpace(l.Description) == "" {
+			fields.Add(fmt.Sprintf("line_items[%d].description", i), "must not be empty")
+		} else if len(l.Description) > 255 {
+			fields.Add(fmt.Sprintf("line_items[%d].description", i), "must not be longer than 255 characters")
+		}
+		if l.Quantity <= 0 {
+			fields.Add(fmt.Sprintf("line_items[%d].quantity", i), "must be greater than zero")
+		}
+		if l.VatRate < 0 {
+			fields.Add(fmt.Sprintf("line_items[%d].vat_rate", i), "must not be negative")
+		}
+
+		gross, ok := multiplyCents(l.Quantity, l.UnitCent)
+		if !ok || (gross > 0 && sum > math.MaxInt64-gross) || (gross < 0 && sum < math.MinInt64-gross) {
+			fields.Add(fmt.Sprintf("line_items[%d].unit_cent", i), "quantity times unit_cent is out of range")
+			overflow = true
+			continue
+		}
+		sum += gross
+	}
+
+	if !overflow && sum != t.Amount.GrossCent {
+		fields.Add("line_items", fmt.Sprintf("must add up to amount.gross_cent %d, but add up to %d", t.Amount.GrossCent, sum))
+	}
+}
+
+// multiplyCents returns quantity times unitCent, and false if the result does not fit into an int64.
+func multiplyCents(quantity int64, unitCent int64) (int64, bool) {
+	if quantity == 0 || unitCent == 0 {
+		return 0, true
+	}
+	gross := quantity * unitCent
+	if gross/quantity != unitCent || (quantity == -1 && unitCent == math.MinInt64) {
+		return 0, false
+	}
+	return gross, true
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -22,6 +23,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/require"
 
+	"github.com/eurofurence/reg-payment-service/internal/apierrors"
 	"github.com/eurofurence/reg-payment-service/internal/entities"
 	"github.com/eurofurence/reg-payment-service/internal/interaction"
 	"github.com/eurofurence/reg-payment-service/internal/logging"
@@ -337,6 +339,122 @@ func TestHandleTransactions(t *testing.T) {
 	}
 }
 
+func TestHandleTransaction(t *testing.T) {
+	_, err := config.UnmarshalFromYamlConfiguration(strings.NewReader(securityConfig))
+	require.Nil(t, err)
+
+	populate := func(t *testing.T, db database.Repository) {
+		fillDefaultDBValues(t, db)
+
+		tran := newTransaction(1, "1234567890", entities.TransactionTypeDue, entities.PaymentMethodCredit, entities.TransactionStatusTentative, newEffDate(t, "2022-12-01"))
+		require.NoError(t, db.CreateTransactionLog(context.Background(), tran.ToTransactionLog()))
+		tran.Comment = "same status, changed comment"
+		require.NoError(t, db.CreateTransactionLog(context.Background(), tran.ToTransactionLog()))
+		tran.TransactionStatus = entities.TransactionStatusDeleted
+		tran.Deletion = entities.Deletion{Status: entities.TransactionStatusTentative, Comment: "duplicate", By: "admin"}
+		require.NoError(t, db.CreateTransactionLog(context.Background(), tran.ToTransactionLog()))
+	}
+
+	att := &AttendeeServiceMock{
+		ListMyRegistrationIdsFunc: func(ctx context.Context) ([]int64, error) {
+			return []int64{1}, nil
+		},
+	}
+
+	tests := []struct {
+		name            string
+		transactionID   string
+		ctx             context.Context
+		expectedHistory []StatusHistory
+		expectedErr     func(error) bool
+	}{
+		{
+			name:          "Should return own transaction with status history",
+			transactionID: "1234567890",
+			ctx:           attendeeCtx(),
+			expectedHistory: []StatusHistory{
+				{Status: entities.TransactionStatusTentative, Comment: "Comment"},
+				{Status: entities.TransactionStatusDeleted, Comment: "duplicate", ChangedBy: "admin"},
+			},
+		},
+		{
+			name:            "Should return transaction of any debitor to admins",
+			transactionID:   "2234567890",
+			ctx:             adminCtx(),
+			expectedHistory: []StatusHistory{},
+		},
+		{
+			name:          "Should not return transactions of other debitors to users",
+			transactionID: "2234567890",
+			ctx:           attendeeCtx(),
+			expectedErr:   apierrors.IsForbiddenError,
+		},
+		{
+			name:          "Should return not found for unknown transactions",
+			transactionID: "9999999999",
+			ctx:           adminCtx(),
+			expectedErr:   apierrors.IsNotFoundError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := inmemory.NewInMemoryProvider()
+			populate(t, db)
+
+			i, err := interaction.NewServiceInteractor(db, att, &CncrdAdapterMock{})
+			require.NoError(t, err)
+
+			resp, err := MakeGetTransactionEndpoint(i)(tt.ctx, &GetTransactionRequest{TransactionIdentifier: tt.transactionID}, logging.NewNoopLogger())
+			if tt.expectedErr != nil {
+				require.True(t, tt.expectedErr(err), "unexpected error %v", err)
+				require.Nil(t, resp)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.transactionID, resp.Transaction.TransactionIdentifier)
+
+			require.Len(t, resp.Transaction.StatusHistory, len(tt.expectedHistory))
+			for idx, entry := range resp.Transaction.StatusHistory {
+				require.False(t, entry.ChangeDate.IsZero())
+				entry.ChangeDate = time.Time{}
+				require.Equal(t, tt.expectedHistory[idx], entry)
+			}
+		})
+	}
+}
+
+func TestGetTransactionResponseHandler(t *testing.T) {
+	res := &GetTransactionResponse{
+		Transaction: ToV1Transaction(newTransaction(1, "1234567890", entities.TransactionTypeDue, entities.PaymentMethodCredit, entities.TransactionStatusTentative, time.Now())),
+	}
+
+	w := &statusCodeResponseWriter{}
+	require.NoError(t, getTransactionResponseHandler(context.Background(), res, w))
+	require.False(t, w.called, "should use the implicit 200")
+
+	etag := w.Header().Get("ETag")
+	require.Regexp(t, `^"[0-9a-f]{64}"$`, etag)
+
+	var decoded GetTransactionResponse
+	require.NoError(t, json.Unmarshal(w.contents, &decoded))
+	require.Equal(t, res.Transaction.TransactionIdentifier, decoded.Transaction.TransactionIdentifier)
+
+	res.ifNoneMatch = `"other", W/` + etag
+	w = &statusCodeResponseWriter{}
+	require.NoError(t, getTransactionResponseHandler(context.Background(), res, w))
+	require.Equal(t, http.StatusNotModified, w.statusCode)
+	require.Equal(t, etag, w.Header().Get("ETag"))
+	require.Empty(t, w.contents)
+
+	res.Transaction.Status = entities.TransactionStatusValid
+	w = &statusCodeResponseWriter{}
+	require.NoError(t, getTransactionResponseHandler(context.Background(), res, w))
+	require.False(t, w.called)
+	require.NotEqual(t, etag, w.Header().Get("ETag"))
+}
+
 func TestGetTransactionsRequestHandler(t *testing.T) {
 	var testTime time.Time = time.Date(2022, time.January, 10, 0, 0, 0, 0, time.UTC)
 	tests := []struct {
@@ -367,7 +485,7 @@ func TestGetTransactionsRequestHandler(t *testing.T) {
 				params.Add("effective_from", testTime.Format("2006-01-02"))
 				params.Add("effective_before", testTime.Format("2006-01-02"))
 			},
-			expectedError: errors.New("strconv.Atoi: parsing \"Hans\": invalid syntax"),
+			expectedError: errors.New("invalid values for fields: debitor_id"),
 		},
 		{
 			// Effective dates must only be defined by an exact day without time.
@@ -378,7 +496,7 @@ func TestGetTransactionsRequestHandler(t *testing.T) {
 				params.Add("effective_from", testTime.Format("02.01.2006"))
 				params.Add("effective_before", testTime.Format("2006-01-02"))
 			},
-			expectedError: errors.New("parsing time \"10.01.2022\" as \"2006-01-02\": cannot parse"), // truncated because go 1.20 on Windows produces different error message
+			expectedError: errors.New("invalid values for fields: effective_from"),
 		},
 		{
 			// Effective dates must only be defined by an exact day without time.
@@ -389,7 +507,7 @@ func TestGetTransactionsRequestHandler(t *testing.T) {
 				params.Add("effective_from", testTime.Format("2006-01-02"))
 				params.Add("effective_before", testTime.Format("02.01.2006"))
 			},
-			expectedError: errors.New("parsing time \"10.01.2022\" as \"2006-01-02\": cannot parse"), // truncated because go 1.20 on Windows produces different error message
+			expectedError: errors.New("invalid values for fields: effective_before"),
 		},
 		{
 			name: "Should return result when only debitor ID is set",
@@ -550,7 +668,7 @@ func TestCreateTransactionRequestHandler(t *testing.T) {
 			name:  "Should return error when body is empty",
 			input: Transaction{},
 			expected: expected{
-				err: errors.New("EOF"),
+				err: apierrors.NewBadRequest("request body could not be parsed"),
 				req: nil,
 			},
 		},
@@ -558,7 +676,7 @@ func TestCreateTransactionRequestHandler(t *testing.T) {
 			name:  "Should return error when transaction contains invalid debitor ID",
 			input: ToV1Transaction(newTransaction(0, "1230", entities.TransactionTypeDue, entities.PaymentMethodCredit, entities.TransactionStatusTentative, testTime)),
 			expected: expected{
-				err: errors.New("invalid debitor id supplied - DebitorID: 0"),
+				err: apierrors.NewUnprocessableEntity(url.Values{"debitor_id": {"must be greater than zero"}}),
 				req: nil,
 			},
 		},
@@ -566,7 +684,7 @@ func TestCreateTransactionRequestHandler(t *testing.T) {
 			name:  "Should return error when transaction contains invalid type",
 			input: ToV1Transaction(newTransaction(1, "1230", "Kevin", entities.PaymentMethodCredit, entities.TransactionStatusTentative, testTime)),
 			expected: expected{
-				err: errors.New("invalid transaction type - TransactionType: Kevin"),
+				err: apierrors.NewUnprocessableEntity(url.Values{"transaction_type": {common.InvalidTransactionTypeMessage}}),
 				req: nil,
 			},
 		},
@@ -574,7 +692,7 @@ func TestCreateTransactionRequestHandler(t *testing.T) {
 			name:  "Should return error when transaction contains invalid method",
 			input: ToV1Transaction(newTransaction(1, "1230", entities.TransactionTypeDue, "Kevin", entities.TransactionStatusTentative, testTime)),
 			expected: expected{
-				err: errors.New("invalid payment method - Method: Kevin"),
+				err: apierrors.NewUnprocessableEntity(url.Values{"method": {common.InvalidPaymentMethodMessage}}),
 				req: nil,
 			},
 		},
@@ -582,7 +700,7 @@ func TestCreateTransactionRequestHandler(t *testing.T) {
 			name:  "Should return error when transaction contains invalid status",
 			input: ToV1Transaction(newTransaction(1, "1230", entities.TransactionTypeDue, entities.PaymentMethodCredit, "Kevin", testTime)),
 			expected: expected{
-				err: errors.New("invalid transaction status - Status: Kevin"),
+				err: apierrors.NewUnprocessableEntity(url.Values{"status": {common.InvalidUndeletedStatusMessage}}),
 				req: nil,
 			},
 		},
@@ -590,7 +708,7 @@ func TestCreateTransactionRequestHandler(t *testing.T) {
 			name:  "Should return error when transaction status is deleted",
 			input: ToV1Transaction(newTransaction(1, "1230", entities.TransactionTypeDue, entities.PaymentMethodCredit, entities.TransactionStatusDeleted, testTime)),
 			expected: expected{
-				err: errors.New("invalid transaction status - Status: deleted"),
+				err: apierrors.NewUnprocessableEntity(url.Values{"status": {common.InvalidUndeletedStatusMessage}}),
 				req: nil,
 			},
 		},
@@ -625,7 +743,7 @@ func TestCreateTransactionRequestHandler(t *testing.T) {
 			r := httptest.NewRequest(http.MethodPost, "http://example.com/transaction", toTransactionRequestBody(tt.input))
 			req, err := createTransactionRequestHandler(r)
 			if tt.expected.err != nil {
-				require.EqualError(t, err, tt.expected.err.Error())
+				require.Equal(t, tt.expected.err, err)
 				require.Nil(t, req)
 			} else {
 				require.NoError(t, err)
@@ -635,6 +753,104 @@ func TestCreateTransactionRequestHandler(t *testing.T) {
 	}
 }
 
+func TestValidateTransactionLineItems(t *testing.T) {
+	itemized := func(tType entities.TransactionType, items ...LineItem) *Transaction {
+		tran := ToV1Transaction(newTransaction(1, "1230", tType, entities.PaymentMethodCredit, entities.TransactionStatusValid, time.Now()))
+		tran.LineItems = items
+		return &tran
+	}
+
+	membership := LineItem{Description: "Membership", Quantity: 1, UnitCent: 1500, VatRate: 19.0}
+	shirts := LineItem{Description: "T-Shirt", Quantity: 2, UnitCent: 200, VatRate: 7.0}
+
+	tests := []struct {
+		name string
+		tran *Transaction
+		err  error
+	}{
+		{
+			name: "should accept line items adding up to the gross amount",
+			tran: itemized(entities.TransactionTypeDue, membership, shirts),
+		},
+		{
+			name: "should reject line items that do not add up",
+			tran: itemized(entities.TransactionTypeDue, membership),
+			err: apierrors.NewUnprocessableEntity(url.Values{
+				"line_items": {"must add up to amount.gross_cent 1900, but add up to 1500"},
+			}),
+		},
+		{
+			name: "should reject line items on payments",
+			tran: itemized(entities.TransactionTypePayment, membership, shirts),
+			err: apierrors.NewUnprocessableEntity(url.Values{
+				"line_items": {"only allowed for transactions of type due"},
+			}),
+		},
+		{
+			name: "should report invalid lines by index",
+			tran: itemized(entities.TransactionTypeDue, membership, LineItem{Quantity: 0, UnitCent: 400, VatRate: 7.0}),
+			err: apierrors.NewUnprocessableEntity(url.Values{
+				"line_items[1].description": {"must not be empty"},
+				"line_items[1].quantity":    {"must be greater than zero"},
+				"line_items":                {"must add up to amount.gross_cent 1900, but add up to 1500"},
+			}),
+		},
+		{
+			name: "should reject lines whose gross amount overflows",
+			tran: itemized(entities.TransactionTypeDue, membership, LineItem{Description: "Overflow", Quantity: 4, UnitCent: math.MaxInt64/4 + 1}),
+			err: apierrors.NewUnprocessableEntity(url.Values{
+				"line_items[1].unit_cent": {"quantity times unit_cent is out of range"},
+			}),
+		},
+		{
+			name: "should reject lines whose sum overflows",
+			tran: itemized(entities.TransactionTypeDue,
+				LineItem{Description: "Large", Quantity: 1, UnitCent: math.MaxInt64},
+				LineItem{Description: "Wrap", Quantity: 1, UnitCent: math.MaxInt64},
+			),
+			err: apierrors.NewUnprocessableEntity(url.Values{
+				"line_items[1].unit_cent": {"quantity times unit_cent is out of range"},
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTransaction(tt.tran, true)
+			if tt.err != nil {
+				require.Equal(t, tt.err, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestToV1TransactionLineItems(t *testing.T) {
+	due := newTransaction(1, "1230", entities.TransactionTypeDue, entities.PaymentMethodCredit, entities.TransactionStatusValid, time.Now())
+	due.LineItems = []entities.LineItem{
+		{Position: 1, Description: "Membership", Quantity: 1, UnitCent: 1500, VatRate: 19.0},
+		{Position: 2, Description: "T-Shirt", Quantity: 2, UnitCent: 200, VatRate: 7.0},
+	}
+
+	v1 := ToV1Transaction(due)
+	require.Equal(t, []LineItem{
+		{Description: "Membership", Quantity: 1, UnitCent: 1500, VatRate: 19.0},
+		{Description: "T-Shirt", Quantity: 2, UnitCent: 200, VatRate: 7.0},
+	}, v1.LineItems)
+	require.Equal(t, []VatTotal{
+		{VatRate: 7.0, GrossCent: 400, NetCent: 374, VatCent: 26},
+		{VatRate: 19.0, GrossCent: 1500, NetCent: 1261, VatCent: 239},
+	}, v1.VatTotals)
+
+	back, err := ToTransactionEntity(v1)
+	require.NoError(t, err)
+	require.Equal(t, due.LineItems, back.LineItems)
+
+	payment := newTransaction(1, "1231", entities.TransactionTypePayment, entities.PaymentMethodCredit, entities.TransactionStatusValid, time.Now())
+	require.Empty(t, ToV1Transaction(payment).VatTotals)
+}
+
 func TestCreateTransactionResponseHandler(t *testing.T) {
 	type expected struct {
 		err        error
@@ -722,7 +938,7 @@ func TestUpdateTransactionRequestHandler(t *testing.T) {
 				transaction:   ToV1Transaction(newTransaction(1, "1234", entities.TransactionTypeDue, entities.PaymentMethodCredit, entities.TransactionStatusPending, testTime)),
 			},
 			expected: expected{
-				err: errors.New("expected transaction id in url parameter, but received empty value"),
+				err: apierrors.NewBadRequest("expected transaction id in url parameter, but received empty value"),
 				req: nil,
 			},
 		},
@@ -733,7 +949,7 @@ func TestUpdateTransactionRequestHandler(t *testing.T) {
 				transaction:   ToV1Transaction(newTransaction(0, "1234", entities.TransactionTypeDue, entities.PaymentMethodCredit, entities.TransactionStatusPending, testTime)),
 			},
 			expected: expected{
-				err: errors.New("invalid debitor id supplied - DebitorID: 0"),
+				err: apierrors.NewUnprocessableEntity(url.Values{"debitor_id": {"must be greater than zero"}}),
 				req: nil,
 			},
 		},
@@ -744,7 +960,13 @@ func TestUpdateTransactionRequestHandler(t *testing.T) {
 				transaction:   Transaction{DebitorID: 10, Status: entities.TransactionStatusPending, PaymentStartUrl: "12398"},
 			},
 			expected: expected{
-				err: errors.New("invalid transaction type - TransactionType: "),
+				err: apierrors.NewUnprocessableEntity(url.Values{
+					"transaction_type":  {common.InvalidTransactionTypeMessage},
+					"method":            {common.InvalidPaymentMethodMessage},
+					"effective_date":    {common.InvalidEffectiveDateMessage},
+					"amount.currency":   {"must not be empty"},
+					"amount.gross_cent": {"must not be 0, use delete instead"},
+				}),
 				req: nil,
 			},
 		},
@@ -799,7 +1021,7 @@ func TestUpdateTransactionRequestHandler(t *testing.T) {
 
 			req, err := updateTransactionRequestHandler(r)
 			if tt.expected.err != nil {
-				require.EqualError(t, err, tt.expected.err.Error())
+				require.Equal(t, tt.expected.err, err)
 				require.Nil(t, req)
 			} else {
 				require.NoError(t, err)
@@ -850,7 +1072,7 @@ func TestInitiatePaymentRequestHandler(t *testing.T) {
 				debitorID: 0,
 			},
 			expected: expected{
-				err: errors.New("invalid value 0 for debitor. Value must be greater than zero"),
+				err: apierrors.NewUnprocessableEntity(url.Values{"debitor_id": {"must be greater than zero"}}),
 				req: nil,
 			},
 		},
@@ -860,7 +1082,7 @@ func TestInitiatePaymentRequestHandler(t *testing.T) {
 				debitorID: -1,
 			},
 			expected: expected{
-				err: errors.New("invalid value -1 for debitor. Value must be greater than zero"),
+				err: apierrors.NewUnprocessableEntity(url.Values{"debitor_id": {"must be greater than zero"}}),
 				req: nil,
 			},
 		},
@@ -870,7 +1092,7 @@ func TestInitiatePaymentRequestHandler(t *testing.T) {
 				withoutID: true,
 			},
 			expected: expected{
-				err: io.EOF,
+				err: apierrors.NewBadRequest("request body could not be parsed"),
 				req: nil,
 			},
 		},
@@ -887,7 +1109,7 @@ func TestInitiatePaymentRequestHandler(t *testing.T) {
 				},
 			},
 			expected: expected{
-				err: errors.New("invalid value 0 for debitor. Value must be greater than zero"),
+				err: apierrors.NewUnprocessableEntity(url.Values{"debitor_id": {"must be greater than zero"}}),
 				req: nil,
 			},
 		},
@@ -898,7 +1120,7 @@ func TestInitiatePaymentRequestHandler(t *testing.T) {
 				customJSON: []byte(`invalid json`),
 			},
 			expected: expected{
-				err: errors.New("invalid character 'i' looking for beginning of value"),
+				err: apierrors.NewBadRequest("request body could not be parsed"),
 				req: nil,
 			},
 		},
@@ -939,7 +1161,7 @@ func TestInitiatePaymentRequestHandler(t *testing.T) {
 
 			req, err := initiatePaymentRequestHandler(r)
 			if tt.expected.err != nil {
-				require.EqualError(t, err, tt.expected.err.Error())
+				require.Equal(t, tt.expected.err, err)
 				require.Nil(t, req)
 			} else {
 				require.NoError(t, err)
@@ -953,6 +1175,160 @@ func TestInitiatePaymentResponseHandler(t *testing.T) {
 	// TODO
 }
 
+func TestInitiateGroupPaymentRequestHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		req  *InitiateGroupPaymentRequest
+		err  error
+	}{
+		{
+			name: "should successfully create request with valid data",
+			body: `{"debitor_ids": [10, 11], "method": "transfer"}`,
+			req: &InitiateGroupPaymentRequest{
+				GroupPaymentInitiator: GroupPaymentInitiator{
+					DebitorIDs: []int64{10, 11},
+					Method:     entities.PaymentMethodTransfer,
+				},
+			},
+		},
+		{
+			name: "should report missing debitors and an invalid method",
+			body: `{"method": "barter"}`,
+			err: apierrors.NewUnprocessableEntity(url.Values{
+				"debitor_ids": {"must not be empty"},
+				"method":      {common.InvalidPaymentMethodMessage},
+			}),
+		},
+		{
+			name: "should fail for debitor ids of the wrong type",
+			body: `{"debitor_ids": "10,11"}`,
+			err: apierrors.NewBadRequest("request body could not be parsed").
+				WithField("debitor_ids", "must be of type array"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://example.com/transactions/initiate-group-payment", strings.NewReader(tt.body))
+
+			req, err := initiateGroupPaymentRequestHandler(r)
+			if tt.err != nil {
+				require.Equal(t, tt.err, err)
+				require.Nil(t, req)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.req, req)
+			}
+		})
+	}
+}
+
+func TestUpdateGroupPaymentRequestHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		groupReference string
+		body           string
+		req            *UpdateGroupPaymentRequest
+		err            error
+	}{
+		{
+			name:           "should successfully create request with valid data",
+			groupReference: "EF-G000010-0101-120000-1234",
+			body:           `{"status": "valid", "comment": "paid", "payment_processor_information": {"paylink_id": "42"}}`,
+			req: &UpdateGroupPaymentRequest{
+				GroupReference: "EF-G000010-0101-120000-1234",
+				GroupPaymentUpdate: GroupPaymentUpdate{
+					Status:  entities.TransactionStatusValid,
+					Comment: "paid",
+					Info:    PaymentProcessorInformation{"paylink_id": "42"},
+				},
+			},
+		},
+		{
+			name: "should fail without group reference",
+			body: `{"status": "valid"}`,
+			err:  apierrors.NewBadRequest("expected group reference in url parameter, but received empty value"),
+		},
+		{
+			name:           "should fail for an invalid status",
+			groupReference: "EF-G000010-0101-120000-1234",
+			body:           `{"status": "paid"}`,
+			err:            apierrors.NewUnprocessableEntity(url.Values{"status": {common.InvalidTransactionStatusMessage}}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "http://example.com/transactions/groups/{group_reference}", strings.NewReader(tt.body))
+			ctx := chi.NewRouteContext()
+			ctx.URLParams.Add("group_reference", tt.groupReference)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, ctx))
+
+			req, err := updateGroupPaymentRequestHandler(r)
+			if tt.err != nil {
+				require.Equal(t, tt.err, err)
+				require.Nil(t, req)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.req, req)
+			}
+		})
+	}
+}
+
+func TestTransferBalanceRequestHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		req  *TransferBalanceRequest
+		err  error
+	}{
+		{
+			name: "should successfully create request with valid data",
+			body: `{"source_debitor_id": 10, "target_debitor_id": 11, "gross_cent": 5000, "comment": "merged registrations"}`,
+			req: &TransferBalanceRequest{
+				BalanceTransfer: BalanceTransfer{
+					SourceDebitorID: 10,
+					TargetDebitorID: 11,
+					GrossCent:       5000,
+					Comment:         "merged registrations",
+				},
+			},
+		},
+		{
+			name: "should report missing debitors and amount",
+			body: `{"comment": "nothing"}`,
+			err: apierrors.NewUnprocessableEntity(url.Values{
+				"source_debitor_id": {"must be greater than zero"},
+				"target_debitor_id": {"must be greater than zero"},
+				"gross_cent":        {"must be greater than zero"},
+			}),
+		},
+		{
+			name: "should fail for an amount of the wrong type",
+			body: `{"source_debitor_id": 10, "target_debitor_id": 11, "gross_cent": "50.00"}`,
+			err: apierrors.NewBadRequest("request body could not be parsed").
+				WithField("gross_cent", "must be of type integer"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://example.com/transactions/transfer-balance", strings.NewReader(tt.body))
+
+			req, err := transferBalanceRequestHandler(r)
+			if tt.err != nil {
+				require.Equal(t, tt.err, err)
+				require.Nil(t, req)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.req, req)
+			}
+		})
+	}
+}
+
 func toTransactionRequestBody(req Transaction) io.Reader {
 	if reflect.ValueOf(req).IsZero() {
 		return nil
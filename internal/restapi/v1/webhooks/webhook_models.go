@@ -0,0 +1,13 @@
+package v1webhooks
+
+type (
+	// RegistrationsChangedRequest tells us which subject's registrations have changed.
+	//
+	// If Subject is empty, the registrations of all subjects are considered changed.
+	RegistrationsChangedRequest struct {
+		Subject string `json:"subject"`
+	}
+
+	// RegistrationsChangedResponse is empty, the endpoint responds with 204
+	RegistrationsChangedResponse struct{}
+)
@@ -0,0 +1,50 @@
+package v1webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/eurofurence/reg-payment-service/internal/interaction"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+	"github.com/eurofurence/reg-payment-service/internal/restapi/common"
+)
+
+func Create(router chi.Router, i interaction.Interactor) {
+	router.Post("/registrations-changed",
+		common.CreateHandler(
+			MakeRegistrationsChangedEndpoint(i),
+			registrationsChangedRequestHandler,
+			registrationsChangedResponseHandler),
+	)
+}
+
+func MakeRegistrationsChangedEndpoint(i interaction.Interactor) common.Endpoint[RegistrationsChangedRequest, RegistrationsChangedResponse] {
+	return func(ctx context.Context, request *RegistrationsChangedRequest, logger logging.Logger) (*RegistrationsChangedResponse, error) {
+		if err := i.RegistrationsChanged(ctx, request.Subject); err != nil {
+			return nil, err
+		}
+
+		return &RegistrationsChangedResponse{}, nil
+	}
+}
+
+func registrationsChangedRequestHandler(r *http.Request) (*RegistrationsChangedRequest, error) {
+	var request RegistrationsChangedRequest
+
+	// the body is optional - no body means all subjects
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+func registrationsChangedResponseHandler(ctx context.Context, _ *RegistrationsChangedResponse, w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
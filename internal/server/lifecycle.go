@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+)
+
+const defaultDrainTimeout = 5 * time.Second
+
+// Hook is a component whose lifetime is managed by the Lifecycle.
+//
+// Start must not block, long running work is started in a goroutine. Stop must return once the
+// component has finished its work, or when ctx is done. Both are optional.
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// Lifecycle starts the registered components in order, and stops them in reverse order.
+//
+// Register the components before the ones that use them, e.g. the database before the http server,
+// so the http server is stopped and has drained all requests before the database pool is closed.
+type Lifecycle struct {
+	logger       logging.Logger
+	delay        time.Duration
+	drainTimeout time.Duration
+
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+	servers int
+
+	shuttingDown atomic.Bool
+	failed       chan error
+}
+
+func NewLifecycle(conf *config.ServerConfig, logger logging.Logger) *Lifecycle {
+	drainTimeout := time.Duration(conf.ShutdownDrainTimeout) * time.Second
+	if drainTimeout == 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+
+	return &Lifecycle{
+		logger:       logger,
+		delay:        time.Duration(conf.ShutdownDelay) * time.Second,
+		drainTimeout: drainTimeout,
+		failed:       make(chan error, 1),
+	}
+}
+
+func (l *Lifecycle) Register(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.hooks = append(l.hooks, hook)
+}
+
+// RegisterServer registers an http server. It is stopped gracefully, waiting for in-flight requests.
+func (l *Lifecycle) RegisterServer(name string, srv *http.Server) {
+	l.mu.Lock()
+	l.servers++
+	l.mu.Unlock()
+
+	l.Register(Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			// listen right away, so a port that is in use fails the start
+			listener, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+					l.Fail(fmt.Errorf("%s closed unexpectedly: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		Stop: srv.Shutdown,
+	})
+}
+
+// Start starts all registered components in order. If one fails, the ones already started are stopped again.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	if err := l.startHooks(ctx); err != nil {
+		_ = l.Shutdown()
+		return err
+	}
+	return nil
+}
+
+func (l *Lifecycle) startHooks(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, hook := range l.hooks {
+		if hook.Start != nil {
+			l.logger.Debug("starting %s", hook.Name)
+			if err := hook.Start(ctx); err != nil {
+				return fmt.Errorf("failed to start %s: %w", hook.Name, err)
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Fail reports that a component stopped unexpectedly. Only the first failure is kept.
+func (l *Lifecycle) Fail(err error) {
+	select {
+	case l.failed <- err:
+	default:
+	}
+}
+
+// Failed receives the error of the first component that stopped unexpectedly.
+func (l *Lifecycle) Failed() <-chan error {
+	return l.failed
+}
+
+// ShuttingDown is true once the shutdown has begun. The readiness check fails from then on.
+func (l *Lifecycle) ShuttingDown() bool {
+	return l.shuttingDown.Load()
+}
+
+// Shutdown stops all started components in reverse order.
+//
+// The readiness check fails right away, and the configured delay gives the load balancer time
+// to notice before the components are stopped. Without servers, e.g. when running a command, there
+// is nothing to route requests to, so there is no delay. All components share the drain timeout.
+func (l *Lifecycle) Shutdown() error {
+	if !l.shuttingDown.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.delay > 0 && l.servers > 0 {
+		l.logger.Info("shutting down in %v, readiness check now fails", l.delay)
+		time.Sleep(l.delay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), l.drainTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := l.started - 1; i >= 0; i-- {
+		hook := l.hooks[i]
+		if hook.Stop == nil {
+			continue
+		}
+
+		l.logger.Info("stopping %s", hook.Name)
+		if err := hook.Stop(ctx); err != nil {
+			l.logger.Error("couldn't stop %s gracefully. [error]: %v", hook.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", hook.Name, err))
+		}
+	}
+	l.started = 0
+
+	return errors.Join(errs...)
+}
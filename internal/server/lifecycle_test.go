@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/eurofurence/reg-payment-service/internal/config"
+	"github.com/eurofurence/reg-payment-service/internal/logging"
+)
+
+func tstLifecycle() *Lifecycle {
+	return NewLifecycle(&config.ServerConfig{ShutdownDrainTimeout: 1}, logging.NewNoopLogger())
+}
+
+func tstRecordingHook(name string, calls *[]string, startErr error) Hook {
+	return Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			*calls = append(*calls, "start "+name)
+			return startErr
+		},
+		Stop: func(ctx context.Context) error {
+			*calls = append(*calls, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestLifecycleOrder(t *testing.T) {
+	calls := make([]string, 0)
+	l := tstLifecycle()
+	l.Register(tstRecordingHook("database", &calls, nil))
+	l.Register(Hook{Name: "no hooks"})
+	l.Register(tstRecordingHook("http server", &calls, nil))
+
+	require.NoError(t, l.Start(context.Background()))
+	require.False(t, l.ShuttingDown())
+
+	require.NoError(t, l.Shutdown())
+	require.True(t, l.ShuttingDown())
+	require.Equal(t, []string{"start database", "start http server", "stop http server", "stop database"}, calls)
+
+	// a second shutdown does nothing
+	require.NoError(t, l.Shutdown())
+	require.Len(t, calls, 4)
+}
+
+func TestLifecycleStartFailure(t *testing.T) {
+	calls := make([]string, 0)
+	l := tstLifecycle()
+	l.Register(tstRecordingHook("database", &calls, nil))
+	l.Register(tstRecordingHook("http server", &calls, errors.New("address already in use")))
+	l.Register(tstRecordingHook("never started", &calls, nil))
+
+	err := l.Start(context.Background())
+	require.EqualError(t, err, "failed to start http server: address already in use")
+	require.Equal(t, []string{"start database", "start http server", "stop database"}, calls)
+}
+
+func TestLifecycleWithoutServersStopsRightAway(t *testing.T) {
+	calls := make([]string, 0)
+	l := NewLifecycle(&config.ServerConfig{ShutdownDelay: 60, ShutdownDrainTimeout: 1}, logging.NewNoopLogger())
+	l.Register(tstRecordingHook("database", &calls, nil))
+
+	require.NoError(t, l.Start(context.Background()))
+
+	started := time.Now()
+	require.NoError(t, l.Shutdown())
+	require.Less(t, time.Since(started), time.Second)
+	require.Equal(t, []string{"start database", "stop database"}, calls)
+}
+
+func TestLifecycleDrainsServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	requestStarted := make(chan struct{})
+	finishRequest := make(chan struct{})
+	srv := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(requestStarted)
+			<-finishRequest
+			w.WriteHeader(http.StatusNoContent)
+		}),
+	}
+
+	l := tstLifecycle()
+	l.RegisterServer("http server", srv)
+	require.NoError(t, l.Start(context.Background()))
+
+	status := make(chan int)
+	go func() {
+		resp, err := http.Get("http://" + addr)
+		if err != nil {
+			status <- 0
+			return
+		}
+		_ = resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-requestStarted
+
+	shutdownDone := make(chan error)
+	go func() {
+		shutdownDone <- l.Shutdown()
+	}()
+
+	// the in-flight request is completed before the shutdown returns
+	close(finishRequest)
+	require.Equal(t, http.StatusNoContent, <-status)
+	require.NoError(t, <-shutdownDone)
+
+	select {
+	case err := <-l.Failed():
+		t.Fatalf("unexpected failure %v", err)
+	default:
+	}
+}
@@ -12,8 +12,15 @@ import (
 	"github.com/eurofurence/reg-payment-service/internal/config"
 	"github.com/eurofurence/reg-payment-service/internal/interaction"
 	"github.com/eurofurence/reg-payment-service/internal/restapi/middleware"
+	v1auditlog "github.com/eurofurence/reg-payment-service/internal/restapi/v1/auditlog"
+	v1documents "github.com/eurofurence/reg-payment-service/internal/restapi/v1/documents"
 	v1health "github.com/eurofurence/reg-payment-service/internal/restapi/v1/health"
+	v1installments "github.com/eurofurence/reg-payment-service/internal/restapi/v1/installments"
+	v1ledger "github.com/eurofurence/reg-payment-service/internal/restapi/v1/ledger"
+	v1overpayments "github.com/eurofurence/reg-payment-service/internal/restapi/v1/overpayments"
+	v1sepaqr "github.com/eurofurence/reg-payment-service/internal/restapi/v1/sepaqr"
 	v1transactions "github.com/eurofurence/reg-payment-service/internal/restapi/v1/transactions"
+	v1webhooks "github.com/eurofurence/reg-payment-service/internal/restapi/v1/webhooks"
 
 	"context"
 	"net"
@@ -22,6 +29,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func NewServer(ctx context.Context, conf *config.ServerConfig, router http.Handler) *http.Server {
@@ -38,25 +46,54 @@ func NewServer(ctx context.Context, conf *config.ServerConfig, router http.Handl
 	}
 }
 
-func CreateRouter(i interaction.Interactor, conf config.SecurityConfig) chi.Router {
+// NewMetricsServer serves the prometheus metrics on its own port, so they are not exposed
+// together with the api.
+func NewMetricsServer(ctx context.Context, conf *config.ServerConfig) *http.Server {
+	router := chi.NewRouter()
+	router.Handle("/metrics", promhttp.Handler())
+
+	return &http.Server{
+		Addr:         fmt.Sprintf("%s:%d", conf.BaseAddress, conf.MetricsPort),
+		Handler:      router,
+		ReadTimeout:  time.Second * time.Duration(conf.ReadTimeout),
+		WriteTimeout: time.Second * time.Duration(conf.WriteTimeout),
+		IdleTimeout:  time.Second * time.Duration(conf.IdleTimeout),
+		BaseContext: func(l net.Listener) context.Context {
+			return ctx
+		},
+	}
+}
+
+func CreateRouter(i interaction.Interactor, conf config.SecurityConfig, lifecycle *Lifecycle) chi.Router {
 	router := chi.NewRouter()
 
 	router.Use(chimiddleware.Recoverer)
+	router.Use(middleware.MetricsMiddleware)
 	router.Use(middleware.RequestIdMiddleware)
+	router.Use(middleware.TracingMiddleware)
+	router.Use(middleware.RequestInfoMiddleware)
 	router.Use(loggermiddleware.AddZerologLoggerToContext)
 	router.Use(middleware.RequestLoggerMiddleware)
 	router.Use(middleware.CorsHeadersMiddleware(&conf))
 	router.Use(middleware.CheckRequestAuthorization(&conf))
+	router.Use(middleware.RateLimitMiddleware(&conf))
 
-	setupV1Routes(router, i, conf)
+	setupV1Routes(router, i, conf, lifecycle)
 
 	return router
 }
 
-func setupV1Routes(router chi.Router, i interaction.Interactor, conf config.SecurityConfig) {
-	v1health.Create(router)
+func setupV1Routes(router chi.Router, i interaction.Interactor, conf config.SecurityConfig, lifecycle *Lifecycle) {
+	v1health.Create(router, i, lifecycle.ShuttingDown)
 
 	router.Route("/api/rest/v1", func(r chi.Router) {
 		v1transactions.Create(r, i)
+		v1webhooks.Create(r, i)
+		v1auditlog.Create(r, i)
+		v1ledger.Create(r, i)
+		v1installments.Create(r, i)
+		v1overpayments.Create(r, i)
+		v1documents.Create(r, i)
+		v1sepaqr.Create(r, i)
 	})
 }
@@ -0,0 +1,79 @@
+// Package tracing sets up OpenTelemetry tracing and W3C trace context propagation.
+//
+// Spans are always started through the global tracer provider. Unless an exporter is configured,
+// that is the no-op provider, so an incoming traceparent is still passed on to downstream services.
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/eurofurence/reg-payment-service/internal/config"
+)
+
+const instrumentationName = "github.com/eurofurence/reg-payment-service"
+
+// tracesPath is where an otlp/http collector receives spans, relative to its base url
+const tracesPath = "/v1/traces"
+
+// Setup installs the propagator and, if an exporter is configured, a tracer provider that exports to it.
+//
+// The returned function flushes all pending spans, call it before the service exits.
+func Setup(ctx context.Context, conf config.TracingConfig, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch conf.Exporter {
+	case config.TracingOtlp:
+		// the endpoint is the base url of the collector, and WithEndpointURL takes the path as given
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(conf.Endpoint+tracesPath))
+	case config.TracingStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case config.TracingNone, "":
+		return func(context.Context) error { return nil }, nil
+	default:
+		err = errors.New("unknown tracing exporter " + string(conf.Exporter))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span as a child of the span in ctx, if any.
+func StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// EndSpan marks the span as failed if err is set, and ends it.
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}